@@ -5,6 +5,7 @@ import (
 	"github.com/AntonRadchenko/mini-redis-go/internal/config"
 	"github.com/AntonRadchenko/mini-redis-go/internal/logx"
 	"github.com/AntonRadchenko/mini-redis-go/internal/server"
+	"os"
 	"os/signal"
 	"syscall"
 )
@@ -12,15 +13,24 @@ import (
 // main — точка входа в приложение.
 // Здесь мы загружаем конфигурацию, создаём сервер и запускаем его.
 // Сервер внутри сам обрабатывает SIGINT/SIGTERM и завершает работу корректно.
+// Если сервер не удалось запустить (повреждённые данные на диске, неверные настройки),
+// процесс завершается с кодом 1, чтобы супервизор увидел ошибку.
 func main() {
 
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
 	cfg := config.Load()
-	s := server.NewServer(cfg.Addr)
+	s, err := server.NewServer(cfg)
+	if err != nil {
+		logx.Error("failed to start server: %v", err)
+		stop()
+		os.Exit(1)
+	}
 
 	if err := s.Run(ctx); err != nil {
 		logx.Error("server stopped with error: %v", err)
+		stop()
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,313 @@
+package aof
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AntonRadchenko/mini-redis-go/internal/resp"
+)
+
+// aof — это журнал команд (append-only file): каждая изменяющая команда
+// дописывается в конец файла в том же RESP-виде, в каком её присылает клиент.
+// При старте сервер "проигрывает" журнал заново и получает то же состояние хранилища.
+
+// Политики fsync — как часто данные из буфера ОС принудительно сбрасываются на диск.
+const (
+	FsyncAlways   = "always"   // после каждой команды (надёжно, но медленно)
+	FsyncEverySec = "everysec" // раз в секунду в фоне (теряем не больше секунды записей)
+	FsyncNo       = "no"       // никогда — решает ОС
+)
+
+// структура AOF — открытый на дозапись журнал команд.
+type AOF struct {
+	mu     sync.Mutex
+	path   string
+	f      *os.File
+	policy string
+	dirty  bool  // есть записи, ещё не сброшенные на диск через fsync
+	size   int64 // текущий размер файла в байтах
+
+	rewriting  bool     // идёт фоновая перезапись журнала
+	rewriteBuf [][]byte // команды, пришедшие во время перезаписи (допишутся в новый файл)
+
+	stop chan struct{} // закрывается в Close, чтобы остановить фоновый fsync
+	done chan struct{} // закрывается, когда фоновый fsync завершился
+}
+
+// конструктор Open открывает (или создаёт) файл журнала на дозапись
+// и, для политики everysec, запускает фоновую горутину fsync.
+func Open(path, policy string) (*AOF, error) {
+	switch policy {
+	case FsyncAlways, FsyncEverySec, FsyncNo:
+	default:
+		return nil, fmt.Errorf("aof: unknown fsync policy %q", policy)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	a := &AOF{
+		path:   path,
+		f:      f,
+		size:   info.Size(),
+		policy: policy,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if policy == FsyncEverySec {
+		go a.syncLoop()
+	} else {
+		close(a.done)
+	}
+	return a, nil
+}
+
+// метод Append - дописывает команду в конец журнала.
+func (a *AOF) Append(args []string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	data := resp.EncodeCommand(args)
+	if a.rewriting {
+		a.rewriteBuf = append(a.rewriteBuf, data)
+	}
+	n, err := a.f.Write(data)
+	a.size += int64(n)
+	if err != nil {
+		return err
+	}
+	if a.policy == FsyncAlways {
+		return a.f.Sync()
+	}
+	a.dirty = true
+	return nil
+}
+
+// метод Size - возвращает текущий размер журнала в байтах.
+func (a *AOF) Size() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.size
+}
+
+// ErrRewriteInProgress возвращает BeginRewrite, если перезапись уже идёт.
+var ErrRewriteInProgress = errors.New("aof: rewrite already in progress")
+
+// метод BeginRewrite - начинает перезапись журнала: с этого момента все новые команды
+// не только пишутся в текущий файл, но и копятся в буфере, чтобы попасть в новый файл.
+// Вызывающий должен снять копию хранилища в тот же момент (пока никакие записи не идут),
+// а затем передать её в FinishRewrite.
+func (a *AOF) BeginRewrite() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.rewriting {
+		return ErrRewriteInProgress
+	}
+	a.rewriting = true
+	a.rewriteBuf = nil
+	return nil
+}
+
+// метод FinishRewrite - записывает минимальный журнал cmds во временный файл,
+// дописывает туда накопленные за время перезаписи команды и атомарно подменяет им старый журнал.
+// Основная запись идёт без блокировки, так что клиенты продолжают работать;
+// мьютекс берётся только на дозапись буфера и переименование файла.
+func (a *AOF) FinishRewrite(cmds [][]string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(a.path), "temp-rewriteaof-*.aof")
+	if err != nil {
+		a.AbortRewrite()
+		return err
+	}
+	fail := func(err error) error {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		a.AbortRewrite()
+		return err
+	}
+
+	if err := tmp.Chmod(0o644); err != nil {
+		return fail(err)
+	}
+	bw := bufio.NewWriter(tmp)
+	var size int64
+	for _, cmd := range cmds {
+		n, err := bw.Write(resp.EncodeCommand(cmd))
+		size += int64(n)
+		if err != nil {
+			return fail(err)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return fail(err)
+	}
+
+	a.mu.Lock()
+	err = a.swapLocked(tmp, size)
+	a.mu.Unlock()
+	if err != nil {
+		return fail(err)
+	}
+	return nil
+}
+
+// метод swapLocked - дописывает в новый файл накопленные команды, сбрасывает его на диск
+// и ставит на место старого журнала. Вызывается под a.mu, поэтому ни одна команда не теряется.
+func (a *AOF) swapLocked(tmp *os.File, size int64) error {
+	for _, data := range a.rewriteBuf {
+		n, err := tmp.Write(data)
+		size += int64(n)
+		if err != nil {
+			return err
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), a.path); err != nil {
+		return err
+	}
+
+	// новый файл уже на месте старого — дальше пишем в него
+	a.f.Close()
+	a.f = tmp
+	a.size = size
+	a.dirty = false
+	a.rewriting = false
+	a.rewriteBuf = nil
+	return nil
+}
+
+// метод AbortRewrite - отменяет перезапись: буфер выбрасывается, старый журнал остаётся как был.
+func (a *AOF) AbortRewrite() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rewriting = false
+	a.rewriteBuf = nil
+}
+
+// метод syncLoop - раз в секунду сбрасывает журнал на диск (политика everysec).
+func (a *AOF) syncLoop() {
+	defer close(a.done)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.stop:
+			return
+		case <-ticker.C:
+			a.mu.Lock()
+			if a.dirty {
+				_ = a.f.Sync()
+				a.dirty = false
+			}
+			a.mu.Unlock()
+		}
+	}
+}
+
+// метод Close - останавливает фоновый fsync, сбрасывает остаток на диск и закрывает файл.
+func (a *AOF) Close() error {
+	close(a.stop)
+	<-a.done
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.f.Sync(); err != nil {
+		a.f.Close()
+		return err
+	}
+	return a.f.Close()
+}
+
+// функция Replay - читает журнал path и вызывает apply для каждой команды по порядку.
+// Если последняя запись оборвана (сервер упал посреди записи), она отбрасывается,
+// а файл обрезается до последней целой команды — сервер при этом стартует нормально.
+// Команды транзакции (MULTI ... EXEC) применяются только целиком после EXEC, сами MULTI и EXEC
+// в apply не передаются; незавершённая транзакция в конце журнала отбрасывается так же, как оборванная запись.
+// Возвращает количество проигранных команд. Отсутствие файла — не ошибка.
+func Replay(path string, apply func(args []string)) (int, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	rd := resp.NewReader(f)
+	var offset int64 // сколько байт занимают уже прочитанные целые команды
+	count := 0
+	var tx [][]string // команды открытой транзакции (nil — транзакции нет)
+	var txStart int64 // смещение её MULTI
+	for offset < info.Size() {
+		args, err := rd.ReadArray()
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return count, fmt.Errorf("aof: bad format at offset %d: %w", offset, err)
+		}
+		// мы сами записывали команды через EncodeCommand, поэтому длина
+		// повторно закодированной команды равна числу прочитанных байт
+		size := int64(len(resp.EncodeCommand(args)))
+		if len(args) == 0 {
+			// пустая запись (*0) — команды в ней нет, пропускаем
+			offset += size
+			continue
+		}
+
+		switch cmd := strings.ToUpper(args[0]); {
+		case cmd == "MULTI":
+			tx, txStart = [][]string{}, offset
+		case tx != nil && cmd == "EXEC":
+			for _, a := range tx {
+				apply(a)
+			}
+			count += len(tx)
+			tx = nil
+		case tx != nil:
+			tx = append(tx, args)
+		default:
+			apply(args)
+			count++
+		}
+		offset += size
+	}
+
+	if tx != nil {
+		offset = txStart
+	}
+	if offset < info.Size() {
+		// файл закончился посреди команды или транзакции — обрезаем хвост
+		if err := os.Truncate(path, offset); err != nil {
+			return count, err
+		}
+		return count, ErrTruncated
+	}
+	return count, nil
+}
+
+// ErrTruncated возвращает Replay, когда оборванная последняя запись была отброшена.
+// Это предупреждение, а не фатальная ошибка: все целые команды уже применены.
+var ErrTruncated = errors.New("aof: truncated last record was discarded")
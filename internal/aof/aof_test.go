@@ -0,0 +1,173 @@
+package aof
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/AntonRadchenko/mini-redis-go/internal/resp"
+)
+
+// проверяет, что записанные команды проигрываются в том же порядке
+func TestAppendReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+
+	a, err := Open(path, FsyncAlways)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cmds := [][]string{
+		{"SET", "name", "anton"},
+		{"SET", "empty", ""},
+		{"DEL", "name"},
+	}
+	for _, c := range cmds {
+		if err := a.Append(c); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := a.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got [][]string
+	n, err := Replay(path, func(args []string) { got = append(got, args) })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != len(cmds) || !reflect.DeepEqual(got, cmds) {
+		t.Errorf("expected %v, got %v", cmds, got)
+	}
+}
+
+// проверяет, что оборванная последняя запись отбрасывается, а файл обрезается
+func TestReplayTruncated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	data := "*3\r\n$3\r\nSET\r\n$1\r\na\r\n$1\r\n1\r\n" + "*3\r\n$3\r\nSET\r\n$1\r\nb"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got [][]string
+	n, err := Replay(path, func(args []string) { got = append(got, args) })
+	if !errors.Is(err, ErrTruncated) {
+		t.Fatalf("expected ErrTruncated, got %v", err)
+	}
+	if n != 1 || !reflect.DeepEqual(got, [][]string{{"SET", "a", "1"}}) {
+		t.Errorf("unexpected replayed commands: %v", got)
+	}
+
+	info, _ := os.Stat(path)
+	if info.Size() != int64(len("*3\r\n$3\r\nSET\r\n$1\r\na\r\n$1\r\n1\r\n")) {
+		t.Errorf("expected file to be truncated to the last full record, size=%d", info.Size())
+	}
+}
+
+// проверяет, что пустая запись (*0) пропускается и не роняет проигрывание журнала
+func TestReplayEmptyRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	data := "*0\r\n" + "*3\r\n$3\r\nSET\r\n$1\r\na\r\n$1\r\n1\r\n" + "*0\r\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got [][]string
+	n, err := Replay(path, func(args []string) { got = append(got, args) })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 1 || !reflect.DeepEqual(got, [][]string{{"SET", "a", "1"}}) {
+		t.Errorf("unexpected replayed commands: %v", got)
+	}
+	if info, _ := os.Stat(path); info.Size() != int64(len(data)) {
+		t.Errorf("file must not be truncated, size=%d", info.Size())
+	}
+}
+
+// проверяет, что транзакция применяется целиком, а незавершённая в конце журнала — отбрасывается
+func TestReplayTransaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	full := string(resp.EncodeCommand([]string{"MULTI"})) +
+		string(resp.EncodeCommand([]string{"SET", "a", "1"})) +
+		string(resp.EncodeCommand([]string{"EXEC"}))
+	data := full + string(resp.EncodeCommand([]string{"MULTI"})) +
+		string(resp.EncodeCommand([]string{"SET", "b", "2"}))
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got [][]string
+	n, err := Replay(path, func(args []string) { got = append(got, args) })
+	if !errors.Is(err, ErrTruncated) {
+		t.Fatalf("expected ErrTruncated, got %v", err)
+	}
+	if n != 1 || !reflect.DeepEqual(got, [][]string{{"SET", "a", "1"}}) {
+		t.Errorf("unexpected replayed commands: %v", got)
+	}
+
+	info, _ := os.Stat(path)
+	if info.Size() != int64(len(full)) {
+		t.Errorf("expected file to be truncated before the unfinished MULTI, size=%d", info.Size())
+	}
+}
+
+// проверяет, что отсутствие файла — не ошибка, а неизвестная политика — ошибка
+func TestReplayMissingAndBadPolicy(t *testing.T) {
+	dir := t.TempDir()
+	if n, err := Replay(filepath.Join(dir, "none.aof"), func([]string) {}); n != 0 || err != nil {
+		t.Errorf("expected 0, nil for missing file, got %d, %v", n, err)
+	}
+	if _, err := Open(filepath.Join(dir, "x.aof"), "sometimes"); err == nil {
+		t.Errorf("expected error for unknown fsync policy")
+	}
+}
+
+// проверяет, что перезапись оставляет минимальный журнал плюс команды,
+// пришедшие во время перезаписи, и что дальнейшие записи идут в новый файл
+func TestRewrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	a, err := Open(path, FsyncNo)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		a.Append([]string{"SET", "counter", "x"})
+	}
+	sizeBefore := a.Size()
+
+	if err := a.BeginRewrite(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := a.BeginRewrite(); !errors.Is(err, ErrRewriteInProgress) {
+		t.Fatalf("expected ErrRewriteInProgress, got %v", err)
+	}
+	a.Append([]string{"SET", "during", "rewrite"}) // пришло во время перезаписи
+
+	if err := a.FinishRewrite([][]string{{"SET", "counter", "x"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a.Append([]string{"DEL", "during"}) // пришло уже после подмены файла
+	if err := a.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got [][]string
+	if _, err := Replay(path, func(args []string) { got = append(got, args) }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := [][]string{
+		{"SET", "counter", "x"},
+		{"SET", "during", "rewrite"},
+		{"DEL", "during"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	info, _ := os.Stat(path)
+	if info.Size() >= sizeBefore {
+		t.Errorf("expected rewritten file to be smaller than %d, got %d", sizeBefore, info.Size())
+	}
+}
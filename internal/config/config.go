@@ -1,6 +1,12 @@
 package config
 
-import "time"
+import (
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // структура Config — это набор всех настроек приложения (например, адрес сервера, уровень логов и т.д.),
 // которые загружаются при запуске, чтобы управлять поведением программы без изменения кода.
@@ -9,16 +15,181 @@ type Config struct {
 	LogLevel     string        // уровень логирования
 	ReadTimeout  time.Duration // таймаут на чтение запросов
 	WriteTimeout time.Duration // таймаут на запись ответов
+
+	Dir        string     // каталог, в котором лежат файлы персистентности
+	DBFilename string     // имя файла снапшота (RDB)
+	SaveRules  []SaveRule // правила автоматического снапшота ("N изменений за M секунд")
+
+	AppendOnly     bool   // включён ли журнал команд (AOF)
+	AppendFilename string // имя файла журнала
+	AppendFsync    string // политика fsync журнала: "always" | "everysec" | "no"
+
+	AutoAOFRewritePercentage int   // перезаписать журнал, когда он вырос на столько процентов с прошлой перезаписи (0 — выключено)
+	AutoAOFRewriteMinSize    int64 // но не раньше, чем журнал достигнет этого размера (в байтах)
+
+	ReplicaOf       string // "host port" мастера, если сервер запускается репликой (пусто — мастер)
+	ReplBacklogSize int    // размер буфера репликации (backlog) в байтах
+
+	ClientQueueSize int // сколько исходящих сообщений может ждать отправки клиенту; при переполнении клиент отключается
+
+	Databases int // число пронумерованных баз данных (SELECT 0 … Databases-1)
+
+	MaxMemory        int64  // предел памяти под данные в байтах (0 — без ограничения)
+	MaxMemoryPolicy  string // что делать при достижении предела: "noeviction" | "allkeys-lru" | "volatile-ttl" | ...
+	MaxMemorySamples int    // сколько ключей проверяется за один шаг приблизительного вытеснения
+}
+
+// структура SaveRule — одно правило автосохранения в духе "save 900 1" из redis.conf:
+// снапшот делается, если за последние Seconds секунд накопилось хотя бы Changes изменений.
+type SaveRule struct {
+	Seconds int
+	Changes int
 }
 
 // метод Load — конструктор, который возвращает структуру Config
 // с дефолтными значениями основных параметров для запуска сервера.
+// Часть параметров можно переопределить переменными окружения
+// (ADDR, DIR, DBFILENAME, SAVE, APPENDONLY, APPENDFILENAME, APPENDFSYNC,
+// AUTO_AOF_REWRITE_PERCENTAGE, AUTO_AOF_REWRITE_MIN_SIZE, REPLICAOF, REPL_BACKLOG_SIZE,
+// CLIENT_QUEUE_SIZE, DATABASES, MAXMEMORY, MAXMEMORY_POLICY, MAXMEMORY_SAMPLES).
 func Load() *Config {
 	cfg := &Config{
 		Addr:         ":6381",
 		LogLevel:     "info",
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 5 * time.Second,
+		Dir:          ".",
+		DBFilename:   "dump.rdb",
+		SaveRules: []SaveRule{
+			{Seconds: 3600, Changes: 1},
+			{Seconds: 300, Changes: 100},
+			{Seconds: 60, Changes: 10000},
+		},
+		AppendOnly:     false,
+		AppendFilename: "appendonly.aof",
+		AppendFsync:    "everysec",
+
+		AutoAOFRewritePercentage: 100,
+		AutoAOFRewriteMinSize:    64 << 20, // 64 МБ
+
+		ReplBacklogSize: 1 << 20, // 1 МБ
+
+		ClientQueueSize: 1024,
+
+		Databases: 16,
+
+		MaxMemoryPolicy:  "noeviction",
+		MaxMemorySamples: 5,
+	}
+
+	if v, ok := os.LookupEnv("ADDR"); ok && v != "" {
+		cfg.Addr = v
+	}
+	if v, ok := os.LookupEnv("DIR"); ok && v != "" {
+		cfg.Dir = v
+	}
+	if v, ok := os.LookupEnv("DBFILENAME"); ok && v != "" {
+		cfg.DBFilename = v
+	}
+	// SAVE="" отключает автосохранение, как `save ""` в redis.conf
+	if v, ok := os.LookupEnv("SAVE"); ok {
+		if rules, ok := ParseSaveRules(v); ok {
+			cfg.SaveRules = rules
+		}
+	}
+	if v, ok := os.LookupEnv("APPENDONLY"); ok {
+		cfg.AppendOnly = strings.EqualFold(v, "yes")
+	}
+	if v, ok := os.LookupEnv("APPENDFILENAME"); ok && v != "" {
+		cfg.AppendFilename = v
+	}
+	if v, ok := os.LookupEnv("APPENDFSYNC"); ok && v != "" {
+		cfg.AppendFsync = strings.ToLower(v)
+	}
+	if v, ok := os.LookupEnv("AUTO_AOF_REWRITE_PERCENTAGE"); ok {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.AutoAOFRewritePercentage = n
+		}
+	}
+	if v, ok := os.LookupEnv("AUTO_AOF_REWRITE_MIN_SIZE"); ok {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			cfg.AutoAOFRewriteMinSize = n
+		}
+	}
+	if v, ok := os.LookupEnv("REPLICAOF"); ok {
+		cfg.ReplicaOf = strings.TrimSpace(v)
+	}
+	if v, ok := os.LookupEnv("REPL_BACKLOG_SIZE"); ok {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.ReplBacklogSize = n
+		}
+	}
+	if v, ok := os.LookupEnv("CLIENT_QUEUE_SIZE"); ok {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.ClientQueueSize = n
+		}
+	}
+	if v, ok := os.LookupEnv("DATABASES"); ok {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.Databases = n
+		}
+	}
+	if v, ok := os.LookupEnv("MAXMEMORY"); ok {
+		if n, ok := ParseMemory(v); ok {
+			cfg.MaxMemory = n
+		}
+	}
+	if v, ok := os.LookupEnv("MAXMEMORY_POLICY"); ok && v != "" {
+		cfg.MaxMemoryPolicy = strings.ToLower(v)
+	}
+	if v, ok := os.LookupEnv("MAXMEMORY_SAMPLES"); ok {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.MaxMemorySamples = n
+		}
 	}
 	return cfg
 }
+
+// функция ParseSaveRules разбирает строку вида "900 1 300 10" в список правил.
+// Пустая строка означает "без правил". Возвращает false, если формат неверный.
+func ParseSaveRules(s string) ([]SaveRule, bool) {
+	fields := strings.Fields(s)
+	if len(fields)%2 != 0 {
+		return nil, false
+	}
+	rules := make([]SaveRule, 0, len(fields)/2)
+	for i := 0; i < len(fields); i += 2 {
+		seconds, err := strconv.Atoi(fields[i])
+		if err != nil || seconds <= 0 {
+			return nil, false
+		}
+		changes, err := strconv.Atoi(fields[i+1])
+		if err != nil || changes <= 0 {
+			return nil, false
+		}
+		rules = append(rules, SaveRule{Seconds: seconds, Changes: changes})
+	}
+	return rules, true
+}
+
+// функция ParseMemory разбирает размер памяти в духе redis.conf: "1048576", "100kb", "64mb", "1gb"
+// (k/m/g — степени тысячи, kb/mb/gb — степени 1024). Возвращает false, если формат неверный.
+func ParseMemory(s string) (int64, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	units := []struct {
+		suffix string
+		mul    int64
+	}{{"kb", 1 << 10}, {"mb", 1 << 20}, {"gb", 1 << 30}, {"k", 1e3}, {"m", 1e6}, {"g", 1e9}, {"b", 1}}
+	mul := int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(s, u.suffix) {
+			s, mul = strings.TrimSuffix(s, u.suffix), u.mul
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 || n > math.MaxInt64/mul {
+		return 0, false
+	}
+	return n * mul, true
+}
@@ -0,0 +1,106 @@
+// Пакет glob — сопоставление строк с шаблонами в стиле Redis (как в KEYS и PSUBSCRIBE):
+// "*" — любая последовательность символов (в том числе пустая), "?" — ровно один любой символ,
+// "[abc]" — один символ из набора, "[a-z]" — из диапазона, "[^abc]" — не из набора,
+// "\x" — символ x как есть (экранирование спецсимволов).
+package glob
+
+// функция Match - проверяет, подходит ли строка s под шаблон pattern.
+// Сравнение побайтовое, с учётом регистра.
+func Match(pattern, s string) bool {
+	p, i := 0, 0
+	// позиция последней '*' в шаблоне и место в строке, с которого она "ест" символы:
+	// при несовпадении откатываемся к ней и даём ей съесть ещё один символ
+	star, starS := -1, 0
+
+	for i < len(s) {
+		if p < len(pattern) {
+			switch pattern[p] {
+			case '*':
+				for p < len(pattern) && pattern[p] == '*' {
+					p++ // несколько звёздочек подряд равносильны одной
+				}
+				if p == len(pattern) {
+					return true
+				}
+				star, starS = p, i
+				continue
+			case '?':
+				p++
+				i++
+				continue
+			case '[':
+				if next, ok := matchClass(pattern, p, s[i]); ok {
+					p = next
+					i++
+					continue
+				}
+			case '\\':
+				if p+1 < len(pattern) {
+					if pattern[p+1] == s[i] {
+						p += 2
+						i++
+						continue
+					}
+				} else if s[i] == '\\' {
+					p++
+					i++
+					continue
+				}
+			default:
+				if pattern[p] == s[i] {
+					p++
+					i++
+					continue
+				}
+			}
+		}
+		if star < 0 {
+			return false
+		}
+		starS++
+		p, i = star, starS
+	}
+
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// функция matchClass - сопоставляет символ c с набором [...], начинающимся в pattern[p].
+// Возвращает позицию сразу за набором и результат. Незакрытый набор тянется до конца шаблона, как в Redis.
+func matchClass(pattern string, p int, c byte) (int, bool) {
+	p++ // пропускаем '['
+	not := p < len(pattern) && pattern[p] == '^'
+	if not {
+		p++
+	}
+	matched := false
+	for p < len(pattern) && pattern[p] != ']' {
+		switch {
+		case pattern[p] == '\\' && p+1 < len(pattern):
+			p++
+			if pattern[p] == c {
+				matched = true
+			}
+		case p+2 < len(pattern) && pattern[p+1] == '-' && pattern[p+2] != ']':
+			lo, hi := pattern[p], pattern[p+2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			if c >= lo && c <= hi {
+				matched = true
+			}
+			p += 2
+		default:
+			if pattern[p] == c {
+				matched = true
+			}
+		}
+		p++
+	}
+	if p < len(pattern) {
+		p++ // пропускаем ']'
+	}
+	return p, matched != not
+}
@@ -0,0 +1,554 @@
+package rdb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/AntonRadchenko/mini-redis-go/internal/store"
+)
+
+// rdb — это модуль снапшотов в духе RDB-файлов Redis:
+// всё содержимое хранилища (ключи, значения и абсолютные моменты истечения)
+// записывается в один компактный бинарный файл, из которого сервер восстанавливается при старте.
+//
+// Формат файла:
+//
+//	"MINIRDB" <версия: 1 байт>
+//	[0xFE <номер базы: uvarint>]                                          — ключи дальше относятся к этой базе
+//	[0xFC <unix ms: 8 байт big-endian>] <тип: 1 байт> <ключ> <значение>   — повторяется для каждого ключа
+//	0xFF <crc32 всего предыдущего содержимого: 4 байта big-endian>
+//
+// Строки кодируются как uvarint-длина и затем сами байты. Пока не встретился 0xFE, ключи относятся к базе 0.
+// Снапшот для реплики может кончаться 0xFE без ключей следом — это база, в которой продолжается поток репликации
+// (см. WriteSelected).
+// Значение зависит от типа: 0x00 — строка, 0x01 — список (uvarint-число элементов и сами элементы-строки),
+// 0x02 — хеш (uvarint-число полей и пары поле/значение), 0x03 — множество (как список, порядок не важен),
+// 0x04 — упорядоченное множество (uvarint-число элементов и пары элемент/счёт, счёт — 8 байт IEEE 754 big-endian),
+// 0x05 — поток (записи, служебные идентификаторы и группы потребителей, см. encoder.stream).
+// Идентификатор записи потока — два uvarint (миллисекунды и номер).
+
+const (
+	magic   = "MINIRDB"
+	version = 1
+
+	opExpireMs   = 0xFC // перед записью ключа: абсолютное время истечения в миллисекундах
+	opSelectDB   = 0xFE // номер базы данных, к которой относятся следующие ключи
+	opEOF        = 0xFF // конец данных, за ним контрольная сумма
+	typeString   = 0x00 // значение-строка
+	typeList     = 0x01 // значение-список
+	typeHash     = 0x02 // значение-хеш
+	typeSet      = 0x03 // значение-множество
+	typeZSet     = 0x04 // значение-упорядоченное множество
+	typeStream   = 0x05 // значение-поток
+	maxStringLen = 512 << 20
+	maxItems     = 1 << 32 // защита от огромных длин в испорченном файле
+)
+
+// ErrCorrupted возвращается, если файл снапшота повреждён (не тот заголовок, обрыв, неверная сумма).
+var ErrCorrupted = errors.New("rdb: corrupted snapshot")
+
+// функция Write - кодирует записи хранилища в формат снапшота и пишет их в w.
+func Write(w io.Writer, entries []store.Entry) error {
+	return write(w, entries, -1)
+}
+
+// функция WriteSelected - как Write, но снапшот кончается номером базы selected: в ней продолжается
+// поток команд, который мастер отправляет реплике следом за снапшотом (как repl-stream-db в Redis).
+func WriteSelected(w io.Writer, entries []store.Entry, selected int) error {
+	return write(w, entries, selected)
+}
+
+// функция write - пишет снапшот; selected < 0 — без номера базы в конце.
+func write(w io.Writer, entries []store.Entry, selected int) error {
+	crc := crc32.NewIEEE()
+	bw := bufio.NewWriter(io.MultiWriter(w, crc)) // всё записанное попадает и в файл, и в контрольную сумму
+	enc := encoder{w: bw}
+
+	enc.raw([]byte(magic))
+	enc.byte(version)
+	db := 0
+	for _, e := range entries {
+		if e.DB != db {
+			enc.byte(opSelectDB)
+			enc.uvarint(uint64(e.DB))
+			db = e.DB
+		}
+		if !e.ExpireAt.IsZero() {
+			enc.byte(opExpireMs)
+			enc.int64(e.ExpireAt.UnixMilli())
+		}
+		switch v := e.Value.(type) {
+		case string:
+			enc.byte(typeString)
+			enc.string(e.Key)
+			enc.string(v)
+		case []string:
+			enc.byte(typeList)
+			enc.string(e.Key)
+			enc.strings(v)
+		case map[string]string:
+			enc.byte(typeHash)
+			enc.string(e.Key)
+			enc.hash(v)
+		case map[string]struct{}:
+			enc.byte(typeSet)
+			enc.string(e.Key)
+			enc.set(v)
+		case []store.ZMember:
+			enc.byte(typeZSet)
+			enc.string(e.Key)
+			enc.zset(v)
+		case store.StreamSnapshot:
+			enc.byte(typeStream)
+			enc.string(e.Key)
+			enc.stream(v)
+		default:
+			return fmt.Errorf("rdb: unsupported value type %T for key %q", e.Value, e.Key)
+		}
+	}
+	if selected >= 0 && selected != db {
+		enc.byte(opSelectDB)
+		enc.uvarint(uint64(selected))
+	}
+	enc.byte(opEOF)
+	if enc.err != nil {
+		return enc.err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+
+	// сумма считается по всему, что было до неё, поэтому пишем её напрямую в w
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc.Sum32())
+	_, err := w.Write(sum[:])
+	return err
+}
+
+// функция Read - читает снапшот из r и возвращает записи хранилища.
+func Read(r io.Reader) ([]store.Entry, error) {
+	entries, _, err := ReadSelected(r)
+	return entries, err
+}
+
+// функция ReadSelected - как Read, но возвращает и номер базы, выбранной в конце снапшота (см. WriteSelected).
+func ReadSelected(r io.Reader) ([]store.Entry, int, error) {
+	crc := crc32.NewIEEE()
+	br := bufio.NewReader(r)
+	dec := decoder{r: br, crc: crc}
+
+	header := dec.raw(len(magic))
+	if dec.err != nil || string(header) != magic {
+		return nil, 0, ErrCorrupted
+	}
+	if v := dec.byte(); dec.err != nil || v != version {
+		return nil, 0, fmt.Errorf("rdb: unsupported version %d", v)
+	}
+
+	var entries []store.Entry
+	db := 0
+	for {
+		op := dec.byte()
+		if dec.err != nil {
+			return nil, 0, ErrCorrupted
+		}
+
+		var expireAt time.Time
+		if op == opExpireMs {
+			expireAt = time.UnixMilli(dec.int64())
+			op = dec.byte()
+		}
+
+		switch op {
+		case opSelectDB:
+			n := dec.uvarint()
+			if dec.err != nil || !expireAt.IsZero() || n > math.MaxInt32 {
+				return nil, 0, ErrCorrupted
+			}
+			db = int(n)
+
+		case opEOF:
+			want := crc.Sum32()
+			var sum [4]byte
+			if _, err := io.ReadFull(br, sum[:]); err != nil || binary.BigEndian.Uint32(sum[:]) != want {
+				return nil, 0, ErrCorrupted
+			}
+			return entries, db, nil
+
+		case typeString:
+			key := dec.string()
+			val := dec.string()
+			if dec.err != nil {
+				return nil, 0, ErrCorrupted
+			}
+			entries = append(entries, store.Entry{DB: db, Key: key, Value: val, ExpireAt: expireAt})
+
+		case typeList:
+			key := dec.string()
+			items := dec.strings()
+			if dec.err != nil {
+				return nil, 0, ErrCorrupted
+			}
+			entries = append(entries, store.Entry{DB: db, Key: key, Value: items, ExpireAt: expireAt})
+
+		case typeHash:
+			key := dec.string()
+			fields := dec.hash()
+			if dec.err != nil {
+				return nil, 0, ErrCorrupted
+			}
+			entries = append(entries, store.Entry{DB: db, Key: key, Value: fields, ExpireAt: expireAt})
+
+		case typeSet:
+			key := dec.string()
+			members := dec.strings()
+			if dec.err != nil {
+				return nil, 0, ErrCorrupted
+			}
+			set := make(map[string]struct{}, len(members))
+			for _, m := range members {
+				set[m] = struct{}{}
+			}
+			entries = append(entries, store.Entry{DB: db, Key: key, Value: set, ExpireAt: expireAt})
+
+		case typeZSet:
+			key := dec.string()
+			items := dec.zset()
+			if dec.err != nil {
+				return nil, 0, ErrCorrupted
+			}
+			entries = append(entries, store.Entry{DB: db, Key: key, Value: items, ExpireAt: expireAt})
+
+		case typeStream:
+			key := dec.string()
+			st := dec.stream()
+			if dec.err != nil {
+				return nil, 0, ErrCorrupted
+			}
+			entries = append(entries, store.Entry{DB: db, Key: key, Value: st, ExpireAt: expireAt})
+
+		default:
+			return nil, 0, ErrCorrupted
+		}
+	}
+}
+
+// функция SaveFile - атомарно сохраняет снапшот в файл path:
+// сначала пишем во временный файл рядом, делаем fsync и только потом переименовываем.
+// Так на диске всегда лежит либо старый, либо полностью записанный новый снапшот.
+func SaveFile(path string, entries []store.Entry) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "temp-*.rdb")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // после успешного Rename файла уже нет, ошибка игнорируется
+
+	if err := tmp.Chmod(0o644); err != nil { // CreateTemp создаёт файл с правами 0600
+		tmp.Close()
+		return err
+	}
+	if err := Write(tmp, entries); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// функция LoadFile - читает снапшот из файла path.
+// Если файла нет — это не ошибка: сервер просто стартует с пустым хранилищем.
+func LoadFile(path string) ([]store.Entry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// encoder — вспомогательная обёртка, которая запоминает первую ошибку записи,
+// чтобы не проверять err после каждого поля.
+type encoder struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (e *encoder) raw(b []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(b)
+	}
+}
+
+func (e *encoder) byte(b byte) {
+	if e.err == nil {
+		e.err = e.w.WriteByte(b)
+	}
+}
+
+func (e *encoder) int64(v int64) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(v))
+	e.raw(buf[:])
+}
+
+func (e *encoder) uvarint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	e.raw(buf[:n])
+}
+
+func (e *encoder) string(s string) {
+	e.uvarint(uint64(len(s)))
+	if e.err == nil {
+		_, e.err = e.w.WriteString(s)
+	}
+}
+
+// метод strings - список строк: uvarint-число элементов и сами строки.
+func (e *encoder) strings(items []string) {
+	e.uvarint(uint64(len(items)))
+	for _, item := range items {
+		e.string(item)
+	}
+}
+
+// метод hash - хеш: uvarint-число полей и пары поле/значение.
+func (e *encoder) hash(fields map[string]string) {
+	e.uvarint(uint64(len(fields)))
+	for field, val := range fields {
+		e.string(field)
+		e.string(val)
+	}
+}
+
+// метод set - множество: uvarint-число элементов и сами элементы.
+func (e *encoder) set(members map[string]struct{}) {
+	e.uvarint(uint64(len(members)))
+	for m := range members {
+		e.string(m)
+	}
+}
+
+// метод zset - упорядоченное множество: uvarint-число элементов и пары элемент/счёт.
+func (e *encoder) zset(items []store.ZMember) {
+	e.uvarint(uint64(len(items)))
+	for _, it := range items {
+		e.string(it.Member)
+		e.int64(int64(math.Float64bits(it.Score)))
+	}
+}
+
+func (e *encoder) streamID(id store.StreamID) {
+	e.uvarint(id.Ms)
+	e.uvarint(id.Seq)
+}
+
+// метод stream - поток: uvarint-число записей и записи (идентификатор и список поле/значение),
+// последний и наибольший удалённый идентификаторы, uvarint-счётчик добавленных записей,
+// затем uvarint-число групп и для каждой: имя, позиция, список потребителей
+// и uvarint-число ожидающих записей (идентификатор, потребитель, время доставки в мс — 8 байт, uvarint-число доставок).
+func (e *encoder) stream(st store.StreamSnapshot) {
+	e.uvarint(uint64(len(st.Entries)))
+	for _, entry := range st.Entries {
+		e.streamID(entry.ID)
+		e.strings(entry.Fields)
+	}
+	e.streamID(st.LastID)
+	e.streamID(st.MaxDeleted)
+	e.uvarint(st.EntriesAdded)
+	e.uvarint(uint64(len(st.Groups)))
+	for _, g := range st.Groups {
+		e.string(g.Name)
+		e.streamID(g.LastID)
+		e.strings(g.Consumers)
+		e.uvarint(uint64(len(g.Pending)))
+		for _, p := range g.Pending {
+			e.streamID(p.ID)
+			e.string(p.Consumer)
+			e.int64(p.DeliveryTime.UnixMilli())
+			e.uvarint(uint64(p.DeliveryCount))
+		}
+	}
+}
+
+// decoder — зеркальная обёртка для чтения: все прочитанные байты
+// дополнительно попадают в crc, чтобы в конце сверить контрольную сумму.
+type decoder struct {
+	r   *bufio.Reader
+	crc hash.Hash32
+	err error
+}
+
+func (d *decoder) raw(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	buf := make([]byte, n)
+	if _, d.err = io.ReadFull(d.r, buf); d.err != nil {
+		return nil
+	}
+	d.crc.Write(buf)
+	return buf
+}
+
+func (d *decoder) byte() byte {
+	b := d.raw(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (d *decoder) int64() int64 {
+	b := d.raw(8)
+	if b == nil {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(b))
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(byteReader{d})
+	if err != nil {
+		d.err = err
+	}
+	return v
+}
+
+func (d *decoder) string() string {
+	n := d.uvarint()
+	if d.err != nil {
+		return ""
+	}
+	if n > maxStringLen {
+		d.err = ErrCorrupted
+		return ""
+	}
+	return string(d.raw(int(n)))
+}
+
+func (d *decoder) strings() []string {
+	n := d.uvarint()
+	if d.err != nil {
+		return nil
+	}
+	if n > maxItems {
+		d.err = ErrCorrupted
+		return nil
+	}
+	items := make([]string, 0, min(n, 1024)) // память растёт по мере чтения, а не по заявленной длине
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		items = append(items, d.string())
+	}
+	return items
+}
+
+func (d *decoder) hash() map[string]string {
+	n := d.uvarint()
+	if d.err != nil {
+		return nil
+	}
+	if n > maxItems {
+		d.err = ErrCorrupted
+		return nil
+	}
+	fields := make(map[string]string, min(n, 1024))
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		field := d.string()
+		fields[field] = d.string()
+	}
+	return fields
+}
+
+func (d *decoder) zset() []store.ZMember {
+	n := d.uvarint()
+	if d.err != nil {
+		return nil
+	}
+	if n > maxItems {
+		d.err = ErrCorrupted
+		return nil
+	}
+	items := make([]store.ZMember, 0, min(n, 1024))
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		member := d.string()
+		score := math.Float64frombits(uint64(d.int64()))
+		if math.IsNaN(score) {
+			d.err = ErrCorrupted
+		}
+		items = append(items, store.ZMember{Member: member, Score: score})
+	}
+	return items
+}
+
+func (d *decoder) streamID() store.StreamID {
+	return store.StreamID{Ms: d.uvarint(), Seq: d.uvarint()}
+}
+
+// метод count - uvarint-длина с защитой от огромных значений в испорченном файле.
+func (d *decoder) count() uint64 {
+	n := d.uvarint()
+	if n > maxItems {
+		d.err = ErrCorrupted
+	}
+	if d.err != nil {
+		return 0
+	}
+	return n
+}
+
+func (d *decoder) stream() store.StreamSnapshot {
+	var st store.StreamSnapshot
+	n := d.count()
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		st.Entries = append(st.Entries, store.StreamEntry{ID: d.streamID(), Fields: d.strings()})
+	}
+	st.LastID = d.streamID()
+	st.MaxDeleted = d.streamID()
+	st.EntriesAdded = d.uvarint()
+	groups := d.count()
+	for i := uint64(0); i < groups && d.err == nil; i++ {
+		g := store.GroupSnapshot{Name: d.string(), LastID: d.streamID(), Consumers: d.strings()}
+		pending := d.count()
+		for j := uint64(0); j < pending && d.err == nil; j++ {
+			g.Pending = append(g.Pending, store.GroupPending{
+				ID:            d.streamID(),
+				Consumer:      d.string(),
+				DeliveryTime:  time.UnixMilli(d.int64()),
+				DeliveryCount: int(d.uvarint()),
+			})
+		}
+		st.Groups = append(st.Groups, g)
+	}
+	return st
+}
+
+// byteReader — адаптер io.ByteReader для binary.ReadUvarint,
+// который читает через decoder, чтобы байты длины тоже учитывались в crc.
+type byteReader struct{ d *decoder }
+
+func (b byteReader) ReadByte() (byte, error) {
+	v := b.d.byte()
+	return v, b.d.err
+}
@@ -0,0 +1,154 @@
+package rdb
+
+import (
+	"bytes"
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/AntonRadchenko/mini-redis-go/internal/store"
+)
+
+// проверяет, что записи переживают цикл Write → Read без изменений
+func TestWriteRead(t *testing.T) {
+	expireAt := time.UnixMilli(time.Now().Add(time.Hour).UnixMilli())
+	entries := []store.Entry{
+		{Key: "name", Value: "anton"},
+		{Key: "session", Value: "token", ExpireAt: expireAt},
+		{Key: "", Value: ""},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, entries); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != len(entries) {
+		t.Fatalf("expected %d entries, got %d", len(entries), len(got))
+	}
+	for i := range entries {
+		if got[i].Key != entries[i].Key || got[i].Value != entries[i].Value || !got[i].ExpireAt.Equal(entries[i].ExpireAt) {
+			t.Errorf("entry %d: expected %+v, got %+v", i, entries[i], got[i])
+		}
+	}
+}
+
+// проверяет, что составные значения (списки, хеши, множества, упорядоченные множества, потоки) переживают цикл Write → Read вместе с TTL
+func TestWriteReadComposite(t *testing.T) {
+	expireAt := time.UnixMilli(time.Now().Add(time.Hour).UnixMilli())
+	entries := []store.Entry{
+		{Key: "queue", Value: []string{"a", "", "c"}, ExpireAt: expireAt},
+		{Key: "name", Value: "anton"},
+		{Key: "user", Value: map[string]string{"name": "anton", "city": ""}},
+		{Key: "tags", Value: map[string]struct{}{"go": {}, "redis": {}}},
+		{Key: "board", Value: []store.ZMember{{Member: "a", Score: math.Inf(-1)}, {Member: "b", Score: 1.5}}},
+		{Key: "events", Value: store.StreamSnapshot{
+			Entries:      []store.StreamEntry{{ID: store.StreamID{Ms: 1, Seq: 0}, Fields: []string{"f", "v"}}},
+			LastID:       store.StreamID{Ms: 3, Seq: 1},
+			MaxDeleted:   store.StreamID{Ms: 3, Seq: 1},
+			EntriesAdded: 4,
+			Groups: []store.GroupSnapshot{{
+				Name:      "g",
+				LastID:    store.StreamID{Ms: 1, Seq: 0},
+				Consumers: []string{"alice", "bob"},
+				Pending:   []store.GroupPending{{ID: store.StreamID{Ms: 1, Seq: 0}, Consumer: "alice", DeliveryTime: expireAt, DeliveryCount: 2}},
+			}},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, entries); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, entries) {
+		t.Errorf("expected %+v, got %+v", entries, got)
+	}
+}
+
+// проверяет, что испорченный файл не загружается
+func TestReadCorrupted(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, []store.Entry{{Key: "a", Value: "b"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data := buf.Bytes()
+	data[len(data)-6] ^= 0xFF // портим байт значения — контрольная сумма не сойдётся
+	if _, err := Read(bytes.NewReader(data)); err == nil {
+		t.Errorf("expected error for corrupted snapshot")
+	}
+
+	if _, err := Read(bytes.NewReader(data[:5])); err == nil {
+		t.Errorf("expected error for truncated snapshot")
+	}
+}
+
+// проверяет сохранение в файл и загрузку, а также отсутствие файла
+func TestSaveLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.rdb")
+
+	entries, err := LoadFile(path)
+	if err != nil || entries != nil {
+		t.Fatalf("expected no entries and no error for missing file, got %v, %v", entries, err)
+	}
+
+	if err := SaveFile(path, []store.Entry{{Key: "k", Value: "v"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	entries, err = LoadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Key != "k" || entries[0].Value != "v" {
+		t.Errorf("unexpected entries: %+v", entries)
+	}
+}
+
+// проверяет, что номер базы данных каждого ключа переживает цикл Write → Read
+func TestWriteReadDatabases(t *testing.T) {
+	entries := []store.Entry{
+		{Key: "a", Value: "0"},
+		{DB: 3, Key: "b", Value: "3"},
+		{DB: 3, Key: "c", Value: []string{"x"}},
+		{DB: 15, Key: "a", Value: "15"},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, entries); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, entries) {
+		t.Fatalf("expected %+v, got %+v", entries, got)
+	}
+}
+
+// проверяет, что снапшот для реплики передаёт базу, в которой продолжается поток репликации
+func TestWriteReadSelected(t *testing.T) {
+	entries := []store.Entry{{Key: "a", Value: "0"}, {DB: 2, Key: "b", Value: "2"}}
+	for _, selected := range []int{0, 2, 5} {
+		var buf bytes.Buffer
+		if err := WriteSelected(&buf, entries, selected); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got, db, err := ReadSelected(&buf)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if db != selected || !reflect.DeepEqual(got, entries) {
+			t.Fatalf("selected %d: got db %d, entries %+v", selected, db, got)
+		}
+	}
+}
@@ -95,3 +95,23 @@ func (r *Reader) ReadArray() ([]string, error) {
 	}
 	return result, nil
 }
+
+// Метод ReadLine() читает одну строку ответа до \r\n (например "+FULLRESYNC <id> <offset>")
+// и возвращает её без завершающих \r\n. Нужен там, где сервер сам выступает клиентом (репликация).
+func (r *Reader) ReadLine() (string, error) {
+	line, err := r.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// Метод ReadN() читает ровно n байт "как есть" — например, тело снапшота,
+// которое мастер передаёт реплике после строки "$<длина>" без завершающих \r\n.
+func (r *Reader) ReadN(n int) ([]byte, error) {
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r.r, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
@@ -25,3 +25,28 @@ func TestReader_ReadArray(t *testing.T) {
 		t.Errorf("expected %v, got %v", expected, result)
 	}
 }
+
+func TestReader_ReadLineReadN(t *testing.T) {
+	// так мастер отвечает реплике на PSYNC: строка, затем "$<длина>" и сырые байты снапшота
+	input := "+FULLRESYNC abc 0\r\n$5\r\nhello*1\r\n$4\r\nPING\r\n"
+	r := NewReader(strings.NewReader(input))
+
+	line, err := r.ReadLine()
+	if err != nil || line != "+FULLRESYNC abc 0" {
+		t.Fatalf("unexpected line %q, err %v", line, err)
+	}
+	line, err = r.ReadLine()
+	if err != nil || line != "$5" {
+		t.Fatalf("unexpected line %q, err %v", line, err)
+	}
+	payload, err := r.ReadN(5)
+	if err != nil || string(payload) != "hello" {
+		t.Fatalf("unexpected payload %q, err %v", payload, err)
+	}
+
+	// после сырых байт поток команд читается как обычно
+	args, err := r.ReadArray()
+	if err != nil || !reflect.DeepEqual(args, []string{"PING"}) {
+		t.Errorf("unexpected args %v, err %v", args, err)
+	}
+}
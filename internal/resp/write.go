@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"strconv"
 )
 
 // структура Writer это обертка над bufio.Writer,
@@ -44,14 +45,7 @@ func (w *Writer) WriteInteger(i int) error {
 }
 
 func (w *Writer) WriteBulk(s string) error {
-	if s == "" { // если nil значение
-		_, err := w.w.WriteString("$-1\r\n")
-		if err != nil {
-			return err
-		}
-		return w.w.Flush()
-	}
-	// обычная строка (длину строки, затем саму строку)
+	// длину строки, затем саму строку (пустая строка — это "$0\r\n\r\n", а не nil)
 	_, err := fmt.Fprintf(w.w, "$%d\r\n%s\r\n", len(s), s)
 	if err != nil {
 		return err
@@ -59,6 +53,34 @@ func (w *Writer) WriteBulk(s string) error {
 	return w.w.Flush()
 }
 
+// WriteNull записывает nil-значение ("$-1\r\n") — например, ответ GET на несуществующий ключ.
+func (w *Writer) WriteNull() error {
+	_, err := w.w.WriteString("$-1\r\n")
+	if err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+// WriteNullArray записывает nil-массив ("*-1\r\n") — например, ответ EXEC на прерванную транзакцию.
+func (w *Writer) WriteNullArray() error {
+	_, err := w.w.WriteString("*-1\r\n")
+	if err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+// WriteArrayHeader записывает только заголовок массива ("*<n>\r\n"),
+// после которого вызывающий сам пишет n элементов любого типа (вложенные ответы).
+func (w *Writer) WriteArrayHeader(n int) error {
+	_, err := fmt.Fprintf(w.w, "*%d\r\n", n)
+	if err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
 func (w *Writer) WriteArray(values []string) error {
 	// заголовок
 	_, err := fmt.Fprintf(w.w, "*%d\r\n", len(values))
@@ -75,3 +97,21 @@ func (w *Writer) WriteArray(values []string) error {
 
 	return w.w.Flush()
 }
+
+// функция EncodeCommand кодирует команду клиента (например ["SET", "key", "value"])
+// в RESP-массив bulk-строк — ровно в том виде, в каком её прислал бы redis-cli.
+// В отличие от WriteArray, пустая строка здесь остаётся пустой ("$0"), а не превращается в nil.
+func EncodeCommand(args []string) []byte {
+	buf := make([]byte, 0, 16*len(args)+16)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, a := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(a)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, a...)
+		buf = append(buf, '\r', '\n')
+	}
+	return buf
+}
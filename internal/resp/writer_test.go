@@ -3,6 +3,7 @@ package resp
 import (
 	"bytes"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -90,3 +91,42 @@ func TestWriter_WriteArray(t *testing.T) {
 		t.Errorf("expected %v, got %v", expected, got)
 	}
 }
+
+func TestEncodeCommand(t *testing.T) {
+	got := string(EncodeCommand([]string{"SET", "key", ""}))
+
+	expected := "*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$0\r\n\r\n"
+	if expected != got {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+
+	// закодированная команда должна читаться обратно нашим же Reader
+	args, err := NewReader(strings.NewReader(got)).ReadArray()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(args, []string{"SET", "key", ""}) {
+		t.Errorf("unexpected args after decode: %v", args)
+	}
+}
+
+func TestWriter_WriteNulls(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	if err := w.WriteBulk(""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.WriteNull(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := w.WriteNullArray(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// пустая строка и nil — разные ответы
+	expected := "$0\r\n\r\n$-1\r\n*-1\r\n"
+	if got := buf.String(); expected != got {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
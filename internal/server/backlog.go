@@ -0,0 +1,59 @@
+package server
+
+// структура backlog — кольцевой буфер последних байт потока репликации.
+// Мастер пишет в него каждую изменяющую команду; реплика, ненадолго потерявшая связь,
+// может дочитать из него пропущенное (частичная ресинхронизация), не загружая весь снапшот заново.
+//
+// Смещения считаются в байтах от начала потока: end — сколько байт записано всего
+// (master_repl_offset), а в буфере лежат байты с позиций [end-histlen, end).
+type backlog struct {
+	buf     []byte
+	histlen int   // сколько байт сейчас хранится (не больше len(buf))
+	end     int64 // смещение сразу после последнего записанного байта
+}
+
+// конструктор newBacklog создаёт пустой буфер заданного размера,
+// начинающийся со смещения offset (с него продолжится поток).
+func newBacklog(size int, offset int64) *backlog {
+	return &backlog{buf: make([]byte, size), end: offset}
+}
+
+// метод write - дописывает данные в буфер, вытесняя самые старые байты.
+func (b *backlog) write(p []byte) {
+	size := len(b.buf)
+	for len(p) > 0 {
+		pos := int(b.end % int64(size))
+		n := copy(b.buf[pos:], p)
+		p = p[n:]
+		b.end += int64(n)
+		b.histlen = min(b.histlen+n, size)
+	}
+}
+
+// метод start - смещение самого старого байта, который ещё есть в буфере.
+func (b *backlog) start() int64 {
+	return b.end - int64(b.histlen)
+}
+
+// метод readFrom - возвращает копию байт, начиная со смещения off (не больше max).
+// Если этих байт в буфере уже (или ещё) нет, возвращает false.
+func (b *backlog) readFrom(off int64, max int) ([]byte, bool) {
+	if off < b.start() || off > b.end {
+		return nil, false
+	}
+	n := int(min(b.end-off, int64(max)))
+	out := make([]byte, n)
+	size := len(b.buf)
+	for i := 0; i < n; {
+		pos := int((off + int64(i)) % int64(size))
+		i += copy(out[i:], b.buf[pos:min(size, pos+n-i)])
+	}
+	return out, true
+}
+
+// метод reset - очищает буфер и переносит его на новое смещение
+// (после полной синхронизации реплики с новым мастером).
+func (b *backlog) reset(offset int64) {
+	b.histlen = 0
+	b.end = offset
+}
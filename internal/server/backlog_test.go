@@ -0,0 +1,37 @@
+package server
+
+import "testing"
+
+// проверяет запись с переполнением кольца и чтение по смещению
+func TestBacklog_WriteRead(t *testing.T) {
+	b := newBacklog(8, 0)
+	b.write([]byte("hello"))
+
+	data, ok := b.readFrom(0, 100)
+	if !ok || string(data) != "hello" {
+		t.Fatalf("expected 'hello', got %q (ok=%v)", data, ok)
+	}
+
+	// перезаписываем начало кольца: теперь в буфере "loworld!" (смещения 4..12)
+	b.write([]byte("world!!"))
+	if b.end != 12 || b.start() != 4 {
+		t.Fatalf("expected range [4, 12), got [%d, %d)", b.start(), b.end)
+	}
+	if _, ok := b.readFrom(3, 100); ok {
+		t.Errorf("expected offset 3 to be evicted from backlog")
+	}
+	data, ok = b.readFrom(4, 100)
+	if !ok || string(data) != "oworld!!" {
+		t.Errorf("expected 'oworld!!', got %q (ok=%v)", data, ok)
+	}
+	data, ok = b.readFrom(10, 2)
+	if !ok || string(data) != "!!" {
+		t.Errorf("expected '!!', got %q (ok=%v)", data, ok)
+	}
+
+	// смещение end — валидное: просто нечего читать
+	data, ok = b.readFrom(12, 100)
+	if !ok || len(data) != 0 {
+		t.Errorf("expected empty read at end, got %q (ok=%v)", data, ok)
+	}
+}
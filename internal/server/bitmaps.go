@@ -0,0 +1,254 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/AntonRadchenko/mini-redis-go/internal/store"
+)
+
+// ответы на ошибки в аргументах битовых команд; тексты совпадают с Redis
+const (
+	errBitOffset    = "ERR bit offset is not an integer or out of range"
+	errBitFieldType = "ERR Invalid bitfield type. Use something like i16 u8. Note that u64 is not supported but i64 is."
+)
+
+// bitOps — операции BITOP по имени.
+var bitOps = map[string]store.BitOp{
+	"AND": store.BitAnd, "OR": store.BitOr, "XOR": store.BitXor, "NOT": store.BitNot,
+}
+
+// метод bitmapCommand - битовые операции над строками (SETBIT, GETBIT, BITCOUNT, BITPOS, BITOP, BITFIELD).
+// Строка при записи дополняется нулевыми байтами, команда к ключу другого типа получает WRONGTYPE.
+func (r *Router) bitmapCommand(cmd string, args []string) Reply {
+	if msg := checkArity(cmd, args); msg != "" {
+		return Reply{Type: "error", Value: msg}
+	}
+	key := args[1]
+
+	switch cmd {
+	case "SETBIT":
+		offset, ok := parseBitOffset(args[2], false, 1)
+		if !ok {
+			return Reply{Type: "error", Value: errBitOffset}
+		}
+		if args[3] != "0" && args[3] != "1" {
+			return Reply{Type: "error", Value: "ERR bit is not an integer or out of range"}
+		}
+		old, err := r.store.SetBit(key, offset, int(args[3][0]-'0'))
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "integer", Value: old}
+
+	case "GETBIT":
+		offset, ok := parseBitOffset(args[2], false, 1)
+		if !ok {
+			return Reply{Type: "error", Value: errBitOffset}
+		}
+		bit, err := r.store.GetBit(key, offset)
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "integer", Value: bit}
+
+	case "BITCOUNT":
+		// BITCOUNT key [start end [BYTE|BIT]]
+		if len(args) == 3 || len(args) > 5 {
+			return Reply{Type: "error", Value: "ERR syntax error"}
+		}
+		rng, msg := parseBitRange(args[2:])
+		if msg != "" {
+			return Reply{Type: "error", Value: msg}
+		}
+		n, err := r.store.BitCount(key, rng)
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "integer", Value: n}
+
+	case "BITPOS":
+		// BITPOS key bit [start [end [BYTE|BIT]]]
+		if len(args) > 6 {
+			return Reply{Type: "error", Value: "ERR syntax error"}
+		}
+		if _, err := strconv.ParseInt(args[2], 10, 64); err != nil {
+			return Reply{Type: "error", Value: errNotInteger}
+		}
+		if args[2] != "0" && args[2] != "1" {
+			return Reply{Type: "error", Value: "ERR The bit argument must be 1 or 0."}
+		}
+		rng, msg := parseBitRange(args[3:])
+		if msg != "" {
+			return Reply{Type: "error", Value: msg}
+		}
+		pos, err := r.store.BitPos(key, int(args[2][0]-'0'), rng)
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "integer", Value: int(pos)}
+
+	case "BITOP":
+		// BITOP op destkey key [key ...]
+		op, ok := bitOps[strings.ToUpper(args[1])]
+		if !ok {
+			return Reply{Type: "error", Value: "ERR syntax error"}
+		}
+		if op == store.BitNot && len(args) != 4 {
+			return Reply{Type: "error", Value: "ERR BITOP NOT must be called with a single source key."}
+		}
+		n, err := r.store.BitOp(op, args[2], args[3:]...)
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "integer", Value: n}
+
+	case "BITFIELD", "BITFIELD_RO":
+		ops, msg := parseBitField(args[2:], cmd == "BITFIELD_RO")
+		if msg != "" {
+			return Reply{Type: "error", Value: msg}
+		}
+		res, err := r.store.BitField(key, ops)
+		if err != nil {
+			return errorReply(err)
+		}
+		out := make([]Reply, len(res))
+		for i, v := range res {
+			if v == nil {
+				out[i] = Reply{Type: "bulk", Value: nil}
+			} else {
+				out[i] = Reply{Type: "integer", Value: int(*v)}
+			}
+		}
+		return Reply{Type: "array", Value: out}
+	}
+	return Reply{Type: "error", Value: "ERR unknown command '" + cmd + "'"}
+}
+
+// функция parseBitOffset - номер бита из аргумента; в BITFIELD запись "#N" означает N-е поле шириной width бит.
+// false — не целое, отрицательное или за пределами наибольшей строки.
+func parseBitOffset(arg string, fieldIndex bool, width uint) (uint64, bool) {
+	mul := uint64(1)
+	if fieldIndex && strings.HasPrefix(arg, "#") {
+		arg, mul = arg[1:], uint64(width)
+	}
+	n, err := strconv.ParseUint(arg, 10, 64)
+	if err != nil || n > store.MaxBitOffset/mul {
+		return 0, false
+	}
+	return n * mul, true
+}
+
+// функция parseBitRange - необязательный диапазон BITCOUNT/BITPOS: [start [end [BYTE|BIT]]].
+func parseBitRange(args []string) (store.BitRange, string) {
+	var rng store.BitRange
+	if len(args) == 0 {
+		return rng, ""
+	}
+	var err error
+	if rng.Start, err = strconv.ParseInt(args[0], 10, 64); err != nil {
+		return rng, errNotInteger
+	}
+	if len(args) == 1 {
+		return rng, ""
+	}
+	if rng.End, err = strconv.ParseInt(args[1], 10, 64); err != nil {
+		return rng, errNotInteger
+	}
+	rng.HasEnd = true
+	if len(args) == 3 {
+		switch strings.ToUpper(args[2]) {
+		case "BIT":
+			rng.Bit = true
+		case "BYTE":
+		default:
+			return rng, "ERR syntax error"
+		}
+	}
+	return rng, ""
+}
+
+// функция parseBitFieldType - тип поля BITFIELD: i1..i64 или u1..u63.
+func parseBitFieldType(arg string) (signed bool, width uint, ok bool) {
+	if len(arg) < 2 {
+		return false, 0, false
+	}
+	switch arg[0] {
+	case 'i', 'I':
+		signed = true
+	case 'u', 'U':
+	default:
+		return false, 0, false
+	}
+	n, err := strconv.Atoi(arg[1:])
+	if err != nil || n < 1 || n > 64 || (!signed && n == 64) {
+		return false, 0, false
+	}
+	return signed, uint(n), true
+}
+
+// функция parseBitField - разбирает подкоманды BITFIELD: GET type offset, SET type offset value,
+// INCRBY type offset increment и OVERFLOW WRAP|SAT|FAIL (действует на следующие за ним SET/INCRBY).
+// readOnly (BITFIELD_RO) разрешает только GET.
+func parseBitField(args []string, readOnly bool) ([]store.BitFieldOp, string) {
+	var ops []store.BitFieldOp
+	overflow := store.OverflowWrap
+	for i := 0; i < len(args); {
+		sub := strings.ToUpper(args[i])
+		if sub == "OVERFLOW" {
+			if i+1 >= len(args) {
+				return nil, "ERR syntax error"
+			}
+			switch strings.ToUpper(args[i+1]) {
+			case "WRAP":
+				overflow = store.OverflowWrap
+			case "SAT":
+				overflow = store.OverflowSat
+			case "FAIL":
+				overflow = store.OverflowFail
+			default:
+				return nil, "ERR Invalid OVERFLOW type specified"
+			}
+			i += 2
+			continue
+		}
+
+		var op store.BitFieldOp
+		argc := 3
+		switch sub {
+		case "GET":
+			op.Kind = store.BitFieldGet
+		case "SET":
+			op.Kind, argc = store.BitFieldSet, 4
+		case "INCRBY":
+			op.Kind, argc = store.BitFieldIncrBy, 4
+		default:
+			return nil, "ERR syntax error"
+		}
+		if i+argc > len(args) {
+			return nil, "ERR syntax error"
+		}
+		signed, width, ok := parseBitFieldType(args[i+1])
+		if !ok {
+			return nil, errBitFieldType
+		}
+		offset, ok := parseBitOffset(args[i+2], true, width)
+		if !ok {
+			return nil, errBitOffset
+		}
+		op.Signed, op.Bits, op.Offset, op.Overflow = signed, width, offset, overflow
+		if argc == 4 {
+			v, err := strconv.ParseInt(args[i+3], 10, 64)
+			if err != nil {
+				return nil, errNotInteger
+			}
+			op.Value = v
+		}
+		if readOnly && op.Kind != store.BitFieldGet {
+			return nil, "ERR BITFIELD_RO only supports the GET subcommand"
+		}
+		ops = append(ops, op)
+		i += argc
+	}
+	return ops, ""
+}
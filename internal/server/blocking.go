@@ -0,0 +1,309 @@
+package server
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// Блокирующие команды (BLPOP, BRPOP, BLMOVE, XREAD и XREADGROUP с BLOCK) устроены как в Redis:
+//   - сначала команда пробует выполниться сразу; если данных нет — клиент встаёт в очередь
+//     каждого из своих ключей и ждёт (соединение не читает новые команды, пока не получит ответ);
+//   - после каждой команды роутер проверяет ключи, которые изменились, пока их кто-то ждал,
+//     и обслуживает ждущих строго в порядке прихода (FIFO);
+//   - клиент обслуживается неблокирующим эквивалентом (LPOP, RPOP, LMOVE), выполненным через dispatch,
+//     поэтому в AOF и репликам уходит именно он — повторное проигрывание никогда не блокируется;
+//   - XREAD и XREADGROUP обслуживаются той же командой без BLOCK. Первый ждущий потока может
+//     не получить ничего (у его группы нет новых записей), поэтому очередь потока просматривается целиком.
+
+// структура waiter — клиент, ждущий данных в блокирующей команде.
+type waiter struct {
+	cmd    string
+	args   []string
+	keys   []string   // ключи, которых ждёт клиент, в порядке проверки
+	result chan Reply // ответ для клиента, когда его обслужили (буфер на 1 элемент)
+	served bool       // клиента уже обслужили (меняется под blockMu)
+	once   bool       // XREAD/XREADGROUP без BLOCK: выполняется один раз и не ждёт
+
+	closed <-chan struct{} // закрывается, когда соединение клиента закрыто
+}
+
+// метод gone - отключился ли уже ждущий клиент: такому данные отдавать нельзя, они потеряются.
+func (w *waiter) gone() bool {
+	select {
+	case <-w.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// метод streams - ждёт ли клиент записей потоков (XREAD, XREADGROUP).
+func (w *waiter) streams() bool {
+	return w.cmd == "XREAD" || w.cmd == "XREADGROUP"
+}
+
+// функция newWaiter - разбирает аргументы блокирующей команды.
+// Возвращает ждущего, таймаут (0 — ждать бесконечно) или текст ошибки.
+func newWaiter(cmd string, args []string) (*waiter, time.Duration, string) {
+	w := &waiter{cmd: cmd, args: args, result: make(chan Reply, 1)}
+	switch cmd {
+	case "BLPOP", "BRPOP":
+		w.keys = args[1 : len(args)-1]
+	case "BLMOVE":
+		_, ok1 := parseSide(args[3])
+		_, ok2 := parseSide(args[4])
+		if !ok1 || !ok2 {
+			return nil, 0, "ERR syntax error"
+		}
+		w.keys = args[1:2]
+	case "XREAD", "XREADGROUP":
+		a, msg := parseXRead(cmd, args)
+		if msg != "" {
+			return nil, 0, msg
+		}
+		w.args, w.keys, w.once = a.command(cmd), a.keys, !a.blocking
+		return w, a.block, ""
+	}
+
+	seconds, err := strconv.ParseFloat(args[len(args)-1], 64)
+	if err != nil || math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+		return nil, 0, "ERR timeout is not a float or out of range"
+	}
+	if seconds < 0 {
+		return nil, 0, "ERR timeout is negative"
+	}
+	return w, time.Duration(seconds * float64(time.Second)), ""
+}
+
+// метод resolveLastIDs - подставляет в XREAD вместо "$" текущий последний идентификатор потока,
+// чтобы ждущий клиент получил только записи, добавленные после начала ожидания.
+// Ключ другого типа остаётся с "$" — попытка выполнить команду ответит WRONGTYPE.
+func (r *Router) resolveLastIDs(w *waiter) {
+	if w.cmd != "XREAD" {
+		return
+	}
+	ids := w.args[len(w.args)-len(w.keys):]
+	for i, id := range ids {
+		if id != "$" {
+			continue
+		}
+		if last, _, err := r.store.StreamLastID(w.keys[i]); err == nil {
+			ids[i] = last.String()
+		}
+	}
+}
+
+// метод timeoutReply - ответ, если данных так и не дождались.
+func (w *waiter) timeoutReply() Reply {
+	if w.cmd == "BLMOVE" {
+		return Reply{Type: "bulk", Value: nil}
+	}
+	return Reply{Type: "array", Value: nil}
+}
+
+// метод attempt - одна неблокирующая попытка обслужить ждущего по ключу key.
+// false — в ключе нет данных. Ошибка (например, WRONGTYPE) возвращается как ответ с true.
+// Команды над потоками выполняются целиком, по всем своим ключам сразу.
+func (r *Router) attempt(w *waiter, key string) (Reply, bool) {
+	var reply Reply
+	switch w.cmd {
+	case "BLPOP", "BRPOP":
+		pop := "LPOP"
+		if w.cmd == "BRPOP" {
+			pop = "RPOP"
+		}
+		reply = r.dispatch(pop, []string{pop, key})
+		if reply.Type == "bulk" && reply.Value != nil {
+			reply = Reply{Type: "array", Value: []string{key, reply.Value.(string)}}
+		}
+	case "BLMOVE":
+		reply = r.dispatch("LMOVE", []string{"LMOVE", w.args[1], w.args[2], w.args[3], w.args[4]})
+	case "XREAD", "XREADGROUP":
+		reply = r.dispatch(w.cmd, w.args)
+	}
+	if (reply.Type == "bulk" || reply.Type == "array") && reply.Value == nil {
+		return Reply{}, false
+	}
+	return reply, true
+}
+
+// метод tryAll - пробует обслужить ждущего по его ключам по порядку.
+func (r *Router) tryAll(w *waiter) (Reply, bool) {
+	if w.streams() {
+		return r.attempt(w, "")
+	}
+	for _, key := range w.keys {
+		if reply, ok := r.attempt(w, key); ok {
+			return reply, true
+		}
+	}
+	return Reply{}, false
+}
+
+// метод block - выполняет блокирующую команду клиента: сразу, если данные есть,
+// иначе ставит клиента в очередь и ждёт данных, таймаута, отключения клиента или остановки сервера.
+func (r *Router) block(c *client, cmd string, args []string) Reply {
+	if msg := checkArity(cmd, args); msg != "" {
+		return Reply{Type: "error", Value: msg}
+	}
+	w, timeout, errMsg := newWaiter(cmd, args)
+	if errMsg != "" {
+		return Reply{Type: "error", Value: errMsg}
+	}
+	if w.once {
+		r.execMu.RLock()
+		defer r.execMu.RUnlock()
+		return r.Handle(args)
+	}
+	w.closed = c.closed
+
+	r.execMu.RLock()
+	if deniedOnReplica(cmd) && r.repl.isReplica() {
+		r.execMu.RUnlock()
+		return Reply{Type: "error", Value: errReadonly}
+	}
+	r.blockMu.Lock()
+	// ключи отмечаются ожидаемыми ещё до первой попытки: данные, пришедшие
+	// между попыткой и постановкой в очередь, заметит serveBlocked
+	for _, key := range w.keys {
+		r.store.BlockOn(key)
+	}
+	r.resolveLastIDs(w)
+	reply, ok := r.tryAll(w)
+	if ok {
+		for _, key := range w.keys {
+			r.store.UnblockOn(key)
+		}
+	} else {
+		for _, key := range w.keys {
+			r.blocked[key] = append(r.blocked[key], w)
+		}
+	}
+	r.blockMu.Unlock()
+	if ok {
+		r.serveBlocked() // BLMOVE мог положить данные в ключ, которого ждут другие
+	}
+	r.execMu.RUnlock()
+	if ok {
+		return reply
+	}
+	return r.wait(c, w, timeout)
+}
+
+// метод wait - ждёт, пока клиента обслужат, и снимает его с очереди, если ждать больше нечего.
+func (r *Router) wait(c *client, w *waiter, timeout time.Duration) Reply {
+	var expired <-chan time.Time
+	if timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		expired = t.C
+	}
+
+	select {
+	case reply := <-w.result:
+		return reply
+	case <-expired:
+	case <-c.closed:
+	case <-c.ctx.Done():
+	}
+
+	r.blockMu.Lock()
+	served := w.served
+	if !served {
+		r.unblock(w)
+	}
+	r.blockMu.Unlock()
+	if served {
+		// клиента обслужили одновременно с таймаутом — данные уже сняты со списка, отдаём их
+		return <-w.result
+	}
+	if c.ctx.Err() != nil {
+		return Reply{Type: "error", Value: "UNBLOCKED server is shutting down"}
+	}
+	return w.timeoutReply()
+}
+
+// метод unblock - убирает ждущего из очередей всех его ключей (вызывается под blockMu).
+func (r *Router) unblock(w *waiter) {
+	for _, key := range w.keys {
+		r.store.UnblockOn(key)
+		queue := r.blocked[key]
+		for i, other := range queue {
+			if other == w {
+				queue = append(queue[:i], queue[i+1:]...)
+				break
+			}
+		}
+		if len(queue) == 0 {
+			delete(r.blocked, key)
+		} else {
+			r.blocked[key] = queue
+		}
+	}
+}
+
+// метод serveBlocked - обслуживает клиентов, ждущих ключи, которые изменились с прошлой проверки.
+// Вызывается после каждой команды (вне writeMu). Проверяются все базы: команда могла изменить
+// и чужую базу (MOVE, SWAPDB, FLUSHALL).
+func (r *Router) serveBlocked() {
+	for _, db := range r.dbs {
+		db.serveReady()
+	}
+}
+
+// метод serveReady - обслуживает клиентов этой базы, чьи ключи стали готовыми. Обслуживание может само
+// изменить ключи (BLMOVE кладёт элемент в другой список), поэтому проверка повторяется, пока готовые ключи не кончатся.
+func (r *Router) serveReady() {
+	keys := r.store.TakeReady()
+	if keys == nil {
+		return
+	}
+	r.blockMu.Lock()
+	defer r.blockMu.Unlock()
+	for len(keys) > 0 {
+		for _, key := range keys {
+			r.serveKey(key)
+		}
+		keys = r.store.TakeReady()
+	}
+}
+
+// метод serveKey - отдаёт данные ключа ждущим его клиентам в порядке прихода (вызывается под blockMu).
+func (r *Router) serveKey(key string) {
+	for i := 0; i < len(r.blocked[key]); {
+		w := r.blocked[key][i]
+		if w.gone() {
+			// клиент отключился, но wait ещё не успел снять его с очереди
+			r.unblock(w)
+			continue
+		}
+		reply, ok := r.attempt(w, key)
+		if !ok || reply.Type == "error" {
+			if w.streams() {
+				i++ // у этого клиента нечего читать, а у следующего (другая группа, другой "$") может быть
+				continue
+			}
+			return // данных нет или ключ сменил тип — клиенты ждут дальше
+		}
+		r.unblock(w)
+		w.served = true
+		w.result <- reply
+	}
+}
+
+// метод blockingOnce - блокирующая команда внутри EXEC: как в Redis, она не ждёт,
+// а выполняется один раз и при отсутствии данных сразу отвечает как по таймауту.
+func (r *Router) blockingOnce(cmd string, args []string) Reply {
+	if msg := checkArity(cmd, args); msg != "" {
+		return Reply{Type: "error", Value: msg}
+	}
+	w, _, errMsg := newWaiter(cmd, args)
+	if errMsg != "" {
+		return Reply{Type: "error", Value: errMsg}
+	}
+	if reply, ok := r.tryAll(w); ok {
+		return reply
+	}
+	return w.timeoutReply()
+}
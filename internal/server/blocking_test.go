@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/AntonRadchenko/mini-redis-go/internal/store"
+)
+
+// проверяет, что LPUSH, пришедший после отключения клиента в BLPOP, но до того, как тот снял себя
+// с очереди, не отдаёт элемент отключившемуся клиенту: элемент остаётся в списке
+func TestBlpopDisconnectedClientNotServed(t *testing.T) {
+	r := New(store.NewStore())
+	conn, peer := net.Pipe()
+	defer peer.Close()
+	c := newClient(context.Background(), conn, 1)
+
+	done := make(chan Reply, 1)
+	go func() { done <- r.block(c, "BLPOP", []string{"BLPOP", "list", "0"}) }()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		r.blockMu.Lock()
+		queued := len(r.blocked["list"]) == 1
+		r.blockMu.Unlock()
+		if queued {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("client was not queued on the key")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// держим blockMu, чтобы wait не успел снять клиента с очереди, пока LPUSH его обслуживает
+	r.blockMu.Lock()
+	c.close()
+	r.dispatch("LPUSH", []string{"LPUSH", "list", "x"})
+	r.serveKey("list")
+	r.blockMu.Unlock()
+
+	if reply := <-done; reply.Type != "array" || reply.Value != nil {
+		t.Fatalf("disconnected client must not be served, got %+v", reply)
+	}
+	if reply := r.Handle([]string{"LLEN", "list"}); reply.Value != 1 {
+		t.Fatalf("element must stay in the list, LLEN = %+v", reply)
+	}
+	if len(r.blocked) != 0 {
+		t.Fatalf("expected no blocked clients left, got %v", r.blocked)
+	}
+}
@@ -0,0 +1,336 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/AntonRadchenko/mini-redis-go/internal/logx"
+	"github.com/AntonRadchenko/mini-redis-go/internal/resp"
+	"github.com/AntonRadchenko/mini-redis-go/internal/store"
+)
+
+// структура client — состояние одного подключённого клиента, которое живёт между командами:
+// выбранная база данных (SELECT), открытая транзакция (MULTI), очередь её команд, ключи под наблюдением (WATCH) и подписки.
+//
+// Все ответы клиенту идут через ограниченную очередь out, которую разбирает отдельная горутина-писатель:
+// так ответы на команды и сообщения PUBLISH из других соединений не перемешиваются в сокете,
+// а публикующий не ждёт медленного подписчика. Если очередь переполнена, клиент отключается
+// (аналог client-output-buffer-limit pubsub в Redis).
+type client struct {
+	db       int                  // номер выбранной базы данных (SELECT), сначала 0
+	multi    bool                 // клиент внутри MULTI — команды не выполняются, а копятся в очереди
+	queue    [][]string           // команды, поставленные в очередь после MULTI
+	queueErr bool                 // при постановке в очередь была ошибка — EXEC вернёт EXECABORT
+	watch    map[int]*store.Watch // ключи, за которыми следит клиент, по номерам баз (nil, пока не было WATCH)
+
+	channels map[string]struct{} // каналы, на которые подписан клиент (SUBSCRIBE)
+	patterns map[string]struct{} // шаблоны, на которые подписан клиент (PSUBSCRIBE)
+
+	ctx        context.Context // контекст сервера: при остановке заблокированные клиенты освобождаются
+	conn       net.Conn
+	out        chan Reply    // исходящие ответы и сообщения, ждущие записи в сокет
+	stop       chan struct{} // просьба к писателю дописать очередь и выйти (передача соединения репликации)
+	closed     chan struct{} // закрывается, когда соединение закрыто
+	closeOnce  sync.Once
+	writerDone chan struct{} // закрывается, когда горутина-писатель завершилась
+}
+
+// noReply — ответ-заглушка (пустой Type): клиенту уже всё отправлено внутри обработчика команды.
+var noReply = Reply{}
+
+// конструктор newClient создаёт состояние для нового соединения
+// с очередью исходящих сообщений на queueSize элементов.
+func newClient(ctx context.Context, conn net.Conn, queueSize int) *client {
+	return &client{
+		ctx:        ctx,
+		channels:   make(map[string]struct{}),
+		patterns:   make(map[string]struct{}),
+		conn:       conn,
+		out:        make(chan Reply, queueSize),
+		stop:       make(chan struct{}),
+		closed:     make(chan struct{}),
+		writerDone: make(chan struct{}),
+	}
+}
+
+// метод writeLoop - горутина-писатель: записывает в сокет всё, что попадает в очередь клиента.
+func (c *client) writeLoop(wr *resp.Writer) {
+	defer close(c.writerDone)
+	for {
+		select {
+		case reply := <-c.out:
+			if err := writeReply(wr, reply); err != nil {
+				if !c.isClosed() { // сами закрыли соединение — это не ошибка записи
+					logx.Error("Write error: %v", err)
+				}
+				c.close()
+				return
+			}
+		case <-c.stop:
+			// дописываем то, что уже успело попасть в очередь, и выходим
+			for {
+				select {
+				case reply := <-c.out:
+					if err := writeReply(wr, reply); err != nil {
+						c.close()
+						return
+					}
+				default:
+					return
+				}
+			}
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+// метод send - ставит в очередь ответ на команду самого клиента.
+// Ждёт, пока в очереди появится место; false — соединение уже закрыто.
+func (c *client) send(reply Reply) bool {
+	select {
+	case c.out <- reply:
+		return true
+	case <-c.closed:
+		return false
+	}
+}
+
+// метод push - ставит в очередь сообщение из другого соединения (PUBLISH), не блокируясь.
+// Если очередь переполнена, подписчик не успевает читать — соединение закрывается.
+func (c *client) push(reply Reply) bool {
+	select {
+	case c.out <- reply:
+		return true
+	case <-c.closed:
+		return false
+	default:
+		logx.Error("Client %s disconnected: output queue overflow (slow subscriber)", c.conn.RemoteAddr())
+		c.close()
+		return false
+	}
+}
+
+// метод detach - останавливает писателя, дождавшись записи уже поставленных ответов:
+// после этого соединением можно пользоваться напрямую (передача репликации после PSYNC).
+func (c *client) detach() {
+	close(c.stop)
+	<-c.writerDone
+}
+
+// метод close - закрывает соединение (повторные вызовы ничего не делают).
+// Закрытие сокета прерывает и чтение команд в handleConn.
+func (c *client) close() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.conn.Close()
+	})
+}
+
+// метод isClosed - закрыто ли уже соединение.
+func (c *client) isClosed() bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// метод subscriptions - сколько всего подписок (каналов и шаблонов) у клиента.
+func (c *client) subscriptions() int {
+	return len(c.channels) + len(c.patterns)
+}
+
+// метод handleClient - выполняет команду с учётом состояния соединения:
+// SELECT и команды транзакций (MULTI/EXEC/DISCARD/WATCH/UNWATCH) обрабатываются здесь,
+// внутри MULTI остальные команды ставятся в очередь, а вне транзакции уходят в Handle роутера выбранной базы.
+func (r *Router) handleClient(c *client, args []string) Reply {
+	if len(args) == 0 {
+		return Reply{"error", "ERR empty command"}
+	}
+	cmd := strings.ToUpper(args[0])
+	r = r.dbs[c.db]
+
+	// в режиме подписки соединение только получает сообщения: разрешены лишь команды подписок и PING
+	if c.subscriptions() > 0 {
+		switch cmd {
+		case "SUBSCRIBE", "UNSUBSCRIBE", "PSUBSCRIBE", "PUNSUBSCRIBE":
+		case "PING":
+			if len(args) > 2 {
+				return Reply{Type: "error", Value: "ERR wrong number of arguments for 'ping' command"}
+			}
+			msg := ""
+			if len(args) == 2 {
+				msg = args[1]
+			}
+			return Reply{Type: "array", Value: []string{"pong", msg}}
+		default:
+			return Reply{Type: "error", Value: fmt.Sprintf(
+				"ERR Can't execute '%s': only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING are allowed in this context",
+				strings.ToLower(args[0]))}
+		}
+	}
+
+	switch cmd {
+	case "SUBSCRIBE", "UNSUBSCRIBE", "PSUBSCRIBE", "PUNSUBSCRIBE":
+		if c.multi {
+			c.queueErr = true
+			return Reply{Type: "error", Value: fmt.Sprintf("ERR Command '%s' not allowed inside a transaction", strings.ToLower(cmd))}
+		}
+		if msg := checkArity(cmd, args); msg != "" {
+			return Reply{Type: "error", Value: msg}
+		}
+		pattern := cmd == "PSUBSCRIBE" || cmd == "PUNSUBSCRIBE"
+		var replies []Reply
+		if cmd == "SUBSCRIBE" || cmd == "PSUBSCRIBE" {
+			replies = r.pubsub.subscribe(c, args[1:], pattern)
+		} else {
+			replies = r.pubsub.unsubscribe(c, args[1:], pattern)
+		}
+		// на каждый канал — отдельное подтверждение
+		for _, reply := range replies {
+			if !c.send(reply) {
+				break
+			}
+		}
+		return noReply
+
+	case "SELECT":
+		if c.multi {
+			break // внутри транзакции SELECT ставится в очередь и выполняется в EXEC
+		}
+		return r.selectDB(c, args)
+
+	case "MULTI":
+		if c.multi {
+			return Reply{Type: "error", Value: "ERR MULTI calls can not be nested"}
+		}
+		c.multi = true
+		return Reply{Type: "simple", Value: "OK"}
+
+	case "EXEC":
+		if !c.multi {
+			return Reply{Type: "error", Value: "ERR EXEC without MULTI"}
+		}
+		return r.exec(c)
+
+	case "DISCARD":
+		if !c.multi {
+			return Reply{Type: "error", Value: "ERR DISCARD without MULTI"}
+		}
+		c.resetMulti()
+		r.unwatch(c)
+		return Reply{Type: "simple", Value: "OK"}
+
+	case "WATCH":
+		if c.multi {
+			return Reply{Type: "error", Value: "ERR WATCH inside MULTI is not allowed"}
+		}
+		if len(args) < 2 {
+			return Reply{Type: "error", Value: "ERR wrong number of arguments for 'watch' command"}
+		}
+		if c.watch == nil {
+			c.watch = make(map[int]*store.Watch)
+		}
+		if c.watch[r.db] == nil {
+			c.watch[r.db] = store.NewWatch()
+		}
+		r.store.Watch(c.watch[r.db], args[1:]...)
+		return Reply{Type: "simple", Value: "OK"}
+
+	case "UNWATCH":
+		if !c.multi {
+			r.unwatch(c)
+		}
+		return Reply{Type: "simple", Value: "OK"}
+	}
+
+	if c.multi {
+		// как в Redis: неизвестная команда или неверное число аргументов
+		// обнаруживаются сразу, а вся транзакция потом отменяется на EXEC
+		if msg := checkArity(cmd, args); msg != "" {
+			c.queueErr = true
+			return Reply{Type: "error", Value: msg}
+		}
+		c.queue = append(c.queue, args)
+		return Reply{Type: "simple", Value: "QUEUED"}
+	}
+
+	// блокирующая команда может ждать долго — она сама отпускает execMu на время ожидания
+	if isBlocking(cmd) {
+		return r.block(c, cmd, args)
+	}
+
+	r.execMu.RLock()
+	defer r.execMu.RUnlock()
+	return r.Handle(args)
+}
+
+// метод exec - выполняет очередь транзакции атомарно (EXEC).
+// Возвращает массив ответов каждой команды, nil-массив, если изменился ключ под WATCH,
+// или EXECABORT, если при постановке в очередь были ошибки.
+func (r *Router) exec(c *client) Reply {
+	queue, queueErr := c.queue, c.queueErr
+	c.resetMulti()
+
+	r.execMu.Lock()
+	defer r.execMu.Unlock()
+
+	watchChanged := false
+	for db, w := range c.watch {
+		watchChanged = watchChanged || r.dbs[db].store.WatchChanged(w)
+	}
+	r.unwatch(c) // после EXEC наблюдение снимается всегда
+
+	if queueErr {
+		return Reply{Type: "error", Value: "EXECABORT Transaction discarded because of previous errors."}
+	}
+	if watchChanged {
+		return Reply{Type: "array", Value: nil}
+	}
+
+	r.inExec = true
+	replies := make([]Reply, 0, len(queue))
+	for _, args := range queue {
+		if strings.EqualFold(args[0], "SELECT") {
+			replies = append(replies, r.selectDB(c, args))
+			r = r.dbs[c.db] // следующие команды транзакции — уже в выбранной базе
+			continue
+		}
+		replies = append(replies, r.Handle(args))
+	}
+	r.inExec = false
+
+	// изменения транзакции уходят в журнал и репликам одним блоком MULTI ... EXEC
+	if len(r.txProp) > 0 {
+		r.writeMu.Lock()
+		r.emit([]string{"MULTI"})
+		for _, lc := range r.txProp {
+			r.emitIn(lc.db, lc.args)
+		}
+		r.emit([]string{"EXEC"})
+		r.writeMu.Unlock()
+		r.txProp = nil
+	}
+	r.serveBlocked() // данные для заблокированных клиентов отдаются уже после всей транзакции
+	return Reply{Type: "array", Value: replies}
+}
+
+// метод unwatch - снимает наблюдение клиента со всех ключей во всех базах.
+func (r *Router) unwatch(c *client) {
+	for db, w := range c.watch {
+		r.dbs[db].store.Unwatch(w)
+	}
+	c.watch = nil
+}
+
+// метод resetMulti - выходит из режима MULTI и очищает очередь.
+func (c *client) resetMulti() {
+	c.multi = false
+	c.queue = nil
+	c.queueErr = false
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+)
+
+// errDBIndex — ответ на номер базы, которой на сервере нет.
+const errDBIndex = "ERR DB index is out of range"
+
+// метод parseDB - роутер базы по номеру из аргумента (SELECT, SWAPDB, MOVE, COPY ... DB).
+// Возвращает текст ошибки, если это не число или базы с таким номером нет.
+func (r *Router) parseDB(arg string) (*Router, string) {
+	n, err := strconv.Atoi(arg)
+	if err != nil {
+		return nil, errNotInteger
+	}
+	if n < 0 || n >= len(r.dbs) {
+		return nil, errDBIndex
+	}
+	return r.dbs[n], ""
+}
+
+// метод selectDB - SELECT index: следующие команды клиента применяются к выбранной базе.
+// Номер базы — часть состояния соединения, как открытая транзакция или WATCH.
+func (r *Router) selectDB(c *client, args []string) Reply {
+	if msg := checkArity("SELECT", args); msg != "" {
+		return Reply{Type: "error", Value: msg}
+	}
+	db, msg := r.parseDB(args[1])
+	if msg != "" {
+		return Reply{Type: "error", Value: msg}
+	}
+	c.db = db.db
+	return Reply{Type: "simple", Value: "OK"}
+}
+
+// метод dbCommand - команды над базами целиком: SWAPDB, FLUSHDB и FLUSHALL.
+func (r *Router) dbCommand(cmd string, args []string) Reply {
+	if msg := checkArity(cmd, args); msg != "" {
+		return Reply{Type: "error", Value: msg}
+	}
+
+	switch cmd {
+	case "SWAPDB":
+		// клиенты остаются при своих номерах баз и сразу видят обменянные данные
+		first, msg := r.parseDB(args[1])
+		if msg == errNotInteger {
+			return Reply{Type: "error", Value: "ERR invalid first DB index"}
+		}
+		if msg != "" {
+			return Reply{Type: "error", Value: msg}
+		}
+		second, msg := r.parseDB(args[2])
+		if msg == errNotInteger {
+			return Reply{Type: "error", Value: "ERR invalid second DB index"}
+		}
+		if msg != "" {
+			return Reply{Type: "error", Value: msg}
+		}
+		first.store.SwapDB(second.store)
+		return Reply{Type: "simple", Value: "OK"}
+
+	case "FLUSHDB", "FLUSHALL":
+		// FLUSHDB|FLUSHALL [ASYNC|SYNC]: с ASYNC значения разбираются в фоне, как у UNLINK
+		if len(args) > 2 {
+			return Reply{Type: "error", Value: "ERR syntax error"}
+		}
+		async := false
+		if len(args) == 2 {
+			switch strings.ToUpper(args[1]) {
+			case "ASYNC":
+				async = true
+			case "SYNC":
+			default:
+				return Reply{Type: "error", Value: "ERR syntax error"}
+			}
+		}
+		dbs := []*Router{r}
+		if cmd == "FLUSHALL" {
+			dbs = r.dbs
+		}
+		for _, db := range dbs {
+			if async {
+				db.store.FlushAsync()
+			} else {
+				db.store.Flush()
+			}
+		}
+		return Reply{Type: "simple", Value: "OK"}
+	}
+	return Reply{Type: "error", Value: "ERR unknown command '" + cmd + "'"}
+}
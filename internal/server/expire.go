@@ -0,0 +1,96 @@
+package server
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AntonRadchenko/mini-redis-go/internal/store"
+)
+
+// метод expireCommand - команды времени жизни ключа: EXPIRE, PEXPIRE, EXPIREAT, PEXPIREAT (с NX/XX/GT/LT),
+// TTL, PTTL, EXPIRETIME, PEXPIRETIME и PERSIST. TTL хранится с точностью до миллисекунды.
+func (r *Router) expireCommand(cmd string, args []string) Reply {
+	if msg := checkArity(cmd, args); msg != "" {
+		return Reply{Type: "error", Value: msg}
+	}
+	key := args[1]
+
+	switch cmd {
+	case "EXPIRE", "PEXPIRE", "EXPIREAT", "PEXPIREAT":
+		n, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			return Reply{Type: "error", Value: errNotInteger}
+		}
+		flags, msg := parseExpireFlags(args[3:])
+		if msg != "" {
+			return Reply{Type: "error", Value: msg}
+		}
+		unit, base := time.Second, time.Now()
+		if strings.HasPrefix(cmd, "P") {
+			unit = time.Millisecond
+		}
+		if strings.HasSuffix(cmd, "AT") {
+			base = time.Unix(0, 0)
+		}
+		at, msg := expireMoment(n, unit, base, cmd)
+		if msg != "" {
+			return Reply{Type: "error", Value: msg}
+		}
+		return boolReply(r.store.ExpireAtWith(key, at, flags))
+
+	case "TTL":
+		return Reply{Type: "integer", Value: r.store.TTL(key)}
+
+	case "PTTL":
+		return Reply{Type: "integer", Value: int(r.store.PTTL(key))}
+
+	case "EXPIRETIME", "PEXPIRETIME":
+		at := r.store.ExpireTimeMillis(key)
+		if at >= 0 && cmd == "EXPIRETIME" {
+			at /= 1000
+		}
+		return Reply{Type: "integer", Value: int(at)}
+
+	case "PERSIST":
+		return boolReply(r.store.Persist(key))
+	}
+	return Reply{Type: "error", Value: "ERR unknown command '" + cmd + "'"}
+}
+
+// функция parseExpireFlags - разбирает условия NX, XX, GT и LT после времени в EXPIRE и его вариантах.
+func parseExpireFlags(args []string) (store.ExpireFlags, string) {
+	var flags store.ExpireFlags
+	for _, arg := range args {
+		switch strings.ToUpper(arg) {
+		case "NX":
+			flags.NX = true
+		case "XX":
+			flags.XX = true
+		case "GT":
+			flags.GT = true
+		case "LT":
+			flags.LT = true
+		default:
+			return flags, "ERR Unsupported option " + arg
+		}
+	}
+	if flags.NX && (flags.XX || flags.GT || flags.LT) {
+		return flags, "ERR NX and XX, GT or LT options at the same time are not compatible"
+	}
+	if flags.GT && flags.LT {
+		return flags, "ERR GT and LT options at the same time are not compatible"
+	}
+	return flags, ""
+}
+
+// функция expireMoment - переводит n единиц времени, отсчитанных от base, в абсолютный момент истечения.
+// Отрицательное n допустимо (момент в прошлом), переполнение — ошибка "invalid expire time".
+func expireMoment(n int64, unit time.Duration, base time.Time, cmd string) (time.Time, string) {
+	ms := int64(unit / time.Millisecond)
+	if n > (math.MaxInt64-base.UnixMilli())/ms || n < math.MinInt64/ms {
+		return time.Time{}, "ERR invalid expire time in '" + strings.ToLower(cmd) + "' command"
+	}
+	return time.UnixMilli(base.UnixMilli() + n*ms), ""
+}
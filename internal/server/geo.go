@@ -0,0 +1,306 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/AntonRadchenko/mini-redis-go/internal/store"
+)
+
+// geoUnits — сколько метров в единице расстояния гео-команд.
+var geoUnits = map[string]float64{"m": 1, "km": 1000, "ft": 0.3048, "mi": 1609.34}
+
+// errGeoUnit — ответ на неизвестную единицу расстояния.
+const errGeoUnit = "ERR unsupported unit provided. please use M, KM, FT, MI"
+
+// метод geoCommand - гео-команды (GEOADD, GEOPOS, GEODIST, GEOSEARCH, GEOSEARCHSTORE).
+// Геоиндекс — обычное упорядоченное множество, где счёт элемента — 52-битный geohash его координат,
+// поэтому к нему применимы и команды Z* (например, ZREM или ZCARD).
+func (r *Router) geoCommand(cmd string, args []string) Reply {
+	if msg := checkArity(cmd, args); msg != "" {
+		return Reply{Type: "error", Value: msg}
+	}
+	key := args[1]
+
+	switch cmd {
+	case "GEOADD":
+		return r.geoAdd(key, args[2:])
+
+	case "GEOPOS":
+		scores, err := r.store.ZMScore(key, args[2:]...)
+		if err != nil {
+			return errorReply(err)
+		}
+		out := make([]Reply, len(scores))
+		for i, score := range scores {
+			if score == nil {
+				out[i] = Reply{Type: "array", Value: nil}
+				continue
+			}
+			out[i] = coordReply(store.GeoFromScore(*score))
+		}
+		return Reply{Type: "array", Value: out}
+
+	case "GEODIST":
+		if len(args) > 5 {
+			return Reply{Type: "error", Value: "ERR syntax error"}
+		}
+		unit := 1.0
+		if len(args) == 5 {
+			u, ok := geoUnits[strings.ToLower(args[4])]
+			if !ok {
+				return Reply{Type: "error", Value: errGeoUnit}
+			}
+			unit = u
+		}
+		scores, err := r.store.ZMScore(key, args[2], args[3])
+		if err != nil {
+			return errorReply(err)
+		}
+		if scores[0] == nil || scores[1] == nil {
+			return Reply{Type: "bulk", Value: nil}
+		}
+		dist := store.GeoDistance(store.GeoFromScore(*scores[0]), store.GeoFromScore(*scores[1]))
+		return Reply{Type: "bulk", Value: strconv.FormatFloat(dist/unit, 'f', 4, 64)}
+
+	case "GEOSEARCH":
+		q, opts, msg := parseGeoSearch(args[2:], false)
+		if msg != "" {
+			return Reply{Type: "error", Value: msg}
+		}
+		res, err := r.store.GeoSearch(key, q)
+		if err != nil {
+			return errorReply(err)
+		}
+		return geoSearchReply(res, opts)
+
+	case "GEOSEARCHSTORE":
+		// GEOSEARCHSTORE dest src ...
+		q, opts, msg := parseGeoSearch(args[3:], true)
+		if msg != "" {
+			return Reply{Type: "error", Value: msg}
+		}
+		n, err := r.store.GeoSearchStore(key, args[2], q, opts.storeDist, opts.unit)
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "integer", Value: n}
+	}
+	return Reply{Type: "error", Value: "ERR unknown command '" + cmd + "'"}
+}
+
+// метод geoAdd - GEOADD key [NX|XX] [CH] longitude latitude member [...]: добавляет элементы с geohash-счётом.
+// Отвечает числом добавленных элементов, а с CH — добавленных и изменённых.
+func (r *Router) geoAdd(key string, args []string) Reply {
+	var flags store.ZAddFlags
+	ch := false
+options:
+	for len(args) > 0 {
+		switch strings.ToUpper(args[0]) {
+		case "NX":
+			flags.NX = true
+		case "XX":
+			flags.XX = true
+		case "CH":
+			ch = true
+		default:
+			break options
+		}
+		args = args[1:]
+	}
+	if flags.NX && flags.XX {
+		return Reply{Type: "error", Value: "ERR XX and NX options at the same time are not compatible"}
+	}
+	if len(args) == 0 || len(args)%3 != 0 {
+		return Reply{Type: "error", Value: "ERR syntax error. Try GEOADD key [x1] [y1] [name1] [x2] [y2] [name2] ... "}
+	}
+
+	items := make([]store.ZMember, 0, len(args)/3)
+	for i := 0; i < len(args); i += 3 {
+		p, msg := parseGeoPoint(args[i], args[i+1])
+		if msg != "" {
+			return Reply{Type: "error", Value: msg}
+		}
+		items = append(items, store.ZMember{Member: args[i+2], Score: store.GeoScore(p)})
+	}
+	added, changed, err := r.store.ZAdd(key, flags, items)
+	if err != nil {
+		return errorReply(err)
+	}
+	if ch {
+		return Reply{Type: "integer", Value: added + changed}
+	}
+	return Reply{Type: "integer", Value: added}
+}
+
+// функция parseGeoPoint - долгота и широта из аргументов с проверкой, что точку можно закодировать.
+func parseGeoPoint(lonArg, latArg string) (store.GeoPoint, string) {
+	lon, err1 := strconv.ParseFloat(lonArg, 64)
+	lat, err2 := strconv.ParseFloat(latArg, 64)
+	if err1 != nil || err2 != nil {
+		return store.GeoPoint{}, "ERR value is not a valid float"
+	}
+	p := store.GeoPoint{Lon: lon, Lat: lat}
+	if !store.GeoValid(p) {
+		return p, fmt.Sprintf("ERR invalid longitude,latitude pair %f,%f", lon, lat)
+	}
+	return p, ""
+}
+
+// функция formatCoord - координата в ответе: 17 знаков после точки без хвостовых нулей, как в Redis.
+func formatCoord(f float64) string {
+	s := strings.TrimRight(strconv.FormatFloat(f, 'f', 17, 64), "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// функция coordReply - пара [долгота, широта].
+func coordReply(p store.GeoPoint) Reply {
+	return Reply{Type: "array", Value: []Reply{
+		{Type: "bulk", Value: formatCoord(p.Lon)},
+		{Type: "bulk", Value: formatCoord(p.Lat)},
+	}}
+}
+
+// структура geoSearchOpts — параметры ответа GEOSEARCH и записи GEOSEARCHSTORE.
+type geoSearchOpts struct {
+	unit                          float64 // метров в единице запроса (расстояния в ответе — в ней)
+	withDist, withHash, withCoord bool
+	storeDist                     bool
+}
+
+// функция parseGeoSearch - разбирает условия GEOSEARCH/GEOSEARCHSTORE: FROMMEMBER|FROMLONLAT, BYRADIUS|BYBOX,
+// ASC|DESC, COUNT n [ANY], WITH* (только GEOSEARCH) и STOREDIST (только GEOSEARCHSTORE).
+func parseGeoSearch(args []string, storing bool) (store.GeoQuery, geoSearchOpts, string) {
+	var q store.GeoQuery
+	opts := geoSearchOpts{unit: 1}
+	fromMember, fromLonLat, byRadius, byBox := false, false, false, false
+
+	parseUnit := func(arg string) bool {
+		u, ok := geoUnits[strings.ToLower(arg)]
+		opts.unit = u
+		return ok
+	}
+
+	for i := 0; i < len(args); i++ {
+		left := len(args) - i - 1 // сколько аргументов осталось после текущего
+		switch opt := strings.ToUpper(args[i]); {
+		case opt == "FROMMEMBER" && left >= 1:
+			q.FromMember, q.ByMember, fromMember = args[i+1], true, true
+			i++
+		case opt == "FROMLONLAT" && left >= 2:
+			p, msg := parseGeoPoint(args[i+1], args[i+2])
+			if msg != "" {
+				return q, opts, msg
+			}
+			q.Center, fromLonLat = p, true
+			i += 2
+		case opt == "BYRADIUS" && left >= 2:
+			radius, err := strconv.ParseFloat(args[i+1], 64)
+			if err != nil {
+				return q, opts, "ERR need numeric radius"
+			}
+			if radius < 0 {
+				return q, opts, "ERR radius cannot be negative"
+			}
+			if !parseUnit(args[i+2]) {
+				return q, opts, errGeoUnit
+			}
+			q.Shape.Radius, byRadius = radius, true
+			i += 2
+		case opt == "BYBOX" && left >= 3:
+			width, err := strconv.ParseFloat(args[i+1], 64)
+			if err != nil {
+				return q, opts, "ERR need numeric width"
+			}
+			height, err := strconv.ParseFloat(args[i+2], 64)
+			if err != nil {
+				return q, opts, "ERR need numeric height"
+			}
+			if width < 0 || height < 0 {
+				return q, opts, "ERR height or width cannot be negative"
+			}
+			if !parseUnit(args[i+3]) {
+				return q, opts, errGeoUnit
+			}
+			q.Shape = store.GeoShape{Box: true, Width: width, Height: height}
+			byBox = true
+			i += 3
+		case opt == "ASC":
+			q.Sort = 1
+		case opt == "DESC":
+			q.Sort = -1
+		case opt == "COUNT" && left >= 1:
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil {
+				return q, opts, errNotInteger
+			}
+			if n <= 0 {
+				return q, opts, "ERR COUNT must be > 0"
+			}
+			q.Count = n
+			i++
+			if i+1 < len(args) && strings.EqualFold(args[i+1], "ANY") {
+				q.Any = true
+				i++
+			}
+		case opt == "WITHDIST":
+			opts.withDist = true
+		case opt == "WITHHASH":
+			opts.withHash = true
+		case opt == "WITHCOORD":
+			opts.withCoord = true
+		case opt == "STOREDIST" && storing:
+			opts.storeDist = true
+		default:
+			return q, opts, "ERR syntax error"
+		}
+	}
+
+	switch {
+	case fromMember == fromLonLat:
+		return q, opts, "ERR exactly one of FROMMEMBER or FROMLONLAT can be specified for " + geoCmdName(storing)
+	case byRadius == byBox:
+		return q, opts, "ERR exactly one of BYRADIUS and BYBOX can be specified for " + geoCmdName(storing)
+	case storing && (opts.withDist || opts.withHash || opts.withCoord):
+		return q, opts, "ERR GEOSEARCHSTORE is not compatible with WITHDIST, WITHHASH and WITHCOORD options"
+	}
+	// размеры области — в метрах
+	q.Shape.Radius *= opts.unit
+	q.Shape.Width *= opts.unit
+	q.Shape.Height *= opts.unit
+	return q, opts, ""
+}
+
+// функция geoCmdName - имя команды для текстов ошибок разбора.
+func geoCmdName(storing bool) string {
+	if storing {
+		return "GEOSEARCHSTORE"
+	}
+	return "GEOSEARCH"
+}
+
+// функция geoSearchReply - ответ GEOSEARCH: имена элементов или, с WITH*, массивы
+// [имя, расстояние, geohash, [долгота, широта]] из запрошенных частей.
+func geoSearchReply(res []store.GeoResult, opts geoSearchOpts) Reply {
+	out := make([]Reply, len(res))
+	for i, g := range res {
+		member := Reply{Type: "bulk", Value: g.Member}
+		if !opts.withDist && !opts.withHash && !opts.withCoord {
+			out[i] = member
+			continue
+		}
+		item := []Reply{member}
+		if opts.withDist {
+			item = append(item, Reply{Type: "bulk", Value: strconv.FormatFloat(g.Dist/opts.unit, 'f', 4, 64)})
+		}
+		if opts.withHash {
+			item = append(item, Reply{Type: "integer", Value: int(g.Score)})
+		}
+		if opts.withCoord {
+			item = append(item, coordReply(g.Point))
+		}
+		out[i] = Reply{Type: "array", Value: item}
+	}
+	return Reply{Type: "array", Value: out}
+}
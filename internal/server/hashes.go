@@ -0,0 +1,134 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+)
+
+// метод hashCommand - команды над хешами (HSET, HGET, HMGET, HDEL, HGETALL, HINCRBY, HINCRBYFLOAT, HSCAN и др.).
+// Команда к ключу другого типа получает WRONGTYPE, хеш без полей удаляется вместе с ключом.
+func (r *Router) hashCommand(cmd string, args []string) Reply {
+	if msg := checkArity(cmd, args); msg != "" {
+		return Reply{Type: "error", Value: msg}
+	}
+	key := args[1]
+
+	switch cmd {
+	case "HSET", "HMSET":
+		if len(args)%2 != 0 {
+			return Reply{Type: "error", Value: "ERR wrong number of arguments for '" + strings.ToLower(cmd) + "' command"}
+		}
+		added, err := r.store.HSet(key, args[2:]...)
+		if err != nil {
+			return errorReply(err)
+		}
+		if cmd == "HMSET" {
+			return Reply{Type: "simple", Value: "OK"}
+		}
+		return Reply{Type: "integer", Value: added}
+
+	case "HSETNX":
+		created, err := r.store.HSetNX(key, args[2], args[3])
+		if err != nil {
+			return errorReply(err)
+		}
+		return boolReply(created)
+
+	case "HGET":
+		val, ok, err := r.store.HGet(key, args[2])
+		if err != nil {
+			return errorReply(err)
+		}
+		if !ok {
+			return Reply{Type: "bulk", Value: nil}
+		}
+		return Reply{Type: "bulk", Value: val}
+
+	case "HMGET":
+		vals, err := r.store.HMGet(key, args[2:]...)
+		if err != nil {
+			return errorReply(err)
+		}
+		out := make([]Reply, len(vals))
+		for i, val := range vals {
+			if val == nil {
+				out[i] = Reply{Type: "bulk", Value: nil}
+			} else {
+				out[i] = Reply{Type: "bulk", Value: *val}
+			}
+		}
+		return Reply{Type: "array", Value: out}
+
+	case "HDEL":
+		n, err := r.store.HDel(key, args[2:]...)
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "integer", Value: n}
+
+	case "HGETALL", "HKEYS", "HVALS":
+		get := r.store.HGetAll
+		switch cmd {
+		case "HKEYS":
+			get = r.store.HKeys
+		case "HVALS":
+			get = r.store.HVals
+		}
+		items, err := get(key)
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "array", Value: items}
+
+	case "HLEN":
+		n, err := r.store.HLen(key)
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "integer", Value: n}
+
+	case "HEXISTS":
+		ok, err := r.store.HExists(key, args[2])
+		if err != nil {
+			return errorReply(err)
+		}
+		return boolReply(ok)
+
+	case "HSTRLEN":
+		n, err := r.store.HStrLen(key, args[2])
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "integer", Value: n}
+
+	case "HINCRBY":
+		incr, err := strconv.ParseInt(args[3], 10, 64)
+		if err != nil {
+			return Reply{Type: "error", Value: errNotInteger}
+		}
+		n, err := r.store.HIncrBy(key, args[2], incr)
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "integer", Value: int(n)}
+
+	case "HINCRBYFLOAT":
+		val, err := r.store.HIncrByFloat(key, args[2], args[3])
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "bulk", Value: val}
+
+	case "HSCAN":
+		sa, errMsg := parseScanArgs(args[2:], false)
+		if errMsg != "" {
+			return Reply{Type: "error", Value: errMsg}
+		}
+		items, next, err := r.store.HScan(key, sa.cursor, sa.count, sa.match)
+		if err != nil {
+			return errorReply(err)
+		}
+		return scanReply(next, items)
+	}
+	return Reply{Type: "error", Value: "ERR unknown command '" + cmd + "'"}
+}
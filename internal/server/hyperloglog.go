@@ -0,0 +1,32 @@
+package server
+
+// метод hllCommand - команды HyperLogLog (PFADD, PFCOUNT, PFMERGE). Значение хранится строкой
+// в формате Redis, поэтому строка в другом формате получает WRONGTYPE.
+func (r *Router) hllCommand(cmd string, args []string) Reply {
+	if msg := checkArity(cmd, args); msg != "" {
+		return Reply{Type: "error", Value: msg}
+	}
+
+	switch cmd {
+	case "PFADD":
+		updated, err := r.store.PFAdd(args[1], args[2:]...)
+		if err != nil {
+			return errorReply(err)
+		}
+		return boolReply(updated)
+
+	case "PFCOUNT":
+		n, err := r.store.PFCount(args[1:]...)
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "integer", Value: int(n)}
+
+	case "PFMERGE":
+		if err := r.store.PFMerge(args[1], args[2:]...); err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "simple", Value: "OK"}
+	}
+	return Reply{Type: "error", Value: "ERR unknown command '" + cmd + "'"}
+}
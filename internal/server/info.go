@@ -0,0 +1,69 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+)
+
+// метод info - собирает ответ команды INFO: текст из секций вида "# Имя\r\nполе:значение\r\n".
+// Без аргумента (или с "all"/"default"/"everything") возвращаются все секции,
+// иначе — только запрошенная.
+func (r *Router) info(section string) string {
+	sections := []struct {
+		name string
+		text func() string
+	}{
+		{"memory", func() string {
+			limit, policy := r.store.MaxMemory()
+			var pending int64
+			for _, db := range r.dbs {
+				n, _ := db.store.LazyFreeStats()
+				pending += n
+			}
+			return "# Memory\r\nused_memory:" + strconv.FormatInt(r.store.UsedMemory(), 10) +
+				"\r\nmaxmemory:" + strconv.FormatInt(limit, 10) +
+				"\r\nmaxmemory_policy:" + policy.String() +
+				"\r\nlazyfree_pending_objects:" + strconv.FormatInt(pending, 10) + "\r\n"
+		}},
+		{"stats", func() string {
+			var expired, evicted, freed int64
+			for _, db := range r.dbs {
+				_, n := db.store.LazyFreeStats()
+				expired += db.store.ExpiredKeys()
+				evicted += db.store.EvictedKeys()
+				freed += n
+			}
+			return "# Stats\r\nexpired_keys:" + strconv.FormatInt(expired, 10) +
+				"\r\nevicted_keys:" + strconv.FormatInt(evicted, 10) +
+				"\r\nlazyfreed_objects:" + strconv.FormatInt(freed, 10) + "\r\n"
+		}},
+		{"replication", func() string {
+			if r.repl == nil {
+				return "# Replication\r\nrole:master\r\nconnected_slaves:0\r\n"
+			}
+			return r.repl.info()
+		}},
+		{"keyspace", func() string {
+			// как в Redis, перечисляются только непустые базы
+			text := "# Keyspace\r\n"
+			for _, db := range r.dbs {
+				if keys := db.store.DBSize(); keys > 0 {
+					text += "db" + strconv.Itoa(db.db) + ":keys=" + strconv.Itoa(keys) +
+						",expires=" + strconv.Itoa(db.store.Expires()) + "\r\n"
+				}
+			}
+			return text
+		}},
+	}
+
+	section = strings.ToLower(section)
+	all := section == "" || section == "all" || section == "default" || section == "everything"
+
+	var parts []string
+	for _, sec := range sections {
+		if all || sec.name == section {
+			parts = append(parts, sec.text())
+		}
+	}
+	return strings.Join(parts, "\r\n")
+}
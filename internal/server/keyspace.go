@@ -0,0 +1,96 @@
+package server
+
+import "strings"
+
+// errSameObject — ответ на COPY/MOVE ключа в самого себя.
+const errSameObject = "ERR source and destination objects are the same"
+
+// метод keyspaceCommand - команды над пространством ключей: обход (KEYS, SCAN, RANDOMKEY, DBSIZE)
+// и управление ключами независимо от типа значения (EXISTS, TYPE, TOUCH, RENAME, COPY, MOVE, UNLINK).
+func (r *Router) keyspaceCommand(cmd string, args []string) Reply {
+	if msg := checkArity(cmd, args); msg != "" {
+		return Reply{Type: "error", Value: msg}
+	}
+
+	switch cmd {
+	case "KEYS":
+		return Reply{Type: "array", Value: r.store.Keys(args[1])}
+
+	case "SCAN":
+		sa, errMsg := parseScanArgs(args[1:], true)
+		if errMsg != "" {
+			return Reply{Type: "error", Value: errMsg}
+		}
+		keys, next := r.store.Scan(sa.cursor, sa.count, sa.match, sa.typ)
+		return scanReply(next, keys)
+
+	case "RANDOMKEY":
+		key, ok := r.store.RandomKey()
+		if !ok {
+			return Reply{Type: "bulk", Value: nil}
+		}
+		return Reply{Type: "bulk", Value: key}
+
+	case "DBSIZE":
+		return Reply{Type: "integer", Value: r.store.DBSize()}
+
+	case "EXISTS":
+		return Reply{Type: "integer", Value: r.store.Exists(args[1:]...)}
+
+	case "TOUCH":
+		return Reply{Type: "integer", Value: r.store.Touch(args[1:]...)}
+
+	case "TYPE":
+		return Reply{Type: "simple", Value: r.store.Type(args[1])}
+
+	case "RENAME", "RENAMENX":
+		renamed, err := r.store.Rename(args[1], args[2], cmd == "RENAMENX")
+		if err != nil {
+			return errorReply(err)
+		}
+		if cmd == "RENAMENX" {
+			return boolReply(renamed)
+		}
+		return Reply{Type: "simple", Value: "OK"}
+
+	case "COPY":
+		// COPY source destination [DB index] [REPLACE]
+		db, replace := r, false
+		for i := 3; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "REPLACE":
+				replace = true
+			case "DB":
+				if i+1 >= len(args) {
+					return Reply{Type: "error", Value: "ERR syntax error"}
+				}
+				i++
+				var errMsg string
+				if db, errMsg = r.parseDB(args[i]); errMsg != "" {
+					return Reply{Type: "error", Value: errMsg}
+				}
+			default:
+				return Reply{Type: "error", Value: "ERR syntax error"}
+			}
+		}
+		if db == r && args[1] == args[2] {
+			return Reply{Type: "error", Value: errSameObject}
+		}
+		return boolReply(r.store.CopyTo(db.store, args[1], args[2], replace))
+
+	case "MOVE":
+		// MOVE key db: ключ переносится вместе с TTL, только если в целевой базе такого ключа нет
+		db, errMsg := r.parseDB(args[2])
+		if errMsg != "" {
+			return Reply{Type: "error", Value: errMsg}
+		}
+		if db == r {
+			return Reply{Type: "error", Value: errSameObject}
+		}
+		return boolReply(r.store.Move(args[1], db.store))
+
+	case "UNLINK":
+		return Reply{Type: "integer", Value: r.store.Unlink(args[1:]...)}
+	}
+	return Reply{Type: "error", Value: "ERR unknown command '" + cmd + "'"}
+}
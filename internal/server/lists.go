@@ -0,0 +1,159 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+)
+
+// метод listCommand - команды над списками (LPUSH, RPUSH, LPOP, RPOP, LRANGE, LLEN, LINDEX, LSET, LREM, LTRIM, LMOVE).
+// Команда к ключу другого типа получает WRONGTYPE, пустой список удаляется вместе с ключом.
+func (r *Router) listCommand(cmd string, args []string) Reply {
+	if msg := checkArity(cmd, args); msg != "" {
+		return Reply{Type: "error", Value: msg}
+	}
+	key := args[1]
+
+	switch cmd {
+	case "LPUSH", "RPUSH", "LPUSHX", "RPUSHX":
+		var n int
+		var err error
+		switch cmd {
+		case "LPUSH":
+			n, err = r.store.LPush(key, args[2:]...)
+		case "RPUSH":
+			n, err = r.store.RPush(key, args[2:]...)
+		case "LPUSHX":
+			n, err = r.store.LPushX(key, args[2:]...)
+		case "RPUSHX":
+			n, err = r.store.RPushX(key, args[2:]...)
+		}
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "integer", Value: n}
+
+	case "LPOP", "RPOP":
+		// без count отвечаем одним элементом, с count — массивом
+		if len(args) > 3 {
+			return Reply{Type: "error", Value: "ERR syntax error"}
+		}
+		count, withCount := 1, len(args) == 3
+		if withCount {
+			n, err := strconv.Atoi(args[2])
+			if err != nil || n < 0 {
+				return Reply{Type: "error", Value: "ERR value is out of range, must be positive"}
+			}
+			count = n
+		}
+		pop := r.store.LPop
+		if cmd == "RPOP" {
+			pop = r.store.RPop
+		}
+		items, err := pop(key, count)
+		if err != nil {
+			return errorReply(err)
+		}
+		if withCount {
+			if items == nil {
+				return Reply{Type: "array", Value: nil}
+			}
+			return Reply{Type: "array", Value: items}
+		}
+		if len(items) == 0 {
+			return Reply{Type: "bulk", Value: nil}
+		}
+		return Reply{Type: "bulk", Value: items[0]}
+
+	case "LRANGE":
+		start, err1 := strconv.Atoi(args[2])
+		stop, err2 := strconv.Atoi(args[3])
+		if err1 != nil || err2 != nil {
+			return Reply{Type: "error", Value: errNotInteger}
+		}
+		items, err := r.store.LRange(key, start, stop)
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "array", Value: items}
+
+	case "LLEN":
+		n, err := r.store.LLen(key)
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "integer", Value: n}
+
+	case "LINDEX":
+		index, err := strconv.Atoi(args[2])
+		if err != nil {
+			return Reply{Type: "error", Value: errNotInteger}
+		}
+		val, ok, err := r.store.LIndex(key, index)
+		if err != nil {
+			return errorReply(err)
+		}
+		if !ok {
+			return Reply{Type: "bulk", Value: nil}
+		}
+		return Reply{Type: "bulk", Value: val}
+
+	case "LSET":
+		index, err := strconv.Atoi(args[2])
+		if err != nil {
+			return Reply{Type: "error", Value: errNotInteger}
+		}
+		if err := r.store.LSet(key, index, args[3]); err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "simple", Value: "OK"}
+
+	case "LREM":
+		count, err := strconv.Atoi(args[2])
+		if err != nil {
+			return Reply{Type: "error", Value: errNotInteger}
+		}
+		n, err := r.store.LRem(key, count, args[3])
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "integer", Value: n}
+
+	case "LTRIM":
+		start, err1 := strconv.Atoi(args[2])
+		stop, err2 := strconv.Atoi(args[3])
+		if err1 != nil || err2 != nil {
+			return Reply{Type: "error", Value: errNotInteger}
+		}
+		if err := r.store.LTrim(key, start, stop); err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "simple", Value: "OK"}
+
+	case "LMOVE":
+		fromLeft, ok1 := parseSide(args[3])
+		toLeft, ok2 := parseSide(args[4])
+		if !ok1 || !ok2 {
+			return Reply{Type: "error", Value: "ERR syntax error"}
+		}
+		val, ok, err := r.store.LMove(key, args[2], fromLeft, toLeft)
+		if err != nil {
+			return errorReply(err)
+		}
+		if !ok {
+			return Reply{Type: "bulk", Value: nil}
+		}
+		return Reply{Type: "bulk", Value: val}
+	}
+	return Reply{Type: "error", Value: "ERR unknown command '" + cmd + "'"}
+}
+
+// функция parseSide - разбирает сторону списка LEFT/RIGHT (true — LEFT).
+func parseSide(s string) (bool, bool) {
+	switch strings.ToUpper(s) {
+	case "LEFT":
+		return true, true
+	case "RIGHT":
+		return false, true
+	}
+	return false, false
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/AntonRadchenko/mini-redis-go/internal/store"
+)
+
+// проверяет, что при noeviction изменяющие команды, которые могут занять память, получают OOM,
+// а чтение и удаление продолжают работать
+func TestMaxMemoryNoEviction(t *testing.T) {
+	s := store.NewStore()
+	r := New(s)
+	r.Handle([]string{"SET", "a", "1"})
+	r.Handle([]string{"SET", "b", "2"})
+	s.SetMaxMemory(s.UsedMemory()-1, store.NoEviction, store.DefaultMaxMemorySamples)
+
+	for _, args := range [][]string{{"SET", "c", "3"}, {"RPUSH", "list", "x"}, {"HSET", "h", "f", "v"}} {
+		if reply := r.Handle(args); reply.Type != "error" || !strings.HasPrefix(reply.Value.(string), "OOM ") {
+			t.Fatalf("%v: expected OOM error, got %+v", args, reply)
+		}
+	}
+	if reply := r.Handle([]string{"GET", "a"}); reply.Value != "1" {
+		t.Fatalf("GET: expected 1, got %+v", reply)
+	}
+	if reply := r.Handle([]string{"DEL", "a"}); reply.Value != 1 {
+		t.Fatalf("DEL must work under OOM, got %+v", reply)
+	}
+	if reply := r.Handle([]string{"SET", "c", "3"}); reply.Type != "simple" {
+		t.Fatalf("SET after freeing memory: expected OK, got %+v", reply)
+	}
+}
+
+// проверяет, что при allkeys-lru запись вытесняет старые ключи и это видно в INFO
+func TestMaxMemoryEviction(t *testing.T) {
+	s := store.NewStore()
+	r := New(s)
+	r.Handle([]string{"SET", "k0", "v"})
+	s.SetMaxMemory(10*s.UsedMemory(), store.AllKeysLRU, store.DefaultMaxMemorySamples)
+
+	for i := 1; i <= 100; i++ {
+		if reply := r.Handle([]string{"SET", "key:" + strconv.Itoa(i), "v"}); reply.Type != "simple" {
+			t.Fatalf("SET: expected OK, got %+v", reply)
+		}
+	}
+	limit, _ := s.MaxMemory()
+	// последняя запись могла превысить предел — вытеснение сработает перед следующей
+	if used := s.UsedMemory(); used > limit+200 {
+		t.Fatalf("used memory %d is far above maxmemory %d", used, limit)
+	}
+	info := r.Handle([]string{"INFO"}).Value.(string)
+	if !strings.Contains(info, "maxmemory_policy:allkeys-lru") || strings.Contains(info, "evicted_keys:0\r\n") {
+		t.Fatalf("expected INFO to show the policy and evicted keys:\n%s", info)
+	}
+}
+
+// проверяет MEMORY USAGE: размер существующего ключа, nil для отсутствующего
+// и ошибки для голого MEMORY и неизвестной подкоманды
+func TestMemoryUsage(t *testing.T) {
+	r := New(store.NewStore())
+	r.Handle([]string{"SET", "k", "value"})
+
+	if reply := r.Handle([]string{"MEMORY"}); reply.Type != "error" {
+		t.Fatalf("MEMORY: expected error, got %+v", reply)
+	}
+	if reply := r.Handle([]string{"MEMORY", "USAGE", "missing"}); reply.Type != "bulk" || reply.Value != nil {
+		t.Fatalf("MEMORY USAGE missing: expected nil bulk, got %+v", reply)
+	}
+	if reply := r.Handle([]string{"memory", "usage", "k"}); reply.Type != "integer" || reply.Value.(int) <= 0 {
+		t.Fatalf("MEMORY USAGE k: expected positive integer, got %+v", reply)
+	}
+	if reply := r.Handle([]string{"MEMORY", "DOCTOR"}); reply.Type != "error" {
+		t.Fatalf("MEMORY DOCTOR: expected error, got %+v", reply)
+	}
+	if reply := r.Handle([]string{"MEMORY", "USAGE"}); reply.Type != "error" {
+		t.Fatalf("MEMORY USAGE without key: expected error, got %+v", reply)
+	}
+}
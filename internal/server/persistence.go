@@ -0,0 +1,472 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AntonRadchenko/mini-redis-go/internal/aof"
+	"github.com/AntonRadchenko/mini-redis-go/internal/config"
+	"github.com/AntonRadchenko/mini-redis-go/internal/logx"
+	"github.com/AntonRadchenko/mini-redis-go/internal/rdb"
+	"github.com/AntonRadchenko/mini-redis-go/internal/store"
+)
+
+// errSaveInProgress — ошибка для SAVE/BGSAVE, если снапшот уже пишется в фоне.
+var errSaveInProgress = errors.New("Background save already in progress")
+
+// errRewriteInProgress — ошибка для BGREWRITEAOF, если журнал уже перезаписывается.
+var errRewriteInProgress = errors.New("Background append only file rewriting already in progress")
+
+// errAOFDisabled — ошибка для BGREWRITEAOF, если журнал команд выключен.
+var errAOFDisabled = errors.New("Append only file is not enabled")
+
+// структура persistence — это всё, что связано с сохранением хранилища на диск:
+// снапшоты (путь к файлу, правила автосохранения, состояние последнего сохранения)
+// и журнал команд (AOF).
+type persistence struct {
+	dbs   []*store.Store // базы данных сервера по номерам
+	path  string
+	rules []config.SaveRule
+
+	appendOnly bool     // включён ли AOF
+	aofPath    string   // путь к файлу журнала
+	aofFsync   string   // политика fsync журнала
+	aof        *aof.AOF // открытый журнал (nil, пока не загрузились или если AOF выключен)
+
+	rewritePercentage int         // порог роста журнала для автоматической перезаписи (в процентах)
+	rewriteMinSize    int64       // минимальный размер журнала для автоматической перезаписи
+	rewriteBaseSize   int64       // размер журнала после загрузки или последней перезаписи
+	rewriting         atomic.Bool // true, пока идёт BGREWRITEAOF
+	router            *Router     // роутер, через который проигрывается журнал и замораживаются записи
+
+	saving atomic.Bool // true, пока идёт SAVE или BGSAVE (одновременно пишется только один снапшот)
+
+	mu         sync.Mutex
+	lastSave   time.Time // когда последний раз успешно сохранили снапшот
+	savedDirty int64     // значение dirty() на момент последнего снапшота
+}
+
+// конструктор newPersistence создаёт объект persistence для баз данных сервера
+// и берёт путь к файлу и правила автосохранения из конфигурации.
+func newPersistence(dbs []*store.Store, cfg *config.Config) *persistence {
+	return &persistence{
+		dbs:        dbs,
+		path:       filepath.Join(cfg.Dir, cfg.DBFilename),
+		rules:      cfg.SaveRules,
+		appendOnly: cfg.AppendOnly,
+		aofPath:    filepath.Join(cfg.Dir, cfg.AppendFilename),
+		aofFsync:   cfg.AppendFsync,
+		lastSave:   time.Now(),
+
+		rewritePercentage: cfg.AutoAOFRewritePercentage,
+		rewriteMinSize:    cfg.AutoAOFRewriteMinSize,
+	}
+}
+
+// метод load - восстанавливает хранилище с диска (вызывается до открытия листенера).
+// Если AOF включён и файл журнала есть — проигрываем журнал через роутер, иначе грузим снапшот.
+// Журнал проигрывается с базы 0, а SELECT в нём переключает базу для следующих команд.
+// Затем открываем журнал на дозапись, чтобы роутер начал писать в него новые команды.
+func (p *persistence) load() error {
+	if !p.appendOnly {
+		return p.loadSnapshot()
+	}
+
+	_, err := os.Stat(p.aofPath)
+	aofExists := err == nil
+	if aofExists {
+		db := p.router
+		n, err := aof.Replay(p.aofPath, func(args []string) {
+			if next, ok := db.loggedSelect(args); ok {
+				db = next
+				return
+			}
+			db.Handle(args)
+		})
+		if errors.Is(err, aof.ErrTruncated) {
+			logx.Error("AOF %s: truncated last record discarded, %d commands loaded", p.aofPath, n)
+		} else if err != nil {
+			return err
+		} else {
+			logx.Info("AOF loaded from %s: %d commands", p.aofPath, n)
+		}
+		p.savedDirty = p.dirty()
+	} else if err := p.loadSnapshot(); err != nil {
+		return err
+	}
+
+	a, err := aof.Open(p.aofPath, p.aofFsync)
+	if err != nil {
+		return err
+	}
+	// журнала ещё не было: записываем в него то, что загрузили из снапшота,
+	// иначе после следующего рестарта (уже из AOF) эти ключи потерялись бы
+	if !aofExists {
+		for _, cmd := range snapshotCommands(snapshotAll(p.dbs)) {
+			if err := a.Append(cmd); err != nil {
+				a.Close()
+				return err
+			}
+		}
+	}
+	p.aof = a
+	p.rewriteBaseSize = a.Size()
+	return nil
+}
+
+// функция snapshotCommands - команды, воссоздающие записи снимка всех баз: журнал проигрывается с базы 0,
+// поэтому перед ключами каждой следующей базы идёт SELECT.
+func snapshotCommands(entries []store.Entry) [][]string {
+	var cmds [][]string
+	db := 0
+	for _, e := range entries {
+		if e.DB != db {
+			cmds = append(cmds, []string{"SELECT", strconv.Itoa(e.DB)})
+			db = e.DB
+		}
+		cmds = append(cmds, entryCommands(e)...)
+	}
+	return cmds
+}
+
+// функция entryCommands - превращает запись хранилища в команды, которые её воссоздают:
+// SET для строки, RPUSH для списка, HSET для хеша, SADD для множества, ZADD для упорядоченного множества
+// или XADD и команды групп для потока (см. streamCommands) и, если есть TTL, PEXPIREAT с абсолютным временем истечения.
+func entryCommands(e store.Entry) [][]string {
+	var cmds [][]string
+	switch v := e.Value.(type) {
+	case string:
+		cmds = append(cmds, []string{"SET", e.Key, v})
+	case []string:
+		cmds = append(cmds, chunkedCommands("RPUSH", e.Key, v, 1)...)
+	case map[string]string:
+		pairs := make([]string, 0, 2*len(v))
+		for field, val := range v {
+			pairs = append(pairs, field, val)
+		}
+		cmds = append(cmds, chunkedCommands("HSET", e.Key, pairs, 2)...)
+	case map[string]struct{}:
+		members := make([]string, 0, len(v))
+		for m := range v {
+			members = append(members, m)
+		}
+		cmds = append(cmds, chunkedCommands("SADD", e.Key, members, 1)...)
+	case []store.ZMember:
+		pairs := make([]string, 0, 2*len(v))
+		for _, it := range v {
+			pairs = append(pairs, formatScore(it.Score), it.Member)
+		}
+		cmds = append(cmds, chunkedCommands("ZADD", e.Key, pairs, 2)...)
+	case store.StreamSnapshot:
+		cmds = append(cmds, streamCommands(e.Key, v)...)
+	}
+	if !e.ExpireAt.IsZero() {
+		cmds = append(cmds, []string{"PEXPIREAT", e.Key, strconv.FormatInt(e.ExpireAt.UnixMilli(), 10)})
+	}
+	return cmds
+}
+
+// функция streamCommands - команды, воссоздающие поток: XADD для каждой записи (пустой поток создаётся
+// записью, которую тут же срезает MAXLEN 0), XSETID со служебными идентификаторами, XGROUP CREATE
+// и CREATECONSUMER для групп и XCLAIM ... FORCE JUSTID для каждой ожидающей записи — с её владельцем,
+// временем доставки и числом доставок.
+func streamCommands(key string, st store.StreamSnapshot) [][]string {
+	var cmds [][]string
+	for _, entry := range st.Entries {
+		cmds = append(cmds, append([]string{"XADD", key, entry.ID.String()}, entry.Fields...))
+	}
+	if len(st.Entries) == 0 {
+		id := st.LastID
+		if id.IsZero() {
+			id = store.StreamID{Seq: 1} // 0-0 в XADD недопустим, настоящий последний идентификатор вернёт XSETID
+		}
+		cmds = append(cmds, []string{"XADD", key, "MAXLEN", "0", id.String(), "x", "y"})
+	}
+	cmds = append(cmds, []string{"XSETID", key, st.LastID.String(),
+		"ENTRIESADDED", strconv.FormatUint(st.EntriesAdded, 10), "MAXDELETEDID", st.MaxDeleted.String()})
+
+	for _, g := range st.Groups {
+		cmds = append(cmds, []string{"XGROUP", "CREATE", key, g.Name, g.LastID.String()})
+		for _, consumer := range g.Consumers {
+			cmds = append(cmds, []string{"XGROUP", "CREATECONSUMER", key, g.Name, consumer})
+		}
+		for _, p := range g.Pending {
+			cmds = append(cmds, []string{"XCLAIM", key, g.Name, p.Consumer, "0", p.ID.String(),
+				"TIME", strconv.FormatInt(p.DeliveryTime.UnixMilli(), 10),
+				"RETRYCOUNT", strconv.Itoa(p.DeliveryCount), "FORCE", "JUSTID"})
+		}
+	}
+	return cmds
+}
+
+// rewriteItemsPerCmd — сколько элементов составного значения кладётся в одну команду
+// при перезаписи журнала (как AOF_REWRITE_ITEMS_PER_CMD в Redis), чтобы команды не были огромными.
+const rewriteItemsPerCmd = 64
+
+// функция chunkedCommands - разбивает элементы на команды вида <cmd> <key> <item> ... по rewriteItemsPerCmd штук.
+// width — сколько аргументов занимает один элемент (2 для пар поле/значение), такие группы не разрываются.
+func chunkedCommands(cmd, key string, items []string, width int) [][]string {
+	var cmds [][]string
+	for len(items) > 0 {
+		n := min(len(items), rewriteItemsPerCmd*width)
+		args := append([]string{cmd, key}, items[:n]...)
+		cmds = append(cmds, args)
+		items = items[n:]
+	}
+	return cmds
+}
+
+// метод aofEnabled - сообщает, пишется ли сейчас журнал команд.
+// Безопасен для nil, чтобы роутер без персистентности работал как раньше.
+func (p *persistence) aofEnabled() bool {
+	return p != nil && p.aof != nil
+}
+
+// метод appendCommand - дописывает изменяющую команду в журнал.
+func (p *persistence) appendCommand(args []string) {
+	if err := p.aof.Append(args); err != nil {
+		logx.Error("AOF write failed: %v", err)
+	}
+}
+
+// метод bgrewrite - запускает фоновую перезапись журнала (команда BGREWRITEAOF).
+func (p *persistence) bgrewrite() error {
+	if !p.aofEnabled() {
+		return errAOFDisabled
+	}
+	if !p.rewriting.CompareAndSwap(false, true) {
+		return errRewriteInProgress
+	}
+	a := p.aof
+
+	go func() {
+		defer p.rewriting.Store(false)
+
+		// копия хранилища снимается при остановленных записях: каждая команда
+		// попадёт либо в копию, либо в буфер перезаписи — но не потеряется и не задвоится.
+		// Замораживаем в горутине: BGREWRITEAOF может прийти изнутри EXEC, который уже держит роутер.
+		p.router.freeze()
+		if err := a.BeginRewrite(); err != nil {
+			p.router.unfreeze()
+			logx.Error("Background AOF rewrite failed: %v", err)
+			return
+		}
+		entries := snapshotAll(p.dbs)
+		p.router.logDB = -1 // новый журнал кончится ключами неизвестно какой базы — следующей команде нужен SELECT
+		p.router.unfreeze()
+
+		if err := a.FinishRewrite(snapshotCommands(entries)); err != nil {
+			logx.Error("Background AOF rewrite failed: %v", err)
+			return
+		}
+		p.mu.Lock()
+		p.rewriteBaseSize = a.Size()
+		p.mu.Unlock()
+		logx.Info("Background AOF rewrite done")
+	}()
+	return nil
+}
+
+// метод rewriteDue - проверяет, вырос ли журнал настолько, что его пора перезаписать.
+func (p *persistence) rewriteDue() bool {
+	if !p.aofEnabled() || p.rewritePercentage <= 0 {
+		return false
+	}
+	size := p.aof.Size()
+	p.mu.Lock()
+	base := p.rewriteBaseSize
+	p.mu.Unlock()
+
+	if size < p.rewriteMinSize {
+		return false
+	}
+	return size >= base+base*int64(p.rewritePercentage)/100
+}
+
+// метод loadSnapshot - загружает снапшот с диска в базы данных.
+func (p *persistence) loadSnapshot() error {
+	entries, err := rdb.LoadFile(p.path)
+	if err != nil {
+		return err
+	}
+	if entries == nil {
+		return nil
+	}
+	n, err := restoreAll(p.dbs, entries)
+	if err != nil {
+		return err
+	}
+	p.savedDirty = p.dirty()
+	logx.Info("Snapshot loaded from %s: %d keys", p.path, n)
+	return nil
+}
+
+// метод save - синхронно пишет снапшот (команда SAVE): клиент ждёт, пока файл не будет записан.
+// fromClient — вызов из команды клиента, которая уже держит execMu (см. snapshot).
+func (p *persistence) save(fromClient bool) error {
+	if !p.saving.CompareAndSwap(false, true) {
+		return errSaveInProgress
+	}
+	defer p.saving.Store(false)
+	dirty, entries := p.snapshot(fromClient)
+	return p.write(dirty, entries)
+}
+
+// метод bgsave - запускает запись снапшота в отдельной горутине (команда BGSAVE).
+// Сама копия хранилища снимается сразу, а запись на диск идёт в фоне и не блокирует клиентов.
+func (p *persistence) bgsave(fromClient bool) error {
+	if !p.saving.CompareAndSwap(false, true) {
+		return errSaveInProgress
+	}
+	dirty, entries := p.snapshot(fromClient)
+	go func() {
+		defer p.saving.Store(false)
+		if err := p.write(dirty, entries); err != nil {
+			logx.Error("Background save failed: %v", err)
+			return
+		}
+		logx.Info("Background save done")
+	}()
+	return nil
+}
+
+// метод snapshot - снимает копию всех баз вместе со счётчиком изменений на этот момент:
+// изменения, сделанные после этой точки, попадут в следующий снапшот.
+// Базы копируются при остановленных записях, иначе MOVE, COPY ... DB или SWAPDB между копиями двух баз
+// могли бы потерять ключ или записать его дважды. Команда клиента уже держит execMu (RLock, а внутри EXEC — Lock),
+// и повторно брать его нельзя; ей хватает writeMu — через него проходит любая изменяющая команда.
+func (p *persistence) snapshot(fromClient bool) (int64, []store.Entry) {
+	if fromClient {
+		p.router.writeMu.Lock()
+		defer p.router.writeMu.Unlock()
+	} else {
+		p.router.freeze()
+		defer p.router.unfreeze()
+	}
+	dirty := p.dirty()
+	return dirty, snapshotAll(p.dbs)
+}
+
+// метод write - записывает снятую копию хранилища в файл.
+func (p *persistence) write(dirty int64, entries []store.Entry) error {
+	if err := rdb.SaveFile(p.path, entries); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.lastSave = time.Now()
+	p.savedDirty = dirty
+	p.mu.Unlock()
+	return nil
+}
+
+// метод dirty - общее число изменений всех баз с момента запуска.
+func (p *persistence) dirty() int64 {
+	var n int64
+	for _, db := range p.dbs {
+		n += db.Dirty()
+	}
+	return n
+}
+
+// функция snapshotAll - снимки всех баз подряд, по возрастанию номеров (номер базы — в Entry.DB).
+func snapshotAll(dbs []*store.Store) []store.Entry {
+	var entries []store.Entry
+	for _, db := range dbs {
+		entries = append(entries, db.Snapshot()...)
+	}
+	return entries
+}
+
+// функция restoreAll - загружает записи снимка в базы по их номерам и возвращает число загруженных ключей.
+// Ошибка — в снимке есть база, которой нет на этом сервере (он запущен с меньшим DATABASES).
+func restoreAll(dbs []*store.Store, entries []store.Entry) (int, error) {
+	byDB := make([][]store.Entry, len(dbs))
+	for _, e := range entries {
+		if e.DB < 0 || e.DB >= len(dbs) {
+			return 0, fmt.Errorf("snapshot has keys in DB %d, but only %d databases are configured", e.DB, len(dbs))
+		}
+		byDB[e.DB] = append(byDB[e.DB], e)
+	}
+	n := 0
+	for i, db := range dbs {
+		n += db.Restore(byDB[i])
+	}
+	return n, nil
+}
+
+// метод due - проверяет, сработало ли хотя бы одно правило автосохранения.
+func (p *persistence) due() bool {
+	p.mu.Lock()
+	changes := p.dirty() - p.savedDirty
+	elapsed := time.Since(p.lastSave)
+	p.mu.Unlock()
+
+	for _, rule := range p.rules {
+		if changes >= int64(rule.Changes) && elapsed >= time.Duration(rule.Seconds)*time.Second {
+			return true
+		}
+	}
+	return false
+}
+
+// метод run - раз в секунду проверяет правила автосохранения и рост журнала
+// и при необходимости запускает BGSAVE или BGREWRITEAOF.
+// Работает, пока не отменён контекст сервера.
+func (p *persistence) run(ctx context.Context) {
+	if len(p.rules) == 0 && !p.aofEnabled() {
+		return
+	}
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if p.due() {
+				_ = p.bgsave(false) // если снапшот уже пишется — просто подождём следующий тик
+			}
+			if p.rewriteDue() {
+				_ = p.bgrewrite()
+			}
+		}
+	}
+}
+
+// метод shutdown - при остановке сервера закрывает журнал команд, дожидается фонового снапшота
+// и, если включены правила автосохранения, делает финальный SAVE.
+// Журнал закрывается при остановленных записях: команды, пришедшие позже
+// (от мастера или от клиента, который ещё доделывает запрос), в него уже не пишутся.
+func (p *persistence) shutdown() {
+	for p.rewriting.Load() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if p.aof != nil {
+		p.router.freeze()
+		if err := p.aof.Close(); err != nil {
+			logx.Error("AOF close failed: %v", err)
+		}
+		p.aof = nil
+		p.router.unfreeze()
+	}
+	for p.saving.Load() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(p.rules) == 0 {
+		return
+	}
+	if err := p.save(false); err != nil {
+		logx.Error("Final save failed: %v", err)
+		return
+	}
+	logx.Info("Snapshot saved to %s", p.path)
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/AntonRadchenko/mini-redis-go/internal/aof"
+	"github.com/AntonRadchenko/mini-redis-go/internal/config"
+	"github.com/AntonRadchenko/mini-redis-go/internal/rdb"
+	"github.com/AntonRadchenko/mini-redis-go/internal/store"
+)
+
+// проверяет, что после shutdown журнал закрыт и отключён: запоздавшая запись
+// выполняется без попытки писать в закрытый файл, а в журнале остаются только команды до остановки
+func TestShutdownClosesAOF(t *testing.T) {
+	cfg := config.Load()
+	cfg.Dir = t.TempDir()
+	cfg.SaveRules = nil
+	cfg.AppendOnly = true
+	cfg.AppendFsync = aof.FsyncAlways
+
+	dbs := store.NewDatabases(1)
+	r := New(dbs...)
+	p := newPersistence(dbs, cfg)
+	p.router = r
+	if err := p.load(); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	r.persist = p
+
+	r.Handle([]string{"SET", "a", "1"})
+	p.shutdown()
+	if p.aofEnabled() {
+		t.Fatal("AOF must be disabled after shutdown")
+	}
+	if reply := r.Handle([]string{"SET", "b", "2"}); reply.Type != "simple" {
+		t.Fatalf("SET after shutdown: expected OK, got %+v", reply)
+	}
+
+	var keys []string
+	if _, err := aof.Replay(p.aofPath, func(args []string) {
+		if args[0] == "SET" {
+			keys = append(keys, args[1])
+		}
+	}); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if len(keys) != 1 || keys[0] != "a" {
+		t.Fatalf("expected only SET a in the journal, got %v", keys)
+	}
+}
+
+// проверяет, что SAVE снимает все базы разом: ключ, который параллельно переезжает
+// между базами через MOVE, всегда попадает в снапшот ровно один раз,
+// а SAVE внутри EXEC (под execMu) не зависает
+func TestSaveIsPointInTime(t *testing.T) {
+	cfg := config.Load()
+	cfg.Dir = t.TempDir()
+	cfg.SaveRules = nil
+
+	dbs := store.NewDatabases(2)
+	r := New(dbs...)
+	p := newPersistence(dbs, cfg)
+	p.router = r
+	r.persist = p
+	r.Handle([]string{"SET", "k", "v"})
+	// остальные ключи делают копию каждой базы достаточно долгой, чтобы MOVE успевал вклиниться между ними
+	for i := 0; i < 2000; i++ {
+		r.dbs[i%2].Handle([]string{"SET", "filler:" + strconv.Itoa(i), "v"})
+	}
+
+	stop := make(chan struct{})
+	moved := make(chan struct{})
+	go func() {
+		defer close(moved)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			r.dbs[0].Handle([]string{"MOVE", "k", "1"})
+			r.dbs[1].Handle([]string{"MOVE", "k", "0"})
+		}
+	}()
+
+	c := newClient(context.Background(), nil, 1)
+	for i := 0; i < 50; i++ {
+		if reply := r.handleClient(c, []string{"SAVE"}); reply.Type != "simple" {
+			t.Fatalf("SAVE: expected OK, got %+v", reply)
+		}
+		entries, err := rdb.LoadFile(p.path)
+		if err != nil {
+			t.Fatalf("load: %v", err)
+		}
+		n := 0
+		for _, e := range entries {
+			if e.Key == "k" {
+				n++
+			}
+		}
+		if n != 1 {
+			t.Fatalf("expected key k exactly once in the snapshot, got %d", n)
+		}
+	}
+	close(stop)
+	<-moved
+
+	// пока идёт изменяющая команда (держит writeMu), снапшот не снимается
+	r.writeMu.RLock()
+	saved := make(chan Reply, 1)
+	go func() { saved <- r.handleClient(c, []string{"SAVE"}) }()
+	select {
+	case reply := <-saved:
+		t.Fatalf("SAVE must wait for the running write, got %+v", reply)
+	case <-time.After(50 * time.Millisecond):
+	}
+	r.writeMu.RUnlock()
+	if reply := <-saved; reply.Type != "simple" {
+		t.Fatalf("SAVE: expected OK, got %+v", reply)
+	}
+
+	r.handleClient(c, []string{"MULTI"})
+	r.handleClient(c, []string{"SAVE"})
+	if reply := r.handleClient(c, []string{"EXEC"}); reply.Type != "array" {
+		t.Fatalf("EXEC with SAVE: expected array, got %+v", reply)
+	}
+}
@@ -0,0 +1,192 @@
+package server
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/AntonRadchenko/mini-redis-go/internal/glob"
+)
+
+// структура pubsub — реестр подписок: какие клиенты слушают какие каналы и шаблоны.
+// PUBLISH раскладывает сообщение по очередям подписчиков, не дожидаясь записи в сокет,
+// поэтому медленный подписчик не задерживает публикующего.
+type pubsub struct {
+	mu       sync.RWMutex
+	channels map[string]map[*client]struct{} // канал → подписчики (SUBSCRIBE)
+	patterns map[string]map[*client]struct{} // шаблон → подписчики (PSUBSCRIBE)
+}
+
+// конструктор newPubsub создаёт пустой реестр подписок.
+func newPubsub() *pubsub {
+	return &pubsub{
+		channels: make(map[string]map[*client]struct{}),
+		patterns: make(map[string]map[*client]struct{}),
+	}
+}
+
+// метод subscribe - подписывает клиента на каналы (или шаблоны, если pattern)
+// и возвращает подтверждение на каждый: [subscribe|psubscribe, имя, число подписок клиента].
+func (ps *pubsub) subscribe(c *client, names []string, pattern bool) []Reply {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	registry, own, kind := ps.channels, c.channels, "subscribe"
+	if pattern {
+		registry, own, kind = ps.patterns, c.patterns, "psubscribe"
+	}
+	replies := make([]Reply, 0, len(names))
+	for _, name := range names {
+		if _, ok := own[name]; !ok {
+			own[name] = struct{}{}
+			if registry[name] == nil {
+				registry[name] = make(map[*client]struct{})
+			}
+			registry[name][c] = struct{}{}
+		}
+		replies = append(replies, subscriptionReply(kind, name, c.subscriptions()))
+	}
+	return replies
+}
+
+// метод unsubscribe - отписывает клиента от каналов (или шаблонов); пустой список — от всех.
+// Возвращает подтверждение на каждый: [unsubscribe|punsubscribe, имя, число оставшихся подписок].
+func (ps *pubsub) unsubscribe(c *client, names []string, pattern bool) []Reply {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	registry, own, kind := ps.channels, c.channels, "unsubscribe"
+	if pattern {
+		registry, own, kind = ps.patterns, c.patterns, "punsubscribe"
+	}
+	if len(names) == 0 {
+		for name := range own {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			// как в Redis: даже если подписок не было, клиент получает одно подтверждение
+			return []Reply{{Type: "array", Value: []Reply{
+				{Type: "bulk", Value: kind},
+				{Type: "bulk", Value: nil},
+				{Type: "integer", Value: c.subscriptions()},
+			}}}
+		}
+	}
+	replies := make([]Reply, 0, len(names))
+	for _, name := range names {
+		if _, ok := own[name]; ok {
+			delete(own, name)
+			delete(registry[name], c)
+			if len(registry[name]) == 0 {
+				delete(registry, name)
+			}
+		}
+		replies = append(replies, subscriptionReply(kind, name, c.subscriptions()))
+	}
+	return replies
+}
+
+// метод unsubscribeAll - снимает все подписки клиента (при закрытии соединения).
+func (ps *pubsub) unsubscribeAll(c *client) {
+	if c.subscriptions() == 0 {
+		return
+	}
+	ps.unsubscribe(c, nil, false)
+	ps.unsubscribe(c, nil, true)
+}
+
+// метод publish - отправляет сообщение подписчикам канала и подходящих шаблонов.
+// Возвращает, скольким подписчикам сообщение поставлено в очередь.
+func (ps *pubsub) publish(channel, message string) int {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	received := 0
+	for c := range ps.channels[channel] {
+		msg := Reply{Type: "array", Value: []string{"message", channel, message}}
+		if c.push(msg) {
+			received++
+		}
+	}
+	for pattern, subs := range ps.patterns {
+		if !glob.Match(pattern, channel) {
+			continue
+		}
+		for c := range subs {
+			msg := Reply{Type: "array", Value: []string{"pmessage", pattern, channel, message}}
+			if c.push(msg) {
+				received++
+			}
+		}
+	}
+	return received
+}
+
+// метод activeChannels - каналы, у которых есть подписчики (PUBSUB CHANNELS [pattern]).
+func (ps *pubsub) activeChannels(pattern string) []string {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	out := make([]string, 0, len(ps.channels))
+	for name := range ps.channels {
+		if pattern == "" || glob.Match(pattern, name) {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// метод numSub - число подписчиков у каждого из каналов (PUBSUB NUMSUB).
+// Подписки по шаблонам не учитываются, как в Redis.
+func (ps *pubsub) numSub(channels []string) []Reply {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	out := make([]Reply, 0, 2*len(channels))
+	for _, name := range channels {
+		out = append(out,
+			Reply{Type: "bulk", Value: name},
+			Reply{Type: "integer", Value: len(ps.channels[name])},
+		)
+	}
+	return out
+}
+
+// метод numPat - число уникальных шаблонов, на которые кто-то подписан (PUBSUB NUMPAT).
+func (ps *pubsub) numPat() int {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	return len(ps.patterns)
+}
+
+// метод pubsubCommand - команда PUBSUB CHANNELS [pattern] | NUMSUB [channel ...] | NUMPAT.
+func (r *Router) pubsubCommand(args []string) Reply {
+	if len(args) < 2 {
+		return Reply{Type: "error", Value: "ERR wrong number of arguments for 'pubsub' command"}
+	}
+	switch sub := strings.ToUpper(args[1]); {
+	case sub == "CHANNELS" && len(args) <= 3:
+		pattern := ""
+		if len(args) == 3 {
+			pattern = args[2]
+		}
+		return Reply{Type: "array", Value: r.pubsub.activeChannels(pattern)}
+	case sub == "NUMSUB":
+		return Reply{Type: "array", Value: r.pubsub.numSub(args[2:])}
+	case sub == "NUMPAT" && len(args) == 2:
+		return Reply{Type: "integer", Value: r.pubsub.numPat()}
+	default:
+		return Reply{Type: "error", Value: "ERR unknown subcommand or wrong number of arguments for '" + args[1] + "'. Try PUBSUB CHANNELS|NUMSUB|NUMPAT."}
+	}
+}
+
+// функция subscriptionReply - подтверждение подписки/отписки в формате Redis.
+func subscriptionReply(kind, name string, count int) Reply {
+	return Reply{Type: "array", Value: []Reply{
+		{Type: "bulk", Value: kind},
+		{Type: "bulk", Value: name},
+		{Type: "integer", Value: count},
+	}}
+}
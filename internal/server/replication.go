@@ -0,0 +1,589 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AntonRadchenko/mini-redis-go/internal/logx"
+	"github.com/AntonRadchenko/mini-redis-go/internal/rdb"
+	"github.com/AntonRadchenko/mini-redis-go/internal/resp"
+	"github.com/AntonRadchenko/mini-redis-go/internal/store"
+)
+
+// replication — репликация мастер → реплика в духе Redis:
+//   - реплика подключается к мастеру и присылает PSYNC <replid> <offset>;
+//   - если мастер может продолжить поток с этого места (offset ещё есть в backlog) — отвечает +CONTINUE
+//     и досылает пропущенные байты (частичная ресинхронизация);
+//   - иначе отвечает +FULLRESYNC <replid> <offset>, отправляет снапшот хранилища,
+//     а дальше — все изменяющие команды в том же RESP-виде, что и AOF;
+//   - реплика раз в секунду сообщает мастеру, до какого смещения дочитала (REPLCONF ACK <offset>).
+
+// errReadonly — ответ реплики на изменяющие команды от обычных клиентов.
+const errReadonly = "READONLY You can't write against a read only replica."
+
+// структура replication — состояние репликации сервера: роль (мастер или реплика),
+// идентификатор и смещение потока, backlog и список подключённых реплик.
+type replication struct {
+	dbs         []*store.Store // базы данных сервера по номерам
+	router      *Router
+	backlogSize int
+	listenPort  string // порт, на котором слушает этот сервер (сообщаем мастеру)
+
+	ctx context.Context // контекст сервера: при его отмене все соединения репликации закрываются
+
+	mu       sync.Mutex
+	replID   string                    // идентификатор текущей "истории" данных
+	offset   int64                     // сколько байт потока репликации применено (master_repl_offset)
+	backlog  *backlog                  // создаётся при подключении первой реплики
+	replicas map[*replicaConn]struct{} // подключённые к нам реплики
+
+	// поля роли реплики (primaryHost == "" — мы мастер)
+	primaryHost string
+	primaryPort string
+	stopSync    context.CancelFunc // останавливает цикл синхронизации с мастером
+	linkUp      bool               // установлено ли сейчас соединение с мастером
+	syncing     bool               // идёт полная синхронизация
+	lastIO      time.Time          // когда последний раз что-то получили от мастера
+
+	hasBacklog atomic.Bool // быстрый флаг для роутера: нужно ли передавать команды репликам
+	replica    atomic.Bool // быстрый флаг для роутера: мы реплика (записи от клиентов запрещены)
+}
+
+// структура replicaConn — одна подключённая к нам реплика.
+type replicaConn struct {
+	conn    net.Conn
+	addr    string        // ip реплики
+	port    string        // порт, который реплика сообщила через REPLCONF listening-port
+	offset  int64         // до какого смещения мы уже отправили поток
+	ack     atomic.Int64  // до какого смещения реплика подтвердила приём
+	lastAck atomic.Int64  // unix-время последнего ACK
+	notify  chan struct{} // сигнал "в backlog появились новые данные"
+}
+
+// конструктор newReplication создаёт состояние репликации сервера в роли мастера.
+func newReplication(dbs []*store.Store, r *Router, backlogSize int, listenPort string) *replication {
+	return &replication{
+		dbs:         dbs,
+		router:      r,
+		backlogSize: backlogSize,
+		listenPort:  listenPort,
+		ctx:         context.Background(),
+		replID:      newReplID(),
+		replicas:    make(map[*replicaConn]struct{}),
+	}
+}
+
+// функция newReplID генерирует случайный идентификатор потока репликации (40 hex-символов, как в Redis).
+func newReplID() string {
+	b := make([]byte, 20)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// метод start - запоминает контекст сервера (нужен для фоновых горутин репликации).
+func (rp *replication) start(ctx context.Context) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	rp.ctx = ctx
+	if rp.primaryHost != "" && rp.stopSync == nil {
+		rp.startSyncLocked()
+	}
+}
+
+// метод active - нужно ли передавать изменяющие команды в поток репликации.
+// Безопасен для nil, чтобы роутер без репликации работал как раньше.
+func (rp *replication) active() bool {
+	return rp != nil && rp.hasBacklog.Load()
+}
+
+// метод isReplica - является ли сервер сейчас репликой. Безопасен для nil.
+func (rp *replication) isReplica() bool {
+	return rp != nil && rp.replica.Load()
+}
+
+// метод feed - дописывает изменяющую команду в поток репликации (вызывается роутером под writeMu).
+// На реплике поток пишет только цикл синхронизации с мастером — байты в нём должны совпадать с мастерскими.
+func (rp *replication) feed(args []string) {
+	if !rp.active() || rp.isReplica() {
+		return
+	}
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	rp.appendLocked(resp.EncodeCommand(args))
+}
+
+// метод appendLocked - пишет байты в backlog, сдвигает смещение и будит реплики.
+func (rp *replication) appendLocked(data []byte) {
+	rp.offset += int64(len(data))
+	if rp.backlog == nil {
+		return
+	}
+	rp.backlog.write(data)
+	for rc := range rp.replicas {
+		select {
+		case rc.notify <- struct{}{}:
+		default: // сигнал уже ждёт — реплика и так проснётся
+		}
+	}
+}
+
+// ---------- сторона мастера ----------
+
+// метод serveReplica - обслуживает соединение, приславшее PSYNC: с этого момента
+// оно целиком принадлежит репликации — сначала снапшот или недостающий кусок backlog,
+// затем непрерывный поток изменяющих команд.
+func (rp *replication) serveReplica(conn net.Conn, rd *resp.Reader, args []string, port string) {
+	replID, psyncOffset := "?", int64(-1)
+	if len(args) == 3 {
+		replID = args[1]
+		if n, err := strconv.ParseInt(args[2], 10, 64); err == nil {
+			psyncOffset = n
+		}
+	}
+
+	rc := &replicaConn{
+		conn:   conn,
+		port:   port,
+		notify: make(chan struct{}, 1),
+	}
+	if host, _, err := net.SplitHostPort(conn.RemoteAddr().String()); err == nil {
+		rc.addr = host
+	}
+
+	// "замораживаем" изменяющие команды, чтобы снапшот и смещение потока соответствовали друг другу
+	if !rp.hasBacklog.Load() {
+		rp.hasBacklog.Store(true)
+	}
+	rp.router.freeze()
+	rp.mu.Lock()
+	if rp.backlog == nil {
+		rp.backlog = newBacklog(rp.backlogSize, rp.offset)
+	}
+	var header string
+	var entries []store.Entry
+	var streamDB int // база, в которой продолжится поток после снапшота
+	full := true
+	// PSYNC присылает смещение следующего нужного байта (на единицу больше уже полученного)
+	if replID == rp.replID && psyncOffset > 0 {
+		if _, ok := rp.backlog.readFrom(psyncOffset-1, 0); ok {
+			full = false
+			rc.offset = psyncOffset - 1
+			header = "+CONTINUE " + rp.replID + "\r\n"
+		}
+	}
+	if full {
+		entries = snapshotAll(rp.dbs)
+		// мастер продолжает с базы последней переданной команды, а реплика пересылает поток своего мастера как есть
+		streamDB = max(rp.router.logDB, 0)
+		if rp.replica.Load() {
+			streamDB = rp.router.primaryDB
+		}
+		rc.offset = rp.offset
+		header = fmt.Sprintf("+FULLRESYNC %s %d\r\n", rp.replID, rp.offset)
+	}
+	rc.ack.Store(rc.offset)
+	rc.lastAck.Store(time.Now().Unix())
+	rp.replicas[rc] = struct{}{}
+	ctx := rp.ctx
+	rp.mu.Unlock()
+	rp.router.unfreeze()
+
+	defer func() {
+		rp.mu.Lock()
+		delete(rp.replicas, rc)
+		rp.mu.Unlock()
+		conn.Close()
+	}()
+
+	if full {
+		logx.Info("Replica %s:%s asks for sync: full resync", rc.addr, rc.port)
+	} else {
+		logx.Info("Replica %s:%s asks for sync: partial resync from offset %d", rc.addr, rc.port, rc.offset)
+	}
+
+	if _, err := conn.Write([]byte(header)); err != nil {
+		return
+	}
+	if full {
+		var buf bytes.Buffer
+		if err := rdb.WriteSelected(&buf, entries, streamDB); err != nil {
+			logx.Error("Replica full sync failed: %v", err)
+			return
+		}
+		// снапшот передаётся как bulk-строка, но без завершающих \r\n (как в Redis)
+		if _, err := fmt.Fprintf(conn, "$%d\r\n", buf.Len()); err != nil {
+			return
+		}
+		if _, err := conn.Write(buf.Bytes()); err != nil {
+			return
+		}
+	}
+
+	// читаем подтверждения от реплики в отдельной горутине; при обрыве закрываем соединение
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			args, err := rd.ReadArray()
+			if err != nil {
+				return
+			}
+			if len(args) == 3 && strings.EqualFold(args[0], "REPLCONF") && strings.EqualFold(args[1], "ACK") {
+				if n, err := strconv.ParseInt(args[2], 10, 64); err == nil {
+					rc.ack.Store(n)
+					rc.lastAck.Store(time.Now().Unix())
+				}
+			}
+		}
+	}()
+
+	// основной цикл: отправляем реплике всё, что появилось в backlog после её смещения
+	for {
+		rp.mu.Lock()
+		data, ok := rp.backlog.readFrom(rc.offset, 64*1024)
+		rp.mu.Unlock()
+		if !ok {
+			logx.Error("Replica %s:%s fell behind the backlog, disconnecting", rc.addr, rc.port)
+			return
+		}
+		if len(data) > 0 {
+			if _, err := conn.Write(data); err != nil {
+				return
+			}
+			rc.offset += int64(len(data))
+			continue
+		}
+
+		select {
+		case <-rc.notify:
+		case <-closed:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// ---------- сторона реплики ----------
+
+// метод replicaOf - делает сервер репликой host:port (REPLICAOF host port).
+func (rp *replication) replicaOf(host, port string) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	if rp.primaryHost == host && rp.primaryPort == port {
+		return // уже реплицируемся с этого мастера
+	}
+	if rp.stopSync != nil {
+		rp.stopSync()
+		rp.stopSync = nil
+	}
+	rp.primaryHost, rp.primaryPort = host, port
+	rp.linkUp = false
+	rp.replica.Store(true)
+	rp.startSyncLocked()
+	logx.Info("Replicating from %s:%s", host, port)
+}
+
+// метод replicaOfNoOne - снова делает сервер мастером (REPLICAOF NO ONE).
+// Данные остаются, но начинается новая история репликации с новым идентификатором.
+func (rp *replication) replicaOfNoOne() {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	if rp.primaryHost == "" {
+		return
+	}
+	if rp.stopSync != nil {
+		rp.stopSync()
+		rp.stopSync = nil
+	}
+	rp.primaryHost, rp.primaryPort = "", ""
+	rp.linkUp = false
+	rp.syncing = false
+	rp.replID = newReplID()
+	rp.replica.Store(false)
+	logx.Info("Replication stopped, this server is a master now")
+}
+
+// метод startSyncLocked - запускает фоновый цикл синхронизации с мастером (вызывается под rp.mu).
+func (rp *replication) startSyncLocked() {
+	ctx, cancel := context.WithCancel(rp.ctx)
+	rp.stopSync = cancel
+	go rp.syncLoop(ctx, rp.primaryHost, rp.primaryPort)
+}
+
+// метод syncLoop - держит соединение с мастером: при обрыве переподключается раз в секунду.
+func (rp *replication) syncLoop(ctx context.Context, host, port string) {
+	for {
+		err := rp.syncOnce(ctx, host, port)
+
+		rp.mu.Lock()
+		rp.linkUp = false
+		rp.syncing = false
+		rp.mu.Unlock()
+
+		if ctx.Err() != nil {
+			return
+		}
+		logx.Error("Connection with master %s:%s lost: %v", host, port, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// метод syncOnce - одна сессия с мастером: рукопожатие, PSYNC, загрузка снапшота (если нужна)
+// и применение потока команд, пока соединение живо.
+func (rp *replication) syncOnce(ctx context.Context, host, port string) error {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, port), 5*time.Second)
+	if err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() }) // при REPLICAOF NO ONE или shutdown рвём соединение
+	defer stop()
+	defer conn.Close()
+
+	rd := resp.NewReader(conn)
+	call := func(args ...string) (string, error) {
+		if _, err := conn.Write(resp.EncodeCommand(args)); err != nil {
+			return "", err
+		}
+		return rd.ReadLine()
+	}
+
+	if line, err := call("PING"); err != nil {
+		return err
+	} else if line != "+PONG" {
+		return fmt.Errorf("unexpected reply to PING: %s", line)
+	}
+	if _, err := call("REPLCONF", "listening-port", rp.listenPort); err != nil {
+		return err
+	}
+
+	rp.mu.Lock()
+	replID, offset := rp.replID, rp.offset
+	rp.mu.Unlock()
+	line, err := call("PSYNC", replID, strconv.FormatInt(offset+1, 10))
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case strings.HasPrefix(line, "+FULLRESYNC "):
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return fmt.Errorf("bad FULLRESYNC reply: %s", line)
+		}
+		newOffset, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return fmt.Errorf("bad FULLRESYNC offset: %s", line)
+		}
+		if err := rp.loadFromPrimary(rd, fields[1], newOffset); err != nil {
+			return err
+		}
+
+	case strings.HasPrefix(line, "+CONTINUE"):
+		rp.mu.Lock()
+		if fields := strings.Fields(line); len(fields) == 2 {
+			rp.replID = fields[1]
+		}
+		rp.mu.Unlock()
+		logx.Info("Partial resync with master %s:%s accepted", host, port)
+
+	default:
+		return fmt.Errorf("unexpected reply to PSYNC: %s", line)
+	}
+
+	rp.mu.Lock()
+	rp.linkUp = true
+	rp.lastIO = time.Now()
+	rp.mu.Unlock()
+
+	// раз в секунду подтверждаем мастеру, до какого смещения дочитали
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				rp.mu.Lock()
+				ack := strconv.FormatInt(rp.offset, 10)
+				rp.mu.Unlock()
+				if _, err := conn.Write(resp.EncodeCommand([]string{"REPLCONF", "ACK", ack})); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	// поток команд от мастера; транзакции (MULTI ... EXEC) копятся и применяются целиком
+	var tx [][]string
+	var txRaw []byte
+	for {
+		args, err := rd.ReadArray()
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			continue
+		}
+		raw := resp.EncodeCommand(args)
+
+		switch cmd := strings.ToUpper(args[0]); {
+		case cmd == "MULTI":
+			tx, txRaw = [][]string{}, raw
+		case tx != nil && cmd == "EXEC":
+			rp.router.applyFromPrimary(tx, append(txRaw, raw...))
+			tx, txRaw = nil, nil
+		case tx != nil:
+			tx = append(tx, args)
+			txRaw = append(txRaw, raw...)
+		default:
+			rp.router.applyFromPrimary([][]string{args}, raw)
+		}
+	}
+}
+
+// метод loadFromPrimary - читает снапшот мастера после +FULLRESYNC и целиком заменяет им хранилище.
+func (rp *replication) loadFromPrimary(rd *resp.Reader, replID string, offset int64) error {
+	rp.mu.Lock()
+	rp.syncing = true
+	rp.mu.Unlock()
+
+	line, err := rd.ReadLine()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "$") {
+		return fmt.Errorf("bad snapshot header: %s", line)
+	}
+	size, err := strconv.Atoi(line[1:])
+	if err != nil || size < 0 {
+		return fmt.Errorf("bad snapshot size: %s", line)
+	}
+	payload, err := rd.ReadN(size)
+	if err != nil {
+		return err
+	}
+	entries, streamDB, err := rdb.ReadSelected(bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	if streamDB >= len(rp.dbs) {
+		return fmt.Errorf("master stream selects DB %d, but only %d databases are configured", streamDB, len(rp.dbs))
+	}
+
+	// заменяем данные под writeMu, чтобы наши собственные реплики получили согласованную картину
+	rp.router.freeze()
+	for _, db := range rp.dbs {
+		db.Flush()
+	}
+	n, err := restoreAll(rp.dbs, entries)
+	if err != nil {
+		rp.router.unfreeze()
+		return err
+	}
+	rp.router.primaryDB = streamDB
+	rp.mu.Lock()
+	rp.replID = replID
+	rp.offset = offset
+	if rp.backlog != nil {
+		rp.backlog.reset(offset)
+	}
+	// у наших реплик теперь другая история данных — пусть синхронизируются заново
+	for rc := range rp.replicas {
+		rc.conn.Close()
+	}
+	rp.syncing = false
+	rp.mu.Unlock()
+	rp.router.unfreeze()
+
+	logx.Info("Full resync with master done: %d keys loaded", n)
+
+	// журнал команд описывает старые данные — переписываем его по новому состоянию
+	if err := rp.router.persist.bgrewrite(); err != nil && !errors.Is(err, errAOFDisabled) {
+		logx.Error("AOF rewrite after full resync failed: %v", err)
+	}
+	return nil
+}
+
+// метод advance - учитывает применённую команду мастера: сдвигает смещение
+// и кладёт те же байты в наш backlog (для наших собственных реплик).
+func (rp *replication) advance(data []byte) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	rp.appendLocked(data)
+	rp.lastIO = time.Now()
+}
+
+// ---------- INFO replication ----------
+
+// метод info - секция "# Replication" для команды INFO.
+func (rp *replication) info() string {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	var b strings.Builder
+	b.WriteString("# Replication\r\n")
+	if rp.primaryHost == "" {
+		b.WriteString("role:master\r\n")
+	} else {
+		linkStatus := "down"
+		if rp.linkUp {
+			linkStatus = "up"
+		}
+		lastIO := -1
+		if !rp.lastIO.IsZero() {
+			lastIO = int(time.Since(rp.lastIO).Seconds())
+		}
+		syncing := 0
+		if rp.syncing {
+			syncing = 1
+		}
+		fmt.Fprintf(&b, "role:slave\r\n")
+		fmt.Fprintf(&b, "master_host:%s\r\n", rp.primaryHost)
+		fmt.Fprintf(&b, "master_port:%s\r\n", rp.primaryPort)
+		fmt.Fprintf(&b, "master_link_status:%s\r\n", linkStatus)
+		fmt.Fprintf(&b, "master_last_io_seconds_ago:%d\r\n", lastIO)
+		fmt.Fprintf(&b, "master_sync_in_progress:%d\r\n", syncing)
+		fmt.Fprintf(&b, "slave_repl_offset:%d\r\n", rp.offset)
+	}
+
+	fmt.Fprintf(&b, "connected_slaves:%d\r\n", len(rp.replicas))
+	i := 0
+	now := time.Now().Unix()
+	for rc := range rp.replicas {
+		fmt.Fprintf(&b, "slave%d:ip=%s,port=%s,state=online,offset=%d,lag=%d\r\n",
+			i, rc.addr, rc.port, rc.ack.Load(), now-rc.lastAck.Load())
+		i++
+	}
+
+	fmt.Fprintf(&b, "master_replid:%s\r\n", rp.replID)
+	fmt.Fprintf(&b, "master_repl_offset:%d\r\n", rp.offset)
+	if rp.backlog == nil {
+		b.WriteString("repl_backlog_active:0\r\n")
+		fmt.Fprintf(&b, "repl_backlog_size:%d\r\n", rp.backlogSize)
+		b.WriteString("repl_backlog_first_byte_offset:0\r\n")
+		b.WriteString("repl_backlog_histlen:0\r\n")
+	} else {
+		b.WriteString("repl_backlog_active:1\r\n")
+		fmt.Fprintf(&b, "repl_backlog_size:%d\r\n", rp.backlogSize)
+		fmt.Fprintf(&b, "repl_backlog_first_byte_offset:%d\r\n", rp.backlog.start()+1)
+		fmt.Fprintf(&b, "repl_backlog_histlen:%d\r\n", rp.backlog.histlen)
+	}
+	return b.String()
+}
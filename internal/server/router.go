@@ -3,7 +3,9 @@ package server
 import (
 	"strconv"
 	"strings"
+	"sync"
 
+	"github.com/AntonRadchenko/mini-redis-go/internal/logx"
 	"github.com/AntonRadchenko/mini-redis-go/internal/store"
 )
 
@@ -12,22 +14,296 @@ import (
 type Reply struct {
 	Type  string      // тип ответа ("simple" | "bulk" | "integer" | "array" | "error")
 	Value interface{} // значение ответа (строка, число, массив и т.п.).
+	// Для "array" значение — []string (массив строк) или []Reply (массив ответов разных типов),
+	// а nil означает nil-массив (*-1).
 }
 
-// структура Router — это обработчик клиентских команд.
-// Содержит ссылку на хранилище и решает, какую операцию выполнить (SET, GET, DEL и т.д.).
+// структура Router — это обработчик клиентских команд одной из баз данных сервера.
+// Содержит ссылку на хранилище этой базы и решает, какую операцию выполнить (SET, GET, DEL и т.д.).
+// Роутеры всех баз делят между собой общее состояние (routerState): журнал, репликацию, подписки,
+// блокировки транзакций; клиент работает с роутером базы, выбранной командой SELECT.
 type Router struct {
-	store *store.Store
+	store   *store.Store
+	db      int                  // номер базы (SELECT)
+	blocked map[string][]*waiter // ключ этой базы → ждущие его клиенты в порядке прихода (FIFO), под blockMu
+
+	*routerState
+}
+
+// структура routerState — общее для всех баз состояние роутера.
+type routerState struct {
+	dbs     []*Router    // роутеры всех баз по номерам
+	persist *persistence // может быть nil, если сервер запущен без персистентности
+	repl    *replication // может быть nil, если сервер запущен без репликации
+	pubsub  *pubsub      // подписки клиентов на каналы (SUBSCRIBE/PUBLISH)
+
+	// execMu делает EXEC атомарным: обычные команды клиентов выполняются под RLock,
+	// а EXEC берёт Lock, и никакая чужая команда не может вклиниться посреди транзакции.
+	execMu sync.RWMutex
+	inExec bool        // сейчас выполняется EXEC (меняется только под execMu.Lock)
+	txProp []loggedCmd // изменяющие команды транзакции, которые уйдут в AOF/репликам одним блоком MULTI/EXEC
+
+	// writeMu упорядочивает изменяющие команды, когда их нужно куда-то передавать (AOF, реплики):
+	// в этом случае они берут Lock и выполняются строго по одной, иначе — RLock и идут параллельно.
+	writeMu sync.RWMutex
+
+	// logDB — база последней команды, переданной в журнал и репликам (под writeMu); -1 — перед следующей
+	// командой нужен SELECT (журнал только что переписан или реплика получила снапшот).
+	// primaryDB — база, к которой относятся команды из потока мастера (на реплике).
+	logDB     int
+	primaryDB int
+
+	// blockMu защищает очереди клиентов, ждущих данных в блокирующих командах (BLPOP и т.п.).
+	// Порядок блокировок: execMu → blockMu → writeMu.
+	blockMu sync.Mutex
+}
+
+// структура loggedCmd — изменяющая команда транзакции вместе с номером базы, к которой она применилась.
+type loggedCmd struct {
+	db   int
+	args []string
 }
 
-// конструктор New создаёт новый объект Router
-// и связывает его с конкретным экземпляром хранилища Store.
-func New(store *store.Store) *Router {
-	return &Router{store: store}
+// структура command — описание команды для проверок до её выполнения.
+type command struct {
+	arity    int  // число аргументов вместе с именем команды; отрицательное — "не меньше чем |arity|"
+	write    bool // команда изменяет хранилище (попадает в AOF и поток репликации)
+	blocking bool // команда может заблокировать клиента; в AOF и репликам уходит её неблокирующий эквивалент
+	denyOOM  bool // команда может увеличить занятую память и отклоняется с OOM, если данные не помещаются в maxmemory
+}
+
+// commands — таблица всех известных роутеру команд.
+// По ней MULTI заранее проверяет команды, которые ставятся в очередь,
+// а роутер понимает, какие из них нужно передавать в журнал и репликам.
+var commands = map[string]command{
+	"PING":             {arity: -1},
+	"ECHO":             {arity: -2},
+	"SET":              {arity: -3, write: true, denyOOM: true},
+	"SETNX":            {arity: 3, write: true, denyOOM: true},
+	"SETEX":            {arity: 4, write: true, denyOOM: true},
+	"PSETEX":           {arity: 4, write: true, denyOOM: true},
+	"GETSET":           {arity: 3, write: true, denyOOM: true},
+	"INCR":             {arity: 2, write: true, denyOOM: true},
+	"DECR":             {arity: 2, write: true, denyOOM: true},
+	"INCRBY":           {arity: 3, write: true, denyOOM: true},
+	"DECRBY":           {arity: 3, write: true, denyOOM: true},
+	"INCRBYFLOAT":      {arity: 3, write: true, denyOOM: true},
+	"APPEND":           {arity: 3, write: true, denyOOM: true},
+	"STRLEN":           {arity: 2},
+	"GETRANGE":         {arity: 4},
+	"SETRANGE":         {arity: 4, write: true, denyOOM: true},
+	"GETDEL":           {arity: 2, write: true},
+	"GETEX":            {arity: -2, write: true},
+	"MSET":             {arity: -3, write: true, denyOOM: true},
+	"MSETNX":           {arity: -3, write: true, denyOOM: true},
+	"SETBIT":           {arity: 4, write: true, denyOOM: true},
+	"GETBIT":           {arity: 3},
+	"BITCOUNT":         {arity: -2},
+	"BITPOS":           {arity: -3},
+	"BITOP":            {arity: -4, write: true, denyOOM: true},
+	"BITFIELD":         {arity: -2, write: true, denyOOM: true},
+	"BITFIELD_RO":      {arity: -2},
+	"PFADD":            {arity: -2, write: true, denyOOM: true},
+	"PFCOUNT":          {arity: -2},
+	"PFMERGE":          {arity: -2, write: true, denyOOM: true},
+	"GEOADD":           {arity: -5, write: true, denyOOM: true},
+	"GEOPOS":           {arity: -2},
+	"GEODIST":          {arity: -4},
+	"GEOSEARCH":        {arity: -7},
+	"GEOSEARCHSTORE":   {arity: -8, write: true, denyOOM: true},
+	"GET":              {arity: 2},
+	"DEL":              {arity: -2, write: true},
+	"MGET":             {arity: -2},
+	"KEYS":             {arity: 2},
+	"SCAN":             {arity: -2},
+	"RANDOMKEY":        {arity: 1},
+	"DBSIZE":           {arity: 1},
+	"EXISTS":           {arity: -2},
+	"TYPE":             {arity: 2},
+	"TOUCH":            {arity: -2},
+	"RENAME":           {arity: 3, write: true},
+	"RENAMENX":         {arity: 3, write: true},
+	"COPY":             {arity: -3, write: true, denyOOM: true},
+	"MOVE":             {arity: 3, write: true},
+	"UNLINK":           {arity: -2, write: true},
+	"SELECT":           {arity: 2},
+	"SWAPDB":           {arity: 3, write: true},
+	"FLUSHDB":          {arity: -1, write: true},
+	"FLUSHALL":         {arity: -1, write: true},
+	"EXPIRE":           {arity: -3, write: true},
+	"PEXPIRE":          {arity: -3, write: true},
+	"EXPIREAT":         {arity: -3, write: true},
+	"PEXPIREAT":        {arity: -3, write: true},
+	"PERSIST":          {arity: 2, write: true},
+	"TTL":              {arity: 2},
+	"PTTL":             {arity: 2},
+	"EXPIRETIME":       {arity: 2},
+	"PEXPIRETIME":      {arity: 2},
+	"SAVE":             {arity: 1},
+	"BGSAVE":           {arity: 1},
+	"BGREWRITEAOF":     {arity: 1},
+	"REPLICAOF":        {arity: 3},
+	"SLAVEOF":          {arity: 3},
+	"REPLCONF":         {arity: -1},
+	"PSYNC":            {arity: 3},
+	"SYNC":             {arity: 1},
+	"INFO":             {arity: -1},
+	"MEMORY":           {arity: -2},
+	"MULTI":            {arity: 1},
+	"EXEC":             {arity: 1},
+	"DISCARD":          {arity: 1},
+	"WATCH":            {arity: -2},
+	"UNWATCH":          {arity: 1},
+	"SUBSCRIBE":        {arity: -2},
+	"UNSUBSCRIBE":      {arity: -1},
+	"PSUBSCRIBE":       {arity: -2},
+	"PUNSUBSCRIBE":     {arity: -1},
+	"PUBLISH":          {arity: 3},
+	"PUBSUB":           {arity: -2},
+	"LPUSH":            {arity: -3, write: true, denyOOM: true},
+	"RPUSH":            {arity: -3, write: true, denyOOM: true},
+	"LPUSHX":           {arity: -3, write: true, denyOOM: true},
+	"RPUSHX":           {arity: -3, write: true, denyOOM: true},
+	"LPOP":             {arity: -2, write: true},
+	"RPOP":             {arity: -2, write: true},
+	"LRANGE":           {arity: 4},
+	"LLEN":             {arity: 2},
+	"LINDEX":           {arity: 3},
+	"LSET":             {arity: 4, write: true, denyOOM: true},
+	"LREM":             {arity: 4, write: true},
+	"LTRIM":            {arity: 4, write: true},
+	"LMOVE":            {arity: 5, write: true, denyOOM: true},
+	"BLPOP":            {arity: -3, blocking: true},
+	"BRPOP":            {arity: -3, blocking: true},
+	"BLMOVE":           {arity: 6, blocking: true},
+	"HSET":             {arity: -4, write: true, denyOOM: true},
+	"HMSET":            {arity: -4, write: true, denyOOM: true},
+	"HSETNX":           {arity: 4, write: true, denyOOM: true},
+	"HGET":             {arity: 3},
+	"HMGET":            {arity: -3},
+	"HDEL":             {arity: -3, write: true},
+	"HGETALL":          {arity: 2},
+	"HKEYS":            {arity: 2},
+	"HVALS":            {arity: 2},
+	"HLEN":             {arity: 2},
+	"HEXISTS":          {arity: 3},
+	"HSTRLEN":          {arity: 3},
+	"HINCRBY":          {arity: 4, write: true, denyOOM: true},
+	"HINCRBYFLOAT":     {arity: 4, write: true, denyOOM: true},
+	"HSCAN":            {arity: -3},
+	"SADD":             {arity: -3, write: true, denyOOM: true},
+	"SREM":             {arity: -3, write: true},
+	"SMEMBERS":         {arity: 2},
+	"SISMEMBER":        {arity: 3},
+	"SMISMEMBER":       {arity: -3},
+	"SCARD":            {arity: 2},
+	"SINTER":           {arity: -2},
+	"SUNION":           {arity: -2},
+	"SDIFF":            {arity: -2},
+	"SINTERSTORE":      {arity: -3, write: true, denyOOM: true},
+	"SUNIONSTORE":      {arity: -3, write: true, denyOOM: true},
+	"SDIFFSTORE":       {arity: -3, write: true, denyOOM: true},
+	"SRANDMEMBER":      {arity: -2},
+	"SPOP":             {arity: -2, write: true},
+	"SMOVE":            {arity: 4, write: true},
+	"SSCAN":            {arity: -3},
+	"ZADD":             {arity: -4, write: true, denyOOM: true},
+	"ZINCRBY":          {arity: 4, write: true, denyOOM: true},
+	"ZREM":             {arity: -3, write: true},
+	"ZCARD":            {arity: 2},
+	"ZSCORE":           {arity: 3},
+	"ZMSCORE":          {arity: -3},
+	"ZRANK":            {arity: 3},
+	"ZREVRANK":         {arity: 3},
+	"ZCOUNT":           {arity: 4},
+	"ZLEXCOUNT":        {arity: 4},
+	"ZRANGE":           {arity: -4},
+	"ZREVRANGE":        {arity: -4},
+	"ZRANGEBYSCORE":    {arity: -4},
+	"ZREVRANGEBYSCORE": {arity: -4},
+	"ZRANGEBYLEX":      {arity: -4},
+	"ZREVRANGEBYLEX":   {arity: -4},
+	"ZPOPMIN":          {arity: -2, write: true},
+	"ZPOPMAX":          {arity: -2, write: true},
+	"ZREMRANGEBYRANK":  {arity: 4, write: true},
+	"ZREMRANGEBYSCORE": {arity: 4, write: true},
+	"ZREMRANGEBYLEX":   {arity: 4, write: true},
+	"ZSCAN":            {arity: -3},
+	"XADD":             {arity: -5, write: true, denyOOM: true},
+	"XLEN":             {arity: 2},
+	"XRANGE":           {arity: -4},
+	"XREVRANGE":        {arity: -4},
+	"XREAD":            {arity: -4, blocking: true},
+	"XTRIM":            {arity: -4, write: true},
+	"XDEL":             {arity: -3, write: true},
+	"XSETID":           {arity: -3, write: true},
+	"XGROUP":           {arity: -2, write: true, denyOOM: true},
+	"XREADGROUP":       {arity: -7, write: true, blocking: true},
+	"XACK":             {arity: -4, write: true},
+	"XPENDING":         {arity: -3},
+	"XCLAIM":           {arity: -6, write: true},
+	"XAUTOCLAIM":       {arity: -6, write: true},
+}
+
+// errNotInteger — ответ на аргумент, который должен быть целым числом.
+const errNotInteger = "ERR value is not an integer or out of range"
+
+// функция errorReply - превращает ошибку хранилища (например, store.ErrWrongType) в ответ-ошибку.
+func errorReply(err error) Reply {
+	return Reply{Type: "error", Value: err.Error()}
+}
+
+// функция boolReply - ответ 1/0 для команд, отвечающих "да/нет".
+func boolReply(ok bool) Reply {
+	if ok {
+		return Reply{Type: "integer", Value: 1}
+	}
+	return Reply{Type: "integer", Value: 0}
+}
+
+// функция isWrite - изменяет ли команда хранилище.
+func isWrite(cmd string) bool {
+	return commands[cmd].write
+}
+
+// функция isBlocking - может ли команда заблокировать клиента в ожидании данных.
+func isBlocking(cmd string) bool {
+	return commands[cmd].blocking
+}
+
+// функция deniedOnReplica - запрещена ли команда клиентам реплики: изменяющие команды
+// и блокирующие, которые, дождавшись данных, их забирают. XREAD только читает — ему можно.
+func deniedOnReplica(cmd string) bool {
+	return isWrite(cmd) || (isBlocking(cmd) && cmd != "XREAD")
+}
+
+// функция checkArity - проверяет, что команда известна и у неё правильное число аргументов.
+// Возвращает текст ошибки или пустую строку.
+func checkArity(cmd string, args []string) string {
+	c, ok := commands[cmd]
+	if !ok {
+		return "ERR unknown command '" + cmd + "'"
+	}
+	if (c.arity > 0 && len(args) != c.arity) || (c.arity < 0 && len(args) < -c.arity) {
+		return "ERR wrong number of arguments for '" + strings.ToLower(cmd) + "' command"
+	}
+	return ""
+}
+
+// конструктор New создаёт роутеры для баз данных dbs (номер базы — её индекс)
+// и возвращает роутер базы 0, с которой начинает работу каждый клиент.
+func New(dbs ...*store.Store) *Router {
+	st := &routerState{pubsub: newPubsub(), logDB: -1}
+	st.dbs = make([]*Router, len(dbs))
+	for i, s := range dbs {
+		st.dbs[i] = &Router{store: s, db: i, blocked: make(map[string][]*waiter), routerState: st}
+	}
+	return st.dbs[0]
 }
 
 // метод - Handle получает распарсенные аргументы команды,
 // определяет, что выполнить, и формирует ответ (Reply) для клиента.
+// Если команда изменила хранилище, она дополнительно передаётся дальше (propagate) — например, в AOF.
 func (r *Router) Handle(args []string) Reply {
 	if len(args) == 0 {
 		return Reply{"error", "ERR empty command"}
@@ -35,6 +311,250 @@ func (r *Router) Handle(args []string) Reply {
 
 	cmd := strings.ToUpper(args[0]) // приводим строку от клиента к верхнему регистру
 
+	// реплика принимает изменения только от своего мастера
+	if deniedOnReplica(cmd) && r.repl.isReplica() {
+		return Reply{Type: "error", Value: errReadonly}
+	}
+	reply := r.dispatch(cmd, args)
+	// команда могла положить данные в ключи, которых ждут заблокированные клиенты;
+	// внутри EXEC их обслужим после всей транзакции
+	if !r.inExec {
+		r.serveBlocked()
+	}
+	return reply
+}
+
+// метод dispatch - выполняет команду и, если она изменила хранилище, передаёт её дальше.
+func (r *Router) dispatch(cmd string, args []string) Reply {
+	if !isWrite(cmd) {
+		return r.execute(cmd, args)
+	}
+
+	r.writeMu.RLock()
+	if !r.propagating() {
+		defer r.writeMu.RUnlock()
+		if reply, ok := r.freeMemory(cmd); !ok {
+			return reply
+		}
+		return r.execute(cmd, args)
+	}
+	r.writeMu.RUnlock()
+
+	// изменяющие команды выполняются по одной, чтобы порядок записей
+	// в журнале и в потоке репликации совпадал с порядком, в котором они применились к хранилищу
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	if reply, ok := r.freeMemory(cmd); !ok {
+		return reply
+	}
+	before := r.dirty()
+	reply := r.execute(cmd, args)
+	if reply.Type != "error" && r.dirty() != before { // команда действительно что-то изменила
+		r.propagate(cmd, args, reply)
+	}
+	return reply
+}
+
+// метод freeMemory - перед изменяющей командой вытесняет ключи, если данные не помещаются в maxmemory
+// (вызывается из dispatch под writeMu). Удаление вытесненных ключей уходит в журнал и репликам как DEL,
+// поэтому реплика сама ничего не вытесняет, а повторяет решения мастера.
+// Предел памяти общий для всех баз: сначала ключи вытесняются из базы команды, а если их не хватило — из остальных.
+// false — памяти не хватает, а команда может её увеличить: клиент получает ответ-ошибку OOM.
+func (r *Router) freeMemory(cmd string) (Reply, bool) {
+	err := r.evict()
+	for _, db := range r.dbs {
+		if err == nil {
+			break
+		}
+		if db != r {
+			err = db.evict()
+		}
+	}
+	if err != nil && commands[cmd].denyOOM {
+		return errorReply(err), false
+	}
+	return Reply{}, true
+}
+
+// метод evict - вытесняет ключи этой базы, пока данные не уложатся в maxmemory, и передаёт их удаление дальше.
+func (r *Router) evict() error {
+	evicted, err := r.store.FreeMemory()
+	if r.propagating() {
+		for _, key := range evicted {
+			r.propagate("DEL", []string{"DEL", key}, Reply{})
+		}
+	}
+	return err
+}
+
+// метод dirty - общее число изменений всех баз: команда может изменить не только свою базу
+// (MOVE, COPY ... DB, SWAPDB, FLUSHALL).
+func (r *Router) dirty() int64 {
+	var n int64
+	for _, db := range r.dbs {
+		n += db.store.Dirty()
+	}
+	return n
+}
+
+// метод applyFromPrimary - применяет команды из потока репликации мастера (на реплике):
+// одну команду или целую транзакцию, атомарно для клиентов реплики.
+// raw — те же байты, что пришли от мастера: они без изменений уходят в наш backlog,
+// чтобы смещения реплики совпадали с мастерскими.
+func (r *Router) applyFromPrimary(cmds [][]string, raw []byte) {
+	r.freeze()
+
+	for _, args := range cmds {
+		db := r.dbs[r.primaryDB]
+		if next, ok := db.loggedSelect(args); ok {
+			r.primaryDB = next.db
+			continue
+		}
+		cmd := strings.ToUpper(args[0])
+		before := r.dirty()
+		reply := db.execute(cmd, args)
+		if isWrite(cmd) && reply.Type != "error" && r.dirty() != before && r.persist.aofEnabled() {
+			r.logSelect(db.db, r.persist.appendCommand)
+			r.persist.appendCommand(db.rewriteForLog(cmd, args, reply))
+		}
+	}
+	r.repl.advance(raw)
+	r.unfreeze()
+	r.serveBlocked() // клиенты реплики могут ждать новых записей потока в XREAD BLOCK
+}
+
+// метод propagating - нужно ли сейчас куда-то передавать изменяющие команды.
+func (r *Router) propagating() bool {
+	return r.persist.aofEnabled() || r.repl.active()
+}
+
+// метод propagate - передаёт применённую изменяющую команду в журнал команд и репликам.
+// Относительный EXPIRE записывается как PEXPIREAT с абсолютным временем,
+// иначе при проигрывании журнала после рестарта TTL отсчитывался бы заново.
+func (r *Router) propagate(cmd string, args []string, reply Reply) {
+	args = r.rewriteForLog(cmd, args, reply)
+	if r.inExec {
+		r.txProp = append(r.txProp, loggedCmd{db: r.db, args: args}) // допишем после EXEC целым блоком
+		return
+	}
+	r.emitIn(r.db, args)
+}
+
+// метод rewriteForLog - приводит команду к виду, который одинаково применится
+// и при проигрывании журнала, и на реплике: относительное время → абсолютное,
+// приращение дробного числа → итоговое значение (чтобы не зависеть от округления),
+// случайный выбор → удаление именно выбранных элементов, GETEX → итоговый TTL ключа, автоматический идентификатор записи потока → итоговый,
+// XCLAIM/XAUTOCLAIM → передача именно тех записей, что передались. reply — ответ, который получила команда.
+func (r *Router) rewriteForLog(cmd string, args []string, reply Reply) []string {
+	switch cmd {
+	case "SET", "SETNX", "SETEX", "PSETEX", "GETSET":
+		// относительный TTL (EX, PX, SETEX) заменяется абсолютным, чтобы повтор журнала не продлил жизнь ключа
+		key, value := args[1], args[2]
+		if cmd == "SETEX" || cmd == "PSETEX" {
+			value = args[3]
+		}
+		if at, ok := r.store.ExpireTime(key); ok {
+			return []string{"SET", key, value, "PXAT", strconv.FormatInt(at.UnixMilli(), 10)}
+		}
+		if r.store.Exists(key) == 0 {
+			return []string{"DEL", key} // EXAT/PXAT в прошлом — значение сразу истекло
+		}
+		return []string{"SET", key, value}
+	case "EXPIRE", "PEXPIRE", "EXPIREAT", "PEXPIREAT":
+		key := args[1]
+		if at, ok := r.store.ExpireTime(key); ok {
+			return []string{"PEXPIREAT", key, strconv.FormatInt(at.UnixMilli(), 10)}
+		}
+		return []string{"DEL", key} // TTL в прошлом — ключ уже удалён
+	case "HINCRBYFLOAT":
+		return []string{"HSET", args[1], args[2], reply.Value.(string)}
+	case "INCRBYFLOAT":
+		return []string{"SET", args[1], reply.Value.(string), "KEEPTTL"}
+	case "GETEX":
+		// относительный EX/PX — абсолютным временем; момент в прошлом уже удалил ключ
+		key := args[1]
+		if r.store.Exists(key) == 0 {
+			return []string{"DEL", key}
+		}
+		if at, ok := r.store.ExpireTime(key); ok {
+			return []string{"PEXPIREAT", key, strconv.FormatInt(at.UnixMilli(), 10)}
+		}
+		return []string{"PERSIST", key}
+	case "SPOP":
+		switch popped := reply.Value.(type) {
+		case string:
+			return []string{"SREM", args[1], popped}
+		case []string:
+			return append([]string{"SREM", args[1]}, popped...)
+		}
+	case "XADD":
+		// "*" и "ms-*" — идентификатор, который реально получила запись
+		a, _ := parseXAdd(args)
+		out := append([]string(nil), args...)
+		out[a.idIndex] = reply.Value.(string)
+		return out
+	case "XCLAIM", "XAUTOCLAIM":
+		return r.rewriteClaim(cmd, args, reply)
+	}
+	return args
+}
+
+// метод emit - отправляет команду в журнал команд и в поток репликации.
+func (r *Router) emit(args []string) {
+	if r.persist.aofEnabled() {
+		r.persist.appendCommand(args)
+	}
+	r.repl.feed(args)
+}
+
+// метод emitIn - отправляет команду базы db в журнал команд и в поток репликации (см. logSelect).
+func (r *Router) emitIn(db int, args []string) {
+	r.logSelect(db, r.emit)
+	r.emit(args)
+}
+
+// метод logSelect - если команда базы db идёт в журнал следом за командой другой базы,
+// сначала отправляет в out команду SELECT db: при проигрывании журнала и на реплике
+// команды применяются к той же базе, что и на мастере (вызывается под writeMu).
+func (r *Router) logSelect(db int, out func(args []string)) {
+	if db != r.logDB {
+		r.logDB = db
+		out([]string{"SELECT", strconv.Itoa(db)})
+	}
+}
+
+// метод loggedSelect - команда SELECT из журнала команд или потока мастера: возвращает роутер выбранной базы.
+// false — это другая команда, её нужно выполнить как обычно.
+// Если базы с таким номером на этом сервере нет (сервер запущен с меньшим DATABASES),
+// ошибка попадает в лог, а следующие команды применяются к текущей базе.
+func (r *Router) loggedSelect(args []string) (*Router, bool) {
+	if len(args) != 2 || !strings.EqualFold(args[0], "SELECT") {
+		return nil, false
+	}
+	db, msg := r.parseDB(args[1])
+	if msg != "" {
+		logx.Error("SELECT %s from the command log: %s", args[1], msg)
+		return r, true
+	}
+	return db, true
+}
+
+// метод freeze - останавливает все изменяющие команды и транзакции
+// (нужно, чтобы снять копию хранилища, согласованную с журналом и потоком репликации).
+func (r *Router) freeze() {
+	r.execMu.Lock()
+	r.writeMu.Lock()
+}
+
+// метод unfreeze - снова разрешает изменяющие команды после freeze.
+func (r *Router) unfreeze() {
+	r.writeMu.Unlock()
+	r.execMu.Unlock()
+}
+
+// метод execute - сопоставляет команду с её обработчиком и выполняет её.
+func (r *Router) execute(cmd string, args []string) Reply {
 	// проверяем введенные данные и сохраняем в структуру тип и значение
 	switch cmd {
 	case "PING":
@@ -49,18 +569,27 @@ func (r *Router) Handle(args []string) Reply {
 		return Reply{Type: "bulk", Value: msg}
 
 	// следующие проверки команд, использующих store/
-	case "SET":
-		if len(args) != 3 {
-			return Reply{Type: "error", Value: "ERR wrong number of arguments for 'set' command"}
-		}
-		r.store.Set(args[1], args[2])
-		return Reply{Type: "simple", Value: "OK"} // просто говорим +OK, типо все записалось хорошо
+	case "SET", "SETNX", "SETEX", "PSETEX", "GETSET", "INCR", "DECR", "INCRBY", "DECRBY", "INCRBYFLOAT",
+		"APPEND", "STRLEN", "GETRANGE", "SETRANGE", "GETDEL", "GETEX", "MSET", "MSETNX":
+		return r.stringCommand(cmd, args)
+
+	case "SETBIT", "GETBIT", "BITCOUNT", "BITPOS", "BITOP", "BITFIELD", "BITFIELD_RO":
+		return r.bitmapCommand(cmd, args)
+
+	case "PFADD", "PFCOUNT", "PFMERGE":
+		return r.hllCommand(cmd, args)
+
+	case "GEOADD", "GEOPOS", "GEODIST", "GEOSEARCH", "GEOSEARCHSTORE":
+		return r.geoCommand(cmd, args)
 
 	case "GET":
 		if len(args) != 2 {
 			return Reply{Type: "error", Value: "ERR wrong number of arguments for 'get' command"}
 		}
-		val, ok := r.store.Get(args[1])
+		val, ok, err := r.store.GetString(args[1])
+		if err != nil {
+			return errorReply(err)
+		}
 		if !ok {
 			return Reply{Type: "bulk", Value: nil}
 		}
@@ -77,37 +606,136 @@ func (r *Router) Handle(args []string) Reply {
 		if len(args) < 2 {
 			return Reply{Type: "error", Value: "ERR wrong number of arguments for 'mget' command"}
 		}
-		var results []string
-		for _, key := range args[1:] {
-			val, ok := r.store.Get(key)
-			if !ok {
-				results = append(results, "") // nil → пустая строка
+		results := make([]Reply, 0, len(args)-1)
+		for _, val := range r.store.MGet(args[1:]...) {
+			if val == nil {
+				results = append(results, Reply{Type: "bulk", Value: nil}) // несуществующий ключ → nil
 			} else {
-				results = append(results, val)
+				results = append(results, Reply{Type: "bulk", Value: *val})
 			}
 		}
 		return Reply{Type: "array", Value: results}
 
-	case "EXPIRE":
+	case "EXPIRE", "PEXPIRE", "EXPIREAT", "PEXPIREAT", "TTL", "PTTL", "EXPIRETIME", "PEXPIRETIME", "PERSIST":
+		return r.expireCommand(cmd, args)
+
+	case "SAVE":
+		if len(args) != 1 {
+			return Reply{Type: "error", Value: "ERR wrong number of arguments for 'save' command"}
+		}
+		if r.persist == nil {
+			return Reply{Type: "error", Value: "ERR persistence is not configured"}
+		}
+		if err := r.persist.save(true); err != nil {
+			return Reply{Type: "error", Value: "ERR " + err.Error()}
+		}
+		return Reply{Type: "simple", Value: "OK"}
+
+	case "BGSAVE":
+		if len(args) != 1 {
+			return Reply{Type: "error", Value: "ERR wrong number of arguments for 'bgsave' command"}
+		}
+		if r.persist == nil {
+			return Reply{Type: "error", Value: "ERR persistence is not configured"}
+		}
+		if err := r.persist.bgsave(true); err != nil {
+			return Reply{Type: "error", Value: "ERR " + err.Error()}
+		}
+		return Reply{Type: "simple", Value: "Background saving started"}
+
+	case "BGREWRITEAOF":
+		if len(args) != 1 {
+			return Reply{Type: "error", Value: "ERR wrong number of arguments for 'bgrewriteaof' command"}
+		}
+		if r.persist == nil {
+			return Reply{Type: "error", Value: "ERR persistence is not configured"}
+		}
+		if err := r.persist.bgrewrite(); err != nil {
+			return Reply{Type: "error", Value: "ERR " + err.Error()}
+		}
+		return Reply{Type: "simple", Value: "Background append only file rewriting started"}
+
+	case "REPLICAOF", "SLAVEOF":
 		if len(args) != 3 {
-			return Reply{Type: "error", Value: "ERR wrong number of arguments for 'expire' command"}
+			return Reply{Type: "error", Value: "ERR wrong number of arguments for 'replicaof' command"}
 		}
-		seconds, err := strconv.Atoi(args[2]) // превращаем длительность из строкового типа в integer
-		if err != nil {
-			return Reply{Type: "error", Value: "ERR value is not an integer or out of range"}
+		if r.repl == nil {
+			return Reply{Type: "error", Value: "ERR replication is not configured"}
+		}
+		if strings.EqualFold(args[1], "NO") && strings.EqualFold(args[2], "ONE") {
+			r.repl.replicaOfNoOne()
+			return Reply{Type: "simple", Value: "OK"}
 		}
-		ok := r.store.Expire(args[1], seconds)
-		if ok {
-			return Reply{Type: "integer", Value: 1}
+		if port, err := strconv.Atoi(args[2]); err != nil || port <= 0 || port > 65535 {
+			return Reply{Type: "error", Value: "ERR Invalid master port"}
 		}
-		return Reply{Type: "integer", Value: 0}
+		r.repl.replicaOf(args[1], args[2])
+		return Reply{Type: "simple", Value: "OK"}
 
-	case "TTL":
-		if len(args) != 2 {
-			return Reply{Type: "error", Value: "ERR wrong number of arguments for 'ttl' command"}
+	case "REPLCONF":
+		// рукопожатие реплики обрабатывается в handleConn; здесь просто соглашаемся
+		return Reply{Type: "simple", Value: "OK"}
+
+	case "INFO":
+		if len(args) > 2 {
+			return Reply{Type: "error", Value: "ERR syntax error"}
+		}
+		section := ""
+		if len(args) == 2 {
+			section = args[1]
+		}
+		return Reply{Type: "bulk", Value: r.info(section)}
+
+	case "PUBLISH":
+		if len(args) != 3 {
+			return Reply{Type: "error", Value: "ERR wrong number of arguments for 'publish' command"}
 		}
-		ttl := r.store.TTL(args[1])
-		return Reply{Type: "integer", Value: ttl}
+		return Reply{Type: "integer", Value: r.pubsub.publish(args[1], args[2])}
+
+	case "PUBSUB":
+		return r.pubsubCommand(args)
+
+	case "KEYS", "SCAN", "RANDOMKEY", "DBSIZE", "EXISTS", "TYPE", "TOUCH", "RENAME", "RENAMENX", "COPY", "MOVE", "UNLINK":
+		return r.keyspaceCommand(cmd, args)
+
+	case "SWAPDB", "FLUSHDB", "FLUSHALL":
+		return r.dbCommand(cmd, args)
+
+	case "MEMORY":
+		if msg := checkArity(cmd, args); msg != "" {
+			return Reply{Type: "error", Value: msg}
+		}
+		if strings.ToUpper(args[1]) != "USAGE" || len(args) != 3 {
+			return Reply{Type: "error", Value: "ERR unknown subcommand or wrong number of arguments for '" + args[1] + "'. Try MEMORY USAGE."}
+		}
+		size, ok := r.store.MemoryUsage(args[2])
+		if !ok {
+			return Reply{Type: "bulk", Value: nil}
+		}
+		return Reply{Type: "integer", Value: int(size)}
+
+	case "LPUSH", "RPUSH", "LPUSHX", "RPUSHX", "LPOP", "RPOP", "LRANGE", "LLEN", "LINDEX", "LSET", "LREM", "LTRIM", "LMOVE":
+		return r.listCommand(cmd, args)
+
+	case "HSET", "HMSET", "HSETNX", "HGET", "HMGET", "HDEL", "HGETALL", "HKEYS", "HVALS", "HLEN", "HEXISTS", "HSTRLEN", "HINCRBY", "HINCRBYFLOAT", "HSCAN":
+		return r.hashCommand(cmd, args)
+
+	case "SADD", "SREM", "SMEMBERS", "SISMEMBER", "SMISMEMBER", "SCARD", "SINTER", "SUNION", "SDIFF",
+		"SINTERSTORE", "SUNIONSTORE", "SDIFFSTORE", "SRANDMEMBER", "SPOP", "SMOVE", "SSCAN":
+		return r.setCommand(cmd, args)
+
+	case "ZADD", "ZINCRBY", "ZREM", "ZCARD", "ZSCORE", "ZMSCORE", "ZRANK", "ZREVRANK", "ZCOUNT", "ZLEXCOUNT",
+		"ZRANGE", "ZREVRANGE", "ZRANGEBYSCORE", "ZREVRANGEBYSCORE", "ZRANGEBYLEX", "ZREVRANGEBYLEX",
+		"ZPOPMIN", "ZPOPMAX", "ZREMRANGEBYRANK", "ZREMRANGEBYSCORE", "ZREMRANGEBYLEX", "ZSCAN":
+		return r.zsetCommand(cmd, args)
+
+	case "XADD", "XLEN", "XRANGE", "XREVRANGE", "XREAD", "XTRIM", "XDEL", "XSETID",
+		"XGROUP", "XREADGROUP", "XACK", "XPENDING", "XCLAIM", "XAUTOCLAIM":
+		return r.streamCommand(cmd, args)
+
+	case "BLPOP", "BRPOP", "BLMOVE":
+		// сюда попадаем только внутри EXEC: там блокирующие команды не ждут, а пробуют один раз
+		return r.blockingOnce(cmd, args)
 
 	default:
 		return Reply{"error", "ERR unknown command '" + cmd + "'"}
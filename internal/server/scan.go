@@ -0,0 +1,69 @@
+package server
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// структура scanArgs — разобранные аргументы курсорных команд (SCAN, HSCAN и т.п.).
+type scanArgs struct {
+	cursor uint64
+	count  int    // 0 — размер порции по умолчанию
+	match  string // пустой — без фильтра
+	typ    string // тип значения (только SCAN); пустой — любой
+}
+
+// scanTypes — типы значений, которые понимает SCAN ... TYPE.
+var scanTypes = []string{"string", "list", "hash", "set", "zset", "stream"}
+
+// функция parseScanArgs - разбирает "<cursor> [MATCH pattern] [COUNT count]",
+// а если withType (SCAN) — ещё и "[TYPE type]". Возвращает аргументы или текст ошибки.
+func parseScanArgs(args []string, withType bool) (scanArgs, string) {
+	var sa scanArgs
+	cursor, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return sa, "ERR invalid cursor"
+	}
+	sa.cursor = cursor
+	for i := 1; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return sa, "ERR syntax error"
+		}
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			sa.match = args[i+1]
+			if sa.match == "*" {
+				sa.match = "" // подходит всё — фильтр не нужен
+			}
+		case "COUNT":
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil {
+				return sa, errNotInteger
+			}
+			if n < 1 {
+				return sa, "ERR syntax error"
+			}
+			sa.count = n
+		case "TYPE":
+			if !withType {
+				return sa, "ERR syntax error"
+			}
+			sa.typ = strings.ToLower(args[i+1])
+			if !slices.Contains(scanTypes, sa.typ) {
+				return sa, "ERR unknown type name '" + args[i+1] + "'"
+			}
+		default:
+			return sa, "ERR syntax error"
+		}
+	}
+	return sa, ""
+}
+
+// функция scanReply - ответ курсорной команды: [следующий курсор, [элементы...]].
+func scanReply(next uint64, items []string) Reply {
+	return Reply{Type: "array", Value: []Reply{
+		{Type: "bulk", Value: strconv.FormatUint(next, 10)},
+		{Type: "array", Value: items},
+	}}
+}
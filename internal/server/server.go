@@ -2,12 +2,15 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"log"
 	"net"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/AntonRadchenko/mini-redis-go/internal/config"
 	"github.com/AntonRadchenko/mini-redis-go/internal/logx"
 	"github.com/AntonRadchenko/mini-redis-go/internal/resp"
 	"github.com/AntonRadchenko/mini-redis-go/internal/store"
@@ -15,25 +18,60 @@ import (
 
 // Структура Server - это место для таких зависимостей как адрес порта, логи, хранилище
 type Server struct {
-	addr       string // адрес порта
-	store      *store.Store
+	addr       string         // адрес порта
+	dbs        []*store.Store // пронумерованные базы данных (SELECT)
 	r          *Router
-	maxClients int // max число клиентов, которые могут подключиться одновременно
+	persist    *persistence // снапшоты хранилища на диск (SAVE/BGSAVE и автосохранение)
+	repl       *replication // репликация мастер → реплика
+	maxClients int          // max число клиентов, которые могут подключиться одновременно
+	queueSize  int          // размер очереди исходящих сообщений клиента
 }
 
-// Конструктор NewServer создает новый объект Server, то есть создает сервер для пользователя
-func NewServer(a string) *Server {
-	s := store.NewStore()
-	s.StartTTLScanner(100 * time.Millisecond) // запускаем фоновой сканер истёкших ключей
-	r := New(s)                               // создаём роутер, связанный с этим хранилищем
-	maxClients := 100                         // задаем максимальное кол-во клиентов
+// Конструктор NewServer создает новый объект Server, то есть создает сервер для пользователя.
+// Данные с диска (снапшот или журнал команд) загружаются в хранилище ещё до того,
+// как сервер начнёт принимать клиентов. Если загрузить их не удалось или настройки неверны — возвращается ошибка.
+func NewServer(cfg *config.Config) (*Server, error) {
+	policy, ok := store.ParseEvictionPolicy(cfg.MaxMemoryPolicy)
+	if !ok {
+		return nil, fmt.Errorf("invalid MAXMEMORY_POLICY %q", cfg.MaxMemoryPolicy)
+	}
+
+	dbs := store.NewDatabases(cfg.Databases)
+	r := New(dbs...) // создаём роутеры баз данных; клиенты начинают с базы 0
+	p := newPersistence(dbs, cfg)
+	p.router = r
+	if err := p.load(); err != nil {
+		return nil, err
+	}
+	r.persist = p
+
+	_, port, _ := net.SplitHostPort(cfg.Addr)
+	rp := newReplication(dbs, r, cfg.ReplBacklogSize, port)
+	if cfg.ReplicaOf != "" {
+		fields := strings.Fields(cfg.ReplicaOf)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid REPLICAOF %q, expected \"host port\"", cfg.ReplicaOf)
+		}
+		rp.primaryHost, rp.primaryPort = fields[0], fields[1]
+		rp.replica.Store(true) // цикл синхронизации запустится в Run
+	}
+	r.repl = rp
+
+	for _, db := range dbs {
+		db.SetMaxMemory(cfg.MaxMemory, policy, cfg.MaxMemorySamples) // после загрузки: сохранённые данные не вытесняются при чтении с диска
+		db.StartTTLScanner(100 * time.Millisecond)                   // у каждой базы свой фоновый сканер истёкших ключей
+	}
+	maxClients := 100 // задаем максимальное кол-во клиентов
 
 	return &Server{
-		addr:       a,
-		store:      s,
+		addr:       cfg.Addr,
+		dbs:        dbs,
 		r:          r,
+		persist:    p,
+		repl:       rp,
 		maxClients: maxClients,
-	}
+		queueSize:  cfg.ClientQueueSize,
+	}, nil
 }
 
 // метод Run - поднимает TCP-листенер и мы принимаем соединения
@@ -50,7 +88,16 @@ func (s *Server) Run(ctx context.Context) error {
 	logx.Info("Server started on %s", s.addr)
 
 	sem := make(chan struct{}, s.maxClients) // семафор для ограничения клиентов
-	var wg sync.WaitGroup                    // для ожидания завершения всех соединений (только потом сможем выйти)
+	var wg sync.WaitGroup                    // для ожидания завершения всех соединений и фоновых задач (только потом сможем выйти)
+
+	// фоновая проверка правил автосохранения; её тоже дожидаемся через wg,
+	// чтобы она не запустила BGSAVE/BGREWRITEAOF посреди shutdown
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.persist.run(ctx)
+	}()
+	s.repl.start(ctx) // если сервер — реплика, начинаем синхронизацию с мастером
 
 	// бесконечный цикл для приема соединений
 	for {
@@ -63,6 +110,7 @@ func (s *Server) Run(ctx context.Context) error {
 			listener.Close() // <- вызывается вручную при Ctrl+C
 			logx.Info("Listener closed, waiting for active clients...")
 			wg.Wait() // дождёмся завершения активных соединений
+			s.persist.shutdown()
 			return nil
 
 		default:
@@ -84,6 +132,7 @@ func (s *Server) Run(ctx context.Context) error {
 					case <-ctx.Done():
 						logx.Info("Shutdown signal received while waiting on Accept")
 						wg.Wait() // дождёмся завершения активных соединений
+						s.persist.shutdown()
 						return nil
 					default:
 						// Если сигнала нет — продолжаем слушать новых клиентов
@@ -95,6 +144,7 @@ func (s *Server) Run(ctx context.Context) error {
 				if ctx.Err() != nil {
 					logx.Info("Listener stopped by context cancel")
 					wg.Wait()
+					s.persist.shutdown()
 					return nil
 				}
 
@@ -111,14 +161,16 @@ func (s *Server) Run(ctx context.Context) error {
 			go func(c net.Conn) {
 				defer wg.Done()
 				defer func() { <-sem }()
-				s.handleConn(c)
+				s.handleConn(ctx, c)
 			}(conn)
 		}
 	}
 }
 
-// метод handleConn - обрабатывает соединение
-func (s *Server) handleConn(conn net.Conn) {
+// метод handleConn - обрабатывает соединение.
+// Команды читаются по одной, но ответы не обязательно приходят сразу: их пишет отдельная
+// горутина клиента, а блокирующая команда (BLPOP и т.п.) может ответить намного позже.
+func (s *Server) handleConn(ctx context.Context, conn net.Conn) {
 	defer conn.Close()
 
 	// у нас открытое TCP-соединение с клиентом;
@@ -130,6 +182,13 @@ func (s *Server) handleConn(conn net.Conn) {
 	rd := resp.NewReader(conn) // оборачиваем conn в Reader
 	wr := resp.NewWriter(conn) // оборачиваем conn в Writer
 
+	c := newClient(ctx, conn, s.queueSize) // состояние соединения (выбранная база, транзакции, WATCH, подписки)
+	go c.writeLoop(wr)                     // ответы пишет отдельная горутина (см. client)
+	defer c.close()
+	defer s.r.pubsub.unsubscribeAll(c) // клиент ушёл — подписки больше не нужны
+	defer s.r.unwatch(c)               // и его WATCH тоже
+	replPort := ""                     // порт, который сообщила реплика в рукопожатии (REPLCONF listening-port)
+
 	// цикл общения с клиентом
 	for {
 		args, err := rd.ReadArray() // читаем данные от клиента
@@ -142,54 +201,80 @@ func (s *Server) handleConn(conn net.Conn) {
 			return
 		}
 
+		// рукопожатие репликации: после PSYNC соединение целиком переходит к репликации
+		if len(args) > 0 {
+			switch strings.ToUpper(args[0]) {
+			case "REPLCONF":
+				if len(args) >= 3 && strings.EqualFold(args[1], "listening-port") {
+					replPort = args[2]
+				}
+			case "PSYNC", "SYNC":
+				c.detach() // дальше в соединение пишет только репликация
+				s.repl.serveReplica(conn, rd, args, replPort)
+				return
+			}
+		}
+
 		// обрабатываем в router данные и получаем в структуре тип команды и само значение которое нужно отдать клиенту (write)
-		reply := s.r.Handle(args)
+		reply := s.r.handleClient(c, args)
 
-		// в зависимости от типа команды, выбираем как записать ответ клиенту
-		switch reply.Type {
-		case "simple":
-			err := wr.WriteSimple(reply.Value.(string)) // достаем из интерфейса Value определенный тип
-			if err != nil {
-				log.Printf("Write error: %v", err)
-			}
+		if reply.Type == "" {
+			continue // обработчик уже сам отправил всё клиенту
+		}
+		// ответ уходит в очередь клиента, а в сокет его запишет writeLoop
+		if !c.send(reply) {
+			return
+		}
+		// сервер останавливается: дописываем ответ и закрываем соединение,
+		// иначе освобождённый из BLPOP клиент просто заблокировался бы снова
+		if ctx.Err() != nil {
+			c.detach()
+			return
+		}
+	}
+}
 
-		case "bulk":
-			if reply.Value == nil {
-				_ = wr.WriteBulk("") // передадим "", чтобы сработала ветка "$-1\r\n"
-				break
-			}
-			err := wr.WriteBulk(reply.Value.(string))
-			if err != nil {
-				log.Printf("Write error: %v", err)
-			}
+// функция writeReply - записывает ответ роутера клиенту в RESP-формате,
+// выбирая метод Writer по типу ответа. Массивы ответов записываются рекурсивно.
+func writeReply(wr *resp.Writer, reply Reply) error {
+	switch reply.Type {
+	case "simple":
+		return wr.WriteSimple(reply.Value.(string)) // достаем из интерфейса Value определенный тип
 
-		case "integer":
-			err := wr.WriteInteger(reply.Value.(int))
-			if err != nil {
-				log.Printf("Write error: %v", err)
-			}
+	case "bulk":
+		if reply.Value == nil {
+			return wr.WriteNull() // "$-1\r\n"
+		}
+		return wr.WriteBulk(reply.Value.(string))
 
-		case "array":
-			values, ok := reply.Value.([]string)
-			if !ok {
-				_ = wr.WriteError("ERR internal: array value type mismatch")
-				return
-			}
-			_ = wr.WriteArray(values)
+	case "integer":
+		return wr.WriteInteger(reply.Value.(int))
 
-		case "error":
-			err := wr.WriteError(reply.Value.(string))
-			if err != nil {
-				log.Printf("Write error: %v", err)
+	case "array":
+		switch values := reply.Value.(type) {
+		case nil:
+			return wr.WriteNullArray() // "*-1\r\n"
+		case []string:
+			return wr.WriteArray(values)
+		case []Reply:
+			if err := wr.WriteArrayHeader(len(values)); err != nil {
+				return err
 			}
-
-		default:
-			// на всякий случай
-			err := wr.WriteError("ERR internal: unsupported reply type")
-			if err != nil {
-				log.Printf("Write Error: %v", err)
+			for _, v := range values {
+				if err := writeReply(wr, v); err != nil {
+					return err
+				}
 			}
-			return
+			return nil
+		default:
+			return wr.WriteError("ERR internal: array value type mismatch")
 		}
+
+	case "error":
+		return wr.WriteError(reply.Value.(string))
+
+	default:
+		// на всякий случай
+		return wr.WriteError("ERR internal: unsupported reply type")
 	}
 }
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"strconv"
+
+	"github.com/AntonRadchenko/mini-redis-go/internal/store"
+)
+
+// setOps — операции над множествами у команд SINTER/SUNION/SDIFF и их *STORE-вариантов.
+var setOps = map[string]store.SetOp{
+	"SINTER": store.SetInter, "SINTERSTORE": store.SetInter,
+	"SUNION": store.SetUnion, "SUNIONSTORE": store.SetUnion,
+	"SDIFF": store.SetDiff, "SDIFFSTORE": store.SetDiff,
+}
+
+// метод setCommand - команды над множествами (SADD, SREM, SMEMBERS, SISMEMBER, SINTER, SPOP, SSCAN и др.).
+// Команда к ключу другого типа получает WRONGTYPE, пустое множество удаляется вместе с ключом.
+func (r *Router) setCommand(cmd string, args []string) Reply {
+	if msg := checkArity(cmd, args); msg != "" {
+		return Reply{Type: "error", Value: msg}
+	}
+	key := args[1]
+
+	switch cmd {
+	case "SADD", "SREM":
+		change := r.store.SAdd
+		if cmd == "SREM" {
+			change = r.store.SRem
+		}
+		n, err := change(key, args[2:]...)
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "integer", Value: n}
+
+	case "SMEMBERS":
+		members, err := r.store.SMembers(key)
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "array", Value: members}
+
+	case "SISMEMBER":
+		ok, err := r.store.SIsMember(key, args[2])
+		if err != nil {
+			return errorReply(err)
+		}
+		return boolReply(ok)
+
+	case "SMISMEMBER":
+		res, err := r.store.SMIsMember(key, args[2:]...)
+		if err != nil {
+			return errorReply(err)
+		}
+		out := make([]Reply, len(res))
+		for i, ok := range res {
+			out[i] = boolReply(ok)
+		}
+		return Reply{Type: "array", Value: out}
+
+	case "SCARD":
+		n, err := r.store.SCard(key)
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "integer", Value: n}
+
+	case "SINTER", "SUNION", "SDIFF":
+		members, err := r.store.SetOp(setOps[cmd], args[1:]...)
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "array", Value: members}
+
+	case "SINTERSTORE", "SUNIONSTORE", "SDIFFSTORE":
+		n, err := r.store.SetOpStore(setOps[cmd], key, args[2:]...)
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "integer", Value: n}
+
+	case "SRANDMEMBER", "SPOP":
+		// без count отвечаем одним элементом, с count — массивом
+		if len(args) > 3 {
+			return Reply{Type: "error", Value: "ERR syntax error"}
+		}
+		count, withCount := 1, len(args) == 3
+		if withCount {
+			n, err := strconv.Atoi(args[2])
+			if err != nil {
+				return Reply{Type: "error", Value: errNotInteger}
+			}
+			if n < 0 && cmd == "SPOP" {
+				return Reply{Type: "error", Value: "ERR value is out of range, must be positive"}
+			}
+			count = n
+		}
+		var members []string
+		var err error
+		if cmd == "SPOP" {
+			members, err = r.store.SPop(key, count)
+		} else {
+			members, err = r.store.SRandMember(key, count)
+		}
+		if err != nil {
+			return errorReply(err)
+		}
+		if withCount {
+			if members == nil {
+				members = []string{}
+			}
+			return Reply{Type: "array", Value: members}
+		}
+		if len(members) == 0 {
+			return Reply{Type: "bulk", Value: nil}
+		}
+		return Reply{Type: "bulk", Value: members[0]}
+
+	case "SMOVE":
+		moved, err := r.store.SMove(key, args[2], args[3])
+		if err != nil {
+			return errorReply(err)
+		}
+		return boolReply(moved)
+
+	case "SSCAN":
+		sa, errMsg := parseScanArgs(args[2:], false)
+		if errMsg != "" {
+			return Reply{Type: "error", Value: errMsg}
+		}
+		members, next, err := r.store.SScan(key, sa.cursor, sa.count, sa.match)
+		if err != nil {
+			return errorReply(err)
+		}
+		return scanReply(next, members)
+	}
+	return Reply{Type: "error", Value: "ERR unknown command '" + cmd + "'"}
+}
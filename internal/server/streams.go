@@ -0,0 +1,838 @@
+package server
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AntonRadchenko/mini-redis-go/internal/store"
+)
+
+// errInvalidStreamID — ответ на неверный идентификатор записи потока.
+const errInvalidStreamID = "ERR Invalid stream ID specified as stream command argument"
+
+// функция parseRangeID - граница диапазона (XRANGE, XPENDING, XAUTOCLAIM): "-", "+", "ms-seq", "ms"
+// или с "(" — не включая саму границу. У "ms" без номера начало получает номер 0, конец — наибольший.
+func parseRangeID(s string, isEnd bool) (store.StreamID, string) {
+	exclusive := strings.HasPrefix(s, "(")
+	if exclusive {
+		s = s[1:]
+	}
+	switch {
+	case s == "-" && !exclusive:
+		return store.StreamID{}, ""
+	case s == "+" && !exclusive:
+		return store.MaxStreamID, ""
+	}
+	defaultSeq := uint64(0)
+	if isEnd {
+		defaultSeq = math.MaxUint64
+	}
+	id, err := store.ParseStreamID(s, defaultSeq)
+	if err != nil {
+		return id, errInvalidStreamID
+	}
+	if !exclusive {
+		return id, ""
+	}
+	var ok bool
+	if isEnd {
+		if id, ok = id.Prev(); !ok {
+			return id, "ERR invalid end ID for the interval"
+		}
+	} else if id, ok = id.Next(); !ok {
+		return id, "ERR invalid start ID for the interval"
+	}
+	return id, ""
+}
+
+// функция parseStreamIDs - разбирает список идентификаторов записей (XDEL, XACK).
+func parseStreamIDs(args []string) ([]store.StreamID, string) {
+	ids := make([]store.StreamID, len(args))
+	for i, arg := range args {
+		id, err := store.ParseStreamID(arg, 0)
+		if err != nil {
+			return nil, errInvalidStreamID
+		}
+		ids[i] = id
+	}
+	return ids, ""
+}
+
+// функция parseTrim - разбирает обрезку "MAXLEN|MINID [=|~] порог [LIMIT n]", начиная с args[i].
+// Возвращает правило и индекс первого аргумента после него.
+func parseTrim(args []string, i int) (store.StreamTrim, int, string) {
+	t := store.StreamTrim{ByMinID: strings.ToUpper(args[i]) == "MINID"}
+	i++
+	if i < len(args) && (args[i] == "=" || args[i] == "~") {
+		t.Approx = args[i] == "~"
+		i++
+	}
+	if i >= len(args) {
+		return t, i, "ERR syntax error"
+	}
+	if t.ByMinID {
+		id, err := store.ParseStreamID(args[i], 0)
+		if err != nil {
+			return t, i, errInvalidStreamID
+		}
+		t.MinID = id
+	} else {
+		n, err := strconv.Atoi(args[i])
+		if err != nil {
+			return t, i, errNotInteger
+		}
+		if n < 0 {
+			return t, i, "ERR The MAXLEN argument must be >= 0."
+		}
+		t.MaxLen = n
+	}
+	i++
+	if i+1 < len(args) && strings.ToUpper(args[i]) == "LIMIT" {
+		n, err := strconv.Atoi(args[i+1])
+		if err != nil {
+			return t, i, errNotInteger
+		}
+		if n < 0 {
+			return t, i, "ERR The LIMIT argument must be >= 0."
+		}
+		if !t.Approx {
+			return t, i, "ERR syntax error, LIMIT cannot be used without the special ~ option"
+		}
+		t.Limit = n
+		i += 2
+	}
+	return t, i, ""
+}
+
+// структура xaddArgs — разобранные аргументы XADD.
+type xaddArgs struct {
+	noMkStream bool
+	trim       *store.StreamTrim
+	idIndex    int // где в args стоит идентификатор (его заменяет итоговый при записи в журнал)
+	spec       store.StreamIDSpec
+	fields     []string
+}
+
+// функция parseXAdd - разбирает "XADD key [NOMKSTREAM] [MAXLEN|MINID ...] *|ms-*|id поле значение ...".
+func parseXAdd(args []string) (xaddArgs, string) {
+	var a xaddArgs
+	i := 2
+options:
+	for i < len(args) {
+		switch strings.ToUpper(args[i]) {
+		case "NOMKSTREAM":
+			a.noMkStream = true
+			i++
+		case "MAXLEN", "MINID":
+			if a.trim != nil {
+				return a, "ERR syntax error, MAXLEN and MINID options at the same time are not compatible"
+			}
+			t, next, msg := parseTrim(args, i)
+			if msg != "" {
+				return a, msg
+			}
+			a.trim, i = &t, next
+		default:
+			break options
+		}
+	}
+
+	if n := len(args) - i - 1; n <= 0 || n%2 != 0 {
+		return a, "ERR wrong number of arguments for 'xadd' command"
+	}
+	a.idIndex, a.fields = i, args[i+1:]
+	switch id := args[i]; {
+	case id == "*":
+		a.spec.AutoMs = true
+	case strings.HasSuffix(id, "-*"):
+		ms, err := strconv.ParseUint(strings.TrimSuffix(id, "-*"), 10, 64)
+		if err != nil {
+			return a, errInvalidStreamID
+		}
+		a.spec = store.StreamIDSpec{ID: store.StreamID{Ms: ms}, AutoSeq: true}
+	default:
+		parsed, err := store.ParseStreamID(id, 0)
+		if err != nil {
+			return a, errInvalidStreamID
+		}
+		a.spec.ID = parsed
+	}
+	return a, ""
+}
+
+// структура xreadArgs — разобранные аргументы XREAD и XREADGROUP.
+type xreadArgs struct {
+	group, consumer string
+	count           int
+	block           time.Duration
+	blocking        bool // указан BLOCK
+	noAck           bool
+	keys, ids       []string
+}
+
+// функция parseXRead - разбирает "XREAD [COUNT n] [BLOCK ms] STREAMS key ... id ..."
+// и "XREADGROUP GROUP group consumer [COUNT n] [BLOCK ms] [NOACK] STREAMS key ... id ...".
+func parseXRead(cmd string, args []string) (xreadArgs, string) {
+	var a xreadArgs
+	grouped := cmd == "XREADGROUP"
+	i := 1
+	for ; i < len(args); i++ {
+		opt := strings.ToUpper(args[i])
+		if opt == "STREAMS" {
+			break
+		}
+		switch {
+		case opt == "COUNT" && i+1 < len(args):
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil {
+				return a, errNotInteger
+			}
+			a.count = max(n, 0)
+			i++
+		case opt == "BLOCK" && i+1 < len(args):
+			ms, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return a, "ERR timeout is not an integer or out of range"
+			}
+			if ms < 0 {
+				return a, "ERR timeout is negative"
+			}
+			a.block, a.blocking = time.Duration(ms)*time.Millisecond, true
+			i++
+		case opt == "GROUP" && i+2 < len(args):
+			if !grouped {
+				return a, "ERR The GROUP option is only supported by XREADGROUP. You called XREAD instead."
+			}
+			a.group, a.consumer = args[i+1], args[i+2]
+			i += 2
+		case opt == "NOACK":
+			if !grouped {
+				return a, "ERR The NOACK option is only supported by XREADGROUP. You called XREAD instead."
+			}
+			a.noAck = true
+		default:
+			return a, "ERR syntax error"
+		}
+	}
+	if i == len(args) {
+		return a, "ERR syntax error"
+	}
+	if grouped && a.group == "" {
+		return a, "ERR Missing GROUP option for XREADGROUP"
+	}
+
+	streams := args[i+1:]
+	if len(streams) == 0 || len(streams)%2 != 0 {
+		if grouped {
+			return a, "ERR Unbalanced 'xreadgroup' list of streams: for each stream key an ID or '>' must be specified."
+		}
+		return a, "ERR Unbalanced 'xread' list of streams: for each stream key an ID or '$' must be specified."
+	}
+	a.keys, a.ids = streams[:len(streams)/2], streams[len(streams)/2:]
+	for _, id := range a.ids {
+		switch {
+		case id == "$" && grouped:
+			return a, "ERR The $ ID is meaningless in the context of XREADGROUP: you want to read the history of this consumer by specifying a proper ID, or use the > ID to get new messages. The $ ID would just return an empty result set."
+		case id == ">" && !grouped:
+			return a, "ERR The > ID can be specified only when calling XREADGROUP using the GROUP <group> <consumer> option."
+		case id == "$" || id == ">":
+		default:
+			if _, err := store.ParseStreamID(id, 0); err != nil {
+				return a, errInvalidStreamID
+			}
+		}
+	}
+	return a, ""
+}
+
+// метод command - та же команда без BLOCK: так её выполняет ждущий клиент и так она уходит в журнал.
+func (a xreadArgs) command(cmd string) []string {
+	out := []string{cmd}
+	if a.group != "" {
+		out = append(out, "GROUP", a.group, a.consumer)
+	}
+	if a.count > 0 {
+		out = append(out, "COUNT", strconv.Itoa(a.count))
+	}
+	if a.noAck {
+		out = append(out, "NOACK")
+	}
+	out = append(out, "STREAMS")
+	out = append(out, a.keys...)
+	return append(out, a.ids...)
+}
+
+// функция parseXClaim - разбирает "XCLAIM key group consumer min-idle id ... [IDLE ms] [TIME ms]
+// [RETRYCOUNT n] [FORCE] [JUSTID] [LASTID id]". Идентификаторы идут до первого аргумента, который им не является.
+func parseXClaim(args []string) ([]store.StreamID, store.XClaimArgs, string) {
+	claim := store.XClaimArgs{RetryCount: -1}
+	minIdle, err := strconv.ParseInt(args[4], 10, 64)
+	if err != nil {
+		return nil, claim, "ERR Invalid min-idle-time argument for XCLAIM"
+	}
+	claim.MinIdle = time.Duration(max(minIdle, 0)) * time.Millisecond
+
+	i := 5
+	var ids []store.StreamID
+	for ; i < len(args); i++ {
+		id, err := store.ParseStreamID(args[i], 0)
+		if err != nil {
+			break
+		}
+		ids = append(ids, id)
+	}
+
+	now := time.Now().UnixMilli()
+	for ; i < len(args); i++ {
+		switch opt := strings.ToUpper(args[i]); {
+		case opt == "FORCE":
+			claim.Force = true
+		case opt == "JUSTID":
+			claim.JustID = true
+		case (opt == "IDLE" || opt == "TIME" || opt == "RETRYCOUNT") && i+1 < len(args):
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return nil, claim, "ERR Invalid " + opt + " option argument for XCLAIM"
+			}
+			switch opt {
+			case "IDLE":
+				n = now - n
+				fallthrough
+			case "TIME":
+				if n >= 0 { // время в прошлом до 1970 года — доставка "сейчас", как в Redis
+					claim.DeliveryTime = time.UnixMilli(n)
+				}
+			case "RETRYCOUNT":
+				claim.RetryCount = int(min(max(n, 0), math.MaxInt32))
+			}
+			i++
+		case opt == "LASTID" && i+1 < len(args):
+			id, err := store.ParseStreamID(args[i+1], 0)
+			if err != nil {
+				return nil, claim, errInvalidStreamID
+			}
+			claim.LastID = &id
+			i++
+		default:
+			return nil, claim, "ERR Unrecognized XCLAIM option '" + args[i] + "'"
+		}
+	}
+	return ids, claim, ""
+}
+
+// функция streamErrorReply - ответ на ошибку хранилища в командах над потоками:
+// текст NOGROUP, как в Redis, зависит от команды.
+func streamErrorReply(cmd string, err error) Reply {
+	var noGroup *store.NoGroupError
+	if errors.As(err, &noGroup) {
+		switch cmd {
+		case "XREADGROUP":
+			return Reply{Type: "error", Value: err.Error() + " in XREADGROUP with GROUP option"}
+		case "XGROUP":
+			return Reply{Type: "error", Value: "NOGROUP No such consumer group '" + noGroup.Group + "' for key name '" + noGroup.Key + "'"}
+		}
+	}
+	return errorReply(err)
+}
+
+// функция entryReply - запись потока: [id, [поле, значение, ...]]; у удалённой записи вместо полей nil-массив.
+func entryReply(e store.StreamEntry) Reply {
+	var fields interface{}
+	if e.Fields != nil {
+		fields = e.Fields
+	}
+	return Reply{Type: "array", Value: []Reply{
+		{Type: "bulk", Value: e.ID.String()},
+		{Type: "array", Value: fields},
+	}}
+}
+
+// функция entriesReply - массив записей потока; при justID — только их идентификаторы.
+func entriesReply(entries []store.StreamEntry, justID bool) Reply {
+	if justID {
+		ids := make([]string, len(entries))
+		for i, e := range entries {
+			ids[i] = e.ID.String()
+		}
+		return Reply{Type: "array", Value: ids}
+	}
+	out := make([]Reply, len(entries))
+	for i, e := range entries {
+		out[i] = entryReply(e)
+	}
+	return Reply{Type: "array", Value: out}
+}
+
+// функция streamReadReply - ответ XREAD/XREADGROUP: [[key, [записи]], ...] или nil-массив, если читать нечего.
+func streamReadReply(reads []store.StreamRead) Reply {
+	if len(reads) == 0 {
+		return Reply{Type: "array", Value: nil}
+	}
+	out := make([]Reply, len(reads))
+	for i, rd := range reads {
+		out[i] = Reply{Type: "array", Value: []Reply{{Type: "bulk", Value: rd.Key}, entriesReply(rd.Entries, false)}}
+	}
+	return Reply{Type: "array", Value: out}
+}
+
+// метод streamCommand - команды над потоками (XADD, XRANGE, XREAD, XTRIM, XDEL, XLEN, XSETID)
+// и группами потребителей (XGROUP, XREADGROUP, XACK, XPENDING, XCLAIM, XAUTOCLAIM).
+// Пустой поток, в отличие от других типов, не удаляется: он хранит последний идентификатор и группы.
+func (r *Router) streamCommand(cmd string, args []string) Reply {
+	if msg := checkArity(cmd, args); msg != "" {
+		return Reply{Type: "error", Value: msg}
+	}
+
+	switch cmd {
+	case "XADD":
+		a, msg := parseXAdd(args)
+		if msg != "" {
+			return Reply{Type: "error", Value: msg}
+		}
+		id, ok, err := r.store.XAdd(args[1], a.spec, a.fields, a.trim, a.noMkStream)
+		if err != nil {
+			return errorReply(err)
+		}
+		if !ok {
+			return Reply{Type: "bulk", Value: nil}
+		}
+		return Reply{Type: "bulk", Value: id.String()}
+
+	case "XLEN":
+		n, err := r.store.XLen(args[1])
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "integer", Value: n}
+
+	case "XRANGE", "XREVRANGE":
+		startArg, endArg := args[2], args[3]
+		if cmd == "XREVRANGE" {
+			startArg, endArg = endArg, startArg
+		}
+		start, msg := parseRangeID(startArg, false)
+		if msg != "" {
+			return Reply{Type: "error", Value: msg}
+		}
+		end, msg := parseRangeID(endArg, true)
+		if msg != "" {
+			return Reply{Type: "error", Value: msg}
+		}
+		count := 0
+		if len(args) > 4 {
+			if len(args) != 6 || strings.ToUpper(args[4]) != "COUNT" {
+				return Reply{Type: "error", Value: "ERR syntax error"}
+			}
+			n, err := strconv.Atoi(args[5])
+			if err != nil {
+				return Reply{Type: "error", Value: errNotInteger}
+			}
+			if n <= 0 {
+				return Reply{Type: "array", Value: []Reply{}}
+			}
+			count = n
+		}
+		entries, err := r.store.XRange(args[1], start, end, count, cmd == "XREVRANGE")
+		if err != nil {
+			return errorReply(err)
+		}
+		return entriesReply(entries, false)
+
+	case "XREAD", "XREADGROUP":
+		// BLOCK здесь не ждёт: сюда попадают внутри EXEC, при проигрывании журнала и из ждущего клиента
+		a, msg := parseXRead(cmd, args)
+		if msg != "" {
+			return Reply{Type: "error", Value: msg}
+		}
+		return r.xread(cmd, a)
+
+	case "XTRIM":
+		if opt := strings.ToUpper(args[2]); opt != "MAXLEN" && opt != "MINID" {
+			return Reply{Type: "error", Value: "ERR syntax error"}
+		}
+		t, next, msg := parseTrim(args, 2)
+		if msg == "" && next != len(args) {
+			msg = "ERR syntax error"
+		}
+		if msg != "" {
+			return Reply{Type: "error", Value: msg}
+		}
+		n, err := r.store.XTrim(args[1], t)
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "integer", Value: n}
+
+	case "XDEL":
+		ids, msg := parseStreamIDs(args[2:])
+		if msg != "" {
+			return Reply{Type: "error", Value: msg}
+		}
+		n, err := r.store.XDel(args[1], ids...)
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "integer", Value: n}
+
+	case "XSETID":
+		return r.xsetid(args)
+
+	case "XGROUP":
+		return r.xgroup(args)
+
+	case "XACK":
+		ids, msg := parseStreamIDs(args[3:])
+		if msg != "" {
+			return Reply{Type: "error", Value: msg}
+		}
+		n, err := r.store.XAck(args[1], args[2], ids...)
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "integer", Value: n}
+
+	case "XPENDING":
+		return r.xpending(args)
+
+	case "XCLAIM":
+		ids, claim, msg := parseXClaim(args)
+		if msg != "" {
+			return Reply{Type: "error", Value: msg}
+		}
+		claimed, err := r.store.XClaim(args[1], args[2], args[3], ids, claim)
+		if err != nil {
+			return streamErrorReply(cmd, err)
+		}
+		return entriesReply(claimed, claim.JustID)
+
+	case "XAUTOCLAIM":
+		return r.xautoclaim(args)
+	}
+	return Reply{Type: "error", Value: "ERR unknown command '" + cmd + "'"}
+}
+
+// метод xread - выполняет XREAD/XREADGROUP один раз, без ожидания.
+// "$" в XREAD означает последнюю запись потока на момент вызова (у ждущего клиента она уже подставлена).
+func (r *Router) xread(cmd string, a xreadArgs) Reply {
+	if cmd == "XREAD" {
+		after := make([]store.StreamID, len(a.keys))
+		for i, id := range a.ids {
+			if id == "$" {
+				last, _, err := r.store.StreamLastID(a.keys[i])
+				if err != nil {
+					return errorReply(err)
+				}
+				after[i] = last
+				continue
+			}
+			after[i], _ = store.ParseStreamID(id, 0)
+		}
+		reads, err := r.store.XRead(a.keys, after, a.count)
+		if err != nil {
+			return errorReply(err)
+		}
+		return streamReadReply(reads)
+	}
+
+	read := store.XReadGroupArgs{Group: a.group, Consumer: a.consumer, Keys: a.keys, Count: a.count, NoAck: a.noAck}
+	read.After = make([]*store.StreamID, len(a.keys))
+	for i, id := range a.ids {
+		if id != ">" {
+			parsed, _ := store.ParseStreamID(id, 0)
+			read.After[i] = &parsed
+		}
+	}
+	reads, err := r.store.XReadGroup(read)
+	if err != nil {
+		return streamErrorReply(cmd, err)
+	}
+	return streamReadReply(reads)
+}
+
+// метод xsetid - XSETID key last-id [ENTRIESADDED n] [MAXDELETEDID id].
+func (r *Router) xsetid(args []string) Reply {
+	last, err := store.ParseStreamID(args[2], 0)
+	if err != nil {
+		return Reply{Type: "error", Value: errInvalidStreamID}
+	}
+	var entriesAdded *uint64
+	var maxDeleted *store.StreamID
+	for i := 3; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return Reply{Type: "error", Value: "ERR syntax error"}
+		}
+		switch strings.ToUpper(args[i]) {
+		case "ENTRIESADDED":
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return Reply{Type: "error", Value: errNotInteger}
+			}
+			if n < 0 {
+				return Reply{Type: "error", Value: "ERR entries_added must be positive"}
+			}
+			added := uint64(n)
+			entriesAdded = &added
+		case "MAXDELETEDID":
+			id, err := store.ParseStreamID(args[i+1], 0)
+			if err != nil {
+				return Reply{Type: "error", Value: errInvalidStreamID}
+			}
+			maxDeleted = &id
+		default:
+			return Reply{Type: "error", Value: "ERR syntax error"}
+		}
+	}
+	if err := r.store.XSetID(args[1], last, entriesAdded, maxDeleted); err != nil {
+		return errorReply(err)
+	}
+	return Reply{Type: "simple", Value: "OK"}
+}
+
+// xgroupArity — сколько аргументов ждёт каждая подкоманда XGROUP (у CREATE — минимум).
+var xgroupArity = map[string]int{"CREATE": 5, "SETID": 5, "DESTROY": 4, "CREATECONSUMER": 5, "DELCONSUMER": 5}
+
+// метод xgroup - XGROUP CREATE / SETID / DESTROY / CREATECONSUMER / DELCONSUMER.
+func (r *Router) xgroup(args []string) Reply {
+	sub := strings.ToUpper(args[1])
+	arity, ok := xgroupArity[sub]
+	if !ok {
+		return Reply{Type: "error", Value: "ERR unknown subcommand '" + args[1] + "'. Try XGROUP HELP."}
+	}
+	if len(args) < arity || (sub != "CREATE" && len(args) != arity) {
+		return Reply{Type: "error", Value: "ERR wrong number of arguments for 'xgroup|" + strings.ToLower(sub) + "' command"}
+	}
+	key, group := args[2], args[3]
+
+	switch sub {
+	case "CREATE", "SETID":
+		var id store.StreamID
+		latest := args[4] == "$"
+		if !latest {
+			parsed, err := store.ParseStreamID(args[4], 0)
+			if err != nil {
+				return Reply{Type: "error", Value: errInvalidStreamID}
+			}
+			id = parsed
+		}
+		var err error
+		if sub == "SETID" {
+			err = r.store.XGroupSetID(key, group, id, latest)
+		} else {
+			mkStream := false
+			for _, opt := range args[5:] {
+				if strings.ToUpper(opt) != "MKSTREAM" {
+					return Reply{Type: "error", Value: "ERR syntax error"}
+				}
+				mkStream = true
+			}
+			err = r.store.XGroupCreate(key, group, id, latest, mkStream)
+		}
+		if err != nil {
+			return streamErrorReply("XGROUP", err)
+		}
+		return Reply{Type: "simple", Value: "OK"}
+
+	case "DESTROY":
+		ok, err := r.store.XGroupDestroy(key, group)
+		if err != nil {
+			return errorReply(err)
+		}
+		return boolReply(ok)
+
+	case "CREATECONSUMER":
+		ok, err := r.store.XGroupCreateConsumer(key, group, args[4])
+		if err != nil {
+			return streamErrorReply("XGROUP", err)
+		}
+		return boolReply(ok)
+
+	default: // DELCONSUMER
+		n, err := r.store.XGroupDelConsumer(key, group, args[4])
+		if err != nil {
+			return streamErrorReply("XGROUP", err)
+		}
+		return Reply{Type: "integer", Value: n}
+	}
+}
+
+// метод xpending - XPENDING key group (сводка) или XPENDING key group [IDLE ms] start end count [consumer].
+func (r *Router) xpending(args []string) Reply {
+	key, group := args[1], args[2]
+	if len(args) == 3 {
+		sum, err := r.store.XPendingSummary(key, group)
+		if err != nil {
+			return streamErrorReply("XPENDING", err)
+		}
+		if sum.Count == 0 {
+			return Reply{Type: "array", Value: []Reply{
+				{Type: "integer", Value: 0}, {Type: "bulk", Value: nil}, {Type: "bulk", Value: nil}, {Type: "array", Value: nil},
+			}}
+		}
+		consumers := make([]Reply, len(sum.Consumers))
+		for i, c := range sum.Consumers {
+			consumers[i] = Reply{Type: "array", Value: []string{c.Name, strconv.Itoa(c.Count)}}
+		}
+		return Reply{Type: "array", Value: []Reply{
+			{Type: "integer", Value: sum.Count},
+			{Type: "bulk", Value: sum.Min.String()},
+			{Type: "bulk", Value: sum.Max.String()},
+			{Type: "array", Value: consumers},
+		}}
+	}
+
+	var f store.PendingFilter
+	rest := args[3:]
+	if strings.ToUpper(rest[0]) == "IDLE" && len(rest) > 1 {
+		ms, err := strconv.ParseInt(rest[1], 10, 64)
+		if err != nil {
+			return Reply{Type: "error", Value: errNotInteger}
+		}
+		f.MinIdle = time.Duration(max(ms, 0)) * time.Millisecond
+		rest = rest[2:]
+	}
+	if len(rest) != 3 && len(rest) != 4 {
+		return Reply{Type: "error", Value: "ERR syntax error"}
+	}
+	var msg string
+	if f.Start, msg = parseRangeID(rest[0], false); msg != "" {
+		return Reply{Type: "error", Value: msg}
+	}
+	if f.End, msg = parseRangeID(rest[1], true); msg != "" {
+		return Reply{Type: "error", Value: msg}
+	}
+	count, err := strconv.Atoi(rest[2])
+	if err != nil {
+		return Reply{Type: "error", Value: errNotInteger}
+	}
+	f.Count = max(count, 0)
+	if len(rest) == 4 {
+		f.Consumer = rest[3]
+	}
+
+	pending, err := r.store.XPending(key, group, f)
+	if err != nil {
+		return streamErrorReply("XPENDING", err)
+	}
+	out := make([]Reply, len(pending))
+	for i, p := range pending {
+		out[i] = Reply{Type: "array", Value: []Reply{
+			{Type: "bulk", Value: p.ID.String()},
+			{Type: "bulk", Value: p.Consumer},
+			{Type: "integer", Value: int(p.Idle.Milliseconds())},
+			{Type: "integer", Value: p.DeliveryCount},
+		}}
+	}
+	return Reply{Type: "array", Value: out}
+}
+
+// метод xautoclaim - XAUTOCLAIM key group consumer min-idle start [COUNT n] [JUSTID].
+// Ответ: [курсор, забранные записи, удалённые из потока идентификаторы].
+func (r *Router) xautoclaim(args []string) Reply {
+	minIdle, err := strconv.ParseInt(args[4], 10, 64)
+	if err != nil {
+		return Reply{Type: "error", Value: "ERR Invalid min-idle-time argument for XAUTOCLAIM"}
+	}
+	start, msg := parseRangeID(args[5], false)
+	if msg != "" {
+		return Reply{Type: "error", Value: msg}
+	}
+	count, justID := 100, false
+	for i := 6; i < len(args); i++ {
+		switch opt := strings.ToUpper(args[i]); {
+		case opt == "JUSTID":
+			justID = true
+		case opt == "COUNT" && i+1 < len(args):
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n < 1 || n > math.MaxInt32/10 {
+				return Reply{Type: "error", Value: "ERR COUNT must be > 0"}
+			}
+			count = n
+			i++
+		default:
+			return Reply{Type: "error", Value: "ERR syntax error"}
+		}
+	}
+
+	idle := time.Duration(max(minIdle, 0)) * time.Millisecond
+	next, claimed, deleted, err := r.store.XAutoClaim(args[1], args[2], args[3], idle, start, count, justID)
+	if err != nil {
+		return streamErrorReply("XAUTOCLAIM", err)
+	}
+	deletedIDs := make([]string, len(deleted))
+	for i, id := range deleted {
+		deletedIDs[i] = id.String()
+	}
+	return Reply{Type: "array", Value: []Reply{
+		{Type: "bulk", Value: next.String()},
+		entriesReply(claimed, justID),
+		{Type: "array", Value: deletedIDs},
+	}}
+}
+
+// функция claimedIDs - идентификаторы записей из ответа XCLAIM/XAUTOCLAIM (с JUSTID и без).
+func claimedIDs(reply Reply) []string {
+	switch v := reply.Value.(type) {
+	case []string:
+		return v
+	case []Reply:
+		ids := make([]string, len(v))
+		for i, entry := range v {
+			ids[i] = entry.Value.([]Reply)[0].Value.(string)
+		}
+		return ids
+	}
+	return nil
+}
+
+// метод rewriteClaim - XCLAIM/XAUTOCLAIM для журнала и реплик: явный список забранных записей
+// и записей, которые пришлось убрать из списка ожидающих (их уже нет в потоке), с нулевым min-idle
+// и абсолютным временем доставки, чтобы повтор не зависел от того, сколько записи простаивали.
+func (r *Router) rewriteClaim(cmd string, args []string, reply Reply) []string {
+	key, group, consumer := args[1], args[2], args[3]
+	var ids []string
+	var opts []string
+	at := time.Now()
+
+	if cmd == "XAUTOCLAIM" {
+		parts := reply.Value.([]Reply)
+		ids = append(claimedIDs(parts[1]), parts[2].Value.([]string)...)
+		for _, arg := range args[6:] {
+			if strings.ToUpper(arg) == "JUSTID" {
+				opts = append(opts, "JUSTID")
+			}
+		}
+	} else {
+		requested, claim, _ := parseXClaim(args)
+		ids = claimedIDs(reply)
+		for _, id := range requested {
+			if entries, _ := r.store.XRange(key, id, id, 1, false); len(entries) == 0 {
+				ids = append(ids, id.String())
+			}
+		}
+		if !claim.DeliveryTime.IsZero() && claim.DeliveryTime.Before(at) {
+			at = claim.DeliveryTime
+		}
+		if claim.RetryCount >= 0 {
+			opts = append(opts, "RETRYCOUNT", strconv.Itoa(claim.RetryCount))
+		}
+		if claim.Force {
+			opts = append(opts, "FORCE")
+		}
+		if claim.JustID {
+			opts = append(opts, "JUSTID")
+		}
+		if claim.LastID != nil {
+			if len(ids) == 0 { // ничего не забрали — изменилась только позиция группы
+				return []string{"XGROUP", "SETID", key, group, claim.LastID.String()}
+			}
+			opts = append(opts, "LASTID", claim.LastID.String())
+		}
+	}
+
+	out := append([]string{"XCLAIM", key, group, consumer, "0"}, ids...)
+	out = append(out, "TIME", strconv.FormatInt(at.UnixMilli(), 10))
+	return append(out, opts...)
+}
@@ -0,0 +1,258 @@
+package server
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AntonRadchenko/mini-redis-go/internal/store"
+)
+
+// метод stringCommand - команды над строками: SET и его варианты, счётчики (INCR, INCRBYFLOAT и др.),
+// работа с частями строки (APPEND, STRLEN, GETRANGE, SETRANGE), GETDEL, GETEX, MSET и MSETNX.
+// Каждая команда — один вызов хранилища под блокировкой ключа, поэтому чтение и запись не разделяются.
+func (r *Router) stringCommand(cmd string, args []string) Reply {
+	if msg := checkArity(cmd, args); msg != "" {
+		return Reply{Type: "error", Value: msg}
+	}
+
+	switch cmd {
+	case "SET", "SETNX", "SETEX", "PSETEX", "GETSET":
+		return r.setString(cmd, args)
+
+	case "INCR", "DECR", "INCRBY", "DECRBY":
+		delta := int64(1)
+		if cmd == "INCRBY" || cmd == "DECRBY" {
+			n, err := strconv.ParseInt(args[2], 10, 64)
+			if err != nil {
+				return Reply{Type: "error", Value: errNotInteger}
+			}
+			delta = n
+		}
+		if cmd == "DECR" || cmd == "DECRBY" {
+			if delta == math.MinInt64 {
+				return Reply{Type: "error", Value: "ERR decrement would overflow"}
+			}
+			delta = -delta
+		}
+		n, err := r.store.IncrBy(args[1], delta)
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "integer", Value: int(n)}
+
+	case "INCRBYFLOAT":
+		res, err := r.store.IncrByFloat(args[1], args[2])
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "bulk", Value: res}
+
+	case "APPEND":
+		n, err := r.store.Append(args[1], args[2])
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "integer", Value: n}
+
+	case "STRLEN":
+		n, err := r.store.StrLen(args[1])
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "integer", Value: n}
+
+	case "GETRANGE":
+		start, err1 := strconv.ParseInt(args[2], 10, 64)
+		end, err2 := strconv.ParseInt(args[3], 10, 64)
+		if err1 != nil || err2 != nil {
+			return Reply{Type: "error", Value: errNotInteger}
+		}
+		res, err := r.store.GetRange(args[1], start, end)
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "bulk", Value: res}
+
+	case "SETRANGE":
+		offset, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			return Reply{Type: "error", Value: errNotInteger}
+		}
+		if offset < 0 {
+			return Reply{Type: "error", Value: "ERR offset is out of range"}
+		}
+		n, err := r.store.SetRange(args[1], offset, args[3])
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "integer", Value: n}
+
+	case "GETDEL":
+		res, ok, err := r.store.GetDel(args[1])
+		return getReply(res, ok, err)
+
+	case "GETEX":
+		at, persist, msg := parseGetExOptions(args[2:])
+		if msg != "" {
+			return Reply{Type: "error", Value: msg}
+		}
+		res, ok, err := r.store.GetEx(args[1], at, persist)
+		return getReply(res, ok, err)
+
+	case "MSET", "MSETNX":
+		if len(args)%2 == 0 {
+			return Reply{Type: "error", Value: "ERR wrong number of arguments for '" + strings.ToLower(cmd) + "' command"}
+		}
+		ok := r.store.MSet(cmd == "MSETNX", args[1:]...)
+		if cmd == "MSETNX" {
+			return boolReply(ok)
+		}
+		return Reply{Type: "simple", Value: "OK"}
+	}
+	return Reply{Type: "error", Value: "ERR unknown command '" + cmd + "'"}
+}
+
+// функция getReply - ответ команды, возвращающей строковое значение ключа (GETDEL, GETEX): nil, если ключа нет.
+func getReply(val string, ok bool, err error) Reply {
+	switch {
+	case err != nil:
+		return errorReply(err)
+	case !ok:
+		return Reply{Type: "bulk", Value: nil}
+	}
+	return Reply{Type: "bulk", Value: val}
+}
+
+// функция parseGetExOptions - разбирает опции GETEX: EX/PX/EXAT/PXAT <время> или PERSIST (не больше одной).
+// Возвращает новый момент истечения (нулевой — не менять), флаг PERSIST или текст ошибки.
+func parseGetExOptions(args []string) (time.Time, bool, string) {
+	if len(args) == 0 {
+		return time.Time{}, false, ""
+	}
+	opt := strings.ToUpper(args[0])
+	switch {
+	case opt == "PERSIST" && len(args) == 1:
+		return time.Time{}, true, ""
+	case (opt == "EX" || opt == "PX" || opt == "EXAT" || opt == "PXAT") && len(args) == 2:
+		unit, base := time.Second, time.Now()
+		if opt == "PX" || opt == "PXAT" {
+			unit = time.Millisecond
+		}
+		if opt == "EXAT" || opt == "PXAT" {
+			base = time.Unix(0, 0)
+		}
+		at, msg := expireAfter(args[1], unit, base, "GETEX")
+		return at, false, msg
+	}
+	return time.Time{}, false, "ERR syntax error"
+}
+
+// метод setString - SET со всеми опциями и старые SETNX, SETEX, PSETEX, GETSET.
+// Все они сводятся к одному вызову store.SetWith, поэтому значение и TTL записываются атомарно.
+func (r *Router) setString(cmd string, args []string) Reply {
+	key, value := args[1], args[2]
+	var opts store.SetOptions
+
+	switch cmd {
+	case "SET":
+		var msg string
+		if opts, msg = parseSetOptions(args[3:]); msg != "" {
+			return Reply{Type: "error", Value: msg}
+		}
+	case "SETNX":
+		opts.NX = true
+	case "GETSET":
+		opts.Get = true
+	case "SETEX", "PSETEX":
+		value = args[3]
+		unit := time.Second
+		if cmd == "PSETEX" {
+			unit = time.Millisecond
+		}
+		at, msg := expireAfter(args[2], unit, time.Now(), cmd)
+		if msg != "" {
+			return Reply{Type: "error", Value: msg}
+		}
+		opts.ExpireAt = at
+	}
+
+	old, hadOld, ok, err := r.store.SetWith(key, value, opts)
+	switch {
+	case err != nil:
+		return errorReply(err)
+	case cmd == "SETNX":
+		return boolReply(ok)
+	case opts.Get && !hadOld:
+		return Reply{Type: "bulk", Value: nil}
+	case opts.Get:
+		return Reply{Type: "bulk", Value: old}
+	case !ok:
+		return Reply{Type: "bulk", Value: nil} // условие NX/XX не выполнено
+	}
+	return Reply{Type: "simple", Value: "OK"}
+}
+
+// функция parseSetOptions - разбирает опции SET: EX/PX/EXAT/PXAT <время>, NX, XX, KEEPTTL и GET.
+// Возвращает текст ошибки в формате Redis, если опции противоречат друг другу.
+func parseSetOptions(args []string) (store.SetOptions, string) {
+	var opts store.SetOptions
+	var expiry bool
+	now := time.Now()
+	for i := 0; i < len(args); i++ {
+		switch opt := strings.ToUpper(args[i]); opt {
+		case "NX":
+			opts.NX = true
+		case "XX":
+			opts.XX = true
+		case "GET":
+			opts.Get = true
+		case "KEEPTTL":
+			if expiry {
+				return opts, "ERR syntax error"
+			}
+			opts.KeepTTL = true
+		case "EX", "PX", "EXAT", "PXAT":
+			if expiry || opts.KeepTTL || i+1 >= len(args) {
+				return opts, "ERR syntax error"
+			}
+			expiry = true
+			i++
+			unit := time.Second
+			if opt == "PX" || opt == "PXAT" {
+				unit = time.Millisecond
+			}
+			var at time.Time
+			var msg string
+			if opt == "EX" || opt == "PX" {
+				at, msg = expireAfter(args[i], unit, now, "SET")
+			} else {
+				at, msg = expireAfter(args[i], unit, time.Unix(0, 0), "SET")
+			}
+			if msg != "" {
+				return opts, msg
+			}
+			opts.ExpireAt = at
+		default:
+			return opts, "ERR syntax error"
+		}
+	}
+	if opts.NX && opts.XX {
+		return opts, "ERR syntax error"
+	}
+	return opts, ""
+}
+
+// функция expireAfter - переводит положительное целое число единиц времени, отсчитанное от base,
+// в абсолютный момент истечения (см. expireMoment). Ноль и отрицательное число — ошибка "invalid expire time".
+func expireAfter(arg string, unit time.Duration, base time.Time, cmd string) (time.Time, string) {
+	n, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return time.Time{}, errNotInteger
+	}
+	if n <= 0 {
+		return time.Time{}, "ERR invalid expire time in '" + strings.ToLower(cmd) + "' command"
+	}
+	return expireMoment(n, unit, base, cmd)
+}
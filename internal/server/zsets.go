@@ -0,0 +1,442 @@
+package server
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/AntonRadchenko/mini-redis-go/internal/store"
+)
+
+// ответы на неверные счета и границы диапазонов; тексты совпадают с ответами Redis
+const (
+	errNotFloat     = "ERR value is not a valid float"
+	errMinMaxFloat  = "ERR min or max is not a float"
+	errMinMaxString = "ERR min or max not valid string range item"
+)
+
+// функция parseScore - разбирает счёт так же строго, как Redis: без пробелов, без NaN,
+// без переполнения; "inf", "+inf" и "-inf" допустимы.
+func parseScore(s string) (float64, bool) {
+	if s == "" || strings.TrimSpace(s) != s {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, false
+	}
+	return f, true
+}
+
+// функция formatScore - печатает число так же, как Redis (d2string): целые — без дробной части,
+// остальные — кратчайшим представлением, которое читается обратно без потерь,
+// в обычной записи для умеренных порядков и в экспоненциальной ("1.5e-7", "3e+19") для остальных.
+func formatScore(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "nan"
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case f == 0:
+		if math.Signbit(f) {
+			return "-0"
+		}
+		return "0"
+	case f == math.Trunc(f) && math.Abs(f) <= math.MaxInt64/2:
+		return strconv.FormatInt(int64(f), 10)
+	}
+
+	// кратчайшие значащие цифры и порядок: f = ±digits × 10^k
+	neg := f < 0
+	mant, exp, _ := strings.Cut(strconv.FormatFloat(math.Abs(f), 'e', -1, 64), "e")
+	digits := strings.Replace(mant, ".", "", 1)
+	e10, _ := strconv.Atoi(exp)
+	k := e10 - (len(digits) - 1)
+	absExp := max(e10, -e10)
+
+	var b strings.Builder
+	if neg {
+		b.WriteByte('-')
+	}
+	switch {
+	case k >= 0 && absExp < len(digits)+7: // целое с нулями на конце
+		b.WriteString(digits)
+		b.WriteString(strings.Repeat("0", k))
+	case k < 0 && (k > -7 || absExp < 4): // десятичная дробь без экспоненты
+		if point := len(digits) + k; point <= 0 {
+			b.WriteString("0.")
+			b.WriteString(strings.Repeat("0", -point))
+			b.WriteString(digits)
+		} else {
+			b.WriteString(digits[:point])
+			b.WriteByte('.')
+			b.WriteString(digits[point:])
+		}
+	default: // экспоненциальная запись
+		b.WriteByte(digits[0])
+		if len(digits) > 1 {
+			b.WriteByte('.')
+			b.WriteString(digits[1:])
+		}
+		b.WriteByte('e')
+		if e10 < 0 {
+			b.WriteByte('-')
+		} else {
+			b.WriteByte('+')
+		}
+		b.WriteString(strconv.Itoa(absExp))
+	}
+	return b.String()
+}
+
+// функция parseScoreRange - разбирает границы ZRANGEBYSCORE/ZCOUNT: число, "(число" (исключая) или ±inf.
+func parseScoreRange(min, max string) (store.ScoreRange, bool) {
+	var r store.ScoreRange
+	var ok1, ok2 bool
+	r.Min, r.MinEx, ok1 = parseScoreBound(min)
+	r.Max, r.MaxEx, ok2 = parseScoreBound(max)
+	return r, ok1 && ok2
+}
+
+// функция parseScoreBound - одна граница диапазона счетов.
+func parseScoreBound(s string) (float64, bool, bool) {
+	exclusive := strings.HasPrefix(s, "(")
+	if exclusive {
+		s = s[1:]
+	}
+	f, ok := parseScore(s)
+	return f, exclusive, ok
+}
+
+// функция parseLexRange - разбирает границы ZRANGEBYLEX/ZLEXCOUNT: "[a", "(a", "-" или "+".
+func parseLexRange(min, max string) (store.LexRange, bool) {
+	lo, ok1 := parseLexBound(min)
+	hi, ok2 := parseLexBound(max)
+	return store.LexRange{Min: lo, Max: hi}, ok1 && ok2
+}
+
+// функция parseLexBound - одна граница лексикографического диапазона.
+func parseLexBound(s string) (store.LexBound, bool) {
+	switch {
+	case s == "-":
+		return store.LexBound{Inf: -1}, true
+	case s == "+":
+		return store.LexBound{Inf: 1}, true
+	case strings.HasPrefix(s, "["):
+		return store.LexBound{Value: s[1:]}, true
+	case strings.HasPrefix(s, "("):
+		return store.LexBound{Value: s[1:], Exclusive: true}, true
+	}
+	return store.LexBound{}, false
+}
+
+// функция zmembersReply - элементы упорядоченного множества плоским массивом,
+// со счетами после каждого элемента, если withScores.
+func zmembersReply(items []store.ZMember, withScores bool) Reply {
+	out := make([]string, 0, len(items)*2)
+	for _, it := range items {
+		out = append(out, it.Member)
+		if withScores {
+			out = append(out, formatScore(it.Score))
+		}
+	}
+	return Reply{Type: "array", Value: out}
+}
+
+// метод zsetCommand - команды над упорядоченными множествами (ZADD, ZINCRBY, ZREM, ZRANGE и его варианты,
+// ZRANK, ZSCORE, ZCOUNT, ZPOPMIN/ZPOPMAX, ZREMRANGEBY*, ZSCAN и др.).
+// Команда к ключу другого типа получает WRONGTYPE, пустое множество удаляется вместе с ключом.
+func (r *Router) zsetCommand(cmd string, args []string) Reply {
+	if msg := checkArity(cmd, args); msg != "" {
+		return Reply{Type: "error", Value: msg}
+	}
+	key := args[1]
+
+	switch cmd {
+	case "ZADD":
+		return r.zadd(args)
+
+	case "ZINCRBY":
+		incr, ok := parseScore(args[2])
+		if !ok {
+			return Reply{Type: "error", Value: errNotFloat}
+		}
+		score, _, err := r.store.ZIncrBy(key, store.ZAddFlags{}, args[3], incr)
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "bulk", Value: formatScore(score)}
+
+	case "ZREM":
+		n, err := r.store.ZRem(key, args[2:]...)
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "integer", Value: n}
+
+	case "ZCARD":
+		n, err := r.store.ZCard(key)
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "integer", Value: n}
+
+	case "ZSCORE":
+		score, ok, err := r.store.ZScore(key, args[2])
+		if err != nil {
+			return errorReply(err)
+		}
+		if !ok {
+			return Reply{Type: "bulk", Value: nil}
+		}
+		return Reply{Type: "bulk", Value: formatScore(score)}
+
+	case "ZMSCORE":
+		scores, err := r.store.ZMScore(key, args[2:]...)
+		if err != nil {
+			return errorReply(err)
+		}
+		out := make([]Reply, len(scores))
+		for i, score := range scores {
+			if score == nil {
+				out[i] = Reply{Type: "bulk", Value: nil}
+			} else {
+				out[i] = Reply{Type: "bulk", Value: formatScore(*score)}
+			}
+		}
+		return Reply{Type: "array", Value: out}
+
+	case "ZRANK", "ZREVRANK":
+		rank, ok, err := r.store.ZRank(key, args[2], cmd == "ZREVRANK")
+		if err != nil {
+			return errorReply(err)
+		}
+		if !ok {
+			return Reply{Type: "bulk", Value: nil}
+		}
+		return Reply{Type: "integer", Value: rank}
+
+	case "ZCOUNT", "ZREMRANGEBYSCORE":
+		rng, ok := parseScoreRange(args[2], args[3])
+		if !ok {
+			return Reply{Type: "error", Value: errMinMaxFloat}
+		}
+		count := r.store.ZCount
+		if cmd == "ZREMRANGEBYSCORE" {
+			count = r.store.ZRemRangeByScore
+		}
+		n, err := count(key, rng)
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "integer", Value: n}
+
+	case "ZLEXCOUNT", "ZREMRANGEBYLEX":
+		rng, ok := parseLexRange(args[2], args[3])
+		if !ok {
+			return Reply{Type: "error", Value: errMinMaxString}
+		}
+		count := r.store.ZLexCount
+		if cmd == "ZREMRANGEBYLEX" {
+			count = r.store.ZRemRangeByLex
+		}
+		n, err := count(key, rng)
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "integer", Value: n}
+
+	case "ZREMRANGEBYRANK":
+		start, err1 := strconv.Atoi(args[2])
+		stop, err2 := strconv.Atoi(args[3])
+		if err1 != nil || err2 != nil {
+			return Reply{Type: "error", Value: errNotInteger}
+		}
+		n, err := r.store.ZRemRangeByRank(key, start, stop)
+		if err != nil {
+			return errorReply(err)
+		}
+		return Reply{Type: "integer", Value: n}
+
+	case "ZPOPMIN", "ZPOPMAX":
+		if len(args) > 3 {
+			return Reply{Type: "error", Value: "ERR syntax error"}
+		}
+		count := 1
+		if len(args) == 3 {
+			n, err := strconv.Atoi(args[2])
+			if err != nil || n < 0 {
+				return Reply{Type: "error", Value: "ERR value is out of range, must be positive"}
+			}
+			count = n
+		}
+		items, err := r.store.ZPop(key, count, cmd == "ZPOPMAX")
+		if err != nil {
+			return errorReply(err)
+		}
+		return zmembersReply(items, true)
+
+	case "ZRANGE", "ZREVRANGE", "ZRANGEBYSCORE", "ZREVRANGEBYSCORE", "ZRANGEBYLEX", "ZREVRANGEBYLEX":
+		return r.zrange(cmd, args)
+
+	case "ZSCAN":
+		sa, errMsg := parseScanArgs(args[2:], false)
+		if errMsg != "" {
+			return Reply{Type: "error", Value: errMsg}
+		}
+		items, next, err := r.store.ZScan(key, sa.cursor, sa.count, sa.match)
+		if err != nil {
+			return errorReply(err)
+		}
+		return scanReply(next, zmembersReply(items, true).Value.([]string))
+	}
+	return Reply{Type: "error", Value: "ERR unknown command '" + cmd + "'"}
+}
+
+// метод zadd - ZADD key [NX|XX] [GT|LT] [CH] [INCR] score member [score member ...].
+// Отвечает числом добавленных элементов (с CH — ещё и изменённых), а с INCR — новым счётом
+// или nil, если флаги не позволили изменение.
+func (r *Router) zadd(args []string) Reply {
+	var flags store.ZAddFlags
+	var ch, incr bool
+	i := 2
+options:
+	for ; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			flags.NX = true
+		case "XX":
+			flags.XX = true
+		case "GT":
+			flags.GT = true
+		case "LT":
+			flags.LT = true
+		case "CH":
+			ch = true
+		case "INCR":
+			incr = true
+		default:
+			break options
+		}
+	}
+
+	pairs := args[i:]
+	if len(pairs) == 0 || len(pairs)%2 != 0 {
+		return Reply{Type: "error", Value: "ERR syntax error"}
+	}
+	if flags.NX && flags.XX {
+		return Reply{Type: "error", Value: "ERR XX and NX options at the same time are not compatible"}
+	}
+	if (flags.GT && flags.LT) || (flags.NX && (flags.GT || flags.LT)) {
+		return Reply{Type: "error", Value: "ERR GT, LT, and/or NX options at the same time are not compatible"}
+	}
+	if incr && len(pairs) > 2 {
+		return Reply{Type: "error", Value: "ERR INCR option supports a single increment-element pair"}
+	}
+
+	// все счета проверяются до изменений: ZADD применяется целиком или не применяется вовсе
+	items := make([]store.ZMember, 0, len(pairs)/2)
+	for j := 0; j < len(pairs); j += 2 {
+		score, ok := parseScore(pairs[j])
+		if !ok {
+			return Reply{Type: "error", Value: errNotFloat}
+		}
+		items = append(items, store.ZMember{Member: pairs[j+1], Score: score})
+	}
+
+	if incr {
+		score, ok, err := r.store.ZIncrBy(args[1], flags, items[0].Member, items[0].Score)
+		if err != nil {
+			return errorReply(err)
+		}
+		if !ok {
+			return Reply{Type: "bulk", Value: nil}
+		}
+		return Reply{Type: "bulk", Value: formatScore(score)}
+	}
+	added, changed, err := r.store.ZAdd(args[1], flags, items)
+	if err != nil {
+		return errorReply(err)
+	}
+	if ch {
+		return Reply{Type: "integer", Value: added + changed}
+	}
+	return Reply{Type: "integer", Value: added}
+}
+
+// метод zrange - ZRANGE key start stop [BYSCORE|BYLEX] [REV] [LIMIT offset count] [WITHSCORES]
+// и старые формы ZREVRANGE, ZRANGEBYSCORE, ZREVRANGEBYSCORE, ZRANGEBYLEX, ZREVRANGEBYLEX.
+// В обратном порядке границы счетов и строк передаются как max min.
+func (r *Router) zrange(cmd string, args []string) Reply {
+	byScore := cmd == "ZRANGEBYSCORE" || cmd == "ZREVRANGEBYSCORE"
+	byLex := cmd == "ZRANGEBYLEX" || cmd == "ZREVRANGEBYLEX"
+	rev := strings.HasPrefix(cmd, "ZREV")
+	withScores, limited := false, false
+	offset, count := 0, -1
+
+	for i := 4; i < len(args); i++ {
+		switch opt := strings.ToUpper(args[i]); {
+		case opt == "WITHSCORES":
+			withScores = true
+		case opt == "LIMIT" && i+2 < len(args):
+			o, err1 := strconv.Atoi(args[i+1])
+			c, err2 := strconv.Atoi(args[i+2])
+			if err1 != nil || err2 != nil {
+				return Reply{Type: "error", Value: errNotInteger}
+			}
+			offset, count, limited = o, c, true
+			i += 2
+		case cmd == "ZRANGE" && opt == "BYSCORE":
+			byScore = true
+		case cmd == "ZRANGE" && opt == "BYLEX":
+			byLex = true
+		case cmd == "ZRANGE" && opt == "REV":
+			rev = true
+		default:
+			return Reply{Type: "error", Value: "ERR syntax error"}
+		}
+	}
+	if byScore && byLex {
+		return Reply{Type: "error", Value: "ERR syntax error"}
+	}
+	if limited && !byScore && !byLex {
+		return Reply{Type: "error", Value: "ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX"}
+	}
+	if withScores && byLex {
+		return Reply{Type: "error", Value: "ERR syntax error, WITHSCORES not supported in combination with BYLEX"}
+	}
+
+	min, max := args[2], args[3]
+	if rev {
+		min, max = max, min
+	}
+	var items []store.ZMember
+	var err error
+	switch {
+	case byScore:
+		rng, ok := parseScoreRange(min, max)
+		if !ok {
+			return Reply{Type: "error", Value: errMinMaxFloat}
+		}
+		items, err = r.store.ZRangeByScore(args[1], rng, rev, offset, count)
+	case byLex:
+		rng, ok := parseLexRange(min, max)
+		if !ok {
+			return Reply{Type: "error", Value: errMinMaxString}
+		}
+		items, err = r.store.ZRangeByLex(args[1], rng, rev, offset, count)
+	default:
+		start, err1 := strconv.Atoi(args[2])
+		stop, err2 := strconv.Atoi(args[3])
+		if err1 != nil || err2 != nil {
+			return Reply{Type: "error", Value: errNotInteger}
+		}
+		items, err = r.store.ZRange(args[1], start, stop, rev)
+	}
+	if err != nil {
+		return errorReply(err)
+	}
+	return zmembersReply(items, withScores)
+}
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"math"
+	"testing"
+)
+
+// проверяет, что счета печатаются так же, как в Redis
+func TestFormatScore(t *testing.T) {
+	tenth, fifth := 0.1, 0.2
+	tests := []struct {
+		score float64
+		want  string
+	}{
+		{1, "1"},
+		{-42, "-42"},
+		{1.5, "1.5"},
+		{tenth + fifth, "0.30000000000000004"},
+		{0.001, "0.001"},
+		{1.5e-7, "1.5e-7"},
+		{3e19, "3e+19"},
+		{1.2345e20, "1.2345e+20"},
+		{1.2345678901234567e19, "12345678901234567000"},
+		{1e100, "1e+100"},
+		{-2.5e-300, "-2.5e-300"},
+		{math.Inf(1), "inf"},
+		{math.Inf(-1), "-inf"},
+		{math.Copysign(0, -1), "-0"},
+	}
+	for _, tt := range tests {
+		if got := formatScore(tt.score); got != tt.want {
+			t.Errorf("formatScore(%v) = %q, want %q", tt.score, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,392 @@
+package store
+
+import (
+	"math"
+	"math/bits"
+)
+
+// Битовые операции над строками (SETBIT, GETBIT, BITCOUNT, BITPOS, BITOP, BITFIELD).
+//
+// Битовая карта — обычное строковое значение: бит с номером 0 — старший бит первого байта.
+// Запись за концом строки дополняет её нулевыми байтами, чтение за концом видит нули.
+
+// MaxBitOffset — наибольший номер бита: строка не может быть длиннее MaxStringSize байт.
+const MaxBitOffset = MaxStringSize*8 - 1
+
+// функция getBit - бит с номером offset (за концом строки — 0).
+func getBit(str string, offset uint64) int {
+	if offset>>3 >= uint64(len(str)) {
+		return 0
+	}
+	return int(str[offset>>3]>>(7-offset&7)) & 1
+}
+
+// функция growBytes - байты строки, дополненные нулями до длины n (если строка короче).
+func growBytes(str string, n uint64) []byte {
+	buf := make([]byte, max(uint64(len(str)), n))
+	copy(buf, str)
+	return buf
+}
+
+// метод SetBit - выставляет бит offset в значение bit (0 или 1) и возвращает прежнее значение бита.
+// Отсутствующий ключ создаётся, строка при необходимости дополняется нулевыми байтами.
+func (s *Store) SetBit(key string, offset uint64, bit int) (int, error) {
+	defer s.lock(key)()
+	str, _, err := s.stringFor(key)
+	if err != nil {
+		return 0, err
+	}
+	old := getBit(str, offset)
+	buf := growBytes(str, offset>>3+1)
+	mask := byte(1) << (7 - offset&7)
+	if bit == 1 {
+		buf[offset>>3] |= mask
+	} else {
+		buf[offset>>3] &^= mask
+	}
+	s.put(key, string(buf))
+	s.touch(key)
+	return old, nil
+}
+
+// метод GetBit - значение бита offset (0, если ключа нет или строка короче).
+func (s *Store) GetBit(key string, offset uint64) (int, error) {
+	str, _, err := s.GetString(key)
+	if err != nil {
+		return 0, err
+	}
+	return getBit(str, offset), nil
+}
+
+// структура BitRange — диапазон BITCOUNT/BITPOS: Start и End включительно, отрицательные отсчитываются от конца.
+// Bit — индексы в битах, а не в байтах; HasEnd — конец указан явно (иначе — до конца строки).
+type BitRange struct {
+	Start, End int64
+	Bit        bool
+	HasEnd     bool
+}
+
+// метод bounds - номера первого и последнего бита диапазона в строке длины n байт, как их считает Redis:
+// отрицательные индексы отсчитываются от конца, выход за границы обрезается. false — диапазон пуст.
+func (r BitRange) bounds(n int) (first, last uint64, ok bool) {
+	total := int64(n)
+	if r.Bit {
+		total *= 8
+	}
+	start, end := r.Start, total-1
+	if r.HasEnd {
+		end = r.End
+	}
+	if start < 0 {
+		start += total
+	}
+	if end < 0 {
+		end += total
+	}
+	start, end = max(start, 0), min(max(end, 0), total-1)
+	if total == 0 || start > end {
+		return 0, 0, false
+	}
+	if r.Bit {
+		return uint64(start), uint64(end), true
+	}
+	return uint64(start) * 8, uint64(end)*8 + 7, true
+}
+
+// функция countBits - число единичных битов строки с бита first по бит last включительно.
+func countBits(str string, first, last uint64) int {
+	n := 0
+	for i := first >> 3; i <= last>>3; i++ {
+		b := str[i]
+		if i == first>>3 {
+			b &= 0xff >> (first & 7)
+		}
+		if i == last>>3 {
+			b &= 0xff << (7 - last&7)
+		}
+		n += bits.OnesCount8(b)
+	}
+	return n
+}
+
+// метод BitCount - число единичных битов строки (во всей строке или в диапазоне r).
+func (s *Store) BitCount(key string, r BitRange) (int, error) {
+	str, _, err := s.GetString(key)
+	if err != nil {
+		return 0, err
+	}
+	first, last, ok := r.bounds(len(str))
+	if !ok {
+		return 0, nil
+	}
+	return countBits(str, first, last), nil
+}
+
+// метод BitPos - номер первого бита, равного bit, в диапазоне r (номер считается от начала строки).
+// Как в Redis: если ищем 0 и конец диапазона не указан, строка считается дополненной нулями справа,
+// поэтому строка из одних единиц даёт номер бита сразу за диапазоном; иначе ненайденный бит — это -1.
+func (s *Store) BitPos(key string, bit int, r BitRange) (int64, error) {
+	str, exists, err := s.GetString(key)
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		if bit == 1 {
+			return -1, nil
+		}
+		return 0, nil
+	}
+	first, last, ok := r.bounds(len(str))
+	if !ok {
+		return -1, nil
+	}
+	skip := byte(0xff) // байт, в котором искомого бита точно нет
+	if bit == 1 {
+		skip = 0
+	}
+	for i := first; i <= last; {
+		// целые байты внутри диапазона без искомого бита пропускаем сразу
+		if i&7 == 0 && i+7 <= last && str[i>>3] == skip {
+			i += 8
+			continue
+		}
+		if getBit(str, i) == bit {
+			return int64(i), nil
+		}
+		i++
+	}
+	if bit == 0 && !r.HasEnd {
+		return int64(last + 1), nil
+	}
+	return -1, nil
+}
+
+// BitOp — логическая операция BITOP.
+type BitOp int
+
+const (
+	BitAnd BitOp = iota
+	BitOr
+	BitXor
+	BitNot
+)
+
+// метод BitOp - записывает в dest результат побайтовой операции op над строками srcs
+// (для BitNot — ровно одна строка) и возвращает длину результата. Отсутствующий ключ — пустая строка,
+// более короткие строки дополняются нулями. Пустой результат удаляет dest.
+func (s *Store) BitOp(op BitOp, dest string, srcs ...string) (int, error) {
+	defer s.lock(append([]string{dest}, srcs...)...)()
+	vals := make([]string, len(srcs))
+	n := 0
+	for i, key := range srcs {
+		str, _, err := s.stringFor(key)
+		if err != nil {
+			return 0, err
+		}
+		vals[i] = str
+		n = max(n, len(str))
+	}
+
+	buf := make([]byte, n)
+	for i := range buf {
+		var b byte
+		for j, str := range vals {
+			var c byte
+			if i < len(str) {
+				c = str[i]
+			}
+			switch {
+			case j == 0:
+				b = c
+			case op == BitAnd:
+				b &= c
+			case op == BitOr:
+				b |= c
+			case op == BitXor:
+				b ^= c
+			}
+		}
+		if op == BitNot {
+			b = ^b
+		}
+		buf[i] = b
+	}
+
+	s.expireIfNeeded(dest)
+	_, existed := s.get(dest)
+	if n == 0 {
+		if existed {
+			s.remove(dest)
+			s.touch(dest)
+		}
+		return 0, nil
+	}
+	s.remove(dest)
+	s.put(dest, string(buf))
+	s.touch(dest)
+	return n, nil
+}
+
+// BitFieldOverflow — поведение BITFIELD SET/INCRBY при выходе значения за пределы поля.
+type BitFieldOverflow int
+
+const (
+	OverflowWrap BitFieldOverflow = iota // по модулю, как целые в Go (по умолчанию)
+	OverflowSat                          // до ближайшей границы
+	OverflowFail                         // не изменять поле и ответить nil
+)
+
+// BitFieldOpKind — подкоманда BITFIELD.
+type BitFieldOpKind int
+
+const (
+	BitFieldGet BitFieldOpKind = iota
+	BitFieldSet
+	BitFieldIncrBy
+)
+
+// структура BitFieldOp — одна подкоманда BITFIELD: поле шириной Bits бит (знаковое, если Signed)
+// с бита Offset; Value — новое значение для SET или приращение для INCRBY.
+type BitFieldOp struct {
+	Kind     BitFieldOpKind
+	Signed   bool
+	Bits     uint
+	Offset   uint64
+	Value    int64
+	Overflow BitFieldOverflow
+}
+
+// метод BitField - выполняет подкоманды BITFIELD по порядку под одной блокировкой ключа.
+// Ответ на каждую подкоманду: GET — значение поля, SET — прежнее значение, INCRBY — новое;
+// nil — SET/INCRBY не выполнена из-за переполнения при OverflowFail.
+// Если есть подкоманды записи, строка заранее дополняется нулями до самого дальнего поля, как в Redis.
+func (s *Store) BitField(key string, ops []BitFieldOp) ([]*int64, error) {
+	defer s.lock(key)()
+	str, _, err := s.stringFor(key)
+	if err != nil {
+		return nil, err
+	}
+	var need uint64
+	for _, op := range ops {
+		if op.Kind != BitFieldGet {
+			need = max(need, (op.Offset+uint64(op.Bits)+7)>>3)
+		}
+	}
+	buf := growBytes(str, need)
+	changed := need > uint64(len(str))
+
+	out := make([]*int64, len(ops))
+	for i, op := range ops {
+		cur := readField(buf, op.Offset, op.Bits, op.Signed)
+		if op.Kind == BitFieldGet {
+			out[i] = &cur
+			continue
+		}
+		next, reply := op.Value, cur
+		var overflow bool
+		if op.Kind == BitFieldIncrBy {
+			next, overflow = fieldAdd(cur, op.Value, op.Bits, op.Signed, op.Overflow)
+			reply = next
+		} else {
+			next, overflow = fieldAdd(op.Value, 0, op.Bits, op.Signed, op.Overflow)
+		}
+		if overflow && op.Overflow == OverflowFail {
+			continue
+		}
+		writeField(buf, op.Offset, op.Bits, next)
+		changed = true
+		out[i] = &reply
+	}
+
+	// даже если все записи отклонены (OVERFLOW FAIL), дополненная нулями строка остаётся, как в Redis
+	if changed {
+		s.put(key, string(buf))
+		s.touch(key)
+	}
+	return out, nil
+}
+
+// функция readField - значение поля шириной width бит с бита offset (за концом строки — нули).
+func readField(buf []byte, offset uint64, width uint, signed bool) int64 {
+	var v uint64
+	for i := uint64(0); i < uint64(width); i++ {
+		pos := offset + i
+		v <<= 1
+		if pos>>3 < uint64(len(buf)) {
+			v |= uint64(buf[pos>>3]>>(7-pos&7)) & 1
+		}
+	}
+	if signed && width < 64 && v&(1<<(width-1)) != 0 {
+		v |= math.MaxUint64 << width // расширяем знак
+	}
+	return int64(v)
+}
+
+// функция writeField - записывает младшие width бит v в поле с бита offset (строка уже нужной длины).
+func writeField(buf []byte, offset uint64, width uint, v int64) {
+	for i := uint64(0); i < uint64(width); i++ {
+		pos := offset + i
+		mask := byte(1) << (7 - pos&7)
+		if uint64(v)>>(uint64(width)-1-i)&1 == 1 {
+			buf[pos>>3] |= mask
+		} else {
+			buf[pos>>3] &^= mask
+		}
+	}
+}
+
+// функция fieldAdd - value+incr в поле шириной width бит с учётом режима переполнения, как
+// checkSignedBitfieldOverflow/checkUnsignedBitfieldOverflow в Redis. Для SET вызывается с incr = 0,
+// и тогда проверяется, помещается ли само value (отрицательное значение в беззнаковом поле не помещается).
+// Возвращает итоговое значение и то, было ли переполнение.
+func fieldAdd(value, incr int64, width uint, signed bool, mode BitFieldOverflow) (int64, bool) {
+	wrap := func() int64 {
+		c := uint64(value) + uint64(incr)
+		if width < 64 {
+			mask := uint64(math.MaxUint64) << width
+			if signed && c&(1<<(width-1)) != 0 {
+				c |= mask
+			} else {
+				c &^= mask
+			}
+		}
+		return int64(c)
+	}
+
+	if !signed {
+		umax := uint64(1)<<width - 1 // беззнаковые поля — не шире 63 бит
+		v := uint64(value)
+		switch {
+		case v > umax || (incr > 0 && uint64(incr) > umax-v):
+			if mode == OverflowSat {
+				return int64(umax), true
+			}
+		case incr < 0 && uint64(-incr) > v:
+			if mode == OverflowSat {
+				return 0, true
+			}
+		default:
+			return int64(v + uint64(incr)), false
+		}
+		return wrap(), true
+	}
+
+	smax := int64(math.MaxInt64)
+	if width < 64 {
+		smax = 1<<(width-1) - 1
+	}
+	smin := -smax - 1
+	switch {
+	case value > smax || (incr > 0 && value > smax-incr):
+		if mode == OverflowSat {
+			return smax, true
+		}
+	case value < smin || (incr < 0 && value < smin-incr):
+		if mode == OverflowSat {
+			return smin, true
+		}
+	default:
+		return value + incr, false
+	}
+	return wrap(), true
+}
@@ -0,0 +1,196 @@
+package store
+
+import (
+	"errors"
+	"testing"
+)
+
+// проверяет SETBIT/GETBIT: рост строки нулевыми байтами и порядок битов в байте
+func TestSetGetBit(t *testing.T) {
+	s := NewStore()
+	if old, _ := s.SetBit("bm", 7, 1); old != 0 {
+		t.Fatalf("SETBIT on missing key: old bit %d", old)
+	}
+	if v, _ := s.Get("bm"); v != "\x01" {
+		t.Fatalf("expected bit 7 to be the lowest bit of the first byte, got %q", v)
+	}
+	if old, _ := s.SetBit("bm", 7, 0); old != 1 {
+		t.Fatalf("SETBIT: expected old bit 1, got %d", old)
+	}
+	s.SetBit("bm", 17, 1)
+	if v, _ := s.Get("bm"); v != "\x00\x00\x40" {
+		t.Fatalf("expected zero padding, got %q", v)
+	}
+	for offset, want := range map[uint64]int{0: 0, 17: 1, 1000: 0} {
+		if bit, _ := s.GetBit("bm", offset); bit != want {
+			t.Fatalf("GETBIT %d: got %d, want %d", offset, bit, want)
+		}
+	}
+	s.RPush("list", "a")
+	if _, err := s.SetBit("list", 0, 1); !errors.Is(err, ErrWrongType) {
+		t.Fatalf("SETBIT on a list: expected ErrWrongType, got %v", err)
+	}
+}
+
+// проверяет BITCOUNT и BITPOS на примерах из документации Redis
+func TestBitCountPos(t *testing.T) {
+	s := NewStore()
+	s.Set("foobar", "foobar")
+	for _, tc := range []struct {
+		r    BitRange
+		want int
+	}{
+		{BitRange{}, 26},
+		{BitRange{Start: 0, End: 0, HasEnd: true}, 4},
+		{BitRange{Start: 1, End: 1, HasEnd: true}, 6},
+		{BitRange{Start: 5, End: 30, HasEnd: true, Bit: true}, 17},
+		{BitRange{Start: -2, End: -1, HasEnd: true}, 7},
+		{BitRange{Start: 3, End: 1, HasEnd: true}, 0},
+	} {
+		if n, _ := s.BitCount("foobar", tc.r); n != tc.want {
+			t.Fatalf("BITCOUNT %+v: got %d, want %d", tc.r, n, tc.want)
+		}
+	}
+
+	s.Set("a", "\xff\xf0\x00")
+	s.Set("b", "\x00\xff\xf0")
+	s.Set("ones", "\xff\xff")
+	s.Set("zeros", "\x00\x00\x00")
+	for _, tc := range []struct {
+		key  string
+		bit  int
+		r    BitRange
+		want int64
+	}{
+		{"a", 0, BitRange{}, 12},
+		{"b", 1, BitRange{}, 8},
+		{"b", 1, BitRange{Start: 2}, 16},
+		{"b", 1, BitRange{Start: 2, End: -1, HasEnd: true}, 16},
+		{"b", 1, BitRange{Start: 7, End: 15, HasEnd: true, Bit: true}, 8},
+		{"zeros", 1, BitRange{}, -1},
+		{"zeros", 1, BitRange{Start: 7, End: -3, HasEnd: true, Bit: true}, -1},
+		{"ones", 0, BitRange{}, 16}, // без конца диапазона строка как будто дополнена нулями
+		{"ones", 0, BitRange{Start: 0, End: -1, HasEnd: true}, -1},
+		{"missing", 0, BitRange{}, 0},
+		{"missing", 1, BitRange{}, -1},
+	} {
+		if pos, _ := s.BitPos(tc.key, tc.bit, tc.r); pos != tc.want {
+			t.Fatalf("BITPOS %s %d %+v: got %d, want %d", tc.key, tc.bit, tc.r, pos, tc.want)
+		}
+	}
+}
+
+// проверяет BITOP над строками разной длины и удаление ключа при пустом результате
+func TestBitOp(t *testing.T) {
+	s := NewStore()
+	s.Set("k1", "foobar")
+	s.Set("k2", "abcdef")
+	if n, _ := s.BitOp(BitAnd, "dest", "k1", "k2"); n != 6 {
+		t.Fatalf("BITOP AND: got length %d", n)
+	}
+	if v, _ := s.Get("dest"); v != "`bc`ab" {
+		t.Fatalf("BITOP AND: got %q", v)
+	}
+	s.Set("short", "\x0f")
+	s.BitOp(BitOr, "dest", "short", "k1")
+	if v, _ := s.Get("dest"); v != "ooobar" {
+		t.Fatalf("BITOP OR: got %q", v)
+	}
+	s.BitOp(BitXor, "dest", "k1", "k1")
+	if v, _ := s.Get("dest"); v != "\x00\x00\x00\x00\x00\x00" {
+		t.Fatalf("BITOP XOR: got %q", v)
+	}
+	s.BitOp(BitNot, "dest", "short")
+	if v, _ := s.Get("dest"); v != "\xf0" {
+		t.Fatalf("BITOP NOT: got %q", v)
+	}
+	if n, _ := s.BitOp(BitAnd, "dest", "missing"); n != 0 {
+		t.Fatalf("BITOP over missing keys: got length %d", n)
+	}
+	if _, ok := s.Get("dest"); ok {
+		t.Fatal("empty BITOP result must delete the destination")
+	}
+	s.RPush("list", "a")
+	if _, err := s.BitOp(BitOr, "dest", "k1", "list"); !errors.Is(err, ErrWrongType) {
+		t.Fatalf("BITOP with a list: expected ErrWrongType, got %v", err)
+	}
+}
+
+// bitField выполняет подкоманды BITFIELD и возвращает ответы, записывая nil как -999
+func bitField(t *testing.T, s *Store, key string, ops ...BitFieldOp) []int64 {
+	t.Helper()
+	res, err := s.BitField(key, ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([]int64, len(res))
+	for i, v := range res {
+		out[i] = -999
+		if v != nil {
+			out[i] = *v
+		}
+	}
+	return out
+}
+
+// проверяет BITFIELD: знаковые и беззнаковые поля и режимы переполнения WRAP/SAT/FAIL
+func TestBitField(t *testing.T) {
+	s := NewStore()
+	got := bitField(t, s, "bf",
+		BitFieldOp{Kind: BitFieldIncrBy, Signed: true, Bits: 5, Offset: 100, Value: 1},
+		BitFieldOp{Kind: BitFieldGet, Bits: 4, Offset: 0})
+	if got[0] != 1 || got[1] != 0 {
+		t.Fatalf("INCRBY i5 / GET u4: got %v", got)
+	}
+
+	// пример из документации: u2 с WRAP и SAT
+	want := [][2]int64{{1, 1}, {2, 2}, {3, 3}, {0, 3}}
+	for _, w := range want {
+		got := bitField(t, s, "counters",
+			BitFieldOp{Kind: BitFieldIncrBy, Bits: 2, Offset: 100, Value: 1},
+			BitFieldOp{Kind: BitFieldIncrBy, Bits: 2, Offset: 102, Value: 1, Overflow: OverflowSat})
+		if got[0] != w[0] || got[1] != w[1] {
+			t.Fatalf("INCRBY u2 WRAP/SAT: got %v, want %v", got, w)
+		}
+	}
+	if got := bitField(t, s, "counters", BitFieldOp{Kind: BitFieldIncrBy, Bits: 2, Offset: 102, Value: 1, Overflow: OverflowFail}); got[0] != -999 {
+		t.Fatalf("INCRBY with FAIL: expected nil, got %v", got)
+	}
+
+	for _, tc := range []struct {
+		op   BitFieldOp
+		want int64
+	}{
+		{BitFieldOp{Kind: BitFieldSet, Signed: true, Bits: 8, Value: 200}, -56},
+		{BitFieldOp{Kind: BitFieldSet, Signed: true, Bits: 8, Value: 200, Overflow: OverflowSat}, 127},
+		{BitFieldOp{Kind: BitFieldSet, Signed: true, Bits: 8, Value: -200, Overflow: OverflowSat}, -128},
+		{BitFieldOp{Kind: BitFieldSet, Bits: 8, Value: -1, Overflow: OverflowSat}, 255},
+		{BitFieldOp{Kind: BitFieldSet, Bits: 8, Value: 300}, 44},
+		{BitFieldOp{Kind: BitFieldSet, Signed: true, Bits: 64, Value: -1}, -1},
+	} {
+		s.Del("f")
+		bitField(t, s, "f", tc.op)
+		get := BitFieldOp{Kind: BitFieldGet, Signed: tc.op.Signed, Bits: tc.op.Bits}
+		if got := bitField(t, s, "f", get); got[0] != tc.want {
+			t.Fatalf("SET %+v: stored %d, want %d", tc.op, got[0], tc.want)
+		}
+	}
+
+	s.Set("i8", "\x7f")
+	if got := bitField(t, s, "i8", BitFieldOp{Kind: BitFieldIncrBy, Signed: true, Bits: 8, Value: 1}); got[0] != -128 {
+		t.Fatalf("INCRBY i8 127+1 with WRAP: got %d", got[0])
+	}
+	if got := bitField(t, s, "i8", BitFieldOp{Kind: BitFieldIncrBy, Signed: true, Bits: 8, Value: -1, Overflow: OverflowSat}); got[0] != -128 {
+		t.Fatalf("INCRBY i8 -128-1 with SAT: got %d", got[0])
+	}
+	if got := bitField(t, s, "i8", BitFieldOp{Kind: BitFieldSet, Signed: true, Bits: 8, Value: 5}); got[0] != -128 {
+		t.Fatalf("SET must reply with the old value, got %d", got[0])
+	}
+
+	if got := bitField(t, s, "untouched", BitFieldOp{Kind: BitFieldGet, Bits: 8, Offset: 8}); got[0] != 0 {
+		t.Fatalf("GET on missing key: got %d", got[0])
+	}
+	if _, ok := s.Get("untouched"); ok {
+		t.Fatal("BITFIELD with only GET must not create the key")
+	}
+}
@@ -0,0 +1,55 @@
+package store
+
+// Поддержка блокирующих команд (BLPOP, BRPOP, BLMOVE).
+// Само ожидание и очередь клиентов живут в роутере; хранилище лишь отмечает,
+// какие из ожидаемых ключей изменились ("готовы"), чтобы роутер после команды
+// проверил только их, а не всех ждущих клиентов.
+
+// метод BlockOn - сообщает, что ещё один клиент ждёт данных по ключу.
+func (s *Store) BlockOn(key string) {
+	s.blockMu.Lock()
+	defer s.blockMu.Unlock()
+	s.blocked[key]++
+	s.waiting.Add(1)
+}
+
+// метод UnblockOn - клиент больше не ждёт ключ (получил данные, истёк таймаут или отключился).
+func (s *Store) UnblockOn(key string) {
+	s.blockMu.Lock()
+	defer s.blockMu.Unlock()
+	s.waiting.Add(-1)
+	if s.blocked[key]--; s.blocked[key] <= 0 {
+		delete(s.blocked, key)
+		delete(s.ready, key)
+	}
+}
+
+// метод TakeReady - возвращает ожидаемые ключи, изменившиеся с прошлого вызова, и очищает список.
+// Без блокировок отвечает nil, если таких ключей нет (частый случай).
+func (s *Store) TakeReady() []string {
+	if !s.hasReady.Load() {
+		return nil
+	}
+	s.blockMu.Lock()
+	defer s.blockMu.Unlock()
+	keys := make([]string, 0, len(s.ready))
+	for key := range s.ready {
+		keys = append(keys, key)
+	}
+	s.ready = make(map[string]struct{})
+	s.hasReady.Store(false)
+	return keys
+}
+
+// метод markReady - отмечает ключ готовым, если его кто-то ждёт (вызывается из touch).
+func (s *Store) markReady(key string) {
+	if s.waiting.Load() == 0 { // никто ничего не ждёт — частый случай
+		return
+	}
+	s.blockMu.Lock()
+	defer s.blockMu.Unlock()
+	if s.blocked[key] > 0 {
+		s.ready[key] = struct{}{}
+		s.hasReady.Store(true)
+	}
+}
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+)
+
+// проверяет, что готовыми отмечаются только ожидаемые ключи и только после изменения
+func TestStore_ReadyKeys(t *testing.T) {
+	s := NewStore()
+	s.RPush("free", "a") // этого ключа никто не ждёт
+	if keys := s.TakeReady(); keys != nil {
+		t.Fatalf("expected no ready keys, got %v", keys)
+	}
+
+	s.BlockOn("jobs")
+	s.RPush("jobs", "j1")
+	s.RPush("jobs", "j2")
+	if keys := s.TakeReady(); !reflect.DeepEqual(keys, []string{"jobs"}) {
+		t.Errorf("expected [jobs], got %v", keys)
+	}
+	if keys := s.TakeReady(); keys != nil {
+		t.Errorf("expected ready keys to be cleared, got %v", keys)
+	}
+
+	s.UnblockOn("jobs")
+	s.RPush("jobs", "j3")
+	if keys := s.TakeReady(); keys != nil {
+		t.Errorf("expected no ready keys after UnblockOn, got %v", keys)
+	}
+}
+
+// проверяет LMOVE между списками и по кругу в одном списке
+func TestStore_LMove(t *testing.T) {
+	s := NewStore()
+	s.RPush("src", "a", "b")
+
+	if v, ok, err := s.LMove("src", "dst", true, false); !ok || err != nil || v != "a" {
+		t.Fatalf("expected 'a', got %q (ok=%v, err=%v)", v, ok, err)
+	}
+	if got, _ := s.LRange("dst", 0, -1); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("unexpected dst: %v", got)
+	}
+
+	s.RPush("ring", "1", "2", "3")
+	s.LMove("ring", "ring", false, true) // последний элемент переезжает в начало
+	if got, _ := s.LRange("ring", 0, -1); !reflect.DeepEqual(got, []string{"3", "1", "2"}) {
+		t.Errorf("unexpected rotation result: %v", got)
+	}
+
+	s.Set("str", "x")
+	if _, _, err := s.LMove("src", "str", true, true); err != ErrWrongType {
+		t.Errorf("expected ErrWrongType for string destination, got %v", err)
+	}
+	if n, _ := s.LLen("src"); n != 1 {
+		t.Errorf("expected src to stay untouched on error, got length %d", n)
+	}
+}
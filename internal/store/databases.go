@@ -0,0 +1,88 @@
+package store
+
+// Пронумерованные базы данных (SELECT, SWAPDB, MOVE, FLUSHALL).
+//
+// Каждая база — отдельное хранилище Store со своими шардами, TTL и сканером истёкших ключей.
+// Базы одного сервера создаются вместе через NewDatabases: у них общий seed хеширования,
+// поэтому ключ живёт в шарде с одним и тем же номером в любой базе и SWAPDB меняет базы
+// содержимым шард за шардом, не перекладывая ключи. Предел maxmemory у баз тоже общий (см. UsedMemory).
+//
+// Операции сразу над двумя базами берут их блокировки в порядке номеров баз,
+// поэтому встречные MOVE или SWAPDB не ждут друг друга вечно.
+
+// конструктор NewDatabases создаёт n пустых баз данных с номерами 0 … n-1.
+func NewDatabases(n int) []*Store {
+	dbs := make([]*Store, max(n, 1))
+	for i := range dbs {
+		dbs[i] = NewStore()
+		dbs[i].db = i
+		dbs[i].seed = dbs[0].seed
+		dbs[i].group = dbs
+	}
+	return dbs
+}
+
+// метод SwapDB - меняет местами содержимое двух баз из одной группы NewDatabases (SWAPDB): ключи, значения и TTL.
+// Клиенты, которые следят за ключами (WATCH) или ждут их (BLPOP), остаются при своей базе
+// и видят её новое содержимое: наблюдаемые ключи считаются изменёнными, а ждущие проверяют свои ключи заново.
+func (s *Store) SwapDB(other *Store) {
+	if s == other {
+		return
+	}
+	first, second := s, other
+	if first.db > second.db {
+		first, second = second, first
+	}
+	defer first.lockAll()()
+	defer second.lockAll()()
+
+	for i, sh := range s.shards {
+		o := other.shards[i]
+		sh.data, o.data = o.data, sh.data
+		sh.ttl, o.ttl = o.ttl, sh.ttl
+		sh.expiry, o.expiry = o.expiry, sh.expiry
+		sh.meta, o.meta = o.meta, sh.meta
+		sh.index, o.index = o.index, sh.index
+		used := sh.used.Load()
+		sh.used.Store(o.used.Load())
+		o.used.Store(used)
+		sh.dirty.Add(int64(len(sh.data)))
+		o.dirty.Add(int64(len(o.data)))
+	}
+	s.touchAll()
+	other.touchAll()
+}
+
+// метод touchAll - отмечает изменёнными все ключи, за которыми следят или которых ждут клиенты
+// (после замены всего содержимого базы; вызывается под lockAll).
+func (s *Store) touchAll() {
+	s.watchMu.RLock()
+	for key := range s.watchers {
+		s.notifyWatchersLocked(key)
+	}
+	s.watchMu.RUnlock()
+
+	s.blockMu.Lock()
+	defer s.blockMu.Unlock()
+	for key := range s.blocked {
+		s.ready[key] = struct{}{}
+		s.hasReady.Store(true)
+	}
+}
+
+// функция lockAcross - берёт блокировки на запись шарда ключа a в базе s и шарда ключа b в базе o
+// (базы — в порядке номеров) и возвращает функцию их снятия. s и o могут быть одной базой.
+func lockAcross(s *Store, a string, o *Store, b string) func() {
+	if s == o {
+		return s.lock(a, b)
+	}
+	if s.db > o.db {
+		s, a, o, b = o, b, s, a
+	}
+	unlockFirst := s.lock(a)
+	unlockSecond := o.lock(b)
+	return func() {
+		unlockSecond()
+		unlockFirst()
+	}
+}
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+// проверяет, что SWAPDB обменивает данные вместе с TTL и будит WATCH обеих баз
+func TestStore_SwapDB(t *testing.T) {
+	dbs := NewDatabases(2)
+	dbs[0].Set("a", "0")
+	at := time.Now().Add(time.Hour)
+	dbs[0].ExpireAt("a", at)
+	dbs[1].Set("b", "1")
+
+	w := NewWatch()
+	dbs[1].Watch(w, "a")
+	dbs[0].SwapDB(dbs[1])
+
+	if _, ok := dbs[0].Get("a"); ok {
+		t.Fatal("key a must move to db 1")
+	}
+	if v, ok := dbs[0].Get("b"); !ok || v != "1" {
+		t.Fatalf("db 0 after SWAPDB: got %q, %v", v, ok)
+	}
+	if got, ok := dbs[1].ExpireTime("a"); !ok || !got.Equal(at) {
+		t.Fatalf("TTL must move with the key, got %v, %v", got, ok)
+	}
+	if dbs[0].Expires() != 0 || dbs[1].Expires() != 1 {
+		t.Fatalf("expires after SWAPDB: %d, %d", dbs[0].Expires(), dbs[1].Expires())
+	}
+	if !dbs[1].WatchChanged(w) {
+		t.Fatal("SWAPDB must touch watched keys")
+	}
+}
+
+// проверяет, что MOVE переносит ключ с TTL и не трогает ключ, уже существующий в целевой базе
+func TestStore_Move(t *testing.T) {
+	dbs := NewDatabases(2)
+	if dbs[0].Move("missing", dbs[1]) {
+		t.Fatal("MOVE of missing key must fail")
+	}
+
+	dbs[0].Set("k", "v")
+	at := time.Now().Add(time.Hour)
+	dbs[0].ExpireAt("k", at)
+	if !dbs[0].Move("k", dbs[1]) {
+		t.Fatal("MOVE must succeed")
+	}
+	if dbs[0].Exists("k") != 0 {
+		t.Fatal("key must be gone from the source db")
+	}
+	if got, ok := dbs[1].ExpireTime("k"); !ok || !got.Equal(at) {
+		t.Fatalf("TTL must move with the key, got %v, %v", got, ok)
+	}
+
+	dbs[0].Set("k", "other")
+	if dbs[0].Move("k", dbs[1]) {
+		t.Fatal("MOVE must fail when the key exists in the target db")
+	}
+	if v, _ := dbs[1].Get("k"); v != "v" {
+		t.Fatalf("target key must stay untouched, got %q", v)
+	}
+}
+
+// проверяет, что базы одной группы делят maxmemory, а FLUSHDB ASYNC очищает только свою базу
+func TestStore_DatabasesMemory(t *testing.T) {
+	dbs := NewDatabases(2)
+	dbs[0].Set("a", "value")
+	dbs[1].Set("b", "value")
+	if dbs[0].UsedMemory() != dbs[1].UsedMemory() || dbs[0].UsedMemory() != dbs[0].usedMemory()+dbs[1].usedMemory() {
+		t.Fatalf("used memory must be shared: %d, %d", dbs[0].UsedMemory(), dbs[1].UsedMemory())
+	}
+
+	if n := dbs[0].FlushAsync(); n != 1 {
+		t.Fatalf("FlushAsync: expected 1 key, got %d", n)
+	}
+	if dbs[0].DBSize() != 0 || dbs[1].DBSize() != 1 {
+		t.Fatalf("FLUSHDB must clear only its db: %d, %d", dbs[0].DBSize(), dbs[1].DBSize())
+	}
+}
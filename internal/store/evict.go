@@ -0,0 +1,390 @@
+package store
+
+import (
+	"cmp"
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// ErrOOM возвращается, когда занятая память превышает maxmemory, а освободить её вытеснением не удалось.
+// Текст совпадает с ответом Redis.
+var ErrOOM = errors.New("OOM command not allowed when used memory > 'maxmemory'.")
+
+// EvictionPolicy — что делать, когда данные перестают помещаться в maxmemory.
+type EvictionPolicy int
+
+const (
+	NoEviction     EvictionPolicy = iota // ничего не вытеснять, изменяющие команды получают ErrOOM
+	AllKeysLRU                           // самые давно не использованные ключи
+	AllKeysLFU                           // самые редко используемые ключи
+	AllKeysRandom                        // случайные ключи
+	VolatileLRU                          // как allkeys-lru, но только среди ключей с TTL
+	VolatileLFU                          // как allkeys-lfu, но только среди ключей с TTL
+	VolatileRandom                       // случайные ключи с TTL
+	VolatileTTL                          // ключи с TTL, которые истекут раньше других
+)
+
+// policyNames — имена политик, как в redis.conf (maxmemory-policy).
+var policyNames = []string{
+	"noeviction", "allkeys-lru", "allkeys-lfu", "allkeys-random",
+	"volatile-lru", "volatile-lfu", "volatile-random", "volatile-ttl",
+}
+
+// метод String - имя политики, как в redis.conf.
+func (p EvictionPolicy) String() string {
+	return policyNames[p]
+}
+
+// метод volatile - выбирает ли политика жертв только среди ключей с TTL.
+func (p EvictionPolicy) volatile() bool {
+	return p >= VolatileLRU
+}
+
+// функция ParseEvictionPolicy - политика по её имени; false, если такой нет.
+func ParseEvictionPolicy(name string) (EvictionPolicy, bool) {
+	i := slices.Index(policyNames, name)
+	return EvictionPolicy(max(i, 0)), i >= 0
+}
+
+const (
+	// DefaultMaxMemorySamples — сколько ключей по умолчанию просматривается за один шаг выбора жертвы
+	// (maxmemory-samples в Redis): больше — точнее, но дороже.
+	DefaultMaxMemorySamples = 5
+
+	evictionPoolSize = 16 // столько лучших кандидатов помнится между шагами, как EVPOOL_SIZE в Redis
+
+	// LFU-счётчик устроен как в Redis: он растёт логарифмически (чем он больше, тем реже растёт)
+	// и уменьшается на единицу за каждую минуту без обращений, поэтому в 8 битах помещается и "горячий" ключ
+	lfuInitVal   = 5  // начальное значение, чтобы новый ключ не вытеснили раньше, чем к нему обратятся
+	lfuLogFactor = 10 // чем больше, тем медленнее растёт счётчик
+	lfuTimeMask  = 1<<24 - 1
+)
+
+// приблизительные накладные расходы (в байтах) на структуры Go, в которых лежат данные;
+// точный размер не важен — важно, что оценка растёт и уменьшается вместе с данными
+const (
+	keyOverhead    = 96 // запись в карте шарда, метаданные ключа и заголовок строки ключа
+	strOverhead    = 16 // заголовок строки
+	entryOverhead  = 32 // место записи во внутренней карте коллекции
+	zsetOverhead   = 64 // узел списка с пропусками и счёт элемента
+	streamOverhead = 40 // идентификатор и срез полей записи потока
+	pelOverhead    = 64 // запись в списке ожидающих подтверждения группы
+	sizeSamples    = 5  // сколько элементов коллекции просматривается для оценки её размера
+)
+
+// структура keyMeta — сведения о ключе для maxmemory: оценка его размера и статистика обращений.
+// Статистика обновляется и при чтении (под блокировкой шарда на чтение), поэтому она атомарная.
+type keyMeta struct {
+	size int64         // оценка занятой памяти (меняется под блокировкой шарда на запись)
+	lru  atomic.Int64  // время последнего обращения, мс
+	lfu  atomic.Uint32 // минута последнего обновления (старшие 24 бита) и логарифмический счётчик (младшие 8)
+}
+
+// конструктор newKeyMeta создаёт сведения о только что появившемся ключе.
+func newKeyMeta(now time.Time) *keyMeta {
+	m := &keyMeta{}
+	m.lru.Store(now.UnixMilli())
+	m.lfu.Store(lfuMinutes(now)<<8 | lfuInitVal)
+	return m
+}
+
+// метод access - отмечает обращение к ключу.
+func (m *keyMeta) access(now time.Time) {
+	m.lru.Store(now.UnixMilli())
+	counter := m.lfuCounter(now)
+	if counter < 255 {
+		// вероятность роста падает с ростом счётчика: 1 / ((counter-lfuInitVal)*lfuLogFactor + 1)
+		base := max(float64(counter)-lfuInitVal, 0)
+		if rand.Float64() < 1/(base*lfuLogFactor+1) {
+			counter++
+		}
+	}
+	m.lfu.Store(lfuMinutes(now)<<8 | counter)
+}
+
+// метод lfuCounter - значение LFU-счётчика с учётом того, сколько минут к ключу не обращались.
+func (m *keyMeta) lfuCounter(now time.Time) uint32 {
+	v := m.lfu.Load()
+	counter := v & 0xFF
+	idle := (lfuMinutes(now) - v>>8) & lfuTimeMask
+	if idle >= counter {
+		return 0
+	}
+	return counter - idle
+}
+
+// функция lfuMinutes - текущее время в минутах, обрезанное до 24 бит.
+func lfuMinutes(now time.Time) uint32 {
+	return uint32(now.Unix()/60) & lfuTimeMask
+}
+
+// метод account - пересчитывает оценку размера ключа и счётчик памяти шарда (под блокировкой шарда на запись).
+func (sh *shard) account(key string, val any) {
+	m, ok := sh.meta[key]
+	if !ok {
+		m = newKeyMeta(time.Now())
+		sh.meta[key] = m
+	}
+	size := sizeOf(key, val)
+	sh.used.Add(size - m.size)
+	m.size = size
+}
+
+// функция sizeOf - приблизительный размер ключа со значением в байтах.
+// Размер коллекции оценивается по нескольким её элементам, как MEMORY USAGE ... SAMPLES в Redis,
+// поэтому пересчёт после каждого изменения стоит O(1), а не O(N).
+func sizeOf(key string, val any) int64 {
+	size := int64(keyOverhead + len(key))
+	switch v := val.(type) {
+	case string:
+		size += int64(len(v))
+	case hash:
+		size += sampleMap(v, func(field, value string) int {
+			return entryOverhead + 2*strOverhead + len(field) + len(value)
+		})
+	case set:
+		size += sampleMap(v, func(member string, _ struct{}) int {
+			return entryOverhead + strOverhead + len(member)
+		})
+	case *zset:
+		size += sampleMap(v.dict, func(member string, _ float64) int {
+			return entryOverhead + zsetOverhead + strOverhead + len(member)
+		})
+	case *list:
+		size += int64(cap(v.buf)) * strOverhead
+		n, sum := min(v.n, sizeSamples), 0
+		for i := 0; i < n; i++ {
+			sum += len(v.at(i))
+		}
+		if n > 0 {
+			size += int64(sum) * int64(v.n) / int64(n)
+		}
+	case *stream:
+		n, sum := min(len(v.entries), sizeSamples), 0
+		for _, e := range v.entries[:n] {
+			sum += streamOverhead
+			for _, f := range e.Fields {
+				sum += strOverhead + len(f)
+			}
+		}
+		if n > 0 {
+			size += int64(sum) * int64(len(v.entries)) / int64(n)
+		}
+		for name, g := range v.groups {
+			size += int64(entryOverhead + len(name) + len(g.pel)*pelOverhead)
+		}
+	}
+	return size
+}
+
+// функция sampleMap - оценка суммарного размера элементов карты по первым sizeSamples из них
+// (порядок обхода карты в Go случайный).
+func sampleMap[V any](m map[string]V, elemSize func(string, V) int) int64 {
+	n, sum := 0, 0
+	for k, v := range m {
+		if n == sizeSamples {
+			break
+		}
+		sum += elemSize(k, v)
+		n++
+	}
+	if n == 0 {
+		return 0
+	}
+	return int64(sum) * int64(len(m)) / int64(n)
+}
+
+// метод SetMaxMemory - задаёт предел памяти под данные (0 — без ограничения), политику вытеснения
+// и число ключей, просматриваемых за один шаг выбора жертвы.
+func (s *Store) SetMaxMemory(limit int64, policy EvictionPolicy, samples int) {
+	s.evictMu.Lock()
+	defer s.evictMu.Unlock()
+	s.policy = policy
+	s.samples = max(samples, 1)
+	s.pool = nil
+	s.maxMemory.Store(limit)
+}
+
+// метод MaxMemory - текущий предел памяти и политика вытеснения.
+func (s *Store) MaxMemory() (int64, EvictionPolicy) {
+	s.evictMu.Lock()
+	defer s.evictMu.Unlock()
+	return s.maxMemory.Load(), s.policy
+}
+
+// метод UsedMemory - оценка памяти, занятой всеми ключами, в байтах.
+// У баз из NewDatabases предел maxmemory общий, поэтому считаются ключи всех баз сервера.
+func (s *Store) UsedMemory() int64 {
+	if s.group == nil {
+		return s.usedMemory()
+	}
+	var n int64
+	for _, db := range s.group {
+		n += db.usedMemory()
+	}
+	return n
+}
+
+// метод usedMemory - оценка памяти, занятой ключами только этой базы.
+func (s *Store) usedMemory() int64 {
+	var n int64
+	for _, sh := range s.shards {
+		n += sh.used.Load()
+	}
+	return n
+}
+
+// метод EvictedKeys - сколько ключей вытеснено из-за maxmemory с момента запуска.
+func (s *Store) EvictedKeys() int64 {
+	return s.evicted.Load()
+}
+
+// метод MemoryUsage - оценка памяти, занятой ключом (MEMORY USAGE); false, если ключа нет.
+// Обращением к ключу не считается.
+func (s *Store) MemoryUsage(key string) (int64, bool) {
+	defer s.rlock(key)()
+	if !s.aliveLocked(key, time.Now()) {
+		return 0, false
+	}
+	return s.shardOf(key).meta[key].size, true
+}
+
+// метод FreeMemory - если занято больше maxmemory, вытесняет ключи по политике, пока данные не уложатся в предел.
+// Возвращает вытесненные ключи (роутер передаёт их удаление в журнал и репликам)
+// и ErrOOM, если уложиться не удалось: политика noeviction или вытеснять больше нечего.
+// Ключи вытесняются только из этой базы; если в ней их не хватило, роутер пробует остальные.
+// Порядок блокировок: evictMu → блокировки шардов.
+func (s *Store) FreeMemory() ([]string, error) {
+	limit := s.maxMemory.Load()
+	if limit == 0 || s.UsedMemory() <= limit { // частый случай — без блокировок
+		return nil, nil
+	}
+
+	s.evictMu.Lock()
+	defer s.evictMu.Unlock()
+	var evicted []string
+	for s.UsedMemory() > limit {
+		if s.policy == NoEviction {
+			return evicted, ErrOOM
+		}
+		key, ok := s.pickVictim()
+		if !ok {
+			return evicted, ErrOOM
+		}
+		if s.evict(key) {
+			evicted = append(evicted, key)
+		}
+	}
+	return evicted, nil
+}
+
+// структура evictCandidate — кандидат на вытеснение; чем больше idle, тем он лучше.
+type evictCandidate struct {
+	key  string
+	idle int64
+}
+
+// метод pickVictim - выбирает ключ для вытеснения (вызывается под evictMu).
+// Как и в Redis, выбор приблизительный: случайные политики берут случайный ключ,
+// остальные просматривают samples ключей, пополняют ими пул лучших кандидатов и забирают из пула лучшего.
+func (s *Store) pickVictim() (string, bool) {
+	volatile := s.policy.volatile()
+	if s.policy == AllKeysRandom || s.policy == VolatileRandom {
+		var victim string
+		found := s.sampleKeys(volatile, 1, func(sh *shard, key string) { victim = key })
+		return victim, found
+	}
+
+	now := time.Now()
+	s.sampleKeys(volatile, s.samples, func(sh *shard, key string) {
+		s.offer(key, s.idleScore(sh, key, now))
+	})
+	if len(s.pool) == 0 {
+		return "", false
+	}
+	best := s.pool[len(s.pool)-1]
+	s.pool = s.pool[:len(s.pool)-1]
+	return best.key, true
+}
+
+// метод sampleKeys - передаёт в fn до n ключей (только с TTL, если volatile), обходя шарды по очереди,
+// начиная со случайного (каждый — под его блокировкой на чтение). Порядок обхода карты в Go случайный,
+// поэтому это выборка, а не всегда одни и те же ключи. false — подходящих ключей нет ни в одном шарде.
+func (s *Store) sampleKeys(volatile bool, n int, fn func(sh *shard, key string)) bool {
+	start := rand.IntN(len(s.shards))
+	taken := 0
+	for i := 0; i < len(s.shards) && taken < n; i++ {
+		sh := s.shards[(start+i)&int(s.mask)]
+		sh.mtx.RLock()
+		if volatile {
+			for key := range sh.ttl {
+				if taken == n {
+					break
+				}
+				fn(sh, key)
+				taken++
+			}
+		} else {
+			for key := range sh.data {
+				if taken == n {
+					break
+				}
+				fn(sh, key)
+				taken++
+			}
+		}
+		sh.mtx.RUnlock()
+	}
+	return taken > 0
+}
+
+// метод idleScore - насколько ключ подходит для вытеснения по текущей политике (под блокировкой его шарда).
+func (s *Store) idleScore(sh *shard, key string, now time.Time) int64 {
+	m, ok := sh.meta[key]
+	if !ok {
+		return 0
+	}
+	switch s.policy {
+	case AllKeysLRU, VolatileLRU:
+		return now.UnixMilli() - m.lru.Load()
+	case AllKeysLFU, VolatileLFU:
+		return 255 - int64(m.lfuCounter(now))
+	case VolatileTTL:
+		return -sh.ttl[key].UnixMilli() // чем раньше истекает, тем лучше кандидат
+	}
+	return 0
+}
+
+// метод offer - добавляет кандидата в пул (вызывается под evictMu). Пул упорядочен по возрастанию idle
+// и хранит не больше evictionPoolSize лучших кандидатов.
+func (s *Store) offer(key string, idle int64) {
+	if i := slices.IndexFunc(s.pool, func(c evictCandidate) bool { return c.key == key }); i >= 0 {
+		s.pool = slices.Delete(s.pool, i, i+1) // ключ уже в пуле — обновим его оценку
+	}
+	i, _ := slices.BinarySearchFunc(s.pool, idle, func(c evictCandidate, idle int64) int {
+		return cmp.Compare(c.idle, idle)
+	})
+	s.pool = slices.Insert(s.pool, i, evictCandidate{key: key, idle: idle})
+	if len(s.pool) > evictionPoolSize {
+		s.pool = s.pool[1:]
+	}
+}
+
+// метод evict - удаляет ключ, выбранный для вытеснения. false — ключ уже исчез
+// (или, для volatile-политик, потерял TTL), пока лежал в пуле.
+func (s *Store) evict(key string) bool {
+	defer s.lock(key)()
+	if _, ok := s.get(key); !ok {
+		return false
+	}
+	if _, ok := s.ttlOf(key); !ok && s.policy.volatile() {
+		return false
+	}
+	s.remove(key)
+	s.touch(key)
+	s.evicted.Add(1)
+	return true
+}
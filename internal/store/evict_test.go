@@ -0,0 +1,189 @@
+package store
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// проверяет, что оценка занятой памяти растёт и уменьшается вместе с данными
+func TestStore_MemoryAccounting(t *testing.T) {
+	s := NewStore()
+	if n := s.UsedMemory(); n != 0 {
+		t.Fatalf("empty store: expected 0 bytes, got %d", n)
+	}
+	s.Set("small", "v")
+	small, _ := s.MemoryUsage("small")
+	s.Set("big", string(make([]byte, 10_000)))
+	big, _ := s.MemoryUsage("big")
+	if big < 10_000 || small > 200 {
+		t.Fatalf("expected sizes to follow value lengths, got %d for 1 byte and %d for 10000 bytes", small, big)
+	}
+
+	for i := 0; i < 1000; i++ {
+		s.SAdd("set", "member:"+strconv.Itoa(i))
+	}
+	set, _ := s.MemoryUsage("set")
+	if set < 1000*10 {
+		t.Fatalf("expected set of 1000 members to be estimated above 10000 bytes, got %d", set)
+	}
+	if used := s.UsedMemory(); used != small+big+set {
+		t.Fatalf("expected used memory %d to be the sum of keys, got %d", small+big+set, used)
+	}
+
+	s.Del("big")
+	s.SRem("set", "member:1")
+	s.SPop("set", 1000) // опустевшее множество удаляется
+	if used := s.UsedMemory(); used != small {
+		t.Fatalf("after deletes: expected %d, got %d", small, used)
+	}
+	s.Flush()
+	if used := s.UsedMemory(); used != 0 {
+		t.Fatalf("after FLUSH: expected 0, got %d", used)
+	}
+}
+
+// fill заполняет хранилище n ключами и возвращает размер одного из них
+func fill(s *Store, prefix string, n int) int64 {
+	for i := 0; i < n; i++ {
+		s.Set(prefix+strconv.Itoa(i), "value")
+	}
+	size, _ := s.MemoryUsage(prefix + "0")
+	return size
+}
+
+// проверяет, что при noeviction ничего не вытесняется, а FreeMemory сообщает об OOM
+func TestStore_NoEviction(t *testing.T) {
+	s := NewStore()
+	size := fill(s, "k", 100)
+	s.SetMaxMemory(50*size, NoEviction, DefaultMaxMemorySamples)
+	if _, err := s.FreeMemory(); !errors.Is(err, ErrOOM) {
+		t.Fatalf("expected ErrOOM, got %v", err)
+	}
+	if n := len(s.Snapshot()); n != 100 {
+		t.Fatalf("noeviction must not delete keys, %d left", n)
+	}
+
+	s.SetMaxMemory(0, NoEviction, DefaultMaxMemorySamples)
+	if _, err := s.FreeMemory(); err != nil {
+		t.Fatalf("without a limit: expected no error, got %v", err)
+	}
+}
+
+// проверяет, что allkeys-lru вытесняет давно не использованные ключи и оставляет те, к которым обращались
+func TestStore_EvictLRU(t *testing.T) {
+	s := NewStore()
+	size := fill(s, "cold:", 100)
+	time.Sleep(20 * time.Millisecond)
+	fill(s, "hot:", 100)
+	for i := 0; i < 100; i++ {
+		s.Get("hot:" + strconv.Itoa(i))
+	}
+
+	s.SetMaxMemory(120*size, AllKeysLRU, 10)
+	evicted, err := s.FreeMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.UsedMemory() > 120*size {
+		t.Fatalf("used memory %d is still above the limit %d", s.UsedMemory(), 120*size)
+	}
+	if len(evicted) < 80 || s.EvictedKeys() != int64(len(evicted)) {
+		t.Fatalf("expected at least 80 evicted keys, got %d (counter %d)", len(evicted), s.EvictedKeys())
+	}
+	hot := 0
+	for i := 0; i < 100; i++ {
+		if _, ok := s.Get("hot:" + strconv.Itoa(i)); ok {
+			hot++
+		}
+	}
+	// выборка приблизительная, но старые ключи должны уходить заметно раньше свежих
+	if hot < 90 {
+		t.Fatalf("expected recently used keys to survive, only %d of 100 left", hot)
+	}
+}
+
+// проверяет, что allkeys-lfu оставляет часто используемые ключи
+func TestStore_EvictLFU(t *testing.T) {
+	s := NewStore()
+	size := fill(s, "rare:", 100)
+	fill(s, "frequent:", 100)
+	for round := 0; round < 50; round++ {
+		for i := 0; i < 100; i++ {
+			s.Get("frequent:" + strconv.Itoa(i))
+		}
+	}
+
+	s.SetMaxMemory(120*size, AllKeysLFU, 10)
+	if _, err := s.FreeMemory(); err != nil {
+		t.Fatal(err)
+	}
+	frequent := 0
+	for i := 0; i < 100; i++ {
+		if _, ok := s.Get("frequent:" + strconv.Itoa(i)); ok {
+			frequent++
+		}
+	}
+	if frequent < 90 {
+		t.Fatalf("expected frequently used keys to survive, only %d of 100 left", frequent)
+	}
+}
+
+// проверяет volatile-политики: ключи без TTL не трогаются, volatile-ttl вытесняет ближайшие к истечению
+func TestStore_EvictVolatile(t *testing.T) {
+	s := NewStore()
+	size := fill(s, "persistent:", 50)
+	fill(s, "volatile:", 50)
+	for i := 0; i < 50; i++ {
+		s.ExpireAt("volatile:"+strconv.Itoa(i), time.Now().Add(time.Duration(i+1)*time.Hour))
+	}
+
+	s.SetMaxMemory(75*size, VolatileTTL, 50)
+	if _, err := s.FreeMemory(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 50; i++ {
+		if _, ok := s.Get("persistent:" + strconv.Itoa(i)); !ok {
+			t.Fatalf("volatile-ttl evicted key without TTL persistent:%d", i)
+		}
+	}
+	// с выборкой в 50 ключей пул видит все ключи с TTL, поэтому порядок точный
+	if _, ok := s.Get("volatile:0"); ok {
+		t.Fatal("expected the key closest to expiry to be evicted first")
+	}
+	if _, ok := s.Get("volatile:49"); !ok {
+		t.Fatal("expected the key with the longest TTL to survive")
+	}
+
+	// ключи с TTL кончились — освободить память нечем
+	s.SetMaxMemory(10*size, VolatileRandom, DefaultMaxMemorySamples)
+	if _, err := s.FreeMemory(); !errors.Is(err, ErrOOM) {
+		t.Fatalf("expected ErrOOM when no volatile keys are left, got %v", err)
+	}
+	if n := len(s.Snapshot()); n != 50 {
+		t.Fatalf("expected only the 50 keys without TTL to stay, got %d", n)
+	}
+
+	s.SetMaxMemory(10*size, AllKeysRandom, DefaultMaxMemorySamples)
+	if _, err := s.FreeMemory(); err != nil {
+		t.Fatal(err)
+	}
+	if s.UsedMemory() > 10*size {
+		t.Fatalf("allkeys-random: used memory %d is still above the limit", s.UsedMemory())
+	}
+}
+
+// проверяет разбор имён политик
+func TestParseEvictionPolicy(t *testing.T) {
+	for _, name := range []string{"noeviction", "allkeys-lru", "allkeys-lfu", "allkeys-random",
+		"volatile-lru", "volatile-lfu", "volatile-random", "volatile-ttl"} {
+		p, ok := ParseEvictionPolicy(name)
+		if !ok || p.String() != name {
+			t.Fatalf("ParseEvictionPolicy(%q) = %v, %v", name, p, ok)
+		}
+	}
+	if _, ok := ParseEvictionPolicy("lru"); ok {
+		t.Fatal("expected unknown policy to be rejected")
+	}
+}
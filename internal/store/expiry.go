@@ -0,0 +1,79 @@
+package store
+
+import (
+	"container/heap"
+	"time"
+)
+
+// структура expiryItem — ключ с TTL в индексе истечения; index — позиция в куче (нужна heap.Fix/heap.Remove).
+type expiryItem struct {
+	key   string
+	at    time.Time
+	index int
+}
+
+// структура expiryHeap — индекс истечения: min-куча по моменту истечения плюс map для поиска ключа.
+// Вершина кучи — ключ, который истечёт раньше всех, поэтому удаление пачки истёкших ключей
+// стоит O(k log n) для k истёкших, а не O(n) для всего хранилища.
+type expiryHeap struct {
+	items []*expiryItem
+	pos   map[string]*expiryItem
+}
+
+// конструктор newExpiryHeap - создаёт пустой индекс истечения.
+func newExpiryHeap() *expiryHeap {
+	return &expiryHeap{pos: make(map[string]*expiryItem)}
+}
+
+// методы heap.Interface
+
+func (h *expiryHeap) Len() int           { return len(h.items) }
+func (h *expiryHeap) Less(i, j int) bool { return h.items[i].at.Before(h.items[j].at) }
+
+func (h *expiryHeap) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.items[i].index = i
+	h.items[j].index = j
+}
+
+func (h *expiryHeap) Push(x any) {
+	it := x.(*expiryItem)
+	it.index = len(h.items)
+	h.items = append(h.items, it)
+}
+
+func (h *expiryHeap) Pop() any {
+	last := len(h.items) - 1
+	it := h.items[last]
+	h.items[last] = nil
+	h.items = h.items[:last]
+	return it
+}
+
+// метод set - добавляет ключ в индекс или переносит его на новый момент истечения.
+func (h *expiryHeap) set(key string, at time.Time) {
+	if it, ok := h.pos[key]; ok {
+		it.at = at
+		heap.Fix(h, it.index)
+		return
+	}
+	it := &expiryItem{key: key, at: at}
+	h.pos[key] = it
+	heap.Push(h, it)
+}
+
+// метод remove - убирает ключ из индекса (если он там есть).
+func (h *expiryHeap) remove(key string) {
+	if it, ok := h.pos[key]; ok {
+		heap.Remove(h, it.index)
+		delete(h.pos, key)
+	}
+}
+
+// метод next - ключ, который истекает раньше всех; false, если индекс пуст.
+func (h *expiryHeap) next() (string, time.Time, bool) {
+	if len(h.items) == 0 {
+		return "", time.Time{}, false
+	}
+	return h.items[0].key, h.items[0].at, true
+}
@@ -0,0 +1,116 @@
+package store
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+// checkExpiryIndex сверяет индекс истечения каждого шарда с его ttl и проверяет свойство кучи
+func checkExpiryIndex(t *testing.T, s *Store) {
+	t.Helper()
+	for _, sh := range s.shards {
+		h := sh.expiry
+		if len(h.items) != len(sh.ttl) || len(h.pos) != len(sh.ttl) {
+			t.Fatalf("index has %d items (%d in pos), ttl map has %d", len(h.items), len(h.pos), len(sh.ttl))
+		}
+		for i, it := range h.items {
+			if at, ok := sh.ttl[it.key]; !ok || !at.Equal(it.at) || it.index != i {
+				t.Fatalf("index entry %q (%v at %d) does not match ttl map (%v, %v)", it.key, it.at, it.index, at, ok)
+			}
+			if i > 0 && h.items[(i-1)/2].at.After(it.at) {
+				t.Fatalf("heap order broken at %d", i)
+			}
+		}
+	}
+}
+
+// проверяет, что индекс истечения следует за SET, DEL, EXPIRE, PERSIST и удалением опустевших коллекций
+func TestExpiryIndexConsistency(t *testing.T) {
+	s := NewStore()
+	now := time.Now()
+	for i := 0; i < 50; i++ {
+		s.SetWith("k"+strconv.Itoa(i), "v", SetOptions{ExpireAt: now.Add(time.Duration(50-i) * time.Minute)})
+	}
+	checkExpiryIndex(t, s)
+
+	s.Expire("k1", 1)    // переносим на самый ранний срок
+	s.Persist("k2")      // снимаем TTL
+	s.Del("k3")          // удаляем ключ
+	s.Set("k4", "plain") // SET без опций сбрасывает TTL
+	s.SetWith("k5", "v", SetOptions{KeepTTL: true})
+	s.RPush("list", "a")
+	s.Expire("list", 60)
+	s.LPop("list", 1)  // опустевший список удаляется вместе с TTL
+	s.Expire("k6", -1) // отрицательный TTL удаляет ключ
+	checkExpiryIndex(t, s)
+
+	if key, _, _ := s.shardOf("k1").expiry.next(); key != "k1" {
+		t.Fatalf("expected k1 to expire first in its shard, got %q", key)
+	}
+	s.Flush()
+	checkExpiryIndex(t, s)
+	if s.ExpireTimeMillis("k10") != -2 {
+		t.Fatal("FLUSH must clear the expiry index")
+	}
+}
+
+// newExpiryStore создаёт хранилище с n ключами, которые истекут через час
+func newExpiryStore(n int) *Store {
+	s := NewStore()
+	at := time.Now().Add(time.Hour)
+	for i := 0; i < n; i++ {
+		s.SetWith("key:"+strconv.Itoa(i), "v", SetOptions{ExpireAt: at})
+	}
+	return s
+}
+
+// scanExpired — прежний способ очистки: полный проход по s.ttl (для сравнения в бенчмарке)
+func scanExpired(s *Store) int {
+	now := time.Now()
+	n := 0
+	for _, sh := range s.shards {
+		sh.mtx.Lock()
+		for key, at := range sh.ttl {
+			if !now.Before(at) {
+				s.deleteExpired(key)
+				n++
+			}
+		}
+		sh.mtx.Unlock()
+	}
+	return n
+}
+
+// BenchmarkExpireCycle сравнивает удаление 10 истёкших ключей среди n ключей с TTL:
+// индекс истечения тратит время только на истёкшие ключи, полный проход — на все n
+func BenchmarkExpireCycle(b *testing.B) {
+	for _, n := range []int{10_000, 100_000, 1_000_000} {
+		for _, mode := range []string{"heap", "scan"} {
+			b.Run(mode+"/"+strconv.Itoa(n), func(b *testing.B) {
+				s := newExpiryStore(n)
+				past := time.Now().Add(-time.Second)
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					b.StopTimer()
+					for j := 0; j < 10; j++ {
+						key := "due:" + strconv.Itoa(j)
+						s.Set(key, "v")
+						setTTL(s, key, past)
+					}
+					b.StartTimer()
+
+					var removed int
+					if mode == "heap" {
+						removed = s.ActiveExpireCycle(time.Second)
+					} else {
+						removed = scanExpired(s)
+					}
+					if removed != 10 {
+						b.Fatalf("expected 10 expired keys, got %d", removed)
+					}
+				}
+			})
+		}
+	}
+}
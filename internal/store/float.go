@@ -0,0 +1,50 @@
+package store
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+)
+
+// ErrNotFloat — приращение (или значение) не является конечным дробным числом.
+var ErrNotFloat = errors.New("ERR value is not a valid float")
+
+// Redis считает INCRBYFLOAT и HINCRBYFLOAT в long double (64-битная мантисса x87)
+// и печатает результат через "%.17Lf" без хвостовых нулей: 1.1 + 2.2 даёт "3.3", а не "3.3000000000000003".
+// Чтобы ответы совпадали, здесь то же самое делается через big.Float с той же точностью.
+const (
+	longDoublePrec   = 64    // бит мантиссы long double
+	longDoubleMaxExp = 16384 // |x| < 2^16384, иначе long double переполняется в бесконечность
+)
+
+// функция parseLongDouble - разбирает число как long double. false — не число, NaN или бесконечность.
+func parseLongDouble(s string) (*big.Float, bool) {
+	if s == "" || strings.TrimSpace(s) != s {
+		return nil, false
+	}
+	f, _, err := big.ParseFloat(s, 10, longDoublePrec, big.ToNearestEven)
+	if err != nil || f.IsInf() || f.MantExp(nil) > longDoubleMaxExp {
+		return nil, false
+	}
+	return f, true
+}
+
+// функция addLongDouble - сумма a + b с точностью long double.
+// false — результат переполнился бы (в Redis это NaN или бесконечность).
+func addLongDouble(a, b *big.Float) (*big.Float, bool) {
+	sum := new(big.Float).SetPrec(longDoublePrec).SetMode(big.ToNearestEven).Add(a, b)
+	return sum, sum.MantExp(nil) <= longDoubleMaxExp
+}
+
+// функция formatLongDouble - печатает число как Redis: 17 знаков после точки без хвостовых нулей.
+func formatLongDouble(f *big.Float) string {
+	s := f.Text('f', 17)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(s, "0")
+		s = strings.TrimSuffix(s, ".")
+	}
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
@@ -0,0 +1,371 @@
+package store
+
+import (
+	"errors"
+	"math"
+	"slices"
+)
+
+// Геоиндексы (GEOADD, GEOPOS, GEODIST, GEOSEARCH, GEOSEARCHSTORE) поверх упорядоченных множеств.
+//
+// Как в Redis, координаты хранятся счётом элемента: 52-битный geohash — по 26 бит долготы и широты,
+// перемежающихся через один (широта в чётных битах). Ячейки с общим префиксом хеша лежат в множестве
+// подряд, поэтому поиск сводится к нескольким диапазонам счетов: ячейка центра нужного размера и восемь
+// соседних, покрывающих область поиска. Найденные точки затем проверяются точным расстоянием.
+
+const (
+	GeoLonMin = -180.0
+	GeoLonMax = 180.0
+	GeoLatMin = -85.05112878 // границы проекции Меркатора, как в Redis
+	GeoLatMax = 85.05112878
+
+	geoStep     = 26 // бит на координату в счёте
+	earthRadius = 6372797.560856
+	mercatorMax = 20037726.37
+)
+
+// ErrGeoMember — центр поиска FROMMEMBER не найден в множестве.
+var ErrGeoMember = errors.New("ERR could not decode requested zset member")
+
+// структура GeoPoint — долгота и широта в градусах.
+type GeoPoint struct {
+	Lon, Lat float64
+}
+
+// функция GeoValid - лежит ли точка в области, которую можно закодировать.
+func GeoValid(p GeoPoint) bool {
+	return p.Lon >= GeoLonMin && p.Lon <= GeoLonMax && p.Lat >= GeoLatMin && p.Lat <= GeoLatMax
+}
+
+// функция spread - раздвигает 32 бита через один (бит i переходит в бит 2i).
+func spread(x uint32) uint64 {
+	v := uint64(x)
+	v = (v | v<<16) & 0x0000FFFF0000FFFF
+	v = (v | v<<8) & 0x00FF00FF00FF00FF
+	v = (v | v<<4) & 0x0F0F0F0F0F0F0F0F
+	v = (v | v<<2) & 0x3333333333333333
+	v = (v | v<<1) & 0x5555555555555555
+	return v
+}
+
+// функция squash - обратная spread: собирает чётные биты в 32-битное число.
+func squash(v uint64) uint32 {
+	v &= 0x5555555555555555
+	v = (v | v>>1) & 0x3333333333333333
+	v = (v | v>>2) & 0x0F0F0F0F0F0F0F0F
+	v = (v | v>>4) & 0x00FF00FF00FF00FF
+	v = (v | v>>8) & 0x0000FFFF0000FFFF
+	v = (v | v>>16) & 0x00000000FFFFFFFF
+	return uint32(v)
+}
+
+// функция geoEncode - geohash точки из step бит на координату (точка должна быть GeoValid).
+func geoEncode(p GeoPoint, step uint) uint64 {
+	lat := (p.Lat - GeoLatMin) / (GeoLatMax - GeoLatMin) * float64(uint64(1)<<step)
+	lon := (p.Lon - GeoLonMin) / (GeoLonMax - GeoLonMin) * float64(uint64(1)<<step)
+	return spread(uint32(lat)) | spread(uint32(lon))<<1
+}
+
+// структура geoArea — ячейка geohash: границы по долготе и широте.
+type geoArea struct {
+	lonMin, lonMax, latMin, latMax float64
+}
+
+// функция geoDecode - ячейка, которую задаёт geohash из step бит на координату.
+func geoDecode(hash uint64, step uint) geoArea {
+	lat, lon := float64(squash(hash)), float64(squash(hash>>1))
+	cells := float64(uint64(1) << step)
+	return geoArea{
+		lonMin: GeoLonMin + lon/cells*(GeoLonMax-GeoLonMin),
+		lonMax: GeoLonMin + (lon+1)/cells*(GeoLonMax-GeoLonMin),
+		latMin: GeoLatMin + lat/cells*(GeoLatMax-GeoLatMin),
+		latMax: GeoLatMin + (lat+1)/cells*(GeoLatMax-GeoLatMin),
+	}
+}
+
+// функция GeoScore - счёт элемента для точки: 52-битный geohash.
+func GeoScore(p GeoPoint) float64 {
+	return float64(geoEncode(p, geoStep))
+}
+
+// функция GeoFromScore - точка, записанная счётом: центр ячейки geohash.
+func GeoFromScore(score float64) GeoPoint {
+	a := geoDecode(uint64(score), geoStep)
+	return GeoPoint{
+		Lon: min(max((a.lonMin+a.lonMax)/2, GeoLonMin), GeoLonMax),
+		Lat: min(max((a.latMin+a.latMax)/2, GeoLatMin), GeoLatMax),
+	}
+}
+
+// функция degRad - градусы в радианы.
+func degRad(deg float64) float64 { return deg * math.Pi / 180 }
+
+// функция radDeg - радианы в градусы.
+func radDeg(rad float64) float64 { return rad * 180 / math.Pi }
+
+// функция latDistance - расстояние в метрах между двумя широтами по меридиану.
+func latDistance(lat1, lat2 float64) float64 {
+	return earthRadius * math.Abs(degRad(lat2)-degRad(lat1))
+}
+
+// функция GeoDistance - расстояние в метрах между точками по формуле гаверсинусов (как в Redis).
+func GeoDistance(a, b GeoPoint) float64 {
+	v := math.Sin((degRad(b.Lon) - degRad(a.Lon)) / 2)
+	if v == 0 {
+		return latDistance(a.Lat, b.Lat)
+	}
+	u := math.Sin((degRad(b.Lat) - degRad(a.Lat)) / 2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(u*u+math.Cos(degRad(a.Lat))*math.Cos(degRad(b.Lat))*v*v))
+}
+
+// структура GeoShape — область поиска вокруг центра: круг радиуса Radius
+// или (если Box) прямоугольник Width × Height; все размеры — в метрах.
+type GeoShape struct {
+	Box           bool
+	Radius        float64
+	Width, Height float64
+}
+
+// метод contains - попадает ли точка p в область с центром c; второе значение — расстояние до центра.
+func (sh GeoShape) contains(c, p GeoPoint) (float64, bool) {
+	if !sh.Box {
+		d := GeoDistance(c, p)
+		return d, d <= sh.Radius
+	}
+	// сначала дешёвая проверка по широте
+	if latDistance(p.Lat, c.Lat) > sh.Height/2 {
+		return 0, false
+	}
+	if GeoDistance(GeoPoint{c.Lon, p.Lat}, p) > sh.Width/2 {
+		return 0, false
+	}
+	return GeoDistance(c, p), true
+}
+
+// метод bounds - прямоугольник в градусах, описанный вокруг области: мин. долгота, мин. широта, макс. долгота, макс. широта.
+func (sh GeoShape) bounds(c GeoPoint) (lonMin, latMin, lonMax, latMax float64) {
+	height, width := sh.Radius, sh.Radius
+	if sh.Box {
+		height, width = sh.Height/2, sh.Width/2
+	}
+	latDelta := radDeg(height / earthRadius)
+	lonDeltaTop := radDeg(width / earthRadius / math.Cos(degRad(c.Lat+latDelta)))
+	lonDeltaBottom := radDeg(width / earthRadius / math.Cos(degRad(c.Lat-latDelta)))
+	// в южном полушарии область шире у верхнего края, в северном — у нижнего
+	lonDelta := lonDeltaTop
+	if c.Lat < 0 {
+		lonDelta = lonDeltaBottom
+	}
+	return c.Lon - lonDelta, c.Lat - latDelta, c.Lon + lonDelta, c.Lat + latDelta
+}
+
+// функция geoStepsForRadius - сколько бит на координату взять, чтобы ячейка была не меньше области поиска.
+func geoStepsForRadius(meters, lat float64) uint {
+	if meters == 0 {
+		return geoStep
+	}
+	step := 1
+	for meters < mercatorMax {
+		meters *= 2
+		step++
+	}
+	step -= 2 // чтобы область почти всегда помещалась в ячейку
+	// к полюсам ячейки сужаются по долготе
+	if lat > 66 || lat < -66 {
+		step--
+		if lat > 80 || lat < -80 {
+			step--
+		}
+	}
+	return uint(min(max(step, 1), geoStep))
+}
+
+// функция geoMove - geohash соседней ячейки: dx — на восток (+1) или запад (-1), dy — на север или юг.
+func geoMove(hash uint64, step uint, dx, dy int) uint64 {
+	const evens, odds = 0x5555555555555555, 0xaaaaaaaaaaaaaaaa
+	lon, lat := hash&odds, hash&evens
+	if dx != 0 {
+		zz := uint64(evens) >> (64 - step*2)
+		if dx > 0 {
+			lon += zz + 1
+		} else {
+			lon = (lon | zz) - (zz + 1)
+		}
+		lon &= odds >> (64 - step*2)
+	}
+	if dy != 0 {
+		zz := uint64(odds) >> (64 - step*2)
+		if dy > 0 {
+			lat += zz + 1
+		} else {
+			lat = (lat | zz) - (zz + 1)
+		}
+		lat &= evens >> (64 - step*2)
+	}
+	return lon | lat
+}
+
+// функция geoSearchAreas - ячейки (geohash из step бит), покрывающие область поиска: ячейка центра
+// и те из восьми соседних, что пересекаются с описанным прямоугольником (как geohashCalculateAreasByShapeWGS84).
+func geoSearchAreas(c GeoPoint, sh GeoShape) ([]uint64, uint) {
+	lonMin, latMin, lonMax, latMax := sh.bounds(c)
+	radius := sh.Radius
+	if sh.Box {
+		radius = math.Hypot(sh.Width/2, sh.Height/2) // до угла прямоугольника
+	}
+	step := geoStepsForRadius(radius, c.Lat)
+	hash := geoEncode(c, step)
+
+	// у края ячейки соседние могут не закрыть область целиком — тогда берём ячейки крупнее
+	if step > 1 {
+		north := geoDecode(geoMove(hash, step, 0, 1), step)
+		south := geoDecode(geoMove(hash, step, 0, -1), step)
+		east := geoDecode(geoMove(hash, step, 1, 0), step)
+		west := geoDecode(geoMove(hash, step, -1, 0), step)
+		if north.latMax < latMax || south.latMin > latMin || east.lonMax < lonMax || west.lonMin > lonMin {
+			step--
+			hash = geoEncode(c, step)
+		}
+	}
+
+	area := geoDecode(hash, step)
+	areas := []uint64{hash}
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			if dx == 0 && dy == 0 {
+				continue
+			}
+			// соседи, целиком лежащие за описанным прямоугольником, не нужны
+			if step >= 2 && (dy < 0 && area.latMin < latMin || dy > 0 && area.latMax > latMax ||
+				dx < 0 && area.lonMin < lonMin || dx > 0 && area.lonMax > lonMax) {
+				continue
+			}
+			if n := geoMove(hash, step, dx, dy); !slices.Contains(areas, n) {
+				areas = append(areas, n)
+			}
+		}
+	}
+	return areas, step
+}
+
+// структура GeoQuery — параметры GEOSEARCH: центр (точка Center или, если ByMember, элемент FromMember), область,
+// порядок по расстоянию (Sort: 1 — по возрастанию, -1 — по убыванию, 0 — без сортировки)
+// и не больше Count результатов (0 — все); Any — остановиться на первых Count найденных.
+type GeoQuery struct {
+	Center     GeoPoint
+	FromMember string
+	ByMember   bool
+	Shape      GeoShape
+	Sort       int
+	Count      int
+	Any        bool
+}
+
+// структура GeoResult — найденный элемент: счёт (geohash), координаты и расстояние до центра в метрах.
+type GeoResult struct {
+	Member string
+	Score  float64
+	Point  GeoPoint
+	Dist   float64
+}
+
+// функция geoSearch - поиск по упорядоченному множеству z (вызывается под блокировкой ключа).
+func geoSearch(z *zset, q GeoQuery) ([]GeoResult, error) {
+	center := q.Center
+	if q.ByMember {
+		score, ok := z.dict[q.FromMember]
+		if !ok {
+			return nil, ErrGeoMember
+		}
+		center = GeoFromScore(score)
+	}
+
+	areas, step := geoSearchAreas(center, q.Shape)
+	out := []GeoResult{}
+	for _, hash := range areas {
+		// все 52-битные хеши с этим префиксом — полуинтервал счетов
+		shift := 2 * (geoStep - step)
+		r := ScoreRange{Min: float64(hash << shift), Max: float64((hash + 1) << shift), MaxEx: true}
+		for x := z.first(scoreRange(r)); x != nil && r.belowMax(x.score); x = x.level[0].forward {
+			p := GeoFromScore(x.score)
+			if dist, ok := q.Shape.contains(center, p); ok {
+				out = append(out, GeoResult{Member: x.member, Score: x.score, Point: p, Dist: dist})
+				if q.Any && len(out) == q.Count {
+					break
+				}
+			}
+		}
+		if q.Any && len(out) == q.Count {
+			break
+		}
+	}
+
+	// COUNT без ANY отдаёт ближайшие, поэтому без явного порядка сортируем по возрастанию
+	sortDir := q.Sort
+	if q.Count > 0 && !q.Any && sortDir == 0 {
+		sortDir = 1
+	}
+	if sortDir != 0 {
+		slices.SortStableFunc(out, func(a, b GeoResult) int {
+			if a.Dist == b.Dist {
+				return 0
+			}
+			if (a.Dist < b.Dist) == (sortDir > 0) {
+				return -1
+			}
+			return 1
+		})
+	}
+	if q.Count > 0 && len(out) > q.Count {
+		out = out[:q.Count]
+	}
+	return out, nil
+}
+
+// метод GeoSearch - элементы геоиндекса key в области q (GEOSEARCH); отсутствующий ключ — пустой результат.
+func (s *Store) GeoSearch(key string, q GeoQuery) ([]GeoResult, error) {
+	defer s.rlock(key)()
+	z, err := s.readZset(key)
+	if err != nil || z == nil {
+		return []GeoResult{}, err
+	}
+	return geoSearch(z, q)
+}
+
+// метод GeoSearchStore - атомарно записывает результат поиска по src в упорядоченное множество dest
+// (GEOSEARCHSTORE) и возвращает число элементов. Счёт — geohash, а при storeDist — расстояние
+// до центра, делённое на unit (метров в единице запроса). Пустой результат удаляет dest.
+func (s *Store) GeoSearchStore(dest, src string, q GeoQuery, storeDist bool, unit float64) (int, error) {
+	defer s.lock(dest, src)()
+	z, err := s.zsetFor(src, false)
+	if err != nil {
+		return 0, err
+	}
+	var res []GeoResult
+	if z != nil {
+		if res, err = geoSearch(z, q); err != nil {
+			return 0, err
+		}
+	}
+
+	s.expireIfNeeded(dest)
+	_, existed := s.get(dest)
+	s.remove(dest)
+	if len(res) == 0 {
+		if existed {
+			s.touch(dest)
+		}
+		return 0, nil
+	}
+	out := newZset()
+	for _, r := range res {
+		score := r.Score
+		if storeDist {
+			score = r.Dist / unit
+		}
+		out.set(r.Member, score)
+	}
+	s.put(dest, out)
+	s.touch(dest)
+	return len(res), nil
+}
@@ -0,0 +1,138 @@
+package store
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+)
+
+// sicily — точки из примеров документации Redis
+var sicily = map[string]GeoPoint{
+	"Palermo": {13.361389, 38.115556},
+	"Catania": {15.087269, 37.502669},
+	"edge1":   {12.758489, 38.788135},
+	"edge2":   {17.241510, 38.788135},
+}
+
+// geoAdd добавляет точки в геоиндекс так же, как GEOADD
+func geoAdd(s *Store, key string, points map[string]GeoPoint) {
+	items := []ZMember{}
+	for name, p := range points {
+		items = append(items, ZMember{name, GeoScore(p)})
+	}
+	s.ZAdd(key, ZAddFlags{}, items)
+}
+
+// проверяет кодирование в 52-битный geohash и обратно, а также расстояние — на значениях из документации Redis
+func TestGeoEncoding(t *testing.T) {
+	if h := GeoScore(sicily["Palermo"]); h != 3479099956230698 {
+		t.Fatalf("Palermo geohash: got %.0f", h)
+	}
+	if h := GeoScore(sicily["Catania"]); h != 3479447370796909 {
+		t.Fatalf("Catania geohash: got %.0f", h)
+	}
+	p := GeoFromScore(3479099956230698)
+	if got := strconv.FormatFloat(p.Lon, 'f', 17, 64); got != "13.36138933897018433" {
+		t.Fatalf("decoded longitude: got %s", got)
+	}
+	if got := strconv.FormatFloat(p.Lat, 'f', 17, 64); got != "38.11555639549629859" {
+		t.Fatalf("decoded latitude: got %s", got)
+	}
+	d := GeoDistance(GeoFromScore(GeoScore(sicily["Palermo"])), GeoFromScore(GeoScore(sicily["Catania"])))
+	if got := strconv.FormatFloat(d, 'f', 4, 64); got != "166274.1516" {
+		t.Fatalf("distance Palermo-Catania: got %s", got)
+	}
+}
+
+// проверяет поиск в круге и прямоугольнике, сортировку и COUNT
+func TestGeoSearch(t *testing.T) {
+	s := NewStore()
+	geoAdd(s, "sicily", sicily)
+	center := GeoPoint{15, 37}
+
+	res, _ := s.GeoSearch("sicily", GeoQuery{Center: center, Shape: GeoShape{Radius: 200_000}, Sort: 1})
+	if len(res) != 2 || res[0].Member != "Catania" || res[1].Member != "Palermo" {
+		t.Fatalf("BYRADIUS 200 km ASC: got %+v", res)
+	}
+
+	res, _ = s.GeoSearch("sicily", GeoQuery{Center: center, Shape: GeoShape{Box: true, Width: 400_000, Height: 400_000}, Sort: -1})
+	want := []string{"edge1", "edge2", "Palermo", "Catania"}
+	if len(res) != len(want) {
+		t.Fatalf("BYBOX 400x400 km: got %+v", res)
+	}
+	for i, name := range want {
+		if res[i].Member != name {
+			t.Fatalf("BYBOX DESC: position %d is %s, want %s", i, res[i].Member, name)
+		}
+	}
+	if got := strconv.FormatFloat(res[0].Dist/1000, 'f', 4, 64); got != "279.7405" {
+		t.Fatalf("distance to edge1: got %s km", got)
+	}
+
+	res, _ = s.GeoSearch("sicily", GeoQuery{Center: center, Shape: GeoShape{Radius: 300_000}, Count: 1})
+	if len(res) != 1 || res[0].Member != "Catania" {
+		t.Fatalf("COUNT 1 must return the nearest member, got %+v", res)
+	}
+	res, _ = s.GeoSearch("sicily", GeoQuery{FromMember: "Palermo", ByMember: true, Shape: GeoShape{Radius: 1}})
+	if len(res) != 1 || res[0].Member != "Palermo" || res[0].Dist != 0 {
+		t.Fatalf("FROMMEMBER with tiny radius: got %+v", res)
+	}
+	if _, err := s.GeoSearch("sicily", GeoQuery{FromMember: "Rome", ByMember: true, Shape: GeoShape{Radius: 1}}); !errors.Is(err, ErrGeoMember) {
+		t.Fatalf("FROMMEMBER of unknown member: expected ErrGeoMember, got %v", err)
+	}
+}
+
+// сравнивает поиск по ячейкам geohash с полным перебором на сетке точек, в том числе у полюсов и линии перемены дат
+func TestGeoSearchMatchesBruteForce(t *testing.T) {
+	s := NewStore()
+	points := map[string]GeoPoint{}
+	// долгота ровно 180 даёт хеш за пределами 52 бит (как и в Redis), поэтому сетка кончается раньше
+	for lon := -180.0; lon < 180; lon += 7.5 {
+		for lat := -85.0; lat <= 85; lat += 2.5 {
+			points[strconv.FormatFloat(lon, 'f', 1, 64)+","+strconv.FormatFloat(lat, 'f', 1, 64)] = GeoPoint{lon, lat}
+		}
+	}
+	geoAdd(s, "grid", points)
+
+	for _, c := range []GeoPoint{{0, 0}, {179, 10}, {-179.5, -40}, {30, 82}, {-100, -84}} {
+		for _, shape := range []GeoShape{{Radius: 500_000}, {Radius: 3_000_000}, {Box: true, Width: 2_000_000, Height: 800_000}} {
+			res, _ := s.GeoSearch("grid", GeoQuery{Center: c, Shape: shape})
+			found := map[string]bool{}
+			for _, r := range res {
+				found[r.Member] = true
+			}
+			for name, p := range points {
+				_, inside := shape.contains(c, GeoFromScore(GeoScore(p)))
+				if inside != found[name] {
+					t.Fatalf("center %v, shape %+v: point %s inside=%v, found=%v", c, shape, name, inside, found[name])
+				}
+			}
+		}
+	}
+}
+
+// проверяет, что GEOSEARCHSTORE записывает geohash или расстояние и удаляет ключ при пустом результате
+func TestGeoSearchStore(t *testing.T) {
+	s := NewStore()
+	geoAdd(s, "sicily", sicily)
+	q := GeoQuery{Center: GeoPoint{15, 37}, Shape: GeoShape{Radius: 200_000}}
+
+	if n, _ := s.GeoSearchStore("near", "sicily", q, false, 1); n != 2 {
+		t.Fatalf("GEOSEARCHSTORE: got %d", n)
+	}
+	if score, _, _ := s.ZScore("near", "Palermo"); score != 3479099956230698 {
+		t.Fatalf("expected geohash score, got %f", score)
+	}
+	s.GeoSearchStore("near", "sicily", q, true, 1000)
+	if score, _, _ := s.ZScore("near", "Catania"); math.Abs(score-56.4413) > 0.0001 {
+		t.Fatalf("STOREDIST: expected distance in km, got %f", score)
+	}
+	q.Shape.Radius = 1
+	if n, _ := s.GeoSearchStore("near", "sicily", q, false, 1); n != 0 {
+		t.Fatalf("empty search: got %d", n)
+	}
+	if _, ok, _ := s.ZScore("near", "Catania"); ok {
+		t.Fatal("empty GEOSEARCHSTORE result must delete the destination")
+	}
+}
@@ -0,0 +1,282 @@
+package store
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// ошибки команд над хешами; тексты совпадают с ответами Redis
+var (
+	ErrHashNotInteger = errors.New("ERR hash value is not an integer")
+	ErrHashNotFloat   = errors.New("ERR hash value is not a float")
+	ErrOverflow       = errors.New("ERR increment or decrement would overflow")
+	ErrNaNOrInfinity  = errors.New("ERR increment would produce NaN or Infinity")
+)
+
+// тип hash — значение-хеш: набор полей со строковыми значениями.
+type hash map[string]string
+
+// метод hashFor - возвращает хеш по ключу (вызывается под s.lock(key)).
+// Если ключа нет и create — создаёт пустой хеш, иначе возвращает nil.
+func (s *Store) hashFor(key string, create bool) (hash, error) {
+	s.expireIfNeeded(key)
+	val, ok := s.get(key)
+	if !ok {
+		if !create {
+			return nil, nil
+		}
+		h := make(hash)
+		s.put(key, h)
+		return h, nil
+	}
+	h, isHash := val.(hash)
+	if !isHash {
+		return nil, ErrWrongType
+	}
+	return h, nil
+}
+
+// метод readHash - возвращает хеш по ключу для чтения (вызывается под s.rlock(key)).
+// nil без ошибки — ключа нет.
+func (s *Store) readHash(key string) (hash, error) {
+	val, ok := s.lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	h, isHash := val.(hash)
+	if !isHash {
+		return nil, ErrWrongType
+	}
+	return h, nil
+}
+
+// метод HSet - задаёт поля хеша из пар поле/значение, создавая хеш при необходимости.
+// Возвращает число новых полей (уже существовавшие просто перезаписываются).
+func (s *Store) HSet(key string, pairs ...string) (int, error) {
+	defer s.lock(key)()
+	h, err := s.hashFor(key, true)
+	if err != nil {
+		return 0, err
+	}
+	added := 0
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if _, ok := h[pairs[i]]; !ok {
+			added++
+		}
+		h[pairs[i]] = pairs[i+1]
+	}
+	s.touch(key)
+	return added, nil
+}
+
+// метод HSetNX - задаёт поле, только если его ещё нет. Возвращает true, если поле создано.
+func (s *Store) HSetNX(key, field, value string) (bool, error) {
+	defer s.lock(key)()
+	h, err := s.hashFor(key, false)
+	if err != nil {
+		return false, err
+	}
+	if _, ok := h[field]; ok {
+		return false, nil
+	}
+	if h == nil {
+		h, _ = s.hashFor(key, true)
+	}
+	h[field] = value
+	s.touch(key)
+	return true, nil
+}
+
+// метод HGet - значение поля хеша. Второе значение false, если нет ключа или поля.
+func (s *Store) HGet(key, field string) (string, bool, error) {
+	defer s.rlock(key)()
+	h, err := s.readHash(key)
+	if err != nil {
+		return "", false, err
+	}
+	val, ok := h[field]
+	return val, ok, nil
+}
+
+// метод HMGet - значения нескольких полей; отсутствующие поля — nil.
+func (s *Store) HMGet(key string, fields ...string) ([]*string, error) {
+	defer s.rlock(key)()
+	h, err := s.readHash(key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*string, len(fields))
+	for i, field := range fields {
+		if val, ok := h[field]; ok {
+			out[i] = &val
+		}
+	}
+	return out, nil
+}
+
+// метод HDel - удаляет поля хеша и возвращает, сколько реально удалено.
+// Хеш без полей удаляется вместе с ключом.
+func (s *Store) HDel(key string, fields ...string) (int, error) {
+	defer s.lock(key)()
+	h, err := s.hashFor(key, false)
+	if err != nil || h == nil {
+		return 0, err
+	}
+	removed := 0
+	for _, field := range fields {
+		if _, ok := h[field]; ok {
+			delete(h, field)
+			removed++
+		}
+	}
+	if removed > 0 {
+		s.touch(key)
+	}
+	if len(h) == 0 {
+		s.remove(key)
+	}
+	return removed, nil
+}
+
+// метод HGetAll - все поля и значения хеша плоским списком: поле, значение, поле, значение...
+func (s *Store) HGetAll(key string) ([]string, error) {
+	defer s.rlock(key)()
+	h, err := s.readHash(key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, 2*len(h))
+	for field, val := range h {
+		out = append(out, field, val)
+	}
+	return out, nil
+}
+
+// метод HKeys - имена всех полей хеша.
+func (s *Store) HKeys(key string) ([]string, error) {
+	defer s.rlock(key)()
+	h, err := s.readHash(key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(h))
+	for field := range h {
+		out = append(out, field)
+	}
+	return out, nil
+}
+
+// метод HVals - значения всех полей хеша.
+func (s *Store) HVals(key string) ([]string, error) {
+	defer s.rlock(key)()
+	h, err := s.readHash(key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(h))
+	for _, val := range h {
+		out = append(out, val)
+	}
+	return out, nil
+}
+
+// метод HLen - число полей хеша (0, если ключа нет).
+func (s *Store) HLen(key string) (int, error) {
+	defer s.rlock(key)()
+	h, err := s.readHash(key)
+	return len(h), err
+}
+
+// метод HExists - есть ли поле в хеше.
+func (s *Store) HExists(key, field string) (bool, error) {
+	_, ok, err := s.HGet(key, field)
+	return ok, err
+}
+
+// метод HStrLen - длина значения поля (0, если поля нет).
+func (s *Store) HStrLen(key, field string) (int, error) {
+	val, _, err := s.HGet(key, field)
+	return len(val), err
+}
+
+// метод HIncrBy - увеличивает целое значение поля на incr (отсутствующее поле считается нулём).
+// Ошибки: ErrHashNotInteger — в поле не целое число, ErrOverflow — результат не помещается в int64.
+func (s *Store) HIncrBy(key, field string, incr int64) (int64, error) {
+	defer s.lock(key)()
+	h, err := s.hashFor(key, false)
+	if err != nil {
+		return 0, err
+	}
+	var cur int64
+	if val, ok := h[field]; ok {
+		cur, err = strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return 0, ErrHashNotInteger
+		}
+	}
+	if (incr > 0 && cur > math.MaxInt64-incr) || (incr < 0 && cur < math.MinInt64-incr) {
+		return 0, ErrOverflow
+	}
+	cur += incr
+	if h == nil {
+		h, _ = s.hashFor(key, true)
+	}
+	h[field] = strconv.FormatInt(cur, 10)
+	s.touch(key)
+	return cur, nil
+}
+
+// метод HIncrByFloat - увеличивает значение поля на дробное incr (строкой, как в команде)
+// и возвращает новое значение. Считает с точностью long double, как Redis (см. float.go).
+// Ошибки: ErrNotFloat — incr не число, ErrHashNotFloat — в поле не число,
+// ErrNaNOrInfinity — результат не конечен.
+func (s *Store) HIncrByFloat(key, field, incr string) (string, error) {
+	delta, ok := parseLongDouble(incr)
+	if !ok {
+		return "", ErrNotFloat
+	}
+	defer s.lock(key)()
+	h, err := s.hashFor(key, false)
+	if err != nil {
+		return "", err
+	}
+	cur := new(big.Float)
+	if val, ok := h[field]; ok {
+		if cur, ok = parseLongDouble(val); !ok {
+			return "", ErrHashNotFloat
+		}
+	}
+	sum, ok := addLongDouble(cur, delta)
+	if !ok {
+		return "", ErrNaNOrInfinity
+	}
+	if h == nil {
+		h, _ = s.hashFor(key, true)
+	}
+	res := formatLongDouble(sum)
+	h[field] = res
+	s.touch(key)
+	return res, nil
+}
+
+// метод HScan - порция полей хеша для курсорного обхода (см. scanNames).
+// Возвращает пары поле/значение плоским списком и курсор следующего вызова (0 — обход закончен).
+func (s *Store) HScan(key string, cursor uint64, count int, match string) ([]string, uint64, error) {
+	defer s.rlock(key)()
+	h, err := s.readHash(key)
+	if err != nil || h == nil {
+		return []string{}, 0, err
+	}
+	fields := make([]string, 0, len(h))
+	for field := range h {
+		fields = append(fields, field)
+	}
+	page, next := scanNames(fields, cursor, count, match)
+	out := make([]string, 0, 2*len(page))
+	for _, field := range page {
+		out = append(out, field, h[field])
+	}
+	return out, next, nil
+}
@@ -0,0 +1,133 @@
+package store
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// проверяет HSET/HGET/HDEL и удаление хеша без полей
+func TestStore_HashSetDel(t *testing.T) {
+	s := NewStore()
+	if n, _ := s.HSet("h", "a", "1", "b", "2"); n != 2 {
+		t.Errorf("expected 2 new fields, got %d", n)
+	}
+	if n, _ := s.HSet("h", "a", "10", "c", "3"); n != 1 {
+		t.Errorf("expected 1 new field, got %d", n)
+	}
+	if v, ok, _ := s.HGet("h", "a"); !ok || v != "10" {
+		t.Errorf("expected '10', got %q (ok=%v)", v, ok)
+	}
+	if n, _ := s.HLen("h"); n != 3 {
+		t.Errorf("expected 3 fields, got %d", n)
+	}
+
+	got, _ := s.HGetAll("h")
+	pairs := make([]string, 0, len(got)/2)
+	for i := 0; i < len(got); i += 2 {
+		pairs = append(pairs, got[i]+"="+got[i+1])
+	}
+	sort.Strings(pairs)
+	if want := []string{"a=10", "b=2", "c=3"}; !reflect.DeepEqual(pairs, want) {
+		t.Errorf("expected %v, got %v", want, pairs)
+	}
+
+	if n, _ := s.HDel("h", "a", "b", "missing"); n != 2 {
+		t.Errorf("expected 2 removed fields, got %d", n)
+	}
+	s.HDel("h", "c")
+	if ttl := s.TTL("h"); ttl != -2 {
+		t.Errorf("expected empty hash to be deleted, TTL=%d", ttl)
+	}
+}
+
+// проверяет HINCRBY/HINCRBYFLOAT, в том числе переполнение и нечисловые значения
+func TestStore_HashIncr(t *testing.T) {
+	s := NewStore()
+	if n, _ := s.HIncrBy("h", "n", 5); n != 5 {
+		t.Errorf("expected 5, got %d", n)
+	}
+	s.HSet("h", "max", strconv.FormatInt(math.MaxInt64, 10), "str", "abc")
+	if _, err := s.HIncrBy("h", "max", 1); !errors.Is(err, ErrOverflow) {
+		t.Errorf("expected ErrOverflow, got %v", err)
+	}
+	if _, err := s.HIncrBy("h", "str", 1); !errors.Is(err, ErrHashNotInteger) {
+		t.Errorf("expected ErrHashNotInteger, got %v", err)
+	}
+
+	// как в Redis (long double): 1.1 + 2.2 = 3.3, а не 3.3000000000000003
+	if v, _ := s.HIncrByFloat("h", "f", "1.1"); v != "1.1" {
+		t.Errorf("expected '1.1', got %q", v)
+	}
+	if v, _ := s.HIncrByFloat("h", "f", "2.2"); v != "3.3" {
+		t.Errorf("expected '3.3', got %q", v)
+	}
+	if v, _ := s.HIncrByFloat("h", "f", "-3.3"); v != "0" {
+		t.Errorf("expected '0', got %q", v)
+	}
+	if v, _ := s.HIncrByFloat("h", "e", "5.0e3"); v != "5000" {
+		t.Errorf("expected '5000', got %q", v)
+	}
+	if _, err := s.HIncrByFloat("h", "f", "1e4933"); !errors.Is(err, ErrNotFloat) {
+		t.Errorf("expected ErrNotFloat, got %v", err)
+	}
+	s.HSet("h", "huge", "1e4932")
+	if _, err := s.HIncrByFloat("h", "huge", "1e4932"); !errors.Is(err, ErrNaNOrInfinity) {
+		t.Errorf("expected ErrNaNOrInfinity, got %v", err)
+	}
+	if _, err := s.HIncrByFloat("h", "f", "nan"); !errors.Is(err, ErrNotFloat) {
+		t.Errorf("expected ErrNotFloat, got %v", err)
+	}
+	if _, err := s.HIncrByFloat("h", "str", "1"); !errors.Is(err, ErrHashNotFloat) {
+		t.Errorf("expected ErrHashNotFloat, got %v", err)
+	}
+}
+
+// проверяет, что HSCAN обходит все поля по порциям и фильтрует по шаблону
+func TestStore_HScan(t *testing.T) {
+	s := NewStore()
+	for i := 0; i < 100; i++ {
+		s.HSet("h", "f"+strconv.Itoa(i), strconv.Itoa(i))
+	}
+
+	seen := make(map[string]string)
+	var cursor uint64
+	for calls := 0; ; calls++ {
+		if calls > 100 {
+			t.Fatalf("scan did not finish")
+		}
+		items, next, err := s.HScan("h", cursor, 7, "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for i := 0; i < len(items); i += 2 {
+			seen[items[i]] = items[i+1]
+		}
+		if cursor = next; cursor == 0 {
+			break
+		}
+	}
+	if len(seen) != 100 || seen["f42"] != "42" {
+		t.Errorf("expected all 100 fields, got %d", len(seen))
+	}
+
+	items, next, _ := s.HScan("h", 0, 1000, "f1?")
+	if next != 0 || len(items) != 20 {
+		t.Errorf("expected 10 matching fields in one call, got %v (next=%d)", items, next)
+	}
+}
+
+// проверяет WRONGTYPE для хеш-команд к ключу другого типа
+func TestStore_HashWrongType(t *testing.T) {
+	s := NewStore()
+	s.Set("str", "x")
+	if _, err := s.HSet("str", "a", "1"); !errors.Is(err, ErrWrongType) {
+		t.Errorf("expected ErrWrongType, got %v", err)
+	}
+	if _, _, err := s.HGet("str", "a"); !errors.Is(err, ErrWrongType) {
+		t.Errorf("expected ErrWrongType, got %v", err)
+	}
+}
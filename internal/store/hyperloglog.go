@@ -0,0 +1,436 @@
+package store
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
+)
+
+// HyperLogLog (PFADD, PFCOUNT, PFMERGE) — приблизительный подсчёт различных элементов
+// в 12 КБ памяти со стандартной ошибкой 0.81%.
+//
+// Как и в Redis, HyperLogLog хранится обычной строкой в том же формате байт, что и hyperloglog.c,
+// поэтому значение, прочитанное GET из Redis, можно записать сюда SET (и наоборот):
+//
+//	"HYLL" | кодировка (0 — dense, 1 — sparse) | 3 байта | 8 байт кэша оценки (little endian)
+//
+// Регистров 2^14, в каждом — длина серии нулей хеша элемента плюс один. Dense — регистры по 6 бит подряд
+// (младшие биты — вперёд), sparse — серии одинаковых регистров опкодами ZERO/XZERO/VAL. Новое значение
+// создаётся sparse и переходит в dense, когда регистр превышает 32 или представление вырастает
+// больше hllSparseMaxBytes. Старший бит последнего байта кэша означает, что кэш устарел.
+
+const (
+	hllP              = 14
+	hllQ              = 64 - hllP
+	hllRegisters      = 1 << hllP
+	hllBits           = 6
+	hllHeaderSize     = 16
+	hllDenseSize      = hllHeaderSize + (hllRegisters*hllBits+7)/8
+	hllDense          = 0
+	hllSparse         = 1
+	hllSparseMaxBytes = 3000 // hll-sparse-max-bytes в Redis
+	hllSeed           = 0xadc83b19
+
+	hllXZeroBit      = 0x40
+	hllValBit        = 0x80
+	hllValMaxValue   = 32
+	hllValMaxLen     = 4
+	hllZeroMaxLen    = 64
+	hllXZeroMaxLen   = 16384
+	hllAlphaInf      = 0.721347520444481703680 // 0.5/ln(2)
+	hllCacheInvalid  = 1 << 7
+	hllCacheLastByte = 15
+)
+
+// ошибки HyperLogLog; тексты совпадают с ответами Redis
+var (
+	ErrNotHLL     = errors.New("WRONGTYPE Key is not a valid HyperLogLog string value.")
+	ErrHLLCorrupt = errors.New("INVALIDOBJ Corrupted HLL object detected")
+)
+
+// hllRegs — значения всех регистров, распакованные по байту на регистр.
+type hllRegs [hllRegisters]uint8
+
+// функция murmurHash64A - хеш MurmurHash64A, которым Redis распределяет элементы по регистрам.
+func murmurHash64A(key string, seed uint64) uint64 {
+	const m, r = 0xc6a4a7935bd1e995, 47
+	h := seed ^ uint64(len(key))*m
+	n := len(key) &^ 7
+	for i := 0; i < n; i += 8 {
+		var k uint64
+		for j := 7; j >= 0; j-- { // little endian, как читает Redis на x86
+			k = k<<8 | uint64(key[i+j])
+		}
+		k *= m
+		k ^= k >> r
+		k *= m
+		h ^= k
+		h *= m
+	}
+	if tail := key[n:]; len(tail) > 0 {
+		for i := len(tail) - 1; i >= 0; i-- {
+			h ^= uint64(tail[i]) << (8 * i)
+		}
+		h *= m
+	}
+	h ^= h >> r
+	h *= m
+	h ^= h >> r
+	return h
+}
+
+// функция hllPatLen - номер регистра элемента и значение для него: длина серии нулевых битов хеша плюс один.
+func hllPatLen(elem string) (int, uint8) {
+	h := murmurHash64A(elem, hllSeed)
+	index := int(h & (hllRegisters - 1))
+	h >>= hllP
+	h |= 1 << hllQ // серия не длиннее Q
+	return index, uint8(bits.TrailingZeros64(h) + 1)
+}
+
+// функция checkHLL - проверяет, что строка — HyperLogLog (как isHLLObjectOrReply в Redis).
+func checkHLL(str string) error {
+	if len(str) < hllHeaderSize || str[:4] != "HYLL" || str[4] > hllSparse ||
+		(str[4] == hllDense && len(str) != hllDenseSize) {
+		return ErrNotHLL
+	}
+	return nil
+}
+
+// функция hllDenseGet - регистр regnum из dense-представления (регистры без заголовка).
+func hllDenseGet(p []byte, regnum int) uint8 {
+	b := regnum * hllBits / 8
+	fb := uint(regnum * hllBits & 7)
+	v := p[b] >> fb
+	if b+1 < len(p) {
+		v |= p[b+1] << (8 - fb)
+	}
+	return v & (1<<hllBits - 1)
+}
+
+// функция hllDenseSet - записывает регистр regnum в dense-представление.
+func hllDenseSet(p []byte, regnum int, val uint8) {
+	b := regnum * hllBits / 8
+	fb := uint(regnum * hllBits & 7)
+	p[b] &^= (1<<hllBits - 1) << fb
+	p[b] |= val << fb
+	if b+1 < len(p) {
+		p[b+1] &^= (1<<hllBits - 1) >> (8 - fb)
+		p[b+1] |= val >> (8 - fb)
+	}
+}
+
+// функция hllDecode - распаковывает регистры HyperLogLog; ErrHLLCorrupt — sparse-опкоды не покрывают
+// ровно все регистры.
+func hllDecode(str string, regs *hllRegs) error {
+	if str[4] == hllDense {
+		p := []byte(str[hllHeaderSize:])
+		for i := range regs {
+			regs[i] = hllDenseGet(p, i)
+		}
+		return nil
+	}
+	idx := 0
+	for i := hllHeaderSize; i < len(str); i++ {
+		op := str[i]
+		var run int
+		var val uint8
+		switch {
+		case op&hllValBit != 0:
+			val, run = (op>>2)&0x1f+1, int(op&0x3)+1
+		case op&hllXZeroBit != 0:
+			if i+1 >= len(str) {
+				return ErrHLLCorrupt
+			}
+			run = (int(op&0x3f)<<8 | int(str[i+1])) + 1
+			i++
+		default:
+			run = int(op&0x3f) + 1
+		}
+		if idx+run > hllRegisters {
+			return ErrHLLCorrupt
+		}
+		for j := 0; j < run; j++ {
+			regs[idx+j] = val
+		}
+		idx += run
+	}
+	if idx != hllRegisters {
+		return ErrHLLCorrupt
+	}
+	return nil
+}
+
+// функция hllEncode - упаковывает регистры в строку HyperLogLog с заголовком hdr (кэш оценки помечается устаревшим).
+// Sparse-кодировка выбирается, только если dense не требуется: все регистры не больше 32
+// и представление не длиннее hllSparseMaxBytes.
+func hllEncode(hdr string, regs *hllRegs, dense bool) string {
+	if !dense {
+		if out, ok := hllEncodeSparse(hdr, regs); ok {
+			return out
+		}
+	}
+	buf := make([]byte, hllDenseSize)
+	copy(buf, hdr)
+	buf[4] = hllDense
+	buf[hllCacheLastByte] |= hllCacheInvalid
+	p := buf[hllHeaderSize:]
+	for i, v := range regs {
+		if v != 0 {
+			hllDenseSet(p, i, v)
+		}
+	}
+	return string(buf)
+}
+
+// функция hllEncodeSparse - sparse-представление регистров; false — оно невозможно или слишком длинное.
+func hllEncodeSparse(hdr string, regs *hllRegs) (string, bool) {
+	buf := make([]byte, hllHeaderSize, hllHeaderSize+64)
+	copy(buf, hdr)
+	buf[4] = hllSparse
+	buf[hllCacheLastByte] |= hllCacheInvalid
+	for i := 0; i < hllRegisters; {
+		val := regs[i]
+		run := 1
+		for i+run < hllRegisters && regs[i+run] == val {
+			run++
+		}
+		i += run
+		switch {
+		case val > hllValMaxValue:
+			return "", false
+		case val == 0:
+			for run > 0 {
+				if run > hllZeroMaxLen {
+					n := min(run, hllXZeroMaxLen) - 1
+					buf = append(buf, byte(n>>8)|hllXZeroBit, byte(n))
+					run -= n + 1
+				} else {
+					buf = append(buf, byte(run-1))
+					run = 0
+				}
+			}
+		default:
+			for run > 0 {
+				n := min(run, hllValMaxLen)
+				buf = append(buf, hllValBit|(val-1)<<2|byte(n-1))
+				run -= n
+			}
+		}
+		if len(buf)-hllHeaderSize > hllSparseMaxBytes {
+			return "", false
+		}
+	}
+	return string(buf), true
+}
+
+// функция newHLL - пустой HyperLogLog: sparse, один опкод XZERO на все регистры.
+func newHLL() string {
+	var regs hllRegs
+	str, _ := hllEncodeSparse("HYLL", &regs)
+	return str
+}
+
+// функция hllSigma - вспомогательная функция оценки Ertl'а (hllSigma в Redis).
+func hllSigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+	y, z := 1.0, x
+	for {
+		x *= x
+		prev := z
+		z += x * y
+		y += y
+		if prev == z {
+			return z
+		}
+	}
+}
+
+// функция hllTau - вспомогательная функция оценки Ertl'а (hllTau в Redis).
+func hllTau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+	y, z := 1.0, 1-x
+	for {
+		x = math.Sqrt(x)
+		prev := z
+		y *= 0.5
+		z -= (1 - x) * (1 - x) * y
+		if prev == z {
+			return z / 3
+		}
+	}
+}
+
+// функция hllCount - оценка числа различных элементов по регистрам (улучшенная оценка Ertl'а, как в Redis 5+).
+func hllCount(regs *hllRegs) int64 {
+	var histo [64]int
+	for _, v := range regs {
+		histo[v]++
+	}
+	m := float64(hllRegisters)
+	z := m * hllTau((m-float64(histo[hllQ+1]))/m)
+	for j := hllQ; j >= 1; j-- {
+		z += float64(histo[j])
+		z *= 0.5
+	}
+	z += m * hllSigma(float64(histo[0])/m)
+	return int64(math.Round(hllAlphaInf * m * m / z))
+}
+
+// метод hllFor - HyperLogLog ключа для изменения (вызывается под s.lock(key)); false — ключа нет.
+func (s *Store) hllFor(key string) (string, bool, error) {
+	str, exists, err := s.stringFor(key)
+	if err != nil || !exists {
+		return "", false, err
+	}
+	return str, true, checkHLL(str)
+}
+
+// метод PFAdd - добавляет элементы в HyperLogLog (создавая его при необходимости) и сообщает,
+// изменилась ли оценка: true — ключ создан или изменился хотя бы один регистр.
+func (s *Store) PFAdd(key string, elems ...string) (bool, error) {
+	defer s.lock(key)()
+	str, exists, err := s.hllFor(key)
+	if err != nil {
+		return false, err
+	}
+	if !exists {
+		str = newHLL()
+	}
+	if str[4] == hllDense {
+		// dense меняем на месте, без распаковки всех регистров
+		buf := []byte(str)
+		p := buf[hllHeaderSize:]
+		updated := false
+		for _, elem := range elems {
+			i, v := hllPatLen(elem)
+			if v > hllDenseGet(p, i) {
+				hllDenseSet(p, i, v)
+				updated = true
+			}
+		}
+		if !updated {
+			return false, nil
+		}
+		buf[hllCacheLastByte] |= hllCacheInvalid
+		s.put(key, string(buf))
+		s.touch(key)
+		return true, nil
+	}
+	var regs hllRegs
+	if err := hllDecode(str, &regs); err != nil {
+		return false, err
+	}
+	updated := !exists
+	for _, elem := range elems {
+		i, v := hllPatLen(elem)
+		if v > regs[i] {
+			regs[i] = v
+			updated = true
+		}
+	}
+	if !updated {
+		return false, nil
+	}
+	s.put(key, hllEncode(str[:hllHeaderSize], &regs, false))
+	s.touch(key)
+	return true, nil
+}
+
+// метод PFCount - оценка числа различных элементов. Для одного ключа оценка кэшируется в заголовке
+// (это не считается изменением ключа), для нескольких — HyperLogLog объединяются на лету без записи.
+// Отсутствующий ключ считается пустым.
+func (s *Store) PFCount(keys ...string) (int64, error) {
+	if len(keys) == 1 {
+		return s.pfCountOne(keys[0])
+	}
+	defer s.rlock(keys...)()
+	var merged, regs hllRegs
+	for _, key := range keys {
+		val, ok := s.lookup(key)
+		if !ok {
+			continue
+		}
+		str, isString := val.(string)
+		if !isString {
+			return 0, ErrWrongType
+		}
+		if err := checkHLL(str); err != nil {
+			return 0, err
+		}
+		if err := hllDecode(str, &regs); err != nil {
+			return 0, err
+		}
+		for i, v := range regs {
+			merged[i] = max(merged[i], v)
+		}
+	}
+	return hllCount(&merged), nil
+}
+
+// метод pfCountOne - PFCOUNT одного ключа: оценка из кэша, а если он устарел — пересчёт и запись в кэш.
+func (s *Store) pfCountOne(key string) (int64, error) {
+	defer s.lock(key)()
+	s.expireIfNeeded(key)
+	val, ok := s.lookup(key)
+	if !ok {
+		return 0, nil
+	}
+	str, isString := val.(string)
+	if !isString {
+		return 0, ErrWrongType
+	}
+	if err := checkHLL(str); err != nil {
+		return 0, err
+	}
+	if str[hllCacheLastByte]&hllCacheInvalid == 0 {
+		return int64(binary.LittleEndian.Uint64([]byte(str[8:hllHeaderSize]))), nil
+	}
+	var regs hllRegs
+	if err := hllDecode(str, &regs); err != nil {
+		return 0, err
+	}
+	n := hllCount(&regs)
+	buf := []byte(str)
+	binary.LittleEndian.PutUint64(buf[8:hllHeaderSize], uint64(n))
+	s.put(key, string(buf))
+	return n, nil
+}
+
+// метод PFMerge - объединяет HyperLogLog srcs и прежнее значение dest и записывает результат в dest.
+// Как в Redis, результат остаётся sparse, только если ни один из объединяемых не был dense.
+func (s *Store) PFMerge(dest string, srcs ...string) error {
+	defer s.lock(append([]string{dest}, srcs...)...)()
+	var merged, regs hllRegs
+	dense := false
+	hdr := "HYLL"
+	for i, key := range append([]string{dest}, srcs...) {
+		str, exists, err := s.hllFor(key)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			continue
+		}
+		if i == 0 {
+			hdr = str[:hllHeaderSize]
+		}
+		if str[4] == hllDense {
+			dense = true
+		}
+		if err := hllDecode(str, &regs); err != nil {
+			return err
+		}
+		for j, v := range regs {
+			merged[j] = max(merged[j], v)
+		}
+	}
+	s.put(dest, hllEncode(hdr, &merged, dense))
+	s.touch(dest)
+	return nil
+}
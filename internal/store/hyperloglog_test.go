@@ -0,0 +1,110 @@
+package store
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+)
+
+// проверяет, что оценка держится в пределах нескольких стандартных ошибок на разных мощностях
+func TestPFCountAccuracy(t *testing.T) {
+	s := NewStore()
+	added := 0
+	for _, n := range []int{10, 1000, 20000, 200000} {
+		batch := []string{}
+		for ; added < n; added++ {
+			batch = append(batch, "user:"+strconv.Itoa(added))
+		}
+		s.PFAdd("hll", batch...)
+		got, err := s.PFCount("hll")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rel := math.Abs(float64(got)-float64(n)) / float64(n); rel > 0.03 {
+			t.Fatalf("PFCOUNT for %d elements: got %d (error %.2f%%)", n, got, rel*100)
+		}
+	}
+}
+
+// проверяет формат значения: пустой HyperLogLog — sparse с одним XZERO, переход в dense и кэш оценки
+func TestPFAddEncoding(t *testing.T) {
+	s := NewStore()
+	if ok, _ := s.PFAdd("hll"); !ok {
+		t.Fatal("PFADD without elements must create the key")
+	}
+	if ok, _ := s.PFAdd("hll"); ok {
+		t.Fatal("PFADD without elements on existing key must report no change")
+	}
+	v, _ := s.Get("hll")
+	if want := "HYLL\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x80\x7f\xff"; v != want {
+		t.Fatalf("empty HLL: got %q, want %q", v, want)
+	}
+	if ok, _ := s.PFAdd("hll", "a", "b", "c"); !ok {
+		t.Fatal("PFADD of new elements must report a change")
+	}
+	if ok, _ := s.PFAdd("hll", "a"); ok {
+		t.Fatal("PFADD of a known element must report no change")
+	}
+	if n, _ := s.PFCount("hll"); n != 3 {
+		t.Fatalf("PFCOUNT: got %d", n)
+	}
+	v, _ = s.Get("hll")
+	if v[15]&0x80 != 0 || v[8] != 3 {
+		t.Fatalf("expected PFCOUNT to cache the estimate in the header, got %q", v[:16])
+	}
+
+	for i := 0; i < 5000; i++ {
+		s.PFAdd("hll", strconv.Itoa(i))
+	}
+	v, _ = s.Get("hll")
+	if v[4] != hllDense || len(v) != hllDenseSize {
+		t.Fatalf("expected the HLL to become dense, encoding %d, length %d", v[4], len(v))
+	}
+}
+
+// проверяет, что PFCOUNT по нескольким ключам и PFMERGE считают объединение
+func TestPFMerge(t *testing.T) {
+	s := NewStore()
+	for i := 0; i < 1000; i++ {
+		s.PFAdd("a", "x"+strconv.Itoa(i))
+		s.PFAdd("b", "x"+strconv.Itoa(i+500))
+	}
+	union, _ := s.PFCount("a", "b", "missing")
+	if union < 1450 || union > 1550 {
+		t.Fatalf("PFCOUNT over two keys: got %d, want about 1500", union)
+	}
+	if err := s.PFMerge("dest", "a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := s.PFCount("dest"); n != union {
+		t.Fatalf("PFMERGE: got %d, want %d like PFCOUNT over the sources", n, union)
+	}
+	if v, _ := s.Get("dest"); v[4] != hllSparse {
+		t.Fatal("merge of sparse HLLs must stay sparse")
+	}
+	s.PFMerge("empty")
+	if n, _ := s.PFCount("empty"); n != 0 {
+		t.Fatalf("PFMERGE without sources must create an empty HLL, got %d", n)
+	}
+}
+
+// проверяет ошибки: строка не HyperLogLog, повреждённое значение и ключ другого типа
+func TestPFErrors(t *testing.T) {
+	s := NewStore()
+	s.Set("str", "hello")
+	if _, err := s.PFAdd("str", "a"); !errors.Is(err, ErrNotHLL) {
+		t.Fatalf("PFADD on plain string: expected ErrNotHLL, got %v", err)
+	}
+	if _, err := s.PFCount("str"); !errors.Is(err, ErrNotHLL) {
+		t.Fatalf("PFCOUNT on plain string: expected ErrNotHLL, got %v", err)
+	}
+	s.Set("broken", "HYLL\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x80\x7f\xfe")
+	if _, err := s.PFCount("broken"); !errors.Is(err, ErrHLLCorrupt) {
+		t.Fatalf("PFCOUNT on corrupted sparse HLL: expected ErrHLLCorrupt, got %v", err)
+	}
+	s.RPush("list", "a")
+	if err := s.PFMerge("dest", "list"); !errors.Is(err, ErrWrongType) {
+		t.Fatalf("PFMERGE from a list: expected ErrWrongType, got %v", err)
+	}
+}
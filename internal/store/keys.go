@@ -0,0 +1,211 @@
+package store
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// lazyFreeThreshold — с какого числа элементов UNLINK разбирает значение в фоне, а не сразу (как в Redis).
+const lazyFreeThreshold = 64
+
+// структура lazyFreeStats — счётчики фонового освобождения значений (UNLINK), видны в INFO.
+type lazyFreeStats struct {
+	pending atomic.Int64 // значения, которые ещё ждут разбора
+	freed   atomic.Int64 // значения, разобранные в фоне с момента запуска
+}
+
+// метод Exists - сколько из перечисленных ключей существует; повторы считаются столько раз, сколько указаны.
+// Обращением к ключу не считается.
+func (s *Store) Exists(keys ...string) int {
+	defer s.rlock(keys...)()
+	now := time.Now()
+	n := 0
+	for _, key := range keys {
+		if s.aliveLocked(key, now) {
+			n++
+		}
+	}
+	return n
+}
+
+// метод Touch - отмечает обращение к ключам (для LRU/LFU) и возвращает, сколько из них существует.
+func (s *Store) Touch(keys ...string) int {
+	defer s.rlock(keys...)()
+	n := 0
+	for _, key := range keys {
+		if _, ok := s.lookup(key); ok {
+			n++
+		}
+	}
+	return n
+}
+
+// метод Type - имя типа значения ключа ("string", "list", "hash", "set", "zset", "stream") или "none".
+func (s *Store) Type(key string) string {
+	defer s.rlock(key)()
+	if !s.aliveLocked(key, time.Now()) {
+		return "none"
+	}
+	val, _ := s.get(key)
+	return typeName(val)
+}
+
+// метод Rename - атомарно переименовывает ключ src в dst вместе с его TTL; прежнее значение dst
+// (любого типа) заменяется. Если nx (RENAMENX) и dst уже существует — ничего не меняется и возвращается false.
+// ErrNoSuchKey — ключа src нет.
+func (s *Store) Rename(src, dst string, nx bool) (bool, error) {
+	defer s.lock(src, dst)()
+	s.expireIfNeeded(src)
+	s.expireIfNeeded(dst)
+	val, ok := s.get(src)
+	if !ok {
+		return false, ErrNoSuchKey
+	}
+	_, exists := s.get(dst)
+	if nx && exists {
+		return false, nil
+	}
+	if src == dst {
+		return true, nil
+	}
+
+	at, hasTTL := s.ttlOf(src)
+	s.remove(src)
+	s.remove(dst)
+	s.put(dst, val)
+	if hasTTL {
+		s.setTTL(dst, at)
+	}
+	s.touch(src)
+	s.touch(dst)
+	return true, nil
+}
+
+// метод Copy - записывает в dst независимую копию значения src вместе с TTL.
+// Если dst уже существует, копия делается только при replace. false — ключа src нет или dst занят.
+func (s *Store) Copy(src, dst string, replace bool) bool {
+	return s.CopyTo(s, src, dst, replace)
+}
+
+// метод CopyTo - то же, что Copy, но копия записывается в базу db (COPY ... DB); db может совпадать с s.
+func (s *Store) CopyTo(db *Store, src, dst string, replace bool) bool {
+	defer lockAcross(s, src, db, dst)()
+	s.expireIfNeeded(src)
+	db.expireIfNeeded(dst)
+	val, ok := s.get(src)
+	if !ok {
+		return false
+	}
+	if _, exists := db.get(dst); exists && !replace {
+		return false
+	}
+
+	at, hasTTL := s.ttlOf(src)
+	db.remove(dst)
+	db.put(dst, cloneValue(val))
+	if hasTTL {
+		db.setTTL(dst, at)
+	}
+	db.touch(dst)
+	return true
+}
+
+// метод Move - переносит ключ вместе с TTL в другую базу db (MOVE).
+// false — ключа нет или в базе db ключ с таким именем уже есть.
+func (s *Store) Move(key string, db *Store) bool {
+	if s == db {
+		return false
+	}
+	defer lockAcross(s, key, db, key)()
+	s.expireIfNeeded(key)
+	db.expireIfNeeded(key)
+	val, ok := s.get(key)
+	if !ok {
+		return false
+	}
+	if _, exists := db.get(key); exists {
+		return false
+	}
+
+	at, hasTTL := s.ttlOf(key)
+	s.remove(key)
+	db.put(key, val)
+	if hasTTL {
+		db.setTTL(key, at)
+	}
+	s.touch(key)
+	db.touch(key)
+	return true
+}
+
+// функция cloneValue - независимая копия значения: через вид для снимка, который умеет копировать любой тип.
+func cloneValue(val any) any {
+	clone, _ := importValue(exportValue(val))
+	return clone
+}
+
+// метод Unlink - как Del, но большие значения (от lazyFreeThreshold элементов) разбираются в фоновой горутине:
+// ключи исчезают сразу, а обход огромной коллекции не задерживает команду и других клиентов.
+func (s *Store) Unlink(keys ...string) int {
+	var large []any
+	count := s.del(keys, func(val any) {
+		if valueLen(val) >= lazyFreeThreshold {
+			large = append(large, val)
+		}
+	})
+	if len(large) > 0 {
+		s.lazyFree.pending.Add(int64(len(large)))
+		go func() {
+			for _, val := range large {
+				freeValue(val)
+				s.lazyFree.pending.Add(-1)
+				s.lazyFree.freed.Add(1)
+			}
+		}()
+	}
+	return count
+}
+
+// метод LazyFreeStats - сколько значений ждут фонового разбора и сколько уже разобрано (INFO).
+func (s *Store) LazyFreeStats() (pending, freed int64) {
+	return s.lazyFree.pending.Load(), s.lazyFree.freed.Load()
+}
+
+// функция valueLen - число элементов значения (у строки — 1).
+func valueLen(val any) int {
+	switch v := val.(type) {
+	case *list:
+		return v.len()
+	case hash:
+		return len(v)
+	case set:
+		return len(v)
+	case *zset:
+		return len(v.dict)
+	case *stream:
+		return len(v.entries)
+	}
+	return 1
+}
+
+// функция freeValue - разбирает значение, удалённое из хранилища: очищает его внутренние карты и срезы,
+// чтобы после этого сборщику мусора не приходилось обходить миллионы элементов, на которые ещё ссылается значение.
+// Вызывается, когда значение уже недостижимо из хранилища, поэтому блокировки не нужны.
+func freeValue(val any) {
+	switch v := val.(type) {
+	case *list:
+		clear(v.buf)
+		v.buf, v.head, v.n = nil, 0, 0
+	case hash:
+		clear(v)
+	case set:
+		clear(v)
+	case *zset:
+		clear(v.dict)
+		v.zsl = newSkiplist()
+	case *stream:
+		clear(v.entries)
+		v.entries = nil
+		clear(v.groups)
+	}
+}
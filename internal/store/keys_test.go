@@ -0,0 +1,114 @@
+package store
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// проверяет, что RENAME переносит значение вместе с TTL, а RENAMENX не трогает существующий ключ
+func TestStore_Rename(t *testing.T) {
+	s := NewStore()
+	if _, err := s.Rename("missing", "x", false); !errors.Is(err, ErrNoSuchKey) {
+		t.Fatalf("RENAME of missing key: expected ErrNoSuchKey, got %v", err)
+	}
+
+	s.RPush("src", "a", "b")
+	at := time.Now().Add(time.Hour)
+	s.ExpireAt("src", at)
+	s.Set("dst", "old")
+	if ok, err := s.Rename("src", "dst", false); !ok || err != nil {
+		t.Fatalf("RENAME: got %v, %v", ok, err)
+	}
+	if n := s.Exists("src"); n != 0 {
+		t.Fatal("source key must be gone after RENAME")
+	}
+	if items, _ := s.LRange("dst", 0, -1); len(items) != 2 {
+		t.Fatalf("expected list to move to dst, got %v", items)
+	}
+	if got, ok := s.ExpireTime("dst"); !ok || !got.Equal(at) {
+		t.Fatalf("expected TTL %v to move with the key, got %v (%v)", at, got, ok)
+	}
+
+	s.Set("other", "v")
+	if ok, _ := s.Rename("other", "dst", true); ok {
+		t.Fatal("RENAMENX must not overwrite an existing key")
+	}
+	if v, _ := s.Get("other"); v != "v" {
+		t.Fatal("RENAMENX that failed must keep the source")
+	}
+}
+
+// проверяет, что COPY делает независимую копию с TTL и без REPLACE не трогает занятый ключ
+func TestStore_Copy(t *testing.T) {
+	s := NewStore()
+	s.SAdd("src", "a", "b")
+	s.ExpireAt("src", time.Now().Add(time.Hour))
+	if !s.Copy("src", "dst", false) {
+		t.Fatal("COPY to a free key must succeed")
+	}
+	s.SAdd("dst", "c")
+	if n, _ := s.SCard("src"); n != 2 {
+		t.Fatalf("changing the copy must not change the source, SCARD src = %d", n)
+	}
+	if _, ok := s.ExpireTime("dst"); !ok {
+		t.Fatal("COPY must copy the TTL")
+	}
+	if s.Copy("src", "dst", false) {
+		t.Fatal("COPY without REPLACE must not overwrite")
+	}
+	if !s.Copy("src", "dst", true) {
+		t.Fatal("COPY REPLACE must overwrite")
+	}
+	if n, _ := s.SCard("dst"); n != 2 {
+		t.Fatalf("after COPY REPLACE expected 2 members, got %d", n)
+	}
+	if s.Copy("missing", "dst", true) {
+		t.Fatal("COPY of a missing key must fail")
+	}
+}
+
+// проверяет EXISTS с повторами, TYPE и TOUCH
+func TestStore_ExistsTypeTouch(t *testing.T) {
+	s := NewStore()
+	s.Set("str", "v")
+	s.ZAdd("z", ZAddFlags{}, []ZMember{{"m", 1}})
+	if n := s.Exists("str", "str", "missing", "z"); n != 3 {
+		t.Fatalf("EXISTS str str missing z: expected 3, got %d", n)
+	}
+	for key, want := range map[string]string{"str": "string", "z": "zset", "missing": "none"} {
+		if got := s.Type(key); got != want {
+			t.Fatalf("TYPE %s: expected %s, got %s", key, want, got)
+		}
+	}
+	if n := s.Touch("str", "missing"); n != 1 {
+		t.Fatalf("TOUCH: expected 1, got %d", n)
+	}
+}
+
+// проверяет, что UNLINK сразу убирает ключи, а большие значения разбирает в фоне
+func TestStore_Unlink(t *testing.T) {
+	s := NewStore()
+	for i := 0; i < 1000; i++ {
+		s.HSet("big", "f"+strconv.Itoa(i), "v")
+	}
+	s.Set("small", "v")
+	if n := s.Unlink("big", "small", "missing"); n != 2 {
+		t.Fatalf("UNLINK: expected 2, got %d", n)
+	}
+	if n := s.Exists("big", "small"); n != 0 {
+		t.Fatal("keys must be gone right after UNLINK")
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		pending, freed := s.LazyFreeStats()
+		if pending == 0 && freed == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the big hash to be freed in background: pending %d, freed %d", pending, freed)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
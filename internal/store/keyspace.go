@@ -0,0 +1,253 @@
+package store
+
+import (
+	"cmp"
+	"math/bits"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AntonRadchenko/mini-redis-go/internal/glob"
+)
+
+// Обход всего пространства ключей (SCAN, KEYS, RANDOMKEY, DBSIZE).
+//
+// Курсор SCAN состоит из двух частей: в старших битах — номер шарда, в остальных — позиция внутри шарда
+// в порядке scanHash (см. scan.go). Шард ключа не меняется, пока работает сервер, а позиция — это хеш,
+// а не индекс в какой-то структуре, поэтому вставки, удаления и перестройка индекса между вызовами
+// не сдвигают курсор: каждый ключ, существовавший всё время обхода, будет выдан хотя бы один раз.
+// Чтобы не сортировать на каждом вызове весь шард, у шарда есть keyIndex — ключи, разложенные
+// по корзинам по старшим битам хеша.
+
+const (
+	keyIndexMinBits = 4 // у пустого шарда 16 корзин
+	keyIndexLoad    = 8 // индекс удваивается, когда ключей в среднем больше стольких на корзину
+)
+
+// структура keyIndex — ключи шарда, разложенные по корзинам: в корзине i лежат ключи,
+// у которых старшие bits битов scanHash равны i. Корзины идут в порядке хешей,
+// а при удвоении каждая делится на две соседние, поэтому позиция обхода остаётся верной при любом размере.
+type keyIndex struct {
+	bits    int
+	buckets []map[string]uint64 // ключ → его scanHash
+	n       int                 // число ключей
+}
+
+// конструктор newKeyIndex создаёт пустой индекс.
+func newKeyIndex() *keyIndex {
+	return &keyIndex{bits: keyIndexMinBits, buckets: make([]map[string]uint64, 1<<keyIndexMinBits)}
+}
+
+// метод bucketOf - номер корзины для хеша.
+func (ix *keyIndex) bucketOf(h uint64) int {
+	return int(h >> (64 - ix.bits))
+}
+
+// метод add - добавляет новый ключ (вызывается под блокировкой шарда на запись).
+func (ix *keyIndex) add(key string) {
+	if ix.n >= len(ix.buckets)*keyIndexLoad {
+		ix.grow()
+	}
+	h := scanHash(key)
+	b := ix.bucketOf(h)
+	if ix.buckets[b] == nil {
+		ix.buckets[b] = make(map[string]uint64)
+	}
+	ix.buckets[b][key] = h
+	ix.n++
+}
+
+// метод remove - удаляет ключ из индекса (вызывается под блокировкой шарда на запись).
+func (ix *keyIndex) remove(key string) {
+	b := ix.buckets[ix.bucketOf(scanHash(key))]
+	if _, ok := b[key]; ok {
+		delete(b, key)
+		ix.n--
+	}
+}
+
+// метод grow - удваивает число корзин: корзина i делится на корзины 2i и 2i+1.
+func (ix *keyIndex) grow() {
+	ix.bits++
+	buckets := make([]map[string]uint64, 1<<ix.bits)
+	for _, b := range ix.buckets {
+		for key, h := range b {
+			nb := ix.bucketOf(h)
+			if buckets[nb] == nil {
+				buckets[nb] = make(map[string]uint64)
+			}
+			buckets[nb][key] = h
+		}
+	}
+	ix.buckets = buckets
+}
+
+// структура scanItem — ключ и его позиция в порядке обхода шарда.
+type scanItem struct {
+	pos uint64
+	key string
+}
+
+// метод scan - порция ключей шарда с позиции from: позиция ключа — его scanHash без младших shift битов
+// (они заняты номером шарда в курсоре). Порция содержит не меньше count ключей, если столько осталось,
+// и не разрывает ключи с одинаковой позицией. Возвращает ключи и позицию следующей порции;
+// done — шард пройден до конца.
+func (ix *keyIndex) scan(from uint64, count int, shift uint) (items []scanItem, next uint64, done bool) {
+	b := ix.bucketOf(from << shift)
+	for ; b < len(ix.buckets); b++ {
+		for key, h := range ix.buckets[b] {
+			if pos := h >> shift; pos >= from {
+				items = append(items, scanItem{pos, key})
+			}
+		}
+		// ключи следующих корзин идут строго после всех собранных, поэтому целых корзин достаточно
+		if len(items) >= count {
+			break
+		}
+	}
+	slices.SortFunc(items, func(a, b scanItem) int {
+		if a.pos != b.pos {
+			return cmp.Compare(a.pos, b.pos)
+		}
+		return strings.Compare(a.key, b.key)
+	})
+
+	n := min(count, len(items))
+	for n > 0 && n < len(items) && items[n].pos == items[n-1].pos {
+		n++
+	}
+	switch {
+	case n < len(items):
+		return items[:n], items[n].pos, false
+	case b >= len(ix.buckets)-1:
+		return items, 0, true
+	default: // забрали всё собранное — продолжим со следующей корзины
+		return items, uint64(b+1) << (64 - ix.bits) >> shift, false
+	}
+}
+
+// функция typeName - имя типа значения, как его называет Redis (TYPE, SCAN ... TYPE).
+func typeName(val any) string {
+	switch val.(type) {
+	case string:
+		return "string"
+	case *list:
+		return "list"
+	case hash:
+		return "hash"
+	case set:
+		return "set"
+	case *zset:
+		return "zset"
+	case *stream:
+		return "stream"
+	}
+	return "none"
+}
+
+// метод Scan - порция ключей для курсорного обхода всего хранилища (SCAN).
+// match — glob-шаблон имени (пустой — все), typ — имя типа значения (пустой — любой).
+// Фильтры применяются после выбора порции, поэтому порция может оказаться пустой при ненулевом курсоре.
+// Курсор 0 означает и начало, и конец обхода.
+func (s *Store) Scan(cursor uint64, count int, match, typ string) ([]string, uint64) {
+	if count <= 0 {
+		count = defaultScanCount
+	}
+	shift := uint(bits.Len(uint(len(s.shards))) - 1) // сколько старших битов курсора занимает номер шарда
+	first := int(cursor >> (64 - shift))
+	from := cursor << shift >> shift
+
+	out := []string{}
+	scanned := 0
+	for i := first; i < len(s.shards); i++ {
+		sh := s.shards[i]
+		now := time.Now()
+		sh.mtx.RLock()
+		items, next, done := sh.index.scan(from, count-scanned, shift)
+		for _, it := range items {
+			if !s.aliveLocked(it.key, now) {
+				continue
+			}
+			if match != "" && !glob.Match(match, it.key) {
+				continue
+			}
+			if typ != "" && typeName(sh.data[it.key]) != typ {
+				continue
+			}
+			out = append(out, it.key)
+		}
+		sh.mtx.RUnlock()
+
+		if !done {
+			return out, uint64(i)<<(64-shift) | next
+		}
+		from = 0
+		scanned += len(items)
+		if scanned >= count && i+1 < len(s.shards) { // порция набрана — следующий вызов начнёт со следующего шарда
+			return out, uint64(i+1) << (64 - shift)
+		}
+	}
+	return out, 0
+}
+
+// метод Keys - все живые ключи, подходящие под glob-шаблон (KEYS). Шарды просматриваются по очереди,
+// так что ключ, существовавший всё время вызова, попадёт в ответ.
+func (s *Store) Keys(pattern string) []string {
+	out := []string{}
+	for _, sh := range s.shards {
+		now := time.Now()
+		sh.mtx.RLock()
+		for key := range sh.data {
+			if s.aliveLocked(key, now) && (pattern == "*" || glob.Match(pattern, key)) {
+				out = append(out, key)
+			}
+		}
+		sh.mtx.RUnlock()
+	}
+	return out
+}
+
+// randomKeyTries — сколько раз RandomKey пробует найти живой ключ, прежде чем сдаться
+// (например, если почти все ключи уже истекли, но ещё не удалены).
+const randomKeyTries = 100
+
+// метод RandomKey - случайный живой ключ (RANDOMKEY); false, если ключей нет.
+func (s *Store) RandomKey() (string, bool) {
+	for try := 0; try < randomKeyTries; try++ {
+		var key string
+		alive := false
+		found := s.sampleKeys(false, 1, func(sh *shard, k string) {
+			key, alive = k, s.aliveLocked(k, time.Now())
+		})
+		if !found {
+			return "", false
+		}
+		if alive {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// метод DBSize - число ключей в хранилище (DBSIZE). Как и в Redis, сюда входят ключи,
+// которые уже истекли, но ещё не удалены.
+func (s *Store) DBSize() int {
+	n := 0
+	for _, sh := range s.shards {
+		sh.mtx.RLock()
+		n += len(sh.data)
+		sh.mtx.RUnlock()
+	}
+	return n
+}
+
+// метод Expires - сколько ключей базы имеют TTL (для INFO keyspace).
+func (s *Store) Expires() int {
+	n := 0
+	for _, sh := range s.shards {
+		sh.mtx.RLock()
+		n += len(sh.ttl)
+		sh.mtx.RUnlock()
+	}
+	return n
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// scanAll проходит всё хранилище курсором и возвращает, сколько раз встретился каждый ключ;
+// между вызовами выполняется between
+func scanAll(s *Store, count int, match, typ string, between func()) map[string]int {
+	seen := make(map[string]int)
+	cursor := uint64(0)
+	for {
+		keys, next := s.Scan(cursor, count, match, typ)
+		for _, key := range keys {
+			seen[key]++
+		}
+		if next == 0 {
+			return seen
+		}
+		cursor = next
+		between()
+	}
+}
+
+// проверяет, что SCAN выдаёт каждый ключ, существовавший всё время обхода, хотя между вызовами
+// добавляются и удаляются тысячи других ключей (индексы шардов при этом перестраиваются)
+func TestStore_ScanUnderWrites(t *testing.T) {
+	for _, shards := range []int{1, DefaultShards} {
+		s := NewShardedStore(shards)
+		for i := 0; i < 1000; i++ {
+			s.Set("stable:"+strconv.Itoa(i), "v")
+		}
+		next := 0
+		seen := scanAll(s, 10, "", "", func() {
+			for j := 0; j < 50; j++ {
+				s.Set("new:"+strconv.Itoa(next), "v")
+				s.Del("new:" + strconv.Itoa(next-25))
+				next++
+			}
+		})
+		for i := 0; i < 1000; i++ {
+			if seen["stable:"+strconv.Itoa(i)] == 0 {
+				t.Fatalf("shards=%d: key stable:%d was never returned", shards, i)
+			}
+		}
+	}
+}
+
+// проверяет фильтры MATCH и TYPE и то, что истёкшие ключи не выдаются
+func TestStore_ScanFilters(t *testing.T) {
+	s := NewStore()
+	for i := 0; i < 20; i++ {
+		s.Set("user:"+strconv.Itoa(i), "v")
+	}
+	s.RPush("user:list", "a")
+	s.HSet("user:hash", "f", "v")
+	s.Set("other", "v")
+	s.SetWith("user:gone", "v", SetOptions{ExpireAt: time.Now().Add(time.Millisecond)})
+	time.Sleep(5 * time.Millisecond)
+
+	if seen := scanAll(s, 3, "user:*", "", func() {}); len(seen) != 22 {
+		t.Fatalf("MATCH user:*: expected 22 keys, got %d", len(seen))
+	}
+	seen := scanAll(s, 100, "", "list", func() {})
+	if len(seen) != 1 || seen["user:list"] != 1 {
+		t.Fatalf("TYPE list: expected only user:list, got %v", seen)
+	}
+	if keys, next := s.Scan(0, 1000, "", ""); next != 0 || len(keys) != 23 {
+		t.Fatalf("single call with large COUNT: got %d keys, cursor %d", len(keys), next)
+	}
+}
+
+// проверяет KEYS, RANDOMKEY и DBSIZE
+func TestStore_KeysRandomDBSize(t *testing.T) {
+	s := NewStore()
+	if _, ok := s.RandomKey(); ok {
+		t.Fatal("RANDOMKEY on an empty store must return nothing")
+	}
+	for _, key := range []string{"hello", "hallo", "hxllo", "heeeello", "h*llo"} {
+		s.Set(key, "v")
+	}
+	keys := s.Keys(`h[ae]llo`)
+	slices.Sort(keys)
+	if !slices.Equal(keys, []string{"hallo", "hello"}) {
+		t.Fatalf("KEYS h[ae]llo: got %v", keys)
+	}
+	if keys := s.Keys(`h\*llo`); !slices.Equal(keys, []string{"h*llo"}) {
+		t.Fatalf(`KEYS h\*llo: got %v`, keys)
+	}
+	if n := len(s.Keys("*")); n != 5 {
+		t.Fatalf("KEYS *: expected 5, got %d", n)
+	}
+	if key, ok := s.RandomKey(); !ok || !slices.Contains(s.Keys("*"), key) {
+		t.Fatalf("RANDOMKEY: got %q, %v", key, ok)
+	}
+	if n := s.DBSize(); n != 5 {
+		t.Fatalf("DBSIZE: expected 5, got %d", n)
+	}
+}
@@ -0,0 +1,392 @@
+package store
+
+import "errors"
+
+// ошибки команд над списками; тексты совпадают с ответами Redis
+var (
+	ErrNoSuchKey  = errors.New("ERR no such key")
+	ErrOutOfRange = errors.New("ERR index out of range")
+)
+
+// структура list — значение-список: двусторонняя очередь на кольцевом буфере.
+// Вставка и удаление с обоих концов — O(1), доступ по индексу — тоже O(1).
+type list struct {
+	buf  []string
+	head int // индекс первого элемента в buf
+	n    int // число элементов
+}
+
+// конструктор newList создаёт пустой список.
+func newList() *list {
+	return &list{buf: make([]string, 4)}
+}
+
+// метод len - число элементов списка.
+func (l *list) len() int {
+	return l.n
+}
+
+// метод grow - удваивает буфер, раскладывая элементы с начала.
+func (l *list) grow() {
+	buf := make([]string, 2*len(l.buf))
+	for i := 0; i < l.n; i++ {
+		buf[i] = l.at(i)
+	}
+	l.buf, l.head = buf, 0
+}
+
+// метод pos - позиция i-го элемента списка в буфере.
+func (l *list) pos(i int) int {
+	return (l.head + i) % len(l.buf)
+}
+
+// метод at - i-й элемент списка (0 <= i < len).
+func (l *list) at(i int) string {
+	return l.buf[l.pos(i)]
+}
+
+// метод set - заменяет i-й элемент списка.
+func (l *list) set(i int, v string) {
+	l.buf[l.pos(i)] = v
+}
+
+// метод pushFront - добавляет элемент в начало.
+func (l *list) pushFront(v string) {
+	if l.n == len(l.buf) {
+		l.grow()
+	}
+	l.head = (l.head - 1 + len(l.buf)) % len(l.buf)
+	l.buf[l.head] = v
+	l.n++
+}
+
+// метод pushBack - добавляет элемент в конец.
+func (l *list) pushBack(v string) {
+	if l.n == len(l.buf) {
+		l.grow()
+	}
+	l.buf[l.pos(l.n)] = v
+	l.n++
+}
+
+// метод popFront - снимает элемент с начала (список не пуст).
+func (l *list) popFront() string {
+	v := l.buf[l.head]
+	l.buf[l.head] = "" // не держим ссылку на строку
+	l.head = (l.head + 1) % len(l.buf)
+	l.n--
+	return v
+}
+
+// метод popBack - снимает элемент с конца (список не пуст).
+func (l *list) popBack() string {
+	p := l.pos(l.n - 1)
+	v := l.buf[p]
+	l.buf[p] = ""
+	l.n--
+	return v
+}
+
+// метод slice - копия элементов с индексами [start, stop).
+func (l *list) slice(start, stop int) []string {
+	out := make([]string, 0, stop-start)
+	for i := start; i < stop; i++ {
+		out = append(out, l.at(i))
+	}
+	return out
+}
+
+// метод values - копия всех элементов по порядку.
+func (l *list) values() []string {
+	return l.slice(0, l.n)
+}
+
+// функция listRange - переводит индексы Redis (включительные, отрицательные — с конца)
+// в полуинтервал [start, stop) для списка длины n. Пустой диапазон — start == stop.
+func listRange(start, stop, n int) (int, int) {
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	start = max(start, 0)
+	stop = min(stop, n-1)
+	if start > stop || start >= n {
+		return 0, 0
+	}
+	return start, stop + 1
+}
+
+// метод listFor - возвращает список по ключу (вызывается под s.lock(key)).
+// Если ключа нет и create — создаёт пустой список, иначе возвращает nil.
+func (s *Store) listFor(key string, create bool) (*list, error) {
+	s.expireIfNeeded(key)
+	val, ok := s.get(key)
+	if !ok {
+		if !create {
+			return nil, nil
+		}
+		l := newList()
+		s.put(key, l)
+		return l, nil
+	}
+	l, isList := val.(*list)
+	if !isList {
+		return nil, ErrWrongType
+	}
+	return l, nil
+}
+
+// метод readList - возвращает список по ключу для чтения (вызывается под s.rlock(key)).
+// nil без ошибки — ключа нет.
+func (s *Store) readList(key string) (*list, error) {
+	val, ok := s.lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	l, isList := val.(*list)
+	if !isList {
+		return nil, ErrWrongType
+	}
+	return l, nil
+}
+
+// метод dropIfEmpty - удаляет ключ опустевшего списка: в Redis пустых списков не бывает.
+func (s *Store) dropIfEmpty(key string, l *list) {
+	if l.len() == 0 {
+		s.remove(key)
+	}
+}
+
+// метод LPush - добавляет значения в начало списка (по одному, слева направо),
+// создавая список, если ключа нет. Возвращает новую длину списка.
+func (s *Store) LPush(key string, values ...string) (int, error) {
+	return s.push(key, true, false, values)
+}
+
+// метод RPush - добавляет значения в конец списка. Возвращает новую длину списка.
+func (s *Store) RPush(key string, values ...string) (int, error) {
+	return s.push(key, false, false, values)
+}
+
+// метод LPushX - как LPush, но только если список уже существует (иначе возвращает 0).
+func (s *Store) LPushX(key string, values ...string) (int, error) {
+	return s.push(key, true, true, values)
+}
+
+// метод RPushX - как RPush, но только если список уже существует (иначе возвращает 0).
+func (s *Store) RPushX(key string, values ...string) (int, error) {
+	return s.push(key, false, true, values)
+}
+
+// метод push - общая часть LPUSH/RPUSH и их X-вариантов.
+func (s *Store) push(key string, front, onlyExisting bool, values []string) (int, error) {
+	defer s.lock(key)()
+	l, err := s.listFor(key, !onlyExisting)
+	if err != nil || l == nil {
+		return 0, err
+	}
+	for _, v := range values {
+		if front {
+			l.pushFront(v)
+		} else {
+			l.pushBack(v)
+		}
+	}
+	s.touch(key)
+	return l.len(), nil
+}
+
+// метод LPop - снимает до count элементов с начала списка.
+// Возвращает nil, если ключа нет; опустевший список удаляется.
+func (s *Store) LPop(key string, count int) ([]string, error) {
+	return s.pop(key, true, count)
+}
+
+// метод RPop - снимает до count элементов с конца списка.
+func (s *Store) RPop(key string, count int) ([]string, error) {
+	return s.pop(key, false, count)
+}
+
+// метод pop - общая часть LPOP/RPOP.
+func (s *Store) pop(key string, front bool, count int) ([]string, error) {
+	defer s.lock(key)()
+	l, err := s.listFor(key, false)
+	if err != nil || l == nil {
+		return nil, err
+	}
+	out := make([]string, 0, min(count, l.len()))
+	for len(out) < count && l.len() > 0 {
+		if front {
+			out = append(out, l.popFront())
+		} else {
+			out = append(out, l.popBack())
+		}
+	}
+	if len(out) > 0 {
+		s.touch(key)
+	}
+	s.dropIfEmpty(key, l)
+	return out, nil
+}
+
+// метод LLen - длина списка (0, если ключа нет).
+func (s *Store) LLen(key string) (int, error) {
+	defer s.rlock(key)()
+	l, err := s.readList(key)
+	if err != nil || l == nil {
+		return 0, err
+	}
+	return l.len(), nil
+}
+
+// метод LRange - элементы списка с индексами от start до stop включительно
+// (отрицательные индексы считаются с конца: -1 — последний элемент).
+func (s *Store) LRange(key string, start, stop int) ([]string, error) {
+	defer s.rlock(key)()
+	l, err := s.readList(key)
+	if err != nil || l == nil {
+		return []string{}, err
+	}
+	from, to := listRange(start, stop, l.len())
+	return l.slice(from, to), nil
+}
+
+// метод LIndex - элемент списка по индексу (отрицательный — с конца).
+// Второе значение false, если ключа нет или индекс за пределами списка.
+func (s *Store) LIndex(key string, index int) (string, bool, error) {
+	defer s.rlock(key)()
+	l, err := s.readList(key)
+	if err != nil || l == nil {
+		return "", false, err
+	}
+	if index < 0 {
+		index += l.len()
+	}
+	if index < 0 || index >= l.len() {
+		return "", false, nil
+	}
+	return l.at(index), true, nil
+}
+
+// метод LSet - заменяет элемент списка по индексу.
+// Ошибки: ErrNoSuchKey — ключа нет, ErrOutOfRange — индекс за пределами списка.
+func (s *Store) LSet(key string, index int, value string) error {
+	defer s.lock(key)()
+	l, err := s.listFor(key, false)
+	if err != nil {
+		return err
+	}
+	if l == nil {
+		return ErrNoSuchKey
+	}
+	if index < 0 {
+		index += l.len()
+	}
+	if index < 0 || index >= l.len() {
+		return ErrOutOfRange
+	}
+	l.set(index, value)
+	s.touch(key)
+	return nil
+}
+
+// метод LRem - удаляет элементы, равные value:
+// count > 0 — первые count с начала, count < 0 — первые |count| с конца, count == 0 — все.
+// Возвращает число удалённых элементов.
+func (s *Store) LRem(key string, count int, value string) (int, error) {
+	defer s.lock(key)()
+	l, err := s.listFor(key, false)
+	if err != nil || l == nil {
+		return 0, err
+	}
+
+	values := l.values()
+	limit := count
+	if limit < 0 {
+		limit = -limit
+	}
+	keep := make([]bool, len(values))
+	removed := 0
+	for i := range values {
+		idx := i
+		if count < 0 {
+			idx = len(values) - 1 - i // идём с конца
+		}
+		if values[idx] == value && (limit == 0 || removed < limit) {
+			removed++
+			continue
+		}
+		keep[idx] = true
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+
+	rebuilt := newList()
+	for i, v := range values {
+		if keep[i] {
+			rebuilt.pushBack(v)
+		}
+	}
+	s.put(key, rebuilt)
+	s.touch(key)
+	s.dropIfEmpty(key, rebuilt)
+	return removed, nil
+}
+
+// метод LTrim - оставляет в списке только элементы с индексами от start до stop включительно.
+// Если диапазон пуст, ключ удаляется.
+func (s *Store) LTrim(key string, start, stop int) error {
+	defer s.lock(key)()
+	l, err := s.listFor(key, false)
+	if err != nil || l == nil {
+		return err
+	}
+	from, to := listRange(start, stop, l.len())
+	if from == 0 && to == l.len() {
+		return nil // обрезать нечего
+	}
+	for l.len() > to {
+		l.popBack()
+	}
+	for i := 0; i < from; i++ {
+		l.popFront()
+	}
+	s.touch(key)
+	s.dropIfEmpty(key, l)
+	return nil
+}
+
+// метод LMove - атомарно снимает элемент с одного конца списка src и кладёт на конец списка dst
+// (fromLeft/toLeft — с какого конца снимать и на какой класть; src и dst могут совпадать).
+// Второе значение false, если src не существует. Если dst — не список, ничего не меняется и возвращается ErrWrongType.
+func (s *Store) LMove(src, dst string, fromLeft, toLeft bool) (string, bool, error) {
+	defer s.lock(src, dst)()
+	from, err := s.listFor(src, false)
+	if err != nil || from == nil {
+		return "", false, err
+	}
+	if _, err := s.listFor(dst, false); err != nil {
+		return "", false, err
+	}
+
+	var v string
+	if fromLeft {
+		v = from.popFront()
+	} else {
+		v = from.popBack()
+	}
+	s.touch(src)
+	s.dropIfEmpty(src, from)
+
+	to, _ := s.listFor(dst, true)
+	if toLeft {
+		to.pushFront(v)
+	} else {
+		to.pushBack(v)
+	}
+	s.touch(dst)
+	return v, true, nil
+}
@@ -0,0 +1,126 @@
+package store
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// проверяет LPUSH/RPUSH/LRANGE, в том числе рост кольцевого буфера
+func TestStore_PushRange(t *testing.T) {
+	s := NewStore()
+	for i := 0; i < 10; i++ {
+		if _, err := s.RPush("l", string(rune('a'+i))); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if n, _ := s.LPush("l", "x", "y"); n != 12 {
+		t.Errorf("expected length 12, got %d", n)
+	}
+
+	got, _ := s.LRange("l", 0, -1)
+	want := []string{"y", "x", "a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if got, _ := s.LRange("l", -3, 100); !reflect.DeepEqual(got, []string{"h", "i", "j"}) {
+		t.Errorf("unexpected tail range: %v", got)
+	}
+	if got, _ := s.LRange("l", 5, 2); len(got) != 0 {
+		t.Errorf("expected empty range, got %v", got)
+	}
+}
+
+// проверяет LPOP/RPOP и удаление опустевшего списка
+func TestStore_Pop(t *testing.T) {
+	s := NewStore()
+	s.RPush("l", "a", "b", "c")
+
+	if got, _ := s.LPop("l", 1); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("expected [a], got %v", got)
+	}
+	if got, _ := s.RPop("l", 5); !reflect.DeepEqual(got, []string{"c", "b"}) {
+		t.Errorf("expected [c b], got %v", got)
+	}
+	if ttl := s.TTL("l"); ttl != -2 {
+		t.Errorf("expected empty list to be deleted, TTL=%d", ttl)
+	}
+	if got, err := s.LPop("l", 1); got != nil || err != nil {
+		t.Errorf("expected nil for missing key, got %v (err=%v)", got, err)
+	}
+}
+
+// проверяет LINDEX, LSET, LREM и LTRIM
+func TestStore_ListEdit(t *testing.T) {
+	s := NewStore()
+	s.RPush("l", "a", "b", "a", "c", "a")
+
+	if v, ok, _ := s.LIndex("l", -2); !ok || v != "c" {
+		t.Errorf("expected 'c' at -2, got %q (ok=%v)", v, ok)
+	}
+	if err := s.LSet("l", 1, "B"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.LSet("l", 10, "x"); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("expected ErrOutOfRange, got %v", err)
+	}
+	if err := s.LSet("missing", 0, "x"); !errors.Is(err, ErrNoSuchKey) {
+		t.Errorf("expected ErrNoSuchKey, got %v", err)
+	}
+
+	// удаляем одно "a" с конца
+	if n, _ := s.LRem("l", -1, "a"); n != 1 {
+		t.Errorf("expected 1 removed, got %d", n)
+	}
+	if got, _ := s.LRange("l", 0, -1); !reflect.DeepEqual(got, []string{"a", "B", "a", "c"}) {
+		t.Errorf("unexpected list after LREM: %v", got)
+	}
+
+	s.LTrim("l", 1, 2)
+	if got, _ := s.LRange("l", 0, -1); !reflect.DeepEqual(got, []string{"B", "a"}) {
+		t.Errorf("unexpected list after LTRIM: %v", got)
+	}
+	s.LTrim("l", 5, 10)
+	if n, _ := s.LLen("l"); n != 0 {
+		t.Errorf("expected list to be removed by empty LTRIM, got length %d", n)
+	}
+}
+
+// проверяет WRONGTYPE для команд к ключу другого типа
+func TestStore_ListWrongType(t *testing.T) {
+	s := NewStore()
+	s.Set("str", "x")
+	if _, err := s.LPush("str", "a"); !errors.Is(err, ErrWrongType) {
+		t.Errorf("expected ErrWrongType from LPush, got %v", err)
+	}
+	if _, err := s.LRange("str", 0, -1); !errors.Is(err, ErrWrongType) {
+		t.Errorf("expected ErrWrongType from LRange, got %v", err)
+	}
+
+	s.RPush("list", "a")
+	if _, _, err := s.GetString("list"); !errors.Is(err, ErrWrongType) {
+		t.Errorf("expected ErrWrongType from GetString, got %v", err)
+	}
+	// SET перезаписывает значение любого типа
+	s.Set("list", "now a string")
+	if v, ok := s.Get("list"); !ok || v != "now a string" {
+		t.Errorf("expected SET to replace list, got %q (ok=%v)", v, ok)
+	}
+}
+
+// проверяет, что TTL работает и для списков
+func TestStore_ListTTL(t *testing.T) {
+	s := NewStore()
+	s.RPush("l", "a")
+	s.Expire("l", 0)
+	if n, _ := s.LLen("l"); n != 0 {
+		t.Errorf("expected expired list to be invisible, got length %d", n)
+	}
+	// запись в истёкший список начинает новый
+	if n, _ := s.RPush("l", "b"); n != 1 {
+		t.Errorf("expected fresh list of length 1, got %d", n)
+	}
+	if ttl := s.TTL("l"); ttl != -1 {
+		t.Errorf("expected new list without TTL, got %d", ttl)
+	}
+}
@@ -0,0 +1,70 @@
+package store
+
+import (
+	"sort"
+
+	"github.com/AntonRadchenko/mini-redis-go/internal/glob"
+)
+
+// Курсорный обход (SCAN, HSCAN и т.п.) в духе Redis: за один вызов возвращается небольшая порция,
+// а курсор говорит, откуда продолжить. Элементы упорядочены по 64-битному хешу имени, и курсор —
+// это хеш первого ещё не выданного элемента. Поэтому обход не зависит от вставок и удалений между вызовами:
+// каждый элемент, который существовал всё время обхода, будет выдан хотя бы один раз.
+// Курсор 0 означает и начало, и конец обхода.
+
+// defaultScanCount — размер порции, если COUNT не указан (как в Redis).
+const defaultScanCount = 10
+
+// функция scanHash - позиция имени в порядке обхода: 64-битный FNV-1a, посчитанный без выделения памяти
+// (хеш нужен для каждого нового ключа хранилища, см. keyIndex).
+func scanHash(name string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(name); i++ {
+		h ^= uint64(name[i])
+		h *= 1099511628211
+	}
+	return h
+}
+
+// функция scanNames - выбирает из names порцию, начиная с курсора cursor, и возвращает
+// подходящие под шаблон match имена (пустой match — все) и курсор следующего вызова.
+// В порцию попадает не меньше count имён (до фильтрации по шаблону); имена с одинаковым хешем
+// не разрываются между порциями, иначе часть из них можно было бы пропустить.
+func scanNames(names []string, cursor uint64, count int, match string) ([]string, uint64) {
+	if count <= 0 {
+		count = defaultScanCount
+	}
+	type item struct {
+		hash uint64
+		name string
+	}
+	items := make([]item, 0, len(names))
+	for _, name := range names {
+		if h := scanHash(name); h >= cursor {
+			items = append(items, item{h, name})
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].hash != items[j].hash {
+			return items[i].hash < items[j].hash
+		}
+		return items[i].name < items[j].name
+	})
+
+	n := min(count, len(items))
+	for n < len(items) && items[n].hash == items[n-1].hash {
+		n++
+	}
+	var next uint64
+	if n < len(items) {
+		next = items[n].hash
+	}
+
+	out := make([]string, 0, n)
+	for _, it := range items[:n] {
+		if match == "" || glob.Match(match, it.name) {
+			out = append(out, it.name)
+		}
+	}
+	return out, next
+}
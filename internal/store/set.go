@@ -0,0 +1,319 @@
+package store
+
+import "math/rand/v2"
+
+// тип set — значение-множество: уникальные строки без порядка.
+type set map[string]struct{}
+
+// метод members - элементы множества в виде среза (порядок произвольный).
+func (st set) members() []string {
+	out := make([]string, 0, len(st))
+	for m := range st {
+		out = append(out, m)
+	}
+	return out
+}
+
+// метод setFor - возвращает множество по ключу (вызывается под s.lock(key)).
+// Если ключа нет и create — создаёт пустое множество, иначе возвращает nil.
+func (s *Store) setFor(key string, create bool) (set, error) {
+	s.expireIfNeeded(key)
+	val, ok := s.get(key)
+	if !ok {
+		if !create {
+			return nil, nil
+		}
+		st := make(set)
+		s.put(key, st)
+		return st, nil
+	}
+	st, isSet := val.(set)
+	if !isSet {
+		return nil, ErrWrongType
+	}
+	return st, nil
+}
+
+// метод readSet - возвращает множество по ключу для чтения (вызывается под s.rlock(key)).
+// nil без ошибки — ключа нет.
+func (s *Store) readSet(key string) (set, error) {
+	val, ok := s.lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	st, isSet := val.(set)
+	if !isSet {
+		return nil, ErrWrongType
+	}
+	return st, nil
+}
+
+// метод SAdd - добавляет элементы в множество, создавая его при необходимости.
+// Возвращает число реально добавленных (новых) элементов.
+func (s *Store) SAdd(key string, members ...string) (int, error) {
+	defer s.lock(key)()
+	st, err := s.setFor(key, true)
+	if err != nil {
+		return 0, err
+	}
+	added := 0
+	for _, m := range members {
+		if _, ok := st[m]; !ok {
+			st[m] = struct{}{}
+			added++
+		}
+	}
+	if added > 0 {
+		s.touch(key)
+	}
+	return added, nil
+}
+
+// метод SRem - удаляет элементы из множества и возвращает, сколько реально удалено.
+// Опустевшее множество удаляется вместе с ключом.
+func (s *Store) SRem(key string, members ...string) (int, error) {
+	defer s.lock(key)()
+	st, err := s.setFor(key, false)
+	if err != nil || st == nil {
+		return 0, err
+	}
+	removed := 0
+	for _, m := range members {
+		if _, ok := st[m]; ok {
+			delete(st, m)
+			removed++
+		}
+	}
+	if removed > 0 {
+		s.touch(key)
+	}
+	s.dropIfEmptySet(key, st)
+	return removed, nil
+}
+
+// метод dropIfEmptySet - удаляет ключ опустевшего множества (вызывается под s.lock(key)).
+func (s *Store) dropIfEmptySet(key string, st set) {
+	if len(st) == 0 {
+		s.remove(key)
+	}
+}
+
+// метод SMembers - все элементы множества (пустой срез, если ключа нет).
+func (s *Store) SMembers(key string) ([]string, error) {
+	defer s.rlock(key)()
+	st, err := s.readSet(key)
+	if err != nil {
+		return nil, err
+	}
+	return st.members(), nil
+}
+
+// метод SIsMember - входит ли элемент в множество.
+func (s *Store) SIsMember(key, member string) (bool, error) {
+	res, err := s.SMIsMember(key, member)
+	if err != nil {
+		return false, err
+	}
+	return res[0], nil
+}
+
+// метод SMIsMember - входит ли в множество каждый из элементов.
+func (s *Store) SMIsMember(key string, members ...string) ([]bool, error) {
+	defer s.rlock(key)()
+	st, err := s.readSet(key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]bool, len(members))
+	for i, m := range members {
+		_, out[i] = st[m]
+	}
+	return out, nil
+}
+
+// метод SCard - число элементов множества (0, если ключа нет).
+func (s *Store) SCard(key string) (int, error) {
+	defer s.rlock(key)()
+	st, err := s.readSet(key)
+	return len(st), err
+}
+
+// SetOp — операция над множествами для SINTER/SUNION/SDIFF и их *STORE-вариантов.
+type SetOp int
+
+const (
+	SetInter SetOp = iota // пересечение
+	SetUnion              // объединение
+	SetDiff               // разность: элементы первого множества, которых нет в остальных
+)
+
+// функция applySetOp - вычисляет операцию над множествами; отсутствующий ключ — пустое множество (nil).
+func applySetOp(op SetOp, sets []set) set {
+	out := make(set)
+	switch op {
+	case SetInter:
+		// идём по самому маленькому множеству и проверяем остальные
+		smallest := sets[0]
+		for _, st := range sets {
+			if len(st) < len(smallest) {
+				smallest = st
+			}
+		}
+	next:
+		for m := range smallest {
+			for _, st := range sets {
+				if _, ok := st[m]; !ok {
+					continue next
+				}
+			}
+			out[m] = struct{}{}
+		}
+	case SetUnion:
+		for _, st := range sets {
+			for m := range st {
+				out[m] = struct{}{}
+			}
+		}
+	case SetDiff:
+		for m := range sets[0] {
+			out[m] = struct{}{}
+		}
+		for _, st := range sets[1:] {
+			for m := range st {
+				delete(out, m)
+			}
+		}
+	}
+	return out
+}
+
+// метод SetOp - операция над множествами по ключам (SINTER, SUNION, SDIFF).
+// Ключ другого типа — ErrWrongType, даже если результат от него не зависит.
+func (s *Store) SetOp(op SetOp, keys ...string) ([]string, error) {
+	defer s.rlock(keys...)()
+	sets := make([]set, len(keys))
+	for i, key := range keys {
+		st, err := s.readSet(key)
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = st
+	}
+	return applySetOp(op, sets).members(), nil
+}
+
+// метод SetOpStore - как SetOp, но атомарно (под блокировками шардов всех ключей) записывает результат в dst
+// (SINTERSTORE, SUNIONSTORE, SDIFFSTORE). Прежнее значение dst любого типа и его TTL заменяются,
+// пустой результат удаляет dst. Возвращает число элементов результата.
+func (s *Store) SetOpStore(op SetOp, dst string, keys ...string) (int, error) {
+	defer s.lock(append([]string{dst}, keys...)...)()
+	sets := make([]set, len(keys))
+	for i, key := range keys {
+		st, err := s.setFor(key, false)
+		if err != nil {
+			return 0, err
+		}
+		sets[i] = st
+	}
+	res := applySetOp(op, sets)
+
+	s.expireIfNeeded(dst)
+	_, existed := s.get(dst)
+	s.clearTTL(dst)
+	if len(res) == 0 {
+		s.remove(dst)
+	} else {
+		s.put(dst, res)
+	}
+	if existed || len(res) > 0 {
+		s.touch(dst)
+	}
+	return len(res), nil
+}
+
+// метод SRandMember - случайные элементы множества без удаления:
+// count >= 0 — до count разных элементов, count < 0 — ровно |count| элементов, возможно с повторами.
+func (s *Store) SRandMember(key string, count int) ([]string, error) {
+	defer s.rlock(key)()
+	st, err := s.readSet(key)
+	if err != nil || st == nil {
+		return []string{}, err
+	}
+	members := st.members()
+	if count < 0 {
+		out := make([]string, -count)
+		for i := range out {
+			out[i] = members[rand.IntN(len(members))]
+		}
+		return out, nil
+	}
+	return pickRandom(members, count), nil
+}
+
+// метод SPop - удаляет и возвращает до count случайных разных элементов.
+// Возвращает nil, если ключа нет; опустевшее множество удаляется.
+func (s *Store) SPop(key string, count int) ([]string, error) {
+	defer s.lock(key)()
+	st, err := s.setFor(key, false)
+	if err != nil || st == nil {
+		return nil, err
+	}
+	out := pickRandom(st.members(), count)
+	for _, m := range out {
+		delete(st, m)
+	}
+	if len(out) > 0 {
+		s.touch(key)
+	}
+	s.dropIfEmptySet(key, st)
+	return out, nil
+}
+
+// функция pickRandom - до count случайных разных элементов (частичное перемешивание Фишера — Йетса).
+// Переставляет элементы members.
+func pickRandom(members []string, count int) []string {
+	count = min(count, len(members))
+	for i := 0; i < count; i++ {
+		j := i + rand.IntN(len(members)-i)
+		members[i], members[j] = members[j], members[i]
+	}
+	return members[:count]
+}
+
+// метод SMove - атомарно переносит элемент из множества src в множество dst.
+// false — элемента в src нет. Если src или dst другого типа — ErrWrongType и ничего не меняется.
+func (s *Store) SMove(src, dst, member string) (bool, error) {
+	defer s.lock(src, dst)()
+	from, err := s.setFor(src, false)
+	if err != nil {
+		return false, err
+	}
+	if _, err := s.setFor(dst, false); err != nil {
+		return false, err
+	}
+	if _, ok := from[member]; !ok {
+		return false, nil
+	}
+	if src == dst {
+		return true, nil
+	}
+	delete(from, member)
+	s.touch(src)
+	s.dropIfEmptySet(src, from)
+
+	to, _ := s.setFor(dst, true)
+	to[member] = struct{}{}
+	s.touch(dst)
+	return true, nil
+}
+
+// метод SScan - порция элементов множества для курсорного обхода (см. scanNames).
+func (s *Store) SScan(key string, cursor uint64, count int, match string) ([]string, uint64, error) {
+	defer s.rlock(key)()
+	st, err := s.readSet(key)
+	if err != nil || st == nil {
+		return []string{}, 0, err
+	}
+	page, next := scanNames(st.members(), cursor, count, match)
+	return page, next, nil
+}
@@ -0,0 +1,115 @@
+package store
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// sortedCopy возвращает отсортированную копию — порядок элементов множества произвольный
+func sortedCopy(items []string) []string {
+	out := append([]string(nil), items...)
+	sort.Strings(out)
+	return out
+}
+
+// проверяет SADD/SREM/SISMEMBER и удаление опустевшего множества
+func TestStore_SetAddRem(t *testing.T) {
+	s := NewStore()
+	if n, _ := s.SAdd("s", "a", "b", "a"); n != 2 {
+		t.Errorf("expected 2 added, got %d", n)
+	}
+	if ok, _ := s.SIsMember("s", "a"); !ok {
+		t.Errorf("expected 'a' to be a member")
+	}
+	if res, _ := s.SMIsMember("s", "b", "zz"); !reflect.DeepEqual(res, []bool{true, false}) {
+		t.Errorf("unexpected SMISMEMBER result: %v", res)
+	}
+	if n, _ := s.SRem("s", "a", "b", "zz"); n != 2 {
+		t.Errorf("expected 2 removed, got %d", n)
+	}
+	if ttl := s.TTL("s"); ttl != -2 {
+		t.Errorf("expected empty set to be deleted, TTL=%d", ttl)
+	}
+}
+
+// проверяет SINTER/SUNION/SDIFF и атомарную запись результата в *STORE
+func TestStore_SetAlgebra(t *testing.T) {
+	s := NewStore()
+	s.SAdd("a", "1", "2", "3")
+	s.SAdd("b", "2", "3", "4")
+
+	if got, _ := s.SetOp(SetInter, "a", "b"); !reflect.DeepEqual(sortedCopy(got), []string{"2", "3"}) {
+		t.Errorf("unexpected SINTER: %v", got)
+	}
+	if got, _ := s.SetOp(SetUnion, "a", "b", "missing"); !reflect.DeepEqual(sortedCopy(got), []string{"1", "2", "3", "4"}) {
+		t.Errorf("unexpected SUNION: %v", got)
+	}
+	if got, _ := s.SetOp(SetDiff, "a", "b"); !reflect.DeepEqual(got, []string{"1"}) {
+		t.Errorf("unexpected SDIFF: %v", got)
+	}
+	if got, _ := s.SetOp(SetInter, "a", "missing"); len(got) != 0 {
+		t.Errorf("expected empty SINTER with missing key, got %v", got)
+	}
+
+	// *STORE заменяет значение любого типа вместе с TTL
+	s.Set("dst", "x")
+	s.Expire("dst", 100)
+	if n, _ := s.SetOpStore(SetInter, "dst", "a", "b"); n != 2 {
+		t.Errorf("expected 2 members stored, got %d", n)
+	}
+	if got, _ := s.SMembers("dst"); !reflect.DeepEqual(sortedCopy(got), []string{"2", "3"}) {
+		t.Errorf("unexpected stored set: %v", got)
+	}
+	if ttl := s.TTL("dst"); ttl != -1 {
+		t.Errorf("expected TTL to be cleared, got %d", ttl)
+	}
+	// источник может быть и приёмником
+	s.SetOpStore(SetUnion, "a", "a", "b")
+	if n, _ := s.SCard("a"); n != 4 {
+		t.Errorf("expected 4 members in a, got %d", n)
+	}
+	// пустой результат удаляет приёмник
+	if n, _ := s.SetOpStore(SetDiff, "dst", "dst", "a"); n != 0 {
+		t.Errorf("expected empty result, got %d", n)
+	}
+	if ttl := s.TTL("dst"); ttl != -2 {
+		t.Errorf("expected dst to be deleted, TTL=%d", ttl)
+	}
+
+	s.Set("str", "x")
+	if _, err := s.SetOpStore(SetUnion, "dst", "a", "str"); !errors.Is(err, ErrWrongType) {
+		t.Errorf("expected ErrWrongType, got %v", err)
+	}
+}
+
+// проверяет SPOP и SRANDMEMBER с положительным и отрицательным count
+func TestStore_SetRandom(t *testing.T) {
+	s := NewStore()
+	s.SAdd("s", "a", "b", "c")
+
+	if got, _ := s.SRandMember("s", 10); !reflect.DeepEqual(sortedCopy(got), []string{"a", "b", "c"}) {
+		t.Errorf("expected all members, got %v", got)
+	}
+	if got, _ := s.SRandMember("s", -5); len(got) != 5 {
+		t.Errorf("expected 5 members with repeats, got %v", got)
+	}
+
+	popped, _ := s.SPop("s", 2)
+	if len(popped) != 2 {
+		t.Fatalf("expected 2 popped members, got %v", popped)
+	}
+	for _, m := range popped {
+		if ok, _ := s.SIsMember("s", m); ok {
+			t.Errorf("popped member %q is still in the set", m)
+		}
+	}
+	s.SPop("s", 5)
+	if ttl := s.TTL("s"); ttl != -2 {
+		t.Errorf("expected empty set to be deleted, TTL=%d", ttl)
+	}
+	if got, _ := s.SPop("s", 1); got != nil {
+		t.Errorf("expected nil for missing key, got %v", got)
+	}
+}
@@ -0,0 +1,196 @@
+package store
+
+import (
+	"hash/maphash"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultShards — число шардов хранилища по умолчанию (степень двойки).
+const DefaultShards = 64
+
+// структура shard — часть пространства ключей со своей блокировкой (lock striping):
+// команды над ключами из разных шардов не ждут друг друга.
+type shard struct {
+	mtx    sync.RWMutex
+	data   map[string]any
+	ttl    map[string]time.Time // момент истечения ключей этого шарда
+	expiry *expiryHeap          // те же TTL, упорядоченные по моменту истечения (меняются только через setTTL/clearTTL)
+	meta   map[string]*keyMeta  // оценка размера и статистика обращений к ключам (для maxmemory, см. evict.go)
+	index  *keyIndex            // те же ключи в порядке курсорного обхода (для SCAN, см. keyspace.go)
+
+	// счётчики шарда меняются под его блокировкой, а читаются без неё (Dirty, INFO), поэтому атомарные;
+	// общий счётчик на всё хранилище стал бы точкой конкуренции между ядрами
+	dirty   atomic.Int64 // изменения ключей шарда с момента запуска
+	expired atomic.Int64 // ключи шарда, удалённые из-за истечения TTL
+	used    atomic.Int64 // оценка памяти, занятой ключами шарда, в байтах
+}
+
+// конструктор newShard - создаёт пустой шард.
+func newShard() *shard {
+	sh := &shard{}
+	sh.reset()
+	return sh
+}
+
+// метод reset - очищает шард (вызывается под его блокировкой на запись).
+func (sh *shard) reset() {
+	sh.data = make(map[string]any)
+	sh.ttl = make(map[string]time.Time)
+	sh.expiry = newExpiryHeap()
+	sh.meta = make(map[string]*keyMeta)
+	sh.index = newKeyIndex()
+	sh.used.Store(0)
+}
+
+// метод shardIndex - номер шарда, в котором живёт ключ.
+func (s *Store) shardIndex(key string) int {
+	return int(maphash.String(s.seed, key) & s.mask)
+}
+
+// метод shardOf - шард, в котором живёт ключ.
+func (s *Store) shardOf(key string) *shard {
+	return s.shards[s.shardIndex(key)]
+}
+
+// метод lockOrder - номера шардов, в которых живут ключи, без повторов и по возрастанию.
+// Все многоключевые операции берут блокировки шардов в этом порядке, поэтому взаимоблокировок не бывает.
+func (s *Store) lockOrder(keys []string) []int {
+	idx := make([]int, len(keys))
+	for i, key := range keys {
+		idx[i] = s.shardIndex(key)
+	}
+	slices.Sort(idx)
+	return slices.Compact(idx)
+}
+
+// метод lock - берёт блокировки на запись шардов, в которых живут ключи, и возвращает функцию их снятия:
+//
+//	defer s.lock(src, dst)()
+func (s *Store) lock(keys ...string) func() {
+	if len(keys) == 1 { // частый случай — без выделения памяти
+		sh := s.shardOf(keys[0])
+		sh.mtx.Lock()
+		return sh.mtx.Unlock
+	}
+	idx := s.lockOrder(keys)
+	for _, i := range idx {
+		s.shards[i].mtx.Lock()
+	}
+	return func() {
+		for _, i := range slices.Backward(idx) {
+			s.shards[i].mtx.Unlock()
+		}
+	}
+}
+
+// метод rlock - то же, что lock, но блокировки на чтение.
+func (s *Store) rlock(keys ...string) func() {
+	if len(keys) == 1 {
+		sh := s.shardOf(keys[0])
+		sh.mtx.RLock()
+		return sh.mtx.RUnlock
+	}
+	idx := s.lockOrder(keys)
+	for _, i := range idx {
+		s.shards[i].mtx.RLock()
+	}
+	return func() {
+		for _, i := range slices.Backward(idx) {
+			s.shards[i].mtx.RUnlock()
+		}
+	}
+}
+
+// метод lockAll - блокирует на запись все шарды (снимок, FLUSH, загрузка) и возвращает функцию снятия.
+func (s *Store) lockAll() func() {
+	for _, sh := range s.shards {
+		sh.mtx.Lock()
+	}
+	return func() {
+		for _, sh := range slices.Backward(s.shards) {
+			sh.mtx.Unlock()
+		}
+	}
+}
+
+// метод rlockAll - блокирует на чтение все шарды и возвращает функцию снятия.
+func (s *Store) rlockAll() func() {
+	for _, sh := range s.shards {
+		sh.mtx.RLock()
+	}
+	return func() {
+		for _, sh := range slices.Backward(s.shards) {
+			sh.mtx.RUnlock()
+		}
+	}
+}
+
+// метод keyCount - сколько ключей во всех шардах, включая истёкшие, но ещё не удалённые (вызывается под lockAll/rlockAll).
+func (s *Store) keyCount() int {
+	n := 0
+	for _, sh := range s.shards {
+		n += len(sh.data)
+	}
+	return n
+}
+
+// Доступ к значению и TTL ключа. Вызываются под блокировкой шарда этого ключа.
+
+// метод get - значение ключа как есть (без проверки TTL — для этого есть lookup).
+func (s *Store) get(key string) (any, bool) {
+	val, ok := s.shardOf(key).data[key]
+	return val, ok
+}
+
+// метод put - записывает значение ключа, не трогая TTL.
+func (s *Store) put(key string, val any) {
+	sh := s.shardOf(key)
+	if _, ok := sh.data[key]; !ok {
+		sh.index.add(key)
+	}
+	sh.data[key] = val
+	sh.account(key, val)
+}
+
+// метод remove - удаляет ключ вместе с TTL.
+func (s *Store) remove(key string) {
+	sh := s.shardOf(key)
+	if _, ok := sh.data[key]; ok {
+		sh.index.remove(key)
+	}
+	delete(sh.data, key)
+	sh.clearTTL(key)
+	if m, ok := sh.meta[key]; ok {
+		sh.used.Add(-m.size)
+		delete(sh.meta, key)
+	}
+}
+
+// метод ttlOf - момент истечения ключа; false, если TTL нет.
+func (s *Store) ttlOf(key string) (time.Time, bool) {
+	at, ok := s.shardOf(key).ttl[key]
+	return at, ok
+}
+
+// метод setTTL - задаёт момент истечения ключа.
+func (s *Store) setTTL(key string, at time.Time) {
+	sh := s.shardOf(key)
+	sh.ttl[key] = at
+	sh.expiry.set(key, at)
+}
+
+// метод clearTTL - снимает TTL с ключа.
+func (s *Store) clearTTL(key string) {
+	s.shardOf(key).clearTTL(key)
+}
+
+// метод clearTTL - снимает TTL с ключа шарда (в s.ttl и в индексе истечения).
+func (sh *shard) clearTTL(key string) {
+	if _, ok := sh.ttl[key]; ok {
+		delete(sh.ttl, key)
+		sh.expiry.remove(key)
+	}
+}
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// проверяет, что многоключевые операции над ключами из разных шардов, идущие навстречу друг другу,
+// не взаимоблокируются (порядок захвата шардов общий) и оставляют данные согласованными
+func TestShardedMultiKeyNoDeadlock(t *testing.T) {
+	s := NewStore()
+	keys := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	for _, key := range keys {
+		s.SAdd("set:"+key, key) // у каждого множества свой элемент
+		s.RPush("list:"+key, key)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				src, dst := keys[(g+i)%len(keys)], keys[(g+i*3+1)%len(keys)]
+				s.SMove("set:"+src, "set:"+dst, src)
+				s.SMove("set:"+dst, "set:"+src, dst)
+				s.LMove("list:"+src, "list:"+dst, true, false)
+				s.SetOpStore(SetUnion, "union:"+src, "set:"+dst, "set:"+src)
+				s.Del("tmp:"+dst, "tmp:"+src)
+				s.Set("tmp:"+src, "v")
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() { wg.Wait(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("multi-key operations deadlocked")
+	}
+
+	// элементы переходили между множествами и списками, но не могли ни потеряться, ни раздвоиться
+	total, lists := 0, 0
+	for _, key := range keys {
+		n, _ := s.SCard("set:" + key)
+		total += n
+		l, _ := s.LLen("list:" + key)
+		lists += l
+	}
+	if total != len(keys) || lists != len(keys) {
+		t.Fatalf("expected %d members and %d list items, got %d and %d", len(keys), len(keys), total, lists)
+	}
+}
+
+// проверяет, что снимок видит ключи всех шардов и NewShardedStore округляет число шардов до степени двойки
+func TestShardedSnapshot(t *testing.T) {
+	s := NewShardedStore(5)
+	if len(s.shards) != 8 {
+		t.Fatalf("expected 8 shards, got %d", len(s.shards))
+	}
+	for i := 0; i < 100; i++ {
+		s.Set("k"+strconv.Itoa(i), "v")
+	}
+	if n := len(s.Snapshot()); n != 100 {
+		t.Fatalf("expected 100 keys in snapshot, got %d", n)
+	}
+	if n := s.Flush(); n != 100 {
+		t.Fatalf("FLUSH: expected 100, got %d", n)
+	}
+}
+
+// benchStore гоняет параллельную нагрузку: writePercent процентов SET, остальное GET, по 10 000 ключам
+func benchStore(b *testing.B, shards, writePercent int) {
+	s := NewShardedStore(shards)
+	keys := make([]string, 10_000)
+	for i := range keys {
+		keys[i] = "key:" + strconv.Itoa(i)
+		s.Set(keys[i], "v")
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := keys[(i*7919)%len(keys)]
+			if i%100 < writePercent {
+				s.Set(key, "v")
+			} else {
+				s.Get(key)
+			}
+			i++
+		}
+	})
+}
+
+// BenchmarkStore сравнивает пропускную способность одной общей блокировки (shards=1, прежнее устройство)
+// и хранилища с блокировками по шардам: go test ./internal/store -bench Store -cpu 1,4,8
+func BenchmarkStore(b *testing.B) {
+	for _, load := range []struct {
+		name         string
+		writePercent int
+	}{{"writes", 100}, {"mixed", 50}, {"reads", 10}} {
+		for _, shards := range []int{1, DefaultShards} {
+			b.Run(load.name+"/shards="+strconv.Itoa(shards), func(b *testing.B) {
+				benchStore(b, shards, load.writePercent)
+			})
+		}
+	}
+}
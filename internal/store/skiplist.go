@@ -0,0 +1,204 @@
+package store
+
+import "math/rand/v2"
+
+// Список с пропусками (skiplist) для упорядоченных множеств — как zskiplist в Redis.
+// Узлы упорядочены по (score, member). Каждая ссылка вперёд хранит span — сколько узлов она перепрыгивает,
+// поэтому ранг элемента и поиск по рангу, как и вставка с удалением, стоят O(log n) в среднем.
+
+const (
+	skiplistMaxLevel = 32   // достаточно для 2^64 элементов при p = 1/4
+	skiplistP        = 0.25 // вероятность подняться на уровень выше
+)
+
+// структура skiplistLevel — ссылка узла вперёд на одном уровне.
+type skiplistLevel struct {
+	forward *skiplistNode
+	span    int // сколько узлов до forward (для ранга)
+}
+
+// структура skiplistNode — узел списка с пропусками.
+type skiplistNode struct {
+	member   string
+	score    float64
+	backward *skiplistNode // предыдущий узел на нижнем уровне (nil у первого)
+	level    []skiplistLevel
+}
+
+// структура skiplist — сам список: фиктивный заголовок, хвост и текущая высота.
+type skiplist struct {
+	header *skiplistNode
+	tail   *skiplistNode
+	length int
+	level  int
+}
+
+// конструктор newSkiplist создаёт пустой список с пропусками.
+func newSkiplist() *skiplist {
+	return &skiplist{
+		header: &skiplistNode{level: make([]skiplistLevel, skiplistMaxLevel)},
+		level:  1,
+	}
+}
+
+// функция randomLevel - высота нового узла: каждый следующий уровень с вероятностью skiplistP.
+func randomLevel() int {
+	level := 1
+	for level < skiplistMaxLevel && rand.Float64() < skiplistP {
+		level++
+	}
+	return level
+}
+
+// функция nodeBefore - стоит ли узел n раньше элемента (score, member).
+func nodeBefore(n *skiplistNode, score float64, member string) bool {
+	return n.score < score || (n.score == score && n.member < member)
+}
+
+// функция nodeAfter - стоит ли узел n позже элемента (score, member).
+func nodeAfter(n *skiplistNode, score float64, member string) bool {
+	return n.score > score || (n.score == score && n.member > member)
+}
+
+// метод insert - вставляет элемент (его ещё нет в списке) и возвращает новый узел.
+func (sl *skiplist) insert(score float64, member string) *skiplistNode {
+	var update [skiplistMaxLevel]*skiplistNode
+	var rank [skiplistMaxLevel]int // ранг update[i]
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		if i < sl.level-1 {
+			rank[i] = rank[i+1]
+		}
+		for x.level[i].forward != nil && nodeBefore(x.level[i].forward, score, member) {
+			rank[i] += x.level[i].span
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+
+	level := randomLevel()
+	if level > sl.level {
+		for i := sl.level; i < level; i++ {
+			update[i] = sl.header
+			update[i].level[i].span = sl.length
+		}
+		sl.level = level
+	}
+
+	x = &skiplistNode{member: member, score: score, level: make([]skiplistLevel, level)}
+	for i := 0; i < level; i++ {
+		x.level[i].forward = update[i].level[i].forward
+		update[i].level[i].forward = x
+		x.level[i].span = update[i].level[i].span - (rank[0] - rank[i])
+		update[i].level[i].span = rank[0] - rank[i] + 1
+	}
+	// ссылки выше нового узла перепрыгивают теперь на один узел больше
+	for i := level; i < sl.level; i++ {
+		update[i].level[i].span++
+	}
+
+	if update[0] != sl.header {
+		x.backward = update[0]
+	}
+	if x.level[0].forward != nil {
+		x.level[0].forward.backward = x
+	} else {
+		sl.tail = x
+	}
+	sl.length++
+	return x
+}
+
+// метод delete - удаляет элемент (score, member). false — такого элемента нет.
+func (sl *skiplist) delete(score float64, member string) bool {
+	var update [skiplistMaxLevel]*skiplistNode
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && nodeBefore(x.level[i].forward, score, member) {
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+	x = x.level[0].forward
+	if x == nil || x.score != score || x.member != member {
+		return false
+	}
+
+	for i := 0; i < sl.level; i++ {
+		if update[i].level[i].forward == x {
+			update[i].level[i].span += x.level[i].span - 1
+			update[i].level[i].forward = x.level[i].forward
+		} else {
+			update[i].level[i].span--
+		}
+	}
+	if x.level[0].forward != nil {
+		x.level[0].forward.backward = x.backward
+	} else {
+		sl.tail = x.backward
+	}
+	for sl.level > 1 && sl.header.level[sl.level-1].forward == nil {
+		sl.level--
+	}
+	sl.length--
+	return true
+}
+
+// метод rank - ранг элемента, начиная с 1 (0 — элемента нет).
+func (sl *skiplist) rank(score float64, member string) int {
+	rank := 0
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && !nodeAfter(x.level[i].forward, score, member) {
+			rank += x.level[i].span
+			x = x.level[i].forward
+		}
+		if x != sl.header && x.score == score && x.member == member {
+			return rank
+		}
+	}
+	return 0
+}
+
+// метод byRank - узел с рангом rank, начиная с 1 (nil — вне списка).
+func (sl *skiplist) byRank(rank int) *skiplistNode {
+	traversed := 0
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && traversed+x.level[i].span <= rank {
+			traversed += x.level[i].span
+			x = x.level[i].forward
+		}
+		if traversed == rank && x != sl.header {
+			return x
+		}
+	}
+	return nil
+}
+
+// метод firstNotBefore - первый узел, для которого before(узел) == false.
+// before должна быть истинной на начале списка и ложной после него (например, "счёт меньше минимума").
+func (sl *skiplist) firstNotBefore(before func(*skiplistNode) bool) *skiplistNode {
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && before(x.level[i].forward) {
+			x = x.level[i].forward
+		}
+	}
+	return x.level[0].forward
+}
+
+// метод lastWithin - последний узел, для которого within(узел) == true (nil — таких нет).
+// within должна быть истинной на начале списка и ложной после него (например, "счёт не больше максимума").
+func (sl *skiplist) lastWithin(within func(*skiplistNode) bool) *skiplistNode {
+	x := sl.header
+	for i := sl.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && within(x.level[i].forward) {
+			x = x.level[i].forward
+		}
+	}
+	if x == sl.header {
+		return nil
+	}
+	return x
+}
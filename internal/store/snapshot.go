@@ -0,0 +1,150 @@
+package store
+
+import "time"
+
+// структура Entry — снимок одного ключа: значение и абсолютный момент истечения.
+// Используется для сохранения хранилища на диск и загрузки обратно.
+// Value — string для строки, []string для списка (копия элементов по порядку)
+// map[string]string для хеша (копия полей), map[string]struct{} для множества
+// []ZMember для упорядоченного множества (по возрастанию счёта) или StreamSnapshot для потока.
+// Нулевой ExpireAt означает, что у ключа нет TTL. DB — номер базы, из которой снят ключ
+// (Restore его не смотрит: в какую базу грузить запись, решает вызывающий).
+type Entry struct {
+	DB       int
+	Key      string
+	Value    any
+	ExpireAt time.Time
+}
+
+// метод Snapshot - делает копию всего хранилища на текущий момент (point-in-time).
+// Составные значения копируются, поэтому снимок можно спокойно сохранять в фоне.
+// Ключи, у которых TTL уже истёк, но сканер их ещё не удалил, в снимок не попадают.
+func (s *Store) Snapshot() []Entry {
+	defer s.rlockAll()() // все шарды сразу — снимок согласован между ними
+
+	now := time.Now()
+	entries := make([]Entry, 0, s.keyCount())
+	for _, sh := range s.shards {
+		for key, val := range sh.data {
+			if !s.aliveLocked(key, now) {
+				continue
+			}
+			entries = append(entries, Entry{DB: s.db, Key: key, Value: exportValue(val), ExpireAt: sh.ttl[key]})
+		}
+	}
+	return entries
+}
+
+// метод Restore - загружает записи снимка в хранилище.
+// Ключи, которые успели истечь, пока сервер был выключен, отбрасываются.
+// Возвращает количество реально загруженных ключей.
+func (s *Store) Restore(entries []Entry) int {
+	defer s.lockAll()()
+
+	now := time.Now()
+	loaded := 0
+	for _, e := range entries {
+		if !e.ExpireAt.IsZero() && now.After(e.ExpireAt) {
+			continue
+		}
+		val, ok := importValue(e.Value)
+		if !ok {
+			continue
+		}
+		s.put(e.Key, val)
+		if e.ExpireAt.IsZero() {
+			s.clearTTL(e.Key)
+		} else {
+			s.setTTL(e.Key, e.ExpireAt)
+		}
+		s.touch(e.Key)
+		loaded++
+	}
+	return loaded
+}
+
+// функция exportValue - копия значения в виде для снимка (см. Entry).
+func exportValue(val any) any {
+	switch v := val.(type) {
+	case *list:
+		return v.values()
+	case hash:
+		fields := make(map[string]string, len(v))
+		for field, val := range v {
+			fields[field] = val
+		}
+		return fields
+	case set:
+		members := make(map[string]struct{}, len(v))
+		for m := range v {
+			members[m] = struct{}{}
+		}
+		return members
+	case *zset:
+		return v.items()
+	case *stream:
+		return v.snapshot()
+	default:
+		return v
+	}
+}
+
+// функция importValue - обратное преобразование значения из снимка.
+// Пустые составные значения и неизвестные типы пропускаются.
+func importValue(val any) (any, bool) {
+	switch v := val.(type) {
+	case string:
+		return v, true
+	case []string:
+		if len(v) == 0 {
+			return nil, false
+		}
+		l := newList()
+		for _, item := range v {
+			l.pushBack(item)
+		}
+		return l, true
+	case map[string]string:
+		if len(v) == 0 {
+			return nil, false
+		}
+		h := make(hash, len(v))
+		for field, val := range v {
+			h[field] = val
+		}
+		return h, true
+	case map[string]struct{}:
+		if len(v) == 0 {
+			return nil, false
+		}
+		st := make(set, len(v))
+		for m := range v {
+			st[m] = struct{}{}
+		}
+		return st, true
+	case []ZMember:
+		if len(v) == 0 {
+			return nil, false
+		}
+		z := newZset()
+		for _, it := range v {
+			z.set(it.Member, it.Score)
+		}
+		return z, true
+	case StreamSnapshot:
+		return streamFromSnapshot(v), true // пустой поток — тоже значение
+	default:
+		return nil, false
+	}
+}
+
+// метод Dirty - возвращает общее число изменений хранилища с момента запуска.
+// Сравнивая его со значением на момент последнего снапшота, сервер понимает,
+// сколько изменений ещё не сохранено на диск.
+func (s *Store) Dirty() int64 {
+	var n int64
+	for _, sh := range s.shards {
+		n += sh.dirty.Load()
+	}
+	return n
+}
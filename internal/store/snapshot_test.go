@@ -0,0 +1,73 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// проверяет, что Snapshot отдаёт живые ключи вместе с TTL,
+// а Restore загружает их в новое хранилище и отбрасывает уже истёкшие
+func TestStore_SnapshotRestore(t *testing.T) {
+	s := NewStore()
+	s.Set("name", "anton")
+	s.Set("city", "moscow")
+	s.Expire("city", 100)
+
+	entries := s.Snapshot()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+
+	// добавим запись, которая истекла "пока сервер был выключен"
+	entries = append(entries, Entry{Key: "old", Value: "x", ExpireAt: time.Now().Add(-time.Second)})
+
+	restored := NewStore()
+	if n := restored.Restore(entries); n != 2 {
+		t.Errorf("expected 2 loaded keys, got %d", n)
+	}
+
+	if val, ok := restored.Get("name"); !ok || val != "anton" {
+		t.Errorf("expected 'anton', got '%s' (ok=%v)", val, ok)
+	}
+	if ttl := restored.TTL("city"); ttl <= 0 {
+		t.Errorf("expected positive TTL for 'city', got %d", ttl)
+	}
+	if ttl := restored.TTL("name"); ttl != -1 {
+		t.Errorf("expected TTL -1 for 'name', got %d", ttl)
+	}
+	if _, ok := restored.Get("old"); ok {
+		t.Errorf("expected expired key 'old' to be dropped on restore")
+	}
+}
+
+// проверяет, что список попадает в снимок копией и восстанавливается
+func TestStore_SnapshotList(t *testing.T) {
+	s := NewStore()
+	s.RPush("l", "a", "b")
+
+	entries := s.Snapshot()
+	s.RPush("l", "c") // снимок не должен увидеть изменение после него
+	if len(entries) != 1 || !reflect.DeepEqual(entries[0].Value, []string{"a", "b"}) {
+		t.Fatalf("unexpected snapshot: %+v", entries)
+	}
+
+	restored := NewStore()
+	restored.Restore(entries)
+	if got, _ := restored.LRange("l", 0, -1); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("expected restored list [a b], got %v", got)
+	}
+}
+
+// проверяет, что счётчик изменений растёт на каждую модификацию
+func TestStore_Dirty(t *testing.T) {
+	s := NewStore()
+	s.Set("a", "1")
+	s.Set("b", "2")
+	s.Del("a", "missing")
+	s.Expire("b", 10)
+
+	if d := s.Dirty(); d != 4 {
+		t.Errorf("expected dirty=4, got %d", d)
+	}
+}
@@ -1,59 +1,233 @@
 package store
 
 import (
+	"errors"
+	"hash/maphash"
+	"math/bits"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// ErrWrongType возвращается, если команда применена к ключу другого типа (например, LPUSH к строке).
+// Текст совпадает с ответом Redis, поэтому роутер отдаёт его клиенту как есть.
+var ErrWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+
 // структура Store — это потокобезопасное и высокопроизводительное in-memory key-value хранилище,
 // обеспечивающее параллельный доступ к данным и пригодное для юнит-тестирования.
+//
+// Значение ключа типизировано: string для строк, *list для списков.
+// Команда, применённая к ключу чужого типа, получает ErrWrongType.
+//
+// Пространство ключей разбито на шарды (см. shard.go), у каждого своя блокировка,
+// поэтому команды над разными ключами из разных соединений выполняются параллельно.
 type Store struct {
-	data map[string]string
-	mtx  sync.RWMutex
-	ttl  map[string]time.Time // для каждого ключа храним время, через которое данные по этому ключу должны очиститься
+	shards []*shard
+	seed   maphash.Seed // по нему ключ хешируется в номер шарда
+	mask   uint64       // число шардов - 1
+
+	expireCursor atomic.Uint32 // с какого шарда начинать следующий ActiveExpireCycle
+
+	watchMu  sync.RWMutex
+	watchers map[string]map[*Watch]struct{} // кто из клиентов следит за ключом (WATCH)
+	watching atomic.Int64                   // быстрый флаг: сколько пар "клиент, ключ" под WATCH (0 — touch не трогает watchMu)
+
+	blockMu  sync.Mutex
+	blocked  map[string]int      // сколько клиентов ждёт данных по ключу (BLPOP и т.п.)
+	ready    map[string]struct{} // ключи, которые изменились, пока их кто-то ждал
+	waiting  atomic.Int64        // быстрый флаг: сколько всего ожиданий (0 — touch не трогает blockMu)
+	hasReady atomic.Bool         // быстрый флаг: ready не пуст
+
+	maxMemory atomic.Int64 // предел памяти под данные (0 — без ограничения), см. evict.go
+	evictMu   sync.Mutex   // вытесняет ключи одна горутина за раз; защищает policy, samples и pool
+	policy    EvictionPolicy
+	samples   int              // сколько ключей просматривается за один шаг выбора жертвы
+	pool      []evictCandidate // лучшие кандидаты на вытеснение, накопленные за прошлые шаги
+	evicted   atomic.Int64     // ключи, вытесненные из-за maxmemory
+
+	lazyFree lazyFreeStats // значения, которые UNLINK разбирает в фоне (см. keys.go)
+
+	db    int      // номер базы данных (SELECT); у хранилища, созданного не через NewDatabases, — 0
+	group []*Store // все базы сервера, если хранилище создано через NewDatabases (см. databases.go)
 }
 
-// конструктор newStore() создает новый объект Store,
-// создавая пустую мапу для хранения. (мютекс инициализируется по дефолту)
+// конструктор newStore() создает новый объект Store
+// с DefaultShards шардами (мютексы инициализируются по дефолту).
 func NewStore() *Store {
-	return &Store{
-		data: make(map[string]string),
-		ttl:  make(map[string]time.Time),
+	return NewShardedStore(DefaultShards)
+}
+
+// конструктор NewShardedStore создаёт хранилище из n шардов (n округляется вверх до степени двойки).
+// NewShardedStore(1) — хранилище с одной общей блокировкой.
+func NewShardedStore(n int) *Store {
+	n = 1 << bits.Len(uint(max(n, 1)-1))
+	s := &Store{
+		shards:   make([]*shard, n),
+		seed:     maphash.MakeSeed(),
+		mask:     uint64(n - 1),
+		watchers: make(map[string]map[*Watch]struct{}),
+		blocked:  make(map[string]int),
+		ready:    make(map[string]struct{}),
+		samples:  DefaultMaxMemorySamples,
 	}
+	for i := range s.shards {
+		s.shards[i] = newShard()
+	}
+	return s
 }
 
 // метод Set - добавляет или обновляет значение по ключу в хранилище.
+// Значение любого другого типа (например, список) заменяется строкой.
 // Ничего не возвращает — успешность считается гарантированной.
 // Ответ клиенту (+OK) формируется на уровне router.go (через WriteSimple).
+// Как и в Redis, прежний TTL ключа сбрасывается (SET с опциями — см. SetWith).
 func (s *Store) Set(key, value string) {
-	s.mtx.Lock() // лочим для когкурентной записи
-	defer s.mtx.Unlock()
-	s.data[key] = value
+	s.SetWith(key, value, SetOptions{})
 }
 
 // мтеод Get - возвращает значение по ключу и флаг наличия.
 // Если ключ найден — router.go отправит его клиенту через WriteBulk.
-// Если нет — клиенту вернётся nil.
+// Если нет — клиенту вернётся nil. Ключ другого типа для Get не строка — тоже false
+// (отличить такой случай позволяет GetString).
 func (s *Store) Get(key string) (string, bool) {
-	s.mtx.RLock() // лочим для конкурентного чтения (могут читать параллельно)
-	defer s.mtx.RUnlock()
-	val, ok := s.data[key]
+	val, ok, _ := s.GetString(key)
 	return val, ok
 }
 
+// метод GetString - возвращает строковое значение ключа.
+// Если по ключу лежит значение другого типа — ErrWrongType.
+func (s *Store) GetString(key string) (string, bool, error) {
+	defer s.rlock(key)()
+	val, ok := s.lookup(key)
+	if !ok {
+		return "", false, nil
+	}
+	str, isString := val.(string)
+	if !isString {
+		return "", false, ErrWrongType
+	}
+	return str, true, nil
+}
+
+// метод MGet - строковые значения нескольких ключей, прочитанные атомарно (под блокировками их шардов).
+// nil — ключа нет или по нему лежит не строка.
+func (s *Store) MGet(keys ...string) []*string {
+	defer s.rlock(keys...)()
+	out := make([]*string, len(keys))
+	for i, key := range keys {
+		if val, ok := s.lookup(key); ok {
+			if str, isString := val.(string); isString {
+				out[i] = &str
+			}
+		}
+	}
+	return out
+}
+
 // метод Del - удаляет из хранилища один или несколько ключей.
 // Возвращает количество реально удалённых элементов.
 // router.go оборачивает это число в integer-ответ (WriteInteger).
 func (s *Store) Del(keys ...string) int {
+	return s.del(keys, nil)
+}
+
+// метод del - удаляет ключи и передаёт удалённые значения в removed (если он не nil).
+func (s *Store) del(keys []string, removed func(val any)) int {
 	count := 0
-	s.mtx.Lock()
-	defer s.mtx.Unlock()
+	defer s.lock(keys...)()
 	for _, key := range keys {
-		_, ok := s.data[key]
+		s.expireIfNeeded(key) // истёкший ключ не считается удалённым
+		val, ok := s.get(key)
 		if ok {
-			delete(s.data, key) // удаляем ключ если он есть
+			s.remove(key) // удаляем ключ если он есть
+			s.touch(key)
+			if removed != nil {
+				removed(val)
+			}
 			count++
 		}
 	}
 	return count
 }
+
+// метод Flush - удаляет из хранилища все ключи вместе с их TTL.
+// Возвращает количество удалённых ключей.
+func (s *Store) Flush() int {
+	count, _ := s.flush()
+	return count
+}
+
+// метод FlushAsync - как Flush, но удалённые значения разбираются в фоновой горутине (FLUSHDB ASYNC):
+// база пустеет сразу, а обход всех её значений не задерживает команду и других клиентов.
+func (s *Store) FlushAsync() int {
+	count, old := s.flush()
+	if count > 0 {
+		s.lazyFree.pending.Add(int64(count))
+		go func() {
+			for _, data := range old {
+				for _, val := range data {
+					freeValue(val)
+					s.lazyFree.pending.Add(-1)
+					s.lazyFree.freed.Add(1)
+				}
+			}
+		}()
+	}
+	return count
+}
+
+// метод flush - очищает все шарды. Возвращает количество удалённых ключей и прежние карты значений шардов.
+func (s *Store) flush() (int, []map[string]any) {
+	defer s.lockAll()()
+	count := 0
+	old := make([]map[string]any, 0, len(s.shards))
+	for _, sh := range s.shards {
+		count += len(sh.data)
+		sh.dirty.Add(int64(len(sh.data)))
+		old = append(old, sh.data)
+		sh.reset()
+	}
+	s.watchMu.RLock()
+	defer s.watchMu.RUnlock()
+	for key := range s.watchers { // все наблюдаемые ключи считаются изменёнными
+		s.notifyWatchersLocked(key)
+	}
+	return count, old
+}
+
+// метод lookup - возвращает живое значение ключа: истёкший, но ещё не удалённый сканером ключ
+// считается отсутствующим (вызывается под блокировкой шарда ключа).
+// Обращение отмечается в статистике ключа, по которой работают политики вытеснения LRU/LFU.
+func (s *Store) lookup(key string) (any, bool) {
+	now := time.Now()
+	if !s.aliveLocked(key, now) {
+		return nil, false
+	}
+	sh := s.shardOf(key)
+	if m, ok := sh.meta[key]; ok {
+		m.access(now)
+	}
+	return sh.data[key], true
+}
+
+// метод expireIfNeeded - удаляет ключ, если его TTL уже истёк, а сканер до него ещё не добрался.
+// Вызывается под s.lock(key) перед изменением ключа, чтобы не дописать в уже "мёртвое" значение.
+func (s *Store) expireIfNeeded(key string) {
+	if _, ok := s.get(key); ok && !s.aliveLocked(key, time.Now()) {
+		s.deleteExpired(key)
+	}
+}
+
+// метод touch - отмечает, что ключ изменился: увеличивает счётчик изменений
+// и сообщает клиентам, которые следят за этим ключом через WATCH.
+// Вызывается под блокировкой шарда ключа из каждого метода, который меняет данные.
+func (s *Store) touch(key string) {
+	sh := s.shardOf(key)
+	sh.dirty.Add(1)
+	if val, ok := sh.data[key]; ok { // значение могло вырасти или уменьшиться — пересчитываем его размер
+		sh.account(key, val)
+		sh.meta[key].access(time.Now())
+	}
+	s.notifyWatchers(key)
+	s.markReady(key)
+}
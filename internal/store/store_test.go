@@ -35,3 +35,21 @@ func TestStore_SetGetDel(t *testing.T) {
 		t.Errorf("expected key 'name' to be deleted, but still exists")
 	}
 }
+
+// проверяет, что Flush очищает хранилище целиком
+func TestStore_Flush(t *testing.T) {
+	s := NewStore()
+	s.Set("a", "1")
+	s.Set("b", "2")
+	s.Expire("b", 10)
+
+	if n := s.Flush(); n != 2 {
+		t.Errorf("expected 2 flushed keys, got %d", n)
+	}
+	if _, ok := s.Get("a"); ok {
+		t.Errorf("expected key 'a' to be flushed")
+	}
+	if ttl := s.TTL("b"); ttl != -2 {
+		t.Errorf("expected TTL -2 after flush, got %d", ttl)
+	}
+}
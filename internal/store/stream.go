@@ -0,0 +1,425 @@
+package store
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ошибки команд над потоками; тексты совпадают с ответами Redis
+var (
+	ErrInvalidStreamID  = errors.New("ERR Invalid stream ID specified as stream command argument")
+	ErrStreamIDTooSmall = errors.New("ERR The ID specified in XADD is equal or smaller than the target stream top item")
+	ErrStreamIDZero     = errors.New("ERR The ID specified in XADD must be greater than 0-0")
+	ErrStreamExhausted  = errors.New("ERR The stream has exhausted the last possible ID, unable to add more items")
+	ErrSetIDTooSmall    = errors.New("ERR The ID specified in XSETID is smaller than the target stream top item")
+	ErrSetIDBelowMaxDel = errors.New("ERR The ID specified in XSETID is smaller than the provided max_deleted_entry_id")
+	ErrEntriesAdded     = errors.New("ERR The entries_added specified in XSETID is smaller than the target stream length")
+)
+
+// структура StreamID — идентификатор записи потока "<миллисекунды>-<номер>".
+type StreamID struct {
+	Ms, Seq uint64
+}
+
+// MaxStreamID — наибольший возможный идентификатор (граница "+").
+var MaxStreamID = StreamID{math.MaxUint64, math.MaxUint64}
+
+// метод String - идентификатор в виде "ms-seq".
+func (id StreamID) String() string {
+	return strconv.FormatUint(id.Ms, 10) + "-" + strconv.FormatUint(id.Seq, 10)
+}
+
+// метод Less - идёт ли id раньше other.
+func (id StreamID) Less(other StreamID) bool {
+	return id.Ms < other.Ms || (id.Ms == other.Ms && id.Seq < other.Seq)
+}
+
+// метод IsZero - это 0-0.
+func (id StreamID) IsZero() bool {
+	return id.Ms == 0 && id.Seq == 0
+}
+
+// метод Next - следующий идентификатор. false — id уже наибольший.
+func (id StreamID) Next() (StreamID, bool) {
+	switch {
+	case id.Seq < math.MaxUint64:
+		return StreamID{id.Ms, id.Seq + 1}, true
+	case id.Ms < math.MaxUint64:
+		return StreamID{id.Ms + 1, 0}, true
+	}
+	return id, false
+}
+
+// метод Prev - предыдущий идентификатор. false — id равен 0-0.
+func (id StreamID) Prev() (StreamID, bool) {
+	switch {
+	case id.Seq > 0:
+		return StreamID{id.Ms, id.Seq - 1}, true
+	case id.Ms > 0:
+		return StreamID{id.Ms - 1, math.MaxUint64}, true
+	}
+	return id, false
+}
+
+// функция ParseStreamID - разбирает "ms-seq" или "ms" (тогда номер равен defaultSeq).
+func ParseStreamID(s string, defaultSeq uint64) (StreamID, error) {
+	msPart, seqPart, hasSeq := strings.Cut(s, "-")
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return StreamID{}, ErrInvalidStreamID
+	}
+	if !hasSeq {
+		return StreamID{ms, defaultSeq}, nil
+	}
+	seq, err := strconv.ParseUint(seqPart, 10, 64)
+	if err != nil {
+		return StreamID{}, ErrInvalidStreamID
+	}
+	return StreamID{ms, seq}, nil
+}
+
+// структура StreamEntry — запись потока: идентификатор и пары поле/значение.
+// Fields == nil у записи, которая есть в списке ожидающих группы, но уже удалена из потока.
+type StreamEntry struct {
+	ID     StreamID
+	Fields []string
+}
+
+// структура stream — значение-поток: записи по возрастанию идентификатора и группы потребителей.
+// В отличие от других составных типов пустой поток не удаляется: он хранит последний идентификатор и группы.
+type stream struct {
+	entries      []StreamEntry
+	lastID       StreamID // наибольший выданный идентификатор (даже если запись уже удалена)
+	maxDeleted   StreamID // наибольший идентификатор среди удалённых записей
+	entriesAdded uint64   // сколько записей добавлено за всё время
+	groups       map[string]*consumerGroup
+}
+
+// конструктор newStream создаёт пустой поток.
+func newStream() *stream {
+	return &stream{groups: make(map[string]*consumerGroup)}
+}
+
+// метод search - позиция первой записи с идентификатором не меньше id.
+func (st *stream) search(id StreamID) int {
+	return sort.Search(len(st.entries), func(i int) bool { return !st.entries[i].ID.Less(id) })
+}
+
+// метод lookup - запись по идентификатору.
+func (st *stream) lookup(id StreamID) (StreamEntry, bool) {
+	i := st.search(id)
+	if i < len(st.entries) && st.entries[i].ID == id {
+		return st.entries[i], true
+	}
+	return StreamEntry{}, false
+}
+
+// метод rangeOf - записи с идентификаторами от start до end включительно,
+// по возрастанию (или по убыванию, если rev), не больше count (count <= 0 — без ограничения).
+func (st *stream) rangeOf(start, end StreamID, count int, rev bool) []StreamEntry {
+	out := []StreamEntry{}
+	if end.Less(start) {
+		return out
+	}
+	from, to := st.search(start), st.search(end)
+	if to < len(st.entries) && st.entries[to].ID == end {
+		to++
+	}
+	if count <= 0 || count > to-from {
+		count = to - from
+	}
+	for i := 0; i < count; i++ {
+		if rev {
+			out = append(out, st.entries[to-1-i])
+		} else {
+			out = append(out, st.entries[from+i])
+		}
+	}
+	return out
+}
+
+// метод removeFront - удаляет n первых записей.
+func (st *stream) removeFront(n int) {
+	if n <= 0 {
+		return
+	}
+	st.maxDeleted = maxID(st.maxDeleted, st.entries[n-1].ID)
+	st.entries = append([]StreamEntry(nil), st.entries[n:]...) // не держим удалённые записи в памяти
+}
+
+// функция maxID - больший из двух идентификаторов.
+func maxID(a, b StreamID) StreamID {
+	if a.Less(b) {
+		return b
+	}
+	return a
+}
+
+// структура StreamTrim — обрезка потока для XADD и XTRIM: по длине (MAXLEN) или по наименьшему идентификатору (MINID).
+// Приблизительная обрезка ("~") выполняется точно, но удаляет не больше Limit записей, если Limit > 0.
+type StreamTrim struct {
+	ByMinID bool
+	MaxLen  int
+	MinID   StreamID
+	Approx  bool
+	Limit   int
+}
+
+// метод trim - обрезает поток по правилу t и возвращает число удалённых записей.
+func (st *stream) trim(t StreamTrim) int {
+	n := 0
+	if t.ByMinID {
+		n = st.search(t.MinID)
+	} else if len(st.entries) > t.MaxLen {
+		n = len(st.entries) - t.MaxLen
+	}
+	if t.Approx && t.Limit > 0 {
+		n = min(n, t.Limit)
+	}
+	st.removeFront(n)
+	return n
+}
+
+// структура StreamIDSpec — идентификатор для XADD: явный, "*" (всё автоматически)
+// или "ms-*" (номер подбирается автоматически).
+type StreamIDSpec struct {
+	ID      StreamID
+	AutoMs  bool
+	AutoSeq bool
+}
+
+// метод nextID - идентификатор для новой записи по спецификации spec.
+func (st *stream) nextID(spec StreamIDSpec) (StreamID, error) {
+	last := st.lastID
+	switch {
+	case spec.AutoMs:
+		ms := uint64(time.Now().UnixMilli())
+		if ms > last.Ms {
+			return StreamID{ms, 0}, nil
+		}
+		next, ok := last.Next()
+		if !ok {
+			return StreamID{}, ErrStreamExhausted
+		}
+		return next, nil
+	case spec.AutoSeq:
+		if spec.ID.Ms == last.Ms {
+			if last.Seq == math.MaxUint64 {
+				return StreamID{}, ErrStreamIDTooSmall
+			}
+			return StreamID{last.Ms, last.Seq + 1}, nil
+		}
+		id := StreamID{spec.ID.Ms, 0}
+		if id.Ms == 0 {
+			id.Seq = 1 // 0-0 недопустим
+		}
+		if id.Less(last) {
+			return StreamID{}, ErrStreamIDTooSmall
+		}
+		return id, nil
+	default:
+		if spec.ID.IsZero() {
+			return StreamID{}, ErrStreamIDZero
+		}
+		if !last.Less(spec.ID) {
+			return StreamID{}, ErrStreamIDTooSmall
+		}
+		return spec.ID, nil
+	}
+}
+
+// метод streamFor - возвращает поток по ключу (вызывается под s.lock(key)).
+// Если ключа нет и create — создаёт пустой поток, иначе возвращает nil.
+func (s *Store) streamFor(key string, create bool) (*stream, error) {
+	s.expireIfNeeded(key)
+	val, ok := s.get(key)
+	if !ok {
+		if !create {
+			return nil, nil
+		}
+		st := newStream()
+		s.put(key, st)
+		return st, nil
+	}
+	st, isStream := val.(*stream)
+	if !isStream {
+		return nil, ErrWrongType
+	}
+	return st, nil
+}
+
+// метод readStream - возвращает поток по ключу для чтения (вызывается под s.rlock(key)).
+// nil без ошибки — ключа нет.
+func (s *Store) readStream(key string) (*stream, error) {
+	val, ok := s.lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	st, isStream := val.(*stream)
+	if !isStream {
+		return nil, ErrWrongType
+	}
+	return st, nil
+}
+
+// метод XAdd - добавляет запись в поток (создавая его, если не noMkStream) и, если trim != nil, обрезает поток.
+// Возвращает идентификатор новой записи; false — потока нет, а noMkStream запрещает его создавать.
+func (s *Store) XAdd(key string, spec StreamIDSpec, fields []string, trim *StreamTrim, noMkStream bool) (StreamID, bool, error) {
+	defer s.lock(key)()
+	st, err := s.streamFor(key, false)
+	if err != nil {
+		return StreamID{}, false, err
+	}
+	if st == nil && noMkStream {
+		return StreamID{}, false, nil
+	}
+	if st == nil {
+		st = newStream()
+	}
+	id, err := st.nextID(spec)
+	if err != nil {
+		return StreamID{}, false, err
+	}
+	s.put(key, st) // новый поток появляется только вместе с первой записью
+
+	st.entries = append(st.entries, StreamEntry{ID: id, Fields: append([]string(nil), fields...)})
+	st.lastID = id
+	st.entriesAdded++
+	if trim != nil {
+		st.trim(*trim)
+	}
+	s.touch(key)
+	return id, true, nil
+}
+
+// метод XLen - число записей в потоке (0, если ключа нет).
+func (s *Store) XLen(key string) (int, error) {
+	defer s.rlock(key)()
+	st, err := s.readStream(key)
+	if err != nil || st == nil {
+		return 0, err
+	}
+	return len(st.entries), nil
+}
+
+// метод XRange - записи с идентификаторами от start до end включительно (XRANGE, а при rev — XREVRANGE).
+func (s *Store) XRange(key string, start, end StreamID, count int, rev bool) ([]StreamEntry, error) {
+	defer s.rlock(key)()
+	st, err := s.readStream(key)
+	if err != nil || st == nil {
+		return []StreamEntry{}, err
+	}
+	return st.rangeOf(start, end, count, rev), nil
+}
+
+// метод XTrim - обрезает поток и возвращает число удалённых записей.
+func (s *Store) XTrim(key string, t StreamTrim) (int, error) {
+	defer s.lock(key)()
+	st, err := s.streamFor(key, false)
+	if err != nil || st == nil {
+		return 0, err
+	}
+	n := st.trim(t)
+	if n > 0 {
+		s.touch(key)
+	}
+	return n, nil
+}
+
+// метод XDel - удаляет записи по идентификаторам и возвращает, сколько реально удалено.
+// Записи остаются в списках ожидающих групп, пока их не подтвердят или не заберут.
+func (s *Store) XDel(key string, ids ...StreamID) (int, error) {
+	defer s.lock(key)()
+	st, err := s.streamFor(key, false)
+	if err != nil || st == nil {
+		return 0, err
+	}
+	removed := 0
+	for _, id := range ids {
+		i := st.search(id)
+		if i < len(st.entries) && st.entries[i].ID == id {
+			st.entries = append(st.entries[:i], st.entries[i+1:]...)
+			st.maxDeleted = maxID(st.maxDeleted, id)
+			removed++
+		}
+	}
+	if removed > 0 {
+		s.touch(key)
+	}
+	return removed, nil
+}
+
+// метод XSetID - задаёт последний идентификатор потока (и, если указаны, счётчик добавленных записей
+// и наибольший удалённый идентификатор). Используется при перезаписи журнала.
+func (s *Store) XSetID(key string, last StreamID, entriesAdded *uint64, maxDeleted *StreamID) error {
+	defer s.lock(key)()
+	st, err := s.streamFor(key, false)
+	if err != nil {
+		return err
+	}
+	if st == nil {
+		return ErrNoSuchKey
+	}
+	if n := len(st.entries); n > 0 && last.Less(st.entries[n-1].ID) {
+		return ErrSetIDTooSmall
+	}
+	if entriesAdded != nil && *entriesAdded < uint64(len(st.entries)) {
+		return ErrEntriesAdded
+	}
+	if maxDeleted != nil && last.Less(*maxDeleted) {
+		return ErrSetIDBelowMaxDel
+	}
+	st.lastID = last
+	if entriesAdded != nil {
+		st.entriesAdded = *entriesAdded
+	}
+	if maxDeleted != nil {
+		st.maxDeleted = *maxDeleted
+	}
+	s.touch(key)
+	return nil
+}
+
+// метод StreamLastID - последний идентификатор потока (для "$" в XREAD). Второе значение false, если ключа нет.
+func (s *Store) StreamLastID(key string) (StreamID, bool, error) {
+	defer s.rlock(key)()
+	st, err := s.readStream(key)
+	if err != nil || st == nil {
+		return StreamID{}, false, err
+	}
+	return st.lastID, true, nil
+}
+
+// структура StreamRead — записи одного потока в ответе XREAD/XREADGROUP.
+type StreamRead struct {
+	Key     string
+	Entries []StreamEntry
+}
+
+// метод XRead - записи потоков keys с идентификаторами больше after[i], не больше count из каждого
+// (count <= 0 — без ограничения). В ответ попадают только потоки, где есть новые записи.
+func (s *Store) XRead(keys []string, after []StreamID, count int) ([]StreamRead, error) {
+	defer s.rlock(keys...)()
+	var out []StreamRead
+	for i, key := range keys {
+		st, err := s.readStream(key)
+		if err != nil {
+			return nil, err
+		}
+		if st == nil {
+			continue
+		}
+		start, ok := after[i].Next()
+		if !ok {
+			continue
+		}
+		if entries := st.rangeOf(start, MaxStreamID, count, false); len(entries) > 0 {
+			out = append(out, StreamRead{Key: key, Entries: entries})
+		}
+	}
+	return out, nil
+}
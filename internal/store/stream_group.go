@@ -0,0 +1,577 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ошибки команд над группами потребителей
+var (
+	ErrBusyGroup  = errors.New("BUSYGROUP Consumer Group name already exists")
+	ErrGroupNoKey = errors.New("ERR The XGROUP subcommand requires the key to exist. Note that for CREATE you may want to use the MKSTREAM option to create an empty stream automatically.")
+)
+
+// структура NoGroupError — нет ключа или группы потребителей.
+// Текст ответа зависит от команды, поэтому роутер формирует его сам по Key и Group.
+type NoGroupError struct {
+	Key, Group string
+}
+
+// метод Error - текст ошибки в том виде, в каком его отдают XPENDING и XCLAIM.
+func (e *NoGroupError) Error() string {
+	return fmt.Sprintf("NOGROUP No such key '%s' or consumer group '%s'", e.Key, e.Group)
+}
+
+// структура pelEntry — запись в списке ожидающих (PEL): кому доставлена, когда и сколько раз.
+type pelEntry struct {
+	consumer      string
+	deliveryTime  time.Time
+	deliveryCount int
+}
+
+// структура consumerGroup — группа потребителей: до какого идентификатора записи уже раздавались,
+// общий список ожидающих подтверждения (XACK) и списки ожидающих каждого потребителя.
+type consumerGroup struct {
+	lastID    StreamID
+	pel       map[StreamID]*pelEntry
+	consumers map[string]map[StreamID]*pelEntry
+}
+
+// конструктор newConsumerGroup создаёт группу, которая будет раздавать записи после lastID.
+func newConsumerGroup(lastID StreamID) *consumerGroup {
+	return &consumerGroup{
+		lastID:    lastID,
+		pel:       make(map[StreamID]*pelEntry),
+		consumers: make(map[string]map[StreamID]*pelEntry),
+	}
+}
+
+// метод consumer - список ожидающих потребителя, создаёт потребителя при необходимости.
+// Второе значение — потребитель только что создан.
+func (g *consumerGroup) consumer(name string) (map[StreamID]*pelEntry, bool) {
+	pending, ok := g.consumers[name]
+	if !ok {
+		pending = make(map[StreamID]*pelEntry)
+		g.consumers[name] = pending
+	}
+	return pending, !ok
+}
+
+// метод assign - отдаёт запись id потребителю name: снимает её с прежнего владельца,
+// обновляет время доставки и возвращает запись PEL (созданную, если её не было).
+func (g *consumerGroup) assign(id StreamID, name string, at time.Time) *pelEntry {
+	pe, ok := g.pel[id]
+	if ok {
+		delete(g.consumers[pe.consumer], id)
+	} else {
+		pe = &pelEntry{}
+		g.pel[id] = pe
+	}
+	pe.consumer = name
+	pe.deliveryTime = at
+	pending, _ := g.consumer(name)
+	pending[id] = pe
+	return pe
+}
+
+// метод release - убирает запись из списков ожидающих группы и её владельца.
+func (g *consumerGroup) release(id StreamID) bool {
+	pe, ok := g.pel[id]
+	if !ok {
+		return false
+	}
+	delete(g.pel, id)
+	delete(g.consumers[pe.consumer], id)
+	return true
+}
+
+// функция sortedIDs - идентификаторы из списка ожидающих по возрастанию.
+func sortedIDs(pel map[StreamID]*pelEntry) []StreamID {
+	ids := make([]StreamID, 0, len(pel))
+	for id := range pel {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].Less(ids[j]) })
+	return ids
+}
+
+// метод groupFor - поток и группа для изменения (вызывается под s.lock(key)).
+// Нет ключа или группы — *NoGroupError.
+func (s *Store) groupFor(key, group string) (*stream, *consumerGroup, error) {
+	st, err := s.streamFor(key, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	if st == nil || st.groups[group] == nil {
+		return nil, nil, &NoGroupError{Key: key, Group: group}
+	}
+	return st, st.groups[group], nil
+}
+
+// метод readGroup - как groupFor, но для чтения (вызывается под s.rlock(key)).
+func (s *Store) readGroup(key, group string) (*stream, *consumerGroup, error) {
+	st, err := s.readStream(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if st == nil || st.groups[group] == nil {
+		return nil, nil, &NoGroupError{Key: key, Group: group}
+	}
+	return st, st.groups[group], nil
+}
+
+// метод XGroupCreate - создаёт группу, которая будет раздавать записи после id
+// (или после последней записи потока, если latest — это "$" в XGROUP CREATE).
+// Если потока нет: при mkStream создаётся пустой поток, иначе ErrGroupNoKey.
+func (s *Store) XGroupCreate(key, group string, id StreamID, latest, mkStream bool) error {
+	defer s.lock(key)()
+	st, err := s.streamFor(key, mkStream)
+	if err != nil {
+		return err
+	}
+	if st == nil {
+		return ErrGroupNoKey
+	}
+	if _, ok := st.groups[group]; ok {
+		return ErrBusyGroup
+	}
+	if latest {
+		id = st.lastID
+	}
+	st.groups[group] = newConsumerGroup(id)
+	s.touch(key)
+	return nil
+}
+
+// метод XGroupSetID - меняет, после какой записи группа продолжит раздавать новые ("$" — latest).
+func (s *Store) XGroupSetID(key, group string, id StreamID, latest bool) error {
+	defer s.lock(key)()
+	st, g, err := s.groupFor(key, group)
+	if err != nil {
+		return err
+	}
+	if latest {
+		id = st.lastID
+	}
+	g.lastID = id
+	s.touch(key)
+	return nil
+}
+
+// метод XGroupDestroy - удаляет группу вместе с её списками ожидающих. false — такой группы нет.
+func (s *Store) XGroupDestroy(key, group string) (bool, error) {
+	defer s.lock(key)()
+	st, err := s.streamFor(key, false)
+	if err != nil {
+		return false, err
+	}
+	if st == nil {
+		return false, ErrGroupNoKey
+	}
+	if _, ok := st.groups[group]; !ok {
+		return false, nil
+	}
+	delete(st.groups, group)
+	s.touch(key)
+	return true, nil
+}
+
+// метод XGroupCreateConsumer - явно создаёт потребителя в группе. false — он уже есть.
+func (s *Store) XGroupCreateConsumer(key, group, consumer string) (bool, error) {
+	defer s.lock(key)()
+	_, g, err := s.groupFor(key, group)
+	if err != nil {
+		return false, err
+	}
+	_, created := g.consumer(consumer)
+	if created {
+		s.touch(key)
+	}
+	return created, nil
+}
+
+// метод XGroupDelConsumer - удаляет потребителя; его неподтверждённые записи пропадают из списка ожидающих группы.
+// Возвращает, сколько записей ждало подтверждения от потребителя.
+func (s *Store) XGroupDelConsumer(key, group, consumer string) (int, error) {
+	defer s.lock(key)()
+	_, g, err := s.groupFor(key, group)
+	if err != nil {
+		return 0, err
+	}
+	pending, ok := g.consumers[consumer]
+	if !ok {
+		return 0, nil
+	}
+	for id := range pending {
+		delete(g.pel, id)
+	}
+	delete(g.consumers, consumer)
+	s.touch(key)
+	return len(pending), nil
+}
+
+// структура XReadGroupArgs — параметры XREADGROUP.
+// After[i] == nil — ">" для Keys[i] (ещё никому не выданные записи),
+// иначе — история: собственные неподтверждённые записи потребителя после этого идентификатора.
+type XReadGroupArgs struct {
+	Group, Consumer string
+	Keys            []string
+	After           []*StreamID
+	Count           int // <= 0 — без ограничения
+	NoAck           bool
+}
+
+// метод XReadGroup - чтение потоков от имени потребителя группы (XREADGROUP).
+// Новые записи сдвигают позицию группы и, если не NoAck, попадают в списки ожидающих;
+// записи из истории получают новое время доставки и +1 к числу доставок
+// (удалённые из потока возвращаются с Fields == nil).
+// Поток без новых записей в ответ не попадает, поток с историей попадает всегда.
+// Если группы нет хотя бы для одного ключа, ничего не меняется.
+func (s *Store) XReadGroup(args XReadGroupArgs) ([]StreamRead, error) {
+	defer s.lock(args.Keys...)()
+	streams := make([]*stream, len(args.Keys))
+	groups := make([]*consumerGroup, len(args.Keys))
+	for i, key := range args.Keys {
+		st, g, err := s.groupFor(key, args.Group)
+		if err != nil {
+			return nil, err
+		}
+		streams[i], groups[i] = st, g
+	}
+
+	now := time.Now()
+	var out []StreamRead
+	for i, key := range args.Keys {
+		st, g := streams[i], groups[i]
+		pending, changed := g.consumer(args.Consumer)
+
+		if args.After[i] != nil {
+			entries := []StreamEntry{}
+			for _, id := range sortedIDs(pending) {
+				if !args.After[i].Less(id) {
+					continue
+				}
+				if args.Count > 0 && len(entries) == args.Count {
+					break
+				}
+				entry, ok := st.lookup(id)
+				if !ok {
+					entry = StreamEntry{ID: id}
+				} else {
+					pending[id].deliveryTime = now
+					pending[id].deliveryCount++
+					changed = true
+				}
+				entries = append(entries, entry)
+			}
+			out = append(out, StreamRead{Key: key, Entries: entries})
+		} else if start, ok := g.lastID.Next(); ok {
+			entries := st.rangeOf(start, MaxStreamID, args.Count, false)
+			for _, entry := range entries {
+				g.lastID = entry.ID
+				if !args.NoAck {
+					g.assign(entry.ID, args.Consumer, now).deliveryCount = 1
+				}
+			}
+			if len(entries) > 0 {
+				out = append(out, StreamRead{Key: key, Entries: entries})
+				changed = true
+			}
+		}
+		if changed {
+			s.touch(key)
+		}
+	}
+	return out, nil
+}
+
+// метод XAck - подтверждает обработку записей: убирает их из списков ожидающих.
+// Возвращает число подтверждённых; если ключа или группы нет — 0.
+func (s *Store) XAck(key, group string, ids ...StreamID) (int, error) {
+	defer s.lock(key)()
+	_, g, err := s.groupFor(key, group)
+	var noGroup *NoGroupError
+	if errors.As(err, &noGroup) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	acked := 0
+	for _, id := range ids {
+		if g.release(id) {
+			acked++
+		}
+	}
+	if acked > 0 {
+		s.touch(key)
+	}
+	return acked, nil
+}
+
+// структура ConsumerPending — сколько записей ждёт подтверждения от потребителя.
+type ConsumerPending struct {
+	Name  string
+	Count int
+}
+
+// структура PendingSummary — сводка XPENDING: всего ожидающих, наименьший и наибольший
+// идентификаторы и разбивка по потребителям (по имени, только с ненулевым числом).
+type PendingSummary struct {
+	Count     int
+	Min, Max  StreamID
+	Consumers []ConsumerPending
+}
+
+// метод XPendingSummary - сводка по списку ожидающих группы.
+func (s *Store) XPendingSummary(key, group string) (PendingSummary, error) {
+	defer s.rlock(key)()
+	_, g, err := s.readGroup(key, group)
+	if err != nil {
+		return PendingSummary{}, err
+	}
+	ids := sortedIDs(g.pel)
+	sum := PendingSummary{Count: len(ids)}
+	if len(ids) == 0 {
+		return sum, nil
+	}
+	sum.Min, sum.Max = ids[0], ids[len(ids)-1]
+	for name, pending := range g.consumers {
+		if len(pending) > 0 {
+			sum.Consumers = append(sum.Consumers, ConsumerPending{Name: name, Count: len(pending)})
+		}
+	}
+	sort.Slice(sum.Consumers, func(i, j int) bool { return sum.Consumers[i].Name < sum.Consumers[j].Name })
+	return sum, nil
+}
+
+// структура PendingEntry — строка подробного ответа XPENDING.
+type PendingEntry struct {
+	ID            StreamID
+	Consumer      string
+	Idle          time.Duration
+	DeliveryCount int
+}
+
+// структура PendingFilter — отбор записей для подробного XPENDING:
+// идентификаторы от Start до End, не больше Count, простаивающие не меньше MinIdle,
+// только потребителя Consumer (если задан).
+type PendingFilter struct {
+	Start, End StreamID
+	Count      int
+	Consumer   string
+	MinIdle    time.Duration
+}
+
+// метод XPending - подробный список ожидающих записей группы по возрастанию идентификатора.
+func (s *Store) XPending(key, group string, f PendingFilter) ([]PendingEntry, error) {
+	defer s.rlock(key)()
+	_, g, err := s.readGroup(key, group)
+	if err != nil {
+		return nil, err
+	}
+	pel := g.pel
+	if f.Consumer != "" {
+		pel = g.consumers[f.Consumer]
+	}
+	now := time.Now()
+	out := []PendingEntry{}
+	for _, id := range sortedIDs(pel) {
+		if len(out) == f.Count {
+			break
+		}
+		if id.Less(f.Start) || f.End.Less(id) {
+			continue
+		}
+		pe := pel[id]
+		idle := now.Sub(pe.deliveryTime)
+		if idle < f.MinIdle {
+			continue
+		}
+		out = append(out, PendingEntry{ID: id, Consumer: pe.consumer, Idle: idle, DeliveryCount: pe.deliveryCount})
+	}
+	return out, nil
+}
+
+// структура XClaimArgs — параметры XCLAIM.
+// DeliveryTime — новое время доставки (нулевое — сейчас), RetryCount < 0 — число доставок
+// увеличивается на 1 (без JUSTID), иначе задаётся явно. Force — забрать запись,
+// которой нет в списке ожидающих, если она есть в потоке. LastID — сдвинуть позицию группы вперёд.
+type XClaimArgs struct {
+	MinIdle      time.Duration
+	DeliveryTime time.Time
+	RetryCount   int
+	Force        bool
+	JustID       bool
+	LastID       *StreamID
+}
+
+// метод XClaim - передаёт потребителю consumer записи ids, простаивающие не меньше MinIdle.
+// Записи, удалённые из потока, убираются из списка ожидающих и в ответ не попадают.
+// При JustID у возвращённых записей Fields == nil.
+func (s *Store) XClaim(key, group, consumer string, ids []StreamID, args XClaimArgs) ([]StreamEntry, error) {
+	defer s.lock(key)()
+	st, g, err := s.groupFor(key, group)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	at := args.DeliveryTime
+	if at.IsZero() || at.After(now) {
+		at = now
+	}
+
+	changed := false
+	if args.LastID != nil && g.lastID.Less(*args.LastID) {
+		g.lastID = *args.LastID
+		changed = true
+	}
+	claimed := []StreamEntry{}
+	for _, id := range ids {
+		entry, exists := st.lookup(id)
+		pe, pending := g.pel[id]
+		switch {
+		case !exists:
+			// запись удалили из потока: ждать её подтверждения больше незачем
+			changed = g.release(id) || changed
+			continue
+		case !pending && !args.Force:
+			continue
+		case pending && now.Sub(pe.deliveryTime) < args.MinIdle:
+			continue
+		}
+		pe = g.assign(id, consumer, at)
+		if args.RetryCount >= 0 {
+			pe.deliveryCount = args.RetryCount
+		} else if !args.JustID {
+			pe.deliveryCount++
+		}
+		if args.JustID {
+			entry.Fields = nil
+		}
+		claimed = append(claimed, entry)
+		changed = true
+	}
+	if changed {
+		s.touch(key)
+	}
+	return claimed, nil
+}
+
+// метод XAutoClaim - как XClaim, но сама перебирает список ожидающих начиная со start
+// (XAUTOCLAIM): забирает до count записей, простаивающих не меньше minIdle, просматривая не больше count*10.
+// Возвращает курсор для следующего вызова (0-0 — список пройден до конца), забранные записи
+// и идентификаторы записей, которые оказались удалены из потока (они убираются из списка ожидающих).
+func (s *Store) XAutoClaim(key, group, consumer string, minIdle time.Duration, start StreamID, count int, justID bool) (StreamID, []StreamEntry, []StreamID, error) {
+	defer s.lock(key)()
+	st, g, err := s.groupFor(key, group)
+	if err != nil {
+		return StreamID{}, nil, nil, err
+	}
+	now := time.Now()
+	claimed, deleted := []StreamEntry{}, []StreamID{}
+	ids := sortedIDs(g.pel)
+	i := sort.Search(len(ids), func(i int) bool { return !ids[i].Less(start) })
+	for attempts := count * 10; i < len(ids) && attempts > 0 && len(claimed)+len(deleted) < count; i, attempts = i+1, attempts-1 {
+		id := ids[i]
+		if now.Sub(g.pel[id].deliveryTime) < minIdle {
+			continue
+		}
+		entry, exists := st.lookup(id)
+		if !exists {
+			g.release(id)
+			deleted = append(deleted, id)
+			continue
+		}
+		pe := g.assign(id, consumer, now)
+		if !justID {
+			pe.deliveryCount++
+		} else {
+			entry.Fields = nil
+		}
+		claimed = append(claimed, entry)
+	}
+	var next StreamID
+	if i < len(ids) {
+		next = ids[i]
+	}
+	if len(claimed)+len(deleted) > 0 {
+		s.touch(key)
+	}
+	return next, claimed, deleted, nil
+}
+
+// структура StreamSnapshot — снимок потока (см. Entry): записи по порядку, служебные идентификаторы и группы.
+type StreamSnapshot struct {
+	Entries      []StreamEntry
+	LastID       StreamID
+	MaxDeleted   StreamID
+	EntriesAdded uint64
+	Groups       []GroupSnapshot
+}
+
+// структура GroupSnapshot — снимок группы потребителей: позиция, все потребители (по имени)
+// и список ожидающих по возрастанию идентификатора.
+type GroupSnapshot struct {
+	Name      string
+	LastID    StreamID
+	Consumers []string
+	Pending   []GroupPending
+}
+
+// структура GroupPending — запись списка ожидающих в снимке.
+type GroupPending struct {
+	ID            StreamID
+	Consumer      string
+	DeliveryTime  time.Time
+	DeliveryCount int
+}
+
+// метод snapshot - копия потока для снимка.
+func (st *stream) snapshot() StreamSnapshot {
+	snap := StreamSnapshot{
+		Entries:      make([]StreamEntry, len(st.entries)),
+		LastID:       st.lastID,
+		MaxDeleted:   st.maxDeleted,
+		EntriesAdded: st.entriesAdded,
+	}
+	for i, e := range st.entries {
+		snap.Entries[i] = StreamEntry{ID: e.ID, Fields: append([]string(nil), e.Fields...)}
+	}
+	for name, g := range st.groups {
+		gs := GroupSnapshot{Name: name, LastID: g.lastID, Consumers: make([]string, 0, len(g.consumers))}
+		for consumer := range g.consumers {
+			gs.Consumers = append(gs.Consumers, consumer)
+		}
+		sort.Strings(gs.Consumers)
+		for _, id := range sortedIDs(g.pel) {
+			pe := g.pel[id]
+			gs.Pending = append(gs.Pending, GroupPending{ID: id, Consumer: pe.consumer, DeliveryTime: pe.deliveryTime, DeliveryCount: pe.deliveryCount})
+		}
+		snap.Groups = append(snap.Groups, gs)
+	}
+	sort.Slice(snap.Groups, func(i, j int) bool { return snap.Groups[i].Name < snap.Groups[j].Name })
+	return snap
+}
+
+// функция streamFromSnapshot - поток из снимка.
+func streamFromSnapshot(snap StreamSnapshot) *stream {
+	st := newStream()
+	st.lastID, st.maxDeleted, st.entriesAdded = snap.LastID, snap.MaxDeleted, snap.EntriesAdded
+	for _, e := range snap.Entries {
+		st.entries = append(st.entries, StreamEntry{ID: e.ID, Fields: append([]string(nil), e.Fields...)})
+	}
+	for _, gs := range snap.Groups {
+		g := newConsumerGroup(gs.LastID)
+		for _, consumer := range gs.Consumers {
+			g.consumer(consumer)
+		}
+		for _, p := range gs.Pending {
+			g.assign(p.ID, p.Consumer, p.DeliveryTime).deliveryCount = p.DeliveryCount
+		}
+		st.groups[gs.Name] = g
+	}
+	return st
+}
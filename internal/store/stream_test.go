@@ -0,0 +1,175 @@
+package store
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// ids возвращает идентификаторы записей по порядку
+func ids(entries []StreamEntry) []string {
+	out := make([]string, len(entries))
+	for i, e := range entries {
+		out[i] = e.ID.String()
+	}
+	return out
+}
+
+// проверяет выдачу идентификаторов XADD: явные, "ms-*" и "*", и отказ для неубывающих
+func TestStreamAddIDs(t *testing.T) {
+	s := NewStore()
+	add := func(spec StreamIDSpec) (StreamID, error) {
+		id, _, err := s.XAdd("s", spec, []string{"f", "v"}, nil, false)
+		return id, err
+	}
+
+	if id, err := add(StreamIDSpec{AutoSeq: true}); err != nil || id.String() != "0-1" {
+		t.Fatalf("0-*: expected 0-1, got %v (%v)", id, err)
+	}
+	if _, err := add(StreamIDSpec{ID: StreamID{0, 1}}); !errors.Is(err, ErrStreamIDTooSmall) {
+		t.Fatalf("repeated ID: expected ErrStreamIDTooSmall, got %v", err)
+	}
+	if id, _ := add(StreamIDSpec{ID: StreamID{5, 3}}); id.String() != "5-3" {
+		t.Fatalf("explicit ID: got %v", id)
+	}
+	if id, _ := add(StreamIDSpec{ID: StreamID{Ms: 5}, AutoSeq: true}); id.String() != "5-4" {
+		t.Fatalf("5-*: expected 5-4, got %v", id)
+	}
+	id, _ := add(StreamIDSpec{AutoMs: true})
+	if now := uint64(time.Now().UnixMilli()); id.Ms+1000 < now || id.Ms > now {
+		t.Fatalf("*: expected current time, got %v", id)
+	}
+
+	if _, ok, _ := s.XAdd("other", StreamIDSpec{AutoMs: true}, []string{"f", "v"}, nil, true); ok {
+		t.Fatal("NOMKSTREAM must not create a stream")
+	}
+	s.Set("str", "x")
+	if _, _, err := s.XAdd("str", StreamIDSpec{AutoMs: true}, []string{"f", "v"}, nil, false); !errors.Is(err, ErrWrongType) {
+		t.Fatalf("expected ErrWrongType, got %v", err)
+	}
+}
+
+// проверяет диапазоны, обрезку и то, что пустой поток не удаляется и помнит последний идентификатор
+func TestStreamRangeTrim(t *testing.T) {
+	s := NewStore()
+	for ms := uint64(1); ms <= 5; ms++ {
+		s.XAdd("s", StreamIDSpec{ID: StreamID{Ms: ms}}, []string{"f", "v"}, nil, false)
+	}
+
+	got, _ := s.XRange("s", StreamID{2, 0}, StreamID{4, 0}, 0, false)
+	if want := []string{"2-0", "3-0", "4-0"}; !reflect.DeepEqual(ids(got), want) {
+		t.Fatalf("XRANGE: expected %v, got %v", want, ids(got))
+	}
+	got, _ = s.XRange("s", StreamID{}, MaxStreamID, 2, true)
+	if want := []string{"5-0", "4-0"}; !reflect.DeepEqual(ids(got), want) {
+		t.Fatalf("XREVRANGE COUNT 2: expected %v, got %v", want, ids(got))
+	}
+
+	if n, _ := s.XTrim("s", StreamTrim{MaxLen: 1, Approx: true, Limit: 2}); n != 2 {
+		t.Fatalf("XTRIM ~ LIMIT 2: expected 2 removed, got %d", n)
+	}
+	if n, _ := s.XTrim("s", StreamTrim{ByMinID: true, MinID: StreamID{5, 0}}); n != 2 {
+		t.Fatalf("XTRIM MINID: expected 2 removed, got %d", n)
+	}
+	if n, _ := s.XDel("s", StreamID{5, 0}, StreamID{9, 0}); n != 1 {
+		t.Fatalf("XDEL: expected 1, got %d", n)
+	}
+	if last, ok, _ := s.StreamLastID("s"); !ok || last.String() != "5-0" {
+		t.Fatalf("empty stream must keep last ID 5-0, got %v (exists %v)", last, ok)
+	}
+	if _, _, err := s.XAdd("s", StreamIDSpec{ID: StreamID{5, 0}}, []string{"f", "v"}, nil, false); !errors.Is(err, ErrStreamIDTooSmall) {
+		t.Fatalf("XADD below last ID of empty stream: expected ErrStreamIDTooSmall, got %v", err)
+	}
+}
+
+// проверяет группу потребителей: раздачу новых записей, историю, подтверждение и передачу записей
+func TestStreamConsumerGroup(t *testing.T) {
+	s := NewStore()
+	for ms := uint64(1); ms <= 3; ms++ {
+		s.XAdd("s", StreamIDSpec{ID: StreamID{Ms: ms}}, []string{"f", "v"}, nil, false)
+	}
+	if err := s.XGroupCreate("s", "g", StreamID{}, false, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.XGroupCreate("s", "g", StreamID{}, false, false); !errors.Is(err, ErrBusyGroup) {
+		t.Fatalf("expected ErrBusyGroup, got %v", err)
+	}
+
+	read := func(consumer string, after *StreamID, count int) []string {
+		res, err := s.XReadGroup(XReadGroupArgs{Group: "g", Consumer: consumer, Keys: []string{"s"}, After: []*StreamID{after}, Count: count})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(res) == 0 {
+			return nil
+		}
+		return ids(res[0].Entries)
+	}
+	if got := read("alice", nil, 2); !reflect.DeepEqual(got, []string{"1-0", "2-0"}) {
+		t.Fatalf("alice >: got %v", got)
+	}
+	if got := read("bob", nil, 0); !reflect.DeepEqual(got, []string{"3-0"}) {
+		t.Fatalf("bob >: got %v", got)
+	}
+	if got := read("bob", nil, 0); got != nil {
+		t.Fatalf("nothing new for the group: got %v", got)
+	}
+	if got := read("alice", &StreamID{}, 0); !reflect.DeepEqual(got, []string{"1-0", "2-0"}) {
+		t.Fatalf("alice history: got %v", got)
+	}
+
+	if n, _ := s.XAck("s", "g", StreamID{1, 0}, StreamID{3, 0}, StreamID{9, 0}); n != 2 {
+		t.Fatalf("XACK: expected 2, got %d", n)
+	}
+	sum, _ := s.XPendingSummary("s", "g")
+	if sum.Count != 1 || sum.Min.String() != "2-0" || !reflect.DeepEqual(sum.Consumers, []ConsumerPending{{"alice", 1}}) {
+		t.Fatalf("XPENDING summary: got %+v", sum)
+	}
+
+	// запись простаивает меньше min-idle — не передаётся; без ограничения — передаётся
+	claimed, _ := s.XClaim("s", "g", "bob", []StreamID{{2, 0}}, XClaimArgs{MinIdle: time.Hour, RetryCount: -1})
+	if len(claimed) != 0 {
+		t.Fatalf("XCLAIM with min-idle: expected nothing, got %v", ids(claimed))
+	}
+	claimed, _ = s.XClaim("s", "g", "bob", []StreamID{{2, 0}}, XClaimArgs{RetryCount: -1})
+	if !reflect.DeepEqual(ids(claimed), []string{"2-0"}) {
+		t.Fatalf("XCLAIM: got %v", ids(claimed))
+	}
+	pending, _ := s.XPending("s", "g", PendingFilter{End: MaxStreamID, Count: 10})
+	// доставки: XREADGROUP, история и XCLAIM
+	if len(pending) != 1 || pending[0].Consumer != "bob" || pending[0].DeliveryCount != 3 {
+		t.Fatalf("XPENDING: got %+v", pending)
+	}
+
+	// удалённая запись уходит из списка ожидающих при XAUTOCLAIM
+	s.XDel("s", StreamID{2, 0})
+	next, claimed, deleted, _ := s.XAutoClaim("s", "g", "alice", 0, StreamID{}, 10, false)
+	if !next.IsZero() || len(claimed) != 0 || len(deleted) != 1 || deleted[0].String() != "2-0" {
+		t.Fatalf("XAUTOCLAIM: got next %v, claimed %v, deleted %v", next, ids(claimed), deleted)
+	}
+	if sum, _ := s.XPendingSummary("s", "g"); sum.Count != 0 {
+		t.Fatalf("expected empty PEL, got %+v", sum)
+	}
+
+	var noGroup *NoGroupError
+	if _, err := s.XReadGroup(XReadGroupArgs{Group: "missing", Consumer: "c", Keys: []string{"s"}, After: []*StreamID{nil}}); !errors.As(err, &noGroup) {
+		t.Fatalf("expected NoGroupError, got %v", err)
+	}
+}
+
+// проверяет, что поток с группами переживает Snapshot → Restore
+func TestStreamSnapshot(t *testing.T) {
+	s := NewStore()
+	s.XAdd("s", StreamIDSpec{ID: StreamID{1, 0}}, []string{"a", "1"}, nil, false)
+	s.XAdd("s", StreamIDSpec{ID: StreamID{2, 0}}, []string{"b", "2"}, nil, false)
+	s.XGroupCreate("s", "g", StreamID{}, false, false)
+	s.XGroupCreateConsumer("s", "g", "idle")
+	s.XReadGroup(XReadGroupArgs{Group: "g", Consumer: "c", Keys: []string{"s"}, After: []*StreamID{nil}, Count: 1})
+
+	restored := NewStore()
+	restored.Restore(s.Snapshot())
+	if !reflect.DeepEqual(restored.Snapshot(), s.Snapshot()) {
+		t.Fatalf("expected %+v, got %+v", s.Snapshot(), restored.Snapshot())
+	}
+}
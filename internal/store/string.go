@@ -0,0 +1,269 @@
+package store
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// структура SetOptions — условия и TTL для SET: NX — только если ключа нет, XX — только если есть,
+// KeepTTL — сохранить текущий TTL ключа, Get — вернуть прежнее значение,
+// ExpireAt — момент истечения нового значения (нулевое время — без TTL).
+type SetOptions struct {
+	NX, XX, KeepTTL, Get bool
+	ExpireAt             time.Time
+}
+
+// метод SetWith - записывает строку по ключу с учётом условий SET и сразу выставляет TTL,
+// так что значение без срока жизни никто не увидит.
+// Возвращает прежнее строковое значение (hadOld — было ли оно) и флаг, записано ли новое.
+// С Get ключ другого типа даёт ErrWrongType и не перезаписывается; без Get он просто заменяется строкой.
+func (s *Store) SetWith(key, value string, opts SetOptions) (old string, hadOld, ok bool, err error) {
+	defer s.lock(key)()
+	s.expireIfNeeded(key)
+
+	cur, exists := s.get(key)
+	if exists && opts.Get {
+		str, isString := cur.(string)
+		if !isString {
+			return "", false, false, ErrWrongType
+		}
+		old, hadOld = str, true
+	}
+	if (exists && opts.NX) || (!exists && opts.XX) {
+		return old, hadOld, false, nil
+	}
+
+	s.put(key, value)
+	s.touch(key)
+	switch {
+	case !opts.ExpireAt.IsZero() && !opts.ExpireAt.After(time.Now()):
+		// момент истечения уже наступил (EXAT/PXAT в прошлом) — значение сразу истекает
+		s.remove(key)
+	case !opts.ExpireAt.IsZero():
+		s.setTTL(key, opts.ExpireAt)
+	case !opts.KeepTTL:
+		s.clearTTL(key)
+	}
+	return old, hadOld, true, nil
+}
+
+// ошибки строковых команд; тексты совпадают с ответами Redis
+var (
+	ErrNotInteger     = errors.New("ERR value is not an integer or out of range")
+	ErrStringTooLarge = errors.New("ERR string exceeds maximum allowed size (proto-max-bulk-len)")
+)
+
+// MaxStringSize — наибольшая длина строкового значения, как proto-max-bulk-len в Redis (512 МБ).
+const MaxStringSize = 512 << 20
+
+// метод stringFor - строковое значение ключа для изменения (вызывается под s.lock(key)).
+// Истёкший ключ сначала удаляется; false — ключа нет, ErrWrongType — по ключу лежит не строка.
+func (s *Store) stringFor(key string) (string, bool, error) {
+	s.expireIfNeeded(key)
+	val, ok := s.get(key)
+	if !ok {
+		return "", false, nil
+	}
+	str, isString := val.(string)
+	if !isString {
+		return "", false, ErrWrongType
+	}
+	return str, true, nil
+}
+
+// функция parseStrictInt - разбирает целое так же строго, как string2ll в Redis:
+// без знака "+", пробелов и ведущих нулей ("007", "-0" и "+1" — не числа).
+func parseStrictInt(s string) (int64, bool) {
+	if s == "" || s[0] == '+' || (len(s) > 1 && s[0] == '0') || strings.HasPrefix(s, "-0") {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	return n, err == nil
+}
+
+// метод IncrBy - атомарно увеличивает целое значение ключа на delta (INCR, DECR, INCRBY, DECRBY)
+// и возвращает новое значение. Отсутствующий ключ считается нулём, TTL ключа сохраняется.
+// Ошибки: ErrNotInteger — в ключе не целое число, ErrOverflow — результат не помещается в int64.
+func (s *Store) IncrBy(key string, delta int64) (int64, error) {
+	defer s.lock(key)()
+	str, exists, err := s.stringFor(key)
+	if err != nil {
+		return 0, err
+	}
+	var cur int64
+	if exists {
+		var ok bool
+		if cur, ok = parseStrictInt(str); !ok {
+			return 0, ErrNotInteger
+		}
+	}
+	if (delta > 0 && cur > math.MaxInt64-delta) || (delta < 0 && cur < math.MinInt64-delta) {
+		return 0, ErrOverflow
+	}
+	cur += delta
+	s.put(key, strconv.FormatInt(cur, 10))
+	s.touch(key)
+	return cur, nil
+}
+
+// метод IncrByFloat - атомарно увеличивает значение ключа на дробное incr (строкой, как в команде)
+// и возвращает новое значение. Считает с точностью long double, как Redis (см. float.go); TTL сохраняется.
+// Ошибки: ErrNotFloat — incr или значение ключа не число, ErrNaNOrInfinity — результат не конечен.
+func (s *Store) IncrByFloat(key, incr string) (string, error) {
+	delta, ok := parseLongDouble(incr)
+	if !ok {
+		return "", ErrNotFloat
+	}
+	defer s.lock(key)()
+	str, exists, err := s.stringFor(key)
+	if err != nil {
+		return "", err
+	}
+	cur := new(big.Float)
+	if exists {
+		if cur, ok = parseLongDouble(str); !ok {
+			return "", ErrNotFloat
+		}
+	}
+	sum, ok := addLongDouble(cur, delta)
+	if !ok {
+		return "", ErrNaNOrInfinity
+	}
+	res := formatLongDouble(sum)
+	s.put(key, res)
+	s.touch(key)
+	return res, nil
+}
+
+// метод Append - дописывает value в конец строки (создавая её при необходимости) и возвращает новую длину.
+func (s *Store) Append(key, value string) (int, error) {
+	defer s.lock(key)()
+	str, _, err := s.stringFor(key)
+	if err != nil {
+		return 0, err
+	}
+	if len(str)+len(value) > MaxStringSize {
+		return 0, ErrStringTooLarge
+	}
+	str += value
+	s.put(key, str)
+	s.touch(key)
+	return len(str), nil
+}
+
+// метод StrLen - длина строки в байтах (0, если ключа нет).
+func (s *Store) StrLen(key string) (int, error) {
+	str, _, err := s.GetString(key)
+	return len(str), err
+}
+
+// метод GetRange - подстрока с байта start по байт end включительно; отрицательные индексы
+// отсчитываются от конца строки, выход за границы обрезается, как в Redis.
+func (s *Store) GetRange(key string, start, end int64) (string, error) {
+	str, _, err := s.GetString(key)
+	if err != nil {
+		return "", err
+	}
+	n := int64(len(str))
+	if start < 0 && end < 0 && start > end {
+		return "", nil
+	}
+	if start < 0 {
+		start = max(n+start, 0)
+	}
+	if end < 0 {
+		end = max(n+end, 0)
+	}
+	end = min(end, n-1)
+	if n == 0 || start > end {
+		return "", nil
+	}
+	return str[start : end+1], nil
+}
+
+// метод SetRange - перезаписывает строку начиная с байта offset (недостающее место заполняется нулевыми байтами)
+// и возвращает новую длину. Пустой value ничего не меняет и не создаёт ключ.
+func (s *Store) SetRange(key string, offset int64, value string) (int, error) {
+	defer s.lock(key)()
+	str, _, err := s.stringFor(key)
+	if err != nil {
+		return 0, err
+	}
+	if value == "" {
+		return len(str), nil
+	}
+	if offset+int64(len(value)) > MaxStringSize {
+		return 0, ErrStringTooLarge
+	}
+	buf := []byte(str)
+	if end := int(offset) + len(value); end > len(buf) {
+		buf = append(buf, make([]byte, end-len(buf))...)
+	}
+	copy(buf[offset:], value)
+	s.put(key, string(buf))
+	s.touch(key)
+	return len(buf), nil
+}
+
+// метод GetDel - возвращает строковое значение ключа и удаляет ключ.
+func (s *Store) GetDel(key string) (string, bool, error) {
+	defer s.lock(key)()
+	str, exists, err := s.stringFor(key)
+	if err != nil || !exists {
+		return "", false, err
+	}
+	s.remove(key)
+	s.touch(key)
+	return str, true, nil
+}
+
+// метод GetEx - возвращает строковое значение ключа и меняет его TTL: at — новый момент истечения
+// (в прошлом — ключ удаляется), persist — снять TTL; без того и другого TTL не трогается.
+func (s *Store) GetEx(key string, at time.Time, persist bool) (string, bool, error) {
+	defer s.lock(key)()
+	str, exists, err := s.stringFor(key)
+	if err != nil || !exists {
+		return "", false, err
+	}
+	switch {
+	case !at.IsZero() && !at.After(time.Now()):
+		s.remove(key)
+		s.touch(key)
+	case !at.IsZero():
+		s.setTTL(key, at)
+		s.touch(key)
+	case persist:
+		if _, ok := s.ttlOf(key); ok {
+			s.clearTTL(key)
+			s.touch(key)
+		}
+	}
+	return str, true, nil
+}
+
+// метод MSet - атомарно (под блокировками шардов всех ключей) записывает пары ключ/значение из kv;
+// как и SET, снимает прежние TTL. Если nx (MSETNX) и хотя бы один ключ существует — не записывает ничего
+// и возвращает false.
+func (s *Store) MSet(nx bool, kv ...string) bool {
+	keys := make([]string, 0, len(kv)/2)
+	for i := 0; i < len(kv); i += 2 {
+		keys = append(keys, kv[i])
+	}
+	defer s.lock(keys...)()
+	for _, key := range keys {
+		s.expireIfNeeded(key)
+		if _, exists := s.get(key); exists && nx {
+			return false
+		}
+	}
+	for i := 0; i < len(kv); i += 2 {
+		s.put(kv[i], kv[i+1])
+		s.clearTTL(kv[i])
+		s.touch(kv[i])
+	}
+	return true
+}
@@ -0,0 +1,195 @@
+package store
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// проверяет условия NX/XX, возврат прежнего значения и то, как SET обращается с TTL
+func TestSetWith(t *testing.T) {
+	s := NewStore()
+	if _, _, ok, _ := s.SetWith("k", "v1", SetOptions{XX: true}); ok {
+		t.Fatal("XX must not create a key")
+	}
+	if _, _, ok, _ := s.SetWith("k", "v1", SetOptions{NX: true, ExpireAt: time.Now().Add(time.Minute)}); !ok {
+		t.Fatal("NX must create a missing key")
+	}
+	if _, ok := s.ExpireTime("k"); !ok {
+		t.Fatal("expected TTL to be set together with the value")
+	}
+
+	old, hadOld, ok, _ := s.SetWith("k", "v2", SetOptions{NX: true, Get: true})
+	if ok || !hadOld || old != "v1" {
+		t.Fatalf("NX GET on existing key: got old %q (had %v), set %v", old, hadOld, ok)
+	}
+
+	s.SetWith("k", "v3", SetOptions{KeepTTL: true})
+	if _, ok := s.ExpireTime("k"); !ok {
+		t.Fatal("KEEPTTL must keep the TTL")
+	}
+	s.Set("k", "v4")
+	if _, ok := s.ExpireTime("k"); ok {
+		t.Fatal("plain SET must clear the TTL")
+	}
+
+	// момент истечения в прошлом — значение сразу исчезает
+	s.SetWith("k", "v5", SetOptions{ExpireAt: time.Now().Add(-time.Second)})
+	if _, ok := s.Get("k"); ok {
+		t.Fatal("expected key with expire time in the past to be deleted")
+	}
+
+	s.RPush("list", "a")
+	if _, _, _, err := s.SetWith("list", "x", SetOptions{Get: true}); !errors.Is(err, ErrWrongType) {
+		t.Fatalf("GET on a list: expected ErrWrongType, got %v", err)
+	}
+	if _, _, ok, _ := s.SetWith("list", "x", SetOptions{}); !ok {
+		t.Fatal("SET without GET must overwrite a key of another type")
+	}
+}
+
+// проверяет целочисленные счётчики: строгий разбор числа, переполнение и сохранение TTL
+func TestIncrBy(t *testing.T) {
+	s := NewStore()
+	if n, err := s.IncrBy("n", 5); err != nil || n != 5 {
+		t.Fatalf("INCRBY on missing key: got %d, %v", n, err)
+	}
+	s.ExpireAt("n", time.Now().Add(time.Minute))
+	if n, _ := s.IncrBy("n", -7); n != -2 {
+		t.Fatalf("expected -2, got %d", n)
+	}
+	if _, ok := s.ExpireTime("n"); !ok {
+		t.Fatal("INCRBY must keep the TTL")
+	}
+
+	for _, bad := range []string{"", "abc", "007", "+1", " 1", "-0", "99999999999999999999"} {
+		s.Set("bad", bad)
+		if _, err := s.IncrBy("bad", 1); !errors.Is(err, ErrNotInteger) {
+			t.Fatalf("INCR on %q: expected ErrNotInteger, got %v", bad, err)
+		}
+	}
+
+	s.Set("max", "9223372036854775806")
+	if n, err := s.IncrBy("max", 1); err != nil || n != math.MaxInt64 {
+		t.Fatalf("INCR up to MaxInt64: got %d, %v", n, err)
+	}
+	if _, err := s.IncrBy("max", 1); !errors.Is(err, ErrOverflow) {
+		t.Fatalf("expected ErrOverflow, got %v", err)
+	}
+	if v, _ := s.Get("max"); v != "9223372036854775807" {
+		t.Fatalf("failed INCR must not change the value, got %q", v)
+	}
+
+	s.RPush("list", "a")
+	if _, err := s.IncrBy("list", 1); !errors.Is(err, ErrWrongType) {
+		t.Fatalf("INCR on a list: expected ErrWrongType, got %v", err)
+	}
+}
+
+// проверяет дробное приращение: точность, формат ответа и ошибки
+func TestIncrByFloat(t *testing.T) {
+	s := NewStore()
+	s.Set("f", "10.50")
+	if v, err := s.IncrByFloat("f", "0.1"); err != nil || v != "10.6" {
+		t.Fatalf("INCRBYFLOAT: got %q, %v", v, err)
+	}
+	if v, _ := s.IncrByFloat("f", "-5.6"); v != "5" {
+		t.Fatalf("expected trailing zeros to be trimmed, got %q", v)
+	}
+	if v, _ := s.IncrByFloat("e", "5.0e3"); v != "5000" {
+		t.Fatalf("exponent notation: got %q", v)
+	}
+	if _, err := s.IncrByFloat("f", "abc"); !errors.Is(err, ErrNotFloat) {
+		t.Fatalf("expected ErrNotFloat, got %v", err)
+	}
+	if _, err := s.IncrByFloat("f", "inf"); !errors.Is(err, ErrNotFloat) {
+		t.Fatalf("inf: expected ErrNotFloat, got %v", err)
+	}
+}
+
+// проверяет APPEND, GETRANGE и SETRANGE с дополнением нулевыми байтами
+func TestStringRanges(t *testing.T) {
+	s := NewStore()
+	if n, _ := s.Append("s", "Hello"); n != 5 {
+		t.Fatalf("APPEND to missing key: got %d", n)
+	}
+	s.Append("s", " World")
+	for _, tc := range []struct {
+		start, end int64
+		want       string
+	}{{0, 4, "Hello"}, {-5, -1, "World"}, {-100, 2, "Hel"}, {6, 100, "World"}, {5, 2, ""}, {100, 200, ""}} {
+		if got, _ := s.GetRange("s", tc.start, tc.end); got != tc.want {
+			t.Fatalf("GETRANGE %d %d: got %q, want %q", tc.start, tc.end, got, tc.want)
+		}
+	}
+
+	if n, _ := s.SetRange("s", 6, "Redis"); n != 11 {
+		t.Fatalf("SETRANGE: got length %d", n)
+	}
+	if v, _ := s.Get("s"); v != "Hello Redis" {
+		t.Fatalf("SETRANGE: got %q", v)
+	}
+	if n, _ := s.SetRange("pad", 3, "x"); n != 4 {
+		t.Fatalf("SETRANGE on missing key: got length %d", n)
+	}
+	if v, _ := s.Get("pad"); v != "\x00\x00\x00x" {
+		t.Fatalf("expected zero padding, got %q", v)
+	}
+	if n, _ := s.SetRange("empty", 10, ""); n != 0 {
+		t.Fatalf("SETRANGE with empty value must not create the key, got %d", n)
+	}
+	if _, ok := s.Get("empty"); ok {
+		t.Fatal("SETRANGE with empty value created the key")
+	}
+	if _, err := s.SetRange("s", MaxStringSize, "x"); !errors.Is(err, ErrStringTooLarge) {
+		t.Fatalf("expected ErrStringTooLarge, got %v", err)
+	}
+	if n, _ := s.StrLen("s"); n != 11 {
+		t.Fatalf("STRLEN: got %d", n)
+	}
+}
+
+// проверяет GETDEL, GETEX и атомарность MSETNX
+func TestGetDelGetExMSet(t *testing.T) {
+	s := NewStore()
+	s.Set("k", "v")
+	if v, ok, _ := s.GetDel("k"); !ok || v != "v" {
+		t.Fatalf("GETDEL: got %q, %v", v, ok)
+	}
+	if _, ok := s.Get("k"); ok {
+		t.Fatal("GETDEL must delete the key")
+	}
+
+	s.Set("k", "v")
+	s.GetEx("k", time.Now().Add(time.Minute), false)
+	if _, ok := s.ExpireTime("k"); !ok {
+		t.Fatal("GETEX EX must set a TTL")
+	}
+	s.GetEx("k", time.Time{}, true)
+	if _, ok := s.ExpireTime("k"); ok {
+		t.Fatal("GETEX PERSIST must clear the TTL")
+	}
+	if v, ok, _ := s.GetEx("k", time.Now().Add(-time.Second), false); !ok || v != "v" {
+		t.Fatalf("GETEX with past time must still return the value, got %q, %v", v, ok)
+	}
+	if _, ok := s.Get("k"); ok {
+		t.Fatal("GETEX with past time must delete the key")
+	}
+
+	s.Set("b", "old")
+	s.ExpireAt("b", time.Now().Add(time.Minute))
+	if s.MSet(true, "a", "1", "b", "2") {
+		t.Fatal("MSETNX must fail when one of the keys exists")
+	}
+	if _, ok := s.Get("a"); ok {
+		t.Fatal("failed MSETNX must not write any key")
+	}
+	s.MSet(false, "a", "1", "b", "2")
+	if v, _ := s.Get("b"); v != "2" {
+		t.Fatalf("MSET: got %q", v)
+	}
+	if _, ok := s.ExpireTime("b"); ok {
+		t.Fatal("MSET must clear the TTL like SET")
+	}
+}
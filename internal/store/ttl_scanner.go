@@ -4,64 +4,190 @@ import "time"
 
 // метод Expire - задаёт время жизни ключа (в секундах).
 // Возвращает true, если TTL успешно установлен, и false, если ключ не существует.
+// Неположительное время жизни удаляет ключ сразу.
 func (s *Store) Expire(key string, seconds int) bool {
-	s.mtx.Lock()
-	defer s.mtx.Unlock()
-	if _, ok := s.data[key]; !ok {
-		return false
+	return s.ExpireAtWith(key, time.Now().Add(time.Duration(seconds)*time.Second), ExpireFlags{})
+}
+
+// expireBatchSize — сколько истёкших ключей удаляется за один захват блокировки шарда.
+const expireBatchSize = 20
+
+// метод ActiveExpireCycle - удаляет истёкшие ключи, не дольше budget.
+// Ключи берутся с вершины индекса истечения каждого шарда, поэтому цикл тратит время только на ключи,
+// которым пора истечь. Блокировка шарда берётся на одну пачку, так что клиенты успевают работать между пачками,
+// а при массовом истечении цикл не "подвешивает" сервер. Каждый цикл начинается со следующего шарда,
+// чтобы при нехватке времени до дальних шардов тоже доходила очередь. Возвращает число удалённых ключей.
+func (s *Store) ActiveExpireCycle(budget time.Duration) int {
+	start := time.Now()
+	first := int(s.expireCursor.Add(1))
+	total := 0
+	for i := range s.shards {
+		sh := s.shards[(first+i)%len(s.shards)]
+		for {
+			expired, more := s.expireDue(sh, expireBatchSize)
+			total += expired
+			if time.Since(start) >= budget {
+				return total
+			}
+			if !more {
+				break
+			}
+		}
 	}
-	s.ttl[key] = time.Now().Add(time.Duration(seconds) * time.Second) // считаем момент истечения значения по ключу
-	return true                                                       // показываем что ttl установлен успешно
+	return total
 }
 
-// метод CleanExpiredKeys - удаляет значение по ключу если его время жизни истекло
-// то есть проходит по всем s.ttl и чистит просроченные
-func (s *Store) CleanExpiredKeys() {
-	s.mtx.Lock()
-	defer s.mtx.Unlock()
-	for key, expireTime := range s.ttl {
-		if time.Now().After(expireTime) { // если настал момент истечения (если текущее время позже, чем истечение ключа)
-			delete(s.data, key)
-			delete(s.ttl, key)
+// метод expireDue - удаляет до n ключей шарда, чей TTL уже истёк.
+// more — остались ли ещё истёкшие ключи после этой пачки.
+func (s *Store) expireDue(sh *shard, n int) (expired int, more bool) {
+	sh.mtx.Lock()
+	defer sh.mtx.Unlock()
+	now := time.Now()
+	for {
+		key, at, ok := sh.expiry.next()
+		if !ok || now.Before(at) {
+			return expired, false
 		}
+		if expired == n {
+			return expired, true
+		}
+		s.deleteExpired(key)
+		expired++
+	}
+}
+
+// метод deleteExpired - удаляет ключ с истёкшим TTL и учитывает его в expired_keys (вызывается под блокировкой шарда ключа).
+func (s *Store) deleteExpired(key string) {
+	s.remove(key)
+	s.shardOf(key).expired.Add(1)
+	s.touch(key)
+}
+
+// метод ExpiredKeys - сколько ключей удалено из-за истечения TTL (для INFO stats).
+func (s *Store) ExpiredKeys() int64 {
+	var n int64
+	for _, sh := range s.shards {
+		n += sh.expired.Load()
 	}
+	return n
 }
 
 // метод StartTTLScanner — запускает фоновую горутину,
-// которая через равные интервалы времени вызывает CleanExpiredKeys()
-// и удаляет истёкшие ключи из хранилища.
+// которая через равные интервалы времени вызывает ActiveExpireCycle
+// и удаляет истёкшие ключи из хранилища. На один цикл отводится четверть интервала.
 func (s *Store) StartTTLScanner(interval time.Duration) {
 	go func() {
 		ticker := time.NewTicker(interval) // создаёт таймер, который через каждые interval вызывает очистку просроченных ключей.
 		defer ticker.Stop()                // гарантируем остановку таймера при завершении горутины
 
 		for range ticker.C { // ждём каждый "тик" таймера
-			s.CleanExpiredKeys()
+			s.ActiveExpireCycle(interval / 4)
 		}
 	}()
 }
 
+// структура ExpireFlags — условия EXPIRE (Redis 7): NX — только если у ключа нет TTL, XX — только если есть,
+// GT/LT — только если новый момент истечения позже/раньше текущего (ключ без TTL живёт "вечно").
+type ExpireFlags struct {
+	NX, XX, GT, LT bool
+}
+
+// метод allows - можно ли заменить текущий момент истечения cur (has — есть ли он) на at.
+func (f ExpireFlags) allows(has bool, cur, at time.Time) bool {
+	switch {
+	case has && f.NX, !has && f.XX:
+		return false
+	case f.GT && (!has || !at.After(cur)), f.LT && has && !at.Before(cur):
+		return false
+	}
+	return true
+}
+
 // TTL сообщает, сколько секунд осталось до истечения срока жизни ключа.
 // Возвращает:
 // -2 если ключ не существует,
 // -1 если ключ существует, но без TTL,
-// N (в секундах), если TTL установлен и активен.
+// N (в секундах, с округлением, как в Redis), если TTL установлен и активен.
 func (s *Store) TTL(key string) int {
-	s.mtx.RLock()
-	defer s.mtx.RUnlock()
-	if _, ok := s.data[key]; !ok {
-		return -2
+	ms := s.PTTL(key)
+	if ms < 0 {
+		return int(ms)
 	}
+	return int((ms + 500) / 1000)
+}
 
-	expireTime, ttlOk := s.ttl[key]
-	if !ttlOk {
-		return -1
+// метод PTTL - то же, что TTL, но оставшееся время в миллисекундах.
+func (s *Store) PTTL(key string) int64 {
+	at := s.ExpireTimeMillis(key)
+	if at < 0 {
+		return at
 	}
+	return max(at-time.Now().UnixMilli(), 0)
+}
 
-	remainingTime := int(time.Until(expireTime).Seconds())
-	if remainingTime < 0 { // если время уже ключа уже истекло (то есть также его уже нет в хранилище)
+// метод ExpireTimeMillis - абсолютный момент истечения ключа в unix-миллисекундах
+// (-2, если ключа нет, и -1, если у него нет TTL).
+func (s *Store) ExpireTimeMillis(key string) int64 {
+	defer s.rlock(key)()
+	if !s.aliveLocked(key, time.Now()) {
 		return -2
 	}
+	at, ok := s.ttlOf(key)
+	if !ok {
+		return -1
+	}
+	return at.UnixMilli()
+}
 
-	return remainingTime // возвращаем оставшееся время
+// метод ExpireAt - задаёт абсолютный момент истечения ключа.
+// Если этот момент уже наступил, ключ удаляется сразу.
+// Возвращает false, если ключ не существует.
+func (s *Store) ExpireAt(key string, at time.Time) bool {
+	return s.ExpireAtWith(key, at, ExpireFlags{})
+}
+
+// метод ExpireAtWith - задаёт абсолютный момент истечения ключа с учётом условий NX/XX/GT/LT.
+// Если этот момент уже наступил, ключ удаляется сразу.
+// Возвращает false, если ключа нет или условие не выполнено.
+func (s *Store) ExpireAtWith(key string, at time.Time, flags ExpireFlags) bool {
+	defer s.lock(key)()
+	s.expireIfNeeded(key)
+	if _, ok := s.get(key); !ok {
+		return false
+	}
+	cur, has := s.ttlOf(key)
+	if !flags.allows(has, cur, at) {
+		return false
+	}
+	s.touch(key)
+	if !at.After(time.Now()) {
+		s.remove(key)
+		return true
+	}
+	s.setTTL(key, at)
+	return true
+}
+
+// метод Persist - снимает TTL с ключа.
+// Возвращает false, если ключа нет или у него и так нет TTL.
+func (s *Store) Persist(key string) bool {
+	defer s.lock(key)()
+	s.expireIfNeeded(key)
+	if _, ok := s.ttlOf(key); !ok {
+		return false
+	}
+	s.clearTTL(key)
+	s.touch(key)
+	return true
+}
+
+// метод ExpireTime - возвращает абсолютный момент истечения ключа.
+// Второе значение false, если у ключа нет TTL (или самого ключа нет).
+func (s *Store) ExpireTime(key string) (time.Time, bool) {
+	defer s.rlock(key)()
+	if !s.aliveLocked(key, time.Now()) {
+		return time.Time{}, false
+	}
+	at, ok := s.ttlOf(key)
+	return at, ok
 }
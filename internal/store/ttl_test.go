@@ -1,6 +1,7 @@
 package store
 
 import (
+	"strconv"
 	"testing"
 	"time"
 )
@@ -22,7 +23,7 @@ func TestStore_TTL(t *testing.T) {
 
 	// ждем истечения
 	time.Sleep(1500 * time.Millisecond)
-	s.CleanExpiredKeys() // вручную чистим просроченные ключи
+	// сканер не запущен: истёкший ключ должен исчезнуть сразу при обращении
 
 	// проверяем после истечения срока
 	_, ok = s.Get("name")
@@ -30,3 +31,127 @@ func TestStore_TTL(t *testing.T) {
 		t.Errorf("expected key 'anton' to expire")
 	}
 }
+
+func TestStore_ExpireAt(t *testing.T) {
+	s := NewStore()
+	s.Set("name", "anton")
+
+	at := time.Now().Add(time.Minute)
+	if !s.ExpireAt("name", at) {
+		t.Fatalf("expected ExpireAt to succeed")
+	}
+	got, ok := s.ExpireTime("name")
+	if !ok || !got.Equal(at) {
+		t.Errorf("expected expire time %v, got %v (ok=%v)", at, got, ok)
+	}
+
+	// момент в прошлом — ключ удаляется сразу
+	if !s.ExpireAt("name", time.Now().Add(-time.Second)) {
+		t.Fatalf("expected ExpireAt in the past to succeed")
+	}
+	if _, ok := s.Get("name"); ok {
+		t.Errorf("expected key to be deleted by ExpireAt in the past")
+	}
+
+	if s.ExpireAt("missing", at) {
+		t.Errorf("expected ExpireAt on missing key to fail")
+	}
+}
+
+// проверяет условия NX/XX/GT/LT, PERSIST и миллисекундную точность PTTL
+func TestStore_ExpireFlags(t *testing.T) {
+	s := NewStore()
+	s.Set("k", "v")
+	soon, later := time.Now().Add(time.Minute), time.Now().Add(time.Hour)
+
+	if s.ExpireAtWith("k", soon, ExpireFlags{XX: true}) {
+		t.Fatal("XX must fail for a key without TTL")
+	}
+	if s.ExpireAtWith("k", soon, ExpireFlags{GT: true}) {
+		t.Fatal("GT must fail for a key without TTL (it lives forever)")
+	}
+	if !s.ExpireAtWith("k", later, ExpireFlags{NX: true}) {
+		t.Fatal("NX must succeed for a key without TTL")
+	}
+	if !s.ExpireAtWith("k", soon, ExpireFlags{LT: true}) {
+		t.Fatal("LT must succeed for an earlier expire time")
+	}
+	if s.ExpireAtWith("k", later, ExpireFlags{LT: true}) {
+		t.Fatal("LT must fail for a later expire time")
+	}
+	if ms := s.PTTL("k"); ms <= 59000 || ms > 60000 {
+		t.Fatalf("expected PTTL about 60000ms, got %d", ms)
+	}
+
+	if !s.Persist("k") || s.TTL("k") != -1 {
+		t.Fatal("PERSIST must remove the TTL")
+	}
+	if s.Persist("k") {
+		t.Fatal("PERSIST on a key without TTL must return false")
+	}
+
+	// неположительный TTL удаляет ключ сразу
+	if !s.Expire("k", 0) || s.TTL("k") != -2 {
+		t.Fatal("expected EXPIRE 0 to delete the key")
+	}
+}
+
+// setTTL задаёт ключу момент истечения напрямую (в обход проверок ExpireAt, который удалил бы ключ сразу)
+func setTTL(s *Store, key string, at time.Time) {
+	defer s.lock(key)()
+	s.setTTL(key, at)
+}
+
+// проверяет, что активный цикл удаляет все истёкшие ключи пачками и не трогает живые
+func TestStore_ActiveExpireCycle(t *testing.T) {
+	s := NewStore()
+	past := time.Now().Add(-time.Second)
+	for i := 0; i < 1000; i++ {
+		key := strconv.Itoa(i)
+		s.Set(key, "v")
+		setTTL(s, key, past) // ключ истёк, но ещё лежит в хранилище
+	}
+	s.Set("alive", "v")
+	s.Expire("alive", 60)
+
+	if n := s.ActiveExpireCycle(time.Second); n != 1000 {
+		t.Fatalf("expected all 1000 expired keys to be removed, got %d", n)
+	}
+	if s.TTL("alive") <= 0 {
+		t.Fatal("alive key must survive the cycle")
+	}
+	if s.ExpiredKeys() != 1000 {
+		t.Fatalf("expected expired_keys 1000, got %d", s.ExpiredKeys())
+	}
+
+	// нулевой бюджет — ровно одна пачка (в хранилище из одного шарда)
+	one := NewShardedStore(1)
+	for i := 0; i < 100; i++ {
+		key := "late" + strconv.Itoa(i)
+		one.Set(key, "v")
+		setTTL(one, key, past)
+	}
+	if n := one.ActiveExpireCycle(0); n != expireBatchSize {
+		t.Fatalf("zero budget: expected one batch of %d, removed %d", expireBatchSize, n)
+	}
+}
+
+// проверяет, что истёкший ключ не виден ни GET, ни DEL, ни снимку ещё до удаления
+func TestStore_LazyExpire(t *testing.T) {
+	s := NewStore()
+	s.Set("k", "v")
+	setTTL(s, "k", time.Now().Add(-time.Millisecond))
+
+	if _, ok, _ := s.GetString("k"); ok {
+		t.Fatal("GetString returned an expired value")
+	}
+	if len(s.Snapshot()) != 0 {
+		t.Fatal("snapshot contains an expired key")
+	}
+	if n := s.Del("k"); n != 0 {
+		t.Fatalf("DEL of an expired key: expected 0, got %d", n)
+	}
+	if s.ExpiredKeys() != 1 {
+		t.Fatalf("expected expired_keys 1, got %d", s.ExpiredKeys())
+	}
+}
@@ -0,0 +1,101 @@
+package store
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// структура Watch — наблюдение одного клиента за набором ключей (команда WATCH).
+// Если кто-то изменит, удалит или даст истечь любому из этих ключей,
+// флаг touched поднимется, и EXEC этого клиента будет отменён (оптимистическая блокировка).
+type Watch struct {
+	touched atomic.Bool
+	keys    map[string]bool // ключ → существовал ли он в момент WATCH
+}
+
+// конструктор NewWatch создаёт пустое наблюдение.
+func NewWatch() *Watch {
+	return &Watch{keys: make(map[string]bool)}
+}
+
+// метод Watch - начинает следить за ключами от имени w.
+func (s *Store) Watch(w *Watch, keys ...string) {
+	defer s.rlock(keys...)() // шарды ключей, затем watchMu — в том же порядке, что и touch
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	now := time.Now()
+	for _, key := range keys {
+		if _, ok := w.keys[key]; ok {
+			continue // уже следим
+		}
+		w.keys[key] = s.aliveLocked(key, now)
+		if s.watchers[key] == nil {
+			s.watchers[key] = make(map[*Watch]struct{})
+		}
+		s.watchers[key][w] = struct{}{}
+		s.watching.Add(1)
+	}
+}
+
+// метод Unwatch - перестаёт следить за всеми ключами w и сбрасывает флаг изменений.
+func (s *Store) Unwatch(w *Watch) {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	for key := range w.keys {
+		s.watching.Add(-1)
+		delete(s.watchers[key], w)
+		if len(s.watchers[key]) == 0 {
+			delete(s.watchers, key)
+		}
+	}
+	w.keys = make(map[string]bool)
+	w.touched.Store(false)
+}
+
+// метод WatchChanged - проверяет, изменился ли хоть один ключ с момента WATCH.
+// Ключ, который существовал, а теперь истёк (даже если сканер его ещё не удалил), тоже считается изменённым.
+func (s *Store) WatchChanged(w *Watch) bool {
+	if w.touched.Load() {
+		return true
+	}
+	keys := make([]string, 0, len(w.keys))
+	for key := range w.keys {
+		keys = append(keys, key)
+	}
+	defer s.rlock(keys...)()
+	now := time.Now()
+	for key, existed := range w.keys {
+		if existed && !s.aliveLocked(key, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// метод notifyWatchers - поднимает флаг у всех, кто следит за ключом (вызывается под блокировкой шарда ключа).
+func (s *Store) notifyWatchers(key string) {
+	if s.watching.Load() == 0 { // никто ничего не отслеживает — частый случай
+		return
+	}
+	s.watchMu.RLock()
+	defer s.watchMu.RUnlock()
+	s.notifyWatchersLocked(key)
+}
+
+// метод notifyWatchersLocked - то же, что notifyWatchers, когда s.watchMu уже взят.
+func (s *Store) notifyWatchersLocked(key string) {
+	for w := range s.watchers[key] {
+		w.touched.Store(true)
+	}
+}
+
+// метод aliveLocked - существует ли ключ и не истёк ли его TTL (вызывается под блокировкой шарда ключа).
+func (s *Store) aliveLocked(key string, now time.Time) bool {
+	if _, ok := s.get(key); !ok {
+		return false
+	}
+	if at, ok := s.ttlOf(key); ok && !now.Before(at) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+// проверяет, что изменение наблюдаемого ключа поднимает флаг, а чужого — нет
+func TestStore_WatchTouched(t *testing.T) {
+	s := NewStore()
+	s.Set("balance", "100")
+
+	w := NewWatch()
+	s.Watch(w, "balance")
+	s.Set("other", "1")
+	if s.WatchChanged(w) {
+		t.Fatalf("expected watch to be clean after unrelated write")
+	}
+
+	s.Set("balance", "50")
+	if !s.WatchChanged(w) {
+		t.Fatalf("expected watch to be dirty after write to watched key")
+	}
+
+	// Unwatch сбрасывает наблюдение
+	s.Unwatch(w)
+	s.Watch(w, "balance")
+	if s.WatchChanged(w) {
+		t.Errorf("expected watch to be clean after re-watch")
+	}
+	s.Flush()
+	if !s.WatchChanged(w) {
+		t.Errorf("expected watch to be dirty after flush")
+	}
+}
+
+// проверяет, что истечение наблюдаемого ключа тоже отменяет транзакцию,
+// даже если сканер его ещё не удалил
+func TestStore_WatchExpired(t *testing.T) {
+	s := NewStore()
+	s.Set("lock", "token")
+	s.ExpireAt("lock", time.Now().Add(50*time.Millisecond))
+
+	w := NewWatch()
+	s.Watch(w, "lock")
+	time.Sleep(80 * time.Millisecond)
+	if !s.WatchChanged(w) {
+		t.Errorf("expected watch to be dirty after watched key expired")
+	}
+}
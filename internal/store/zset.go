@@ -0,0 +1,555 @@
+package store
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrScoreNaN — приращение дало NaN (например, +inf + -inf).
+var ErrScoreNaN = errors.New("ERR resulting score is not a number (NaN)")
+
+// структура ZMember — элемент упорядоченного множества со своим счётом.
+type ZMember struct {
+	Member string
+	Score  float64
+}
+
+// структура zset — значение-упорядоченное множество: словарь член → счёт для O(1) поиска счёта
+// и список с пропусками для порядка, рангов и диапазонов.
+type zset struct {
+	dict map[string]float64
+	zsl  *skiplist
+}
+
+// конструктор newZset создаёт пустое упорядоченное множество.
+func newZset() *zset {
+	return &zset{dict: make(map[string]float64), zsl: newSkiplist()}
+}
+
+// метод set - задаёт счёт элемента. Возвращает, был ли элемент добавлен и изменился ли счёт.
+func (z *zset) set(member string, score float64) (added, changed bool) {
+	cur, ok := z.dict[member]
+	if ok {
+		if cur == score {
+			return false, false
+		}
+		z.zsl.delete(cur, member)
+		z.zsl.insert(score, member)
+		z.dict[member] = score
+		return false, true
+	}
+	z.zsl.insert(score, member)
+	z.dict[member] = score
+	return true, false
+}
+
+// метод remove - удаляет элемент. false — его не было.
+func (z *zset) remove(member string) bool {
+	score, ok := z.dict[member]
+	if !ok {
+		return false
+	}
+	z.zsl.delete(score, member)
+	delete(z.dict, member)
+	return true
+}
+
+// метод len - число элементов.
+func (z *zset) len() int {
+	return len(z.dict)
+}
+
+// метод items - все элементы по возрастанию счёта.
+func (z *zset) items() []ZMember {
+	out := make([]ZMember, 0, z.len())
+	for x := z.zsl.header.level[0].forward; x != nil; x = x.level[0].forward {
+		out = append(out, ZMember{x.member, x.score})
+	}
+	return out
+}
+
+// структура ScoreRange — диапазон счетов для ZRANGEBYSCORE/ZCOUNT: границы и их исключительность ("(1.5").
+type ScoreRange struct {
+	Min, Max     float64
+	MinEx, MaxEx bool
+}
+
+// метод aboveMin - не ниже ли счёт нижней границы.
+func (r ScoreRange) aboveMin(score float64) bool {
+	if r.MinEx {
+		return score > r.Min
+	}
+	return score >= r.Min
+}
+
+// метод belowMax - не выше ли счёт верхней границы.
+func (r ScoreRange) belowMax(score float64) bool {
+	if r.MaxEx {
+		return score < r.Max
+	}
+	return score <= r.Max
+}
+
+// структура LexBound — граница лексикографического диапазона: "[a" (включительно), "(a" (исключая),
+// "-" (минус бесконечность, Inf = -1) или "+" (плюс бесконечность, Inf = 1).
+type LexBound struct {
+	Value     string
+	Exclusive bool
+	Inf       int
+}
+
+// структура LexRange — диапазон для ZRANGEBYLEX/ZLEXCOUNT (имеет смысл, когда у всех элементов одинаковый счёт).
+type LexRange struct {
+	Min, Max LexBound
+}
+
+// метод aboveMin - не ниже ли элемент нижней границы.
+func (r LexRange) aboveMin(member string) bool {
+	switch {
+	case r.Min.Inf != 0:
+		return r.Min.Inf < 0
+	case r.Min.Exclusive:
+		return member > r.Min.Value
+	default:
+		return member >= r.Min.Value
+	}
+}
+
+// метод belowMax - не выше ли элемент верхней границы.
+func (r LexRange) belowMax(member string) bool {
+	switch {
+	case r.Max.Inf != 0:
+		return r.Max.Inf > 0
+	case r.Max.Exclusive:
+		return member < r.Max.Value
+	default:
+		return member <= r.Max.Value
+	}
+}
+
+// структура zrange — диапазон в терминах узлов: нижняя и верхняя граница как условия на узел.
+type zrange struct {
+	aboveMin func(*skiplistNode) bool
+	belowMax func(*skiplistNode) bool
+}
+
+// функция scoreRange - условия на узлы для диапазона счетов.
+func scoreRange(r ScoreRange) zrange {
+	return zrange{
+		aboveMin: func(n *skiplistNode) bool { return r.aboveMin(n.score) },
+		belowMax: func(n *skiplistNode) bool { return r.belowMax(n.score) },
+	}
+}
+
+// функция lexRange - условия на узлы для лексикографического диапазона.
+func lexRange(r LexRange) zrange {
+	return zrange{
+		aboveMin: func(n *skiplistNode) bool { return r.aboveMin(n.member) },
+		belowMax: func(n *skiplistNode) bool { return r.belowMax(n.member) },
+	}
+}
+
+// метод first - первый узел диапазона (nil — диапазон пуст).
+func (z *zset) first(r zrange) *skiplistNode {
+	x := z.zsl.firstNotBefore(func(n *skiplistNode) bool { return !r.aboveMin(n) })
+	if x == nil || !r.belowMax(x) {
+		return nil
+	}
+	return x
+}
+
+// метод last - последний узел диапазона (nil — диапазон пуст).
+func (z *zset) last(r zrange) *skiplistNode {
+	x := z.zsl.lastWithin(r.belowMax)
+	if x == nil || !r.aboveMin(x) {
+		return nil
+	}
+	return x
+}
+
+// метод inRange - элементы диапазона по возрастанию (или по убыванию, если rev),
+// пропуская первые offset и возвращая не больше count (count < 0 — без ограничения).
+func (z *zset) inRange(r zrange, rev bool, offset, count int) []ZMember {
+	out := []ZMember{}
+	if offset < 0 || count == 0 {
+		return out
+	}
+	x, inside := z.first(r), r.belowMax
+	if rev {
+		x, inside = z.last(r), r.aboveMin
+	}
+	for ; x != nil && offset > 0; offset-- {
+		x = step(x, rev)
+	}
+	for ; x != nil && inside(x) && count != 0; count-- {
+		out = append(out, ZMember{x.member, x.score})
+		x = step(x, rev)
+	}
+	return out
+}
+
+// функция step - следующий узел по возрастанию или по убыванию.
+func step(x *skiplistNode, rev bool) *skiplistNode {
+	if rev {
+		return x.backward
+	}
+	return x.level[0].forward
+}
+
+// метод count - число элементов в диапазоне за O(log n) через ранги.
+func (z *zset) count(r zrange) int {
+	first := z.first(r)
+	if first == nil {
+		return 0
+	}
+	last := z.last(r)
+	return z.zsl.rank(last.score, last.member) - z.zsl.rank(first.score, first.member) + 1
+}
+
+// метод zsetFor - возвращает упорядоченное множество по ключу (вызывается под s.lock(key)).
+// Если ключа нет и create — создаёт пустое, иначе возвращает nil.
+func (s *Store) zsetFor(key string, create bool) (*zset, error) {
+	s.expireIfNeeded(key)
+	val, ok := s.get(key)
+	if !ok {
+		if !create {
+			return nil, nil
+		}
+		z := newZset()
+		s.put(key, z)
+		return z, nil
+	}
+	z, isZset := val.(*zset)
+	if !isZset {
+		return nil, ErrWrongType
+	}
+	return z, nil
+}
+
+// метод readZset - возвращает упорядоченное множество для чтения (вызывается под s.rlock(key)).
+// nil без ошибки — ключа нет.
+func (s *Store) readZset(key string) (*zset, error) {
+	val, ok := s.lookup(key)
+	if !ok {
+		return nil, nil
+	}
+	z, isZset := val.(*zset)
+	if !isZset {
+		return nil, ErrWrongType
+	}
+	return z, nil
+}
+
+// метод dropIfEmptyZset - удаляет ключ опустевшего упорядоченного множества.
+func (s *Store) dropIfEmptyZset(key string, z *zset) {
+	if z.len() == 0 {
+		s.remove(key)
+	}
+}
+
+// структура ZAddFlags — условия ZADD: NX — только новые элементы, XX — только существующие,
+// GT/LT — обновлять счёт, только если новый больше/меньше текущего (новые элементы добавляются).
+type ZAddFlags struct {
+	NX, XX, GT, LT bool
+}
+
+// метод allows - можно ли записать счёт score элементу с текущим счётом cur (exists — элемент есть).
+func (f ZAddFlags) allows(exists bool, cur, score float64) bool {
+	switch {
+	case exists && f.NX, !exists && f.XX:
+		return false
+	case exists && f.GT && score <= cur, exists && f.LT && score >= cur:
+		return false
+	}
+	return true
+}
+
+// метод ZAdd - добавляет элементы или обновляет их счета с учётом флагов.
+// Возвращает число добавленных элементов и число элементов, у которых изменился счёт.
+func (s *Store) ZAdd(key string, flags ZAddFlags, items []ZMember) (added, changed int, err error) {
+	defer s.lock(key)()
+	z, err := s.zsetFor(key, !flags.XX)
+	if err != nil || z == nil {
+		return 0, 0, err
+	}
+	for _, it := range items {
+		cur, exists := z.dict[it.Member]
+		if !flags.allows(exists, cur, it.Score) {
+			continue
+		}
+		a, c := z.set(it.Member, it.Score)
+		if a {
+			added++
+		}
+		if c {
+			changed++
+		}
+	}
+	if added+changed > 0 {
+		s.touch(key)
+	}
+	s.dropIfEmptyZset(key, z) // например, ZADD NX без новых элементов к отсутствующему ключу
+	return added, changed, nil
+}
+
+// метод ZIncrBy - увеличивает счёт элемента на incr (отсутствующий элемент считается с нулём) с учётом флагов.
+// Второе значение false, если флаги не позволили изменение (ZADD ... INCR тогда отвечает nil).
+func (s *Store) ZIncrBy(key string, flags ZAddFlags, member string, incr float64) (float64, bool, error) {
+	defer s.lock(key)()
+	z, err := s.zsetFor(key, !flags.XX)
+	if err != nil || z == nil {
+		return 0, false, err
+	}
+	cur, exists := z.dict[member]
+	score := cur + incr
+	if math.IsNaN(score) {
+		s.dropIfEmptyZset(key, z)
+		return 0, false, ErrScoreNaN
+	}
+	if !flags.allows(exists, cur, score) {
+		s.dropIfEmptyZset(key, z)
+		return 0, false, nil
+	}
+	if added, changed := z.set(member, score); added || changed {
+		s.touch(key)
+	}
+	return score, true, nil
+}
+
+// метод ZRem - удаляет элементы и возвращает, сколько реально удалено.
+// Опустевшее множество удаляется вместе с ключом.
+func (s *Store) ZRem(key string, members ...string) (int, error) {
+	defer s.lock(key)()
+	z, err := s.zsetFor(key, false)
+	if err != nil || z == nil {
+		return 0, err
+	}
+	removed := 0
+	for _, m := range members {
+		if z.remove(m) {
+			removed++
+		}
+	}
+	if removed > 0 {
+		s.touch(key)
+	}
+	s.dropIfEmptyZset(key, z)
+	return removed, nil
+}
+
+// метод ZCard - число элементов (0, если ключа нет).
+func (s *Store) ZCard(key string) (int, error) {
+	defer s.rlock(key)()
+	z, err := s.readZset(key)
+	if err != nil || z == nil {
+		return 0, err
+	}
+	return z.len(), nil
+}
+
+// метод ZScore - счёт элемента. Второе значение false, если нет ключа или элемента.
+func (s *Store) ZScore(key, member string) (float64, bool, error) {
+	defer s.rlock(key)()
+	z, err := s.readZset(key)
+	if err != nil || z == nil {
+		return 0, false, err
+	}
+	score, ok := z.dict[member]
+	return score, ok, nil
+}
+
+// метод ZMScore - счета нескольких элементов; у отсутствующих — nil.
+func (s *Store) ZMScore(key string, members ...string) ([]*float64, error) {
+	defer s.rlock(key)()
+	z, err := s.readZset(key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*float64, len(members))
+	if z == nil {
+		return out, nil
+	}
+	for i, m := range members {
+		if score, ok := z.dict[m]; ok {
+			out[i] = &score
+		}
+	}
+	return out, nil
+}
+
+// метод ZRank - ранг элемента с нуля по возрастанию счёта (по убыванию, если rev).
+// Второе значение false, если нет ключа или элемента.
+func (s *Store) ZRank(key, member string, rev bool) (int, bool, error) {
+	defer s.rlock(key)()
+	z, err := s.readZset(key)
+	if err != nil || z == nil {
+		return 0, false, err
+	}
+	score, ok := z.dict[member]
+	if !ok {
+		return 0, false, nil
+	}
+	rank := z.zsl.rank(score, member)
+	if rev {
+		return z.len() - rank, true, nil
+	}
+	return rank - 1, true, nil
+}
+
+// метод ZRange - элементы с рангами от start до stop включительно (отрицательные — с конца),
+// по возрастанию счёта или по убыванию, если rev.
+func (s *Store) ZRange(key string, start, stop int, rev bool) ([]ZMember, error) {
+	defer s.rlock(key)()
+	z, err := s.readZset(key)
+	if err != nil || z == nil {
+		return []ZMember{}, err
+	}
+	from, to := listRange(start, stop, z.len())
+	out := make([]ZMember, 0, to-from)
+	if from == to {
+		return out, nil
+	}
+	// ранг в обратном порядке r соответствует рангу len-1-r в прямом
+	rank := from + 1
+	if rev {
+		rank = z.len() - from
+	}
+	for x := z.zsl.byRank(rank); x != nil && len(out) < to-from; x = step(x, rev) {
+		out = append(out, ZMember{x.member, x.score})
+	}
+	return out, nil
+}
+
+// метод ZRangeByScore - элементы со счётом в диапазоне r (см. zset.inRange про rev, offset и count).
+func (s *Store) ZRangeByScore(key string, r ScoreRange, rev bool, offset, count int) ([]ZMember, error) {
+	return s.zrangeBy(key, scoreRange(r), rev, offset, count)
+}
+
+// метод ZRangeByLex - элементы в лексикографическом диапазоне r.
+func (s *Store) ZRangeByLex(key string, r LexRange, rev bool, offset, count int) ([]ZMember, error) {
+	return s.zrangeBy(key, lexRange(r), rev, offset, count)
+}
+
+// метод zrangeBy - общая часть ZRangeByScore и ZRangeByLex.
+func (s *Store) zrangeBy(key string, r zrange, rev bool, offset, count int) ([]ZMember, error) {
+	defer s.rlock(key)()
+	z, err := s.readZset(key)
+	if err != nil || z == nil {
+		return []ZMember{}, err
+	}
+	return z.inRange(r, rev, offset, count), nil
+}
+
+// метод ZCount - число элементов со счётом в диапазоне r.
+func (s *Store) ZCount(key string, r ScoreRange) (int, error) {
+	return s.zcountBy(key, scoreRange(r))
+}
+
+// метод ZLexCount - число элементов в лексикографическом диапазоне r.
+func (s *Store) ZLexCount(key string, r LexRange) (int, error) {
+	return s.zcountBy(key, lexRange(r))
+}
+
+// метод zcountBy - общая часть ZCount и ZLexCount.
+func (s *Store) zcountBy(key string, r zrange) (int, error) {
+	defer s.rlock(key)()
+	z, err := s.readZset(key)
+	if err != nil || z == nil {
+		return 0, err
+	}
+	return z.count(r), nil
+}
+
+// метод ZPop - удаляет и возвращает до count элементов с наименьшим счётом (с наибольшим, если max).
+// Опустевшее множество удаляется.
+func (s *Store) ZPop(key string, count int, max bool) ([]ZMember, error) {
+	defer s.lock(key)()
+	z, err := s.zsetFor(key, false)
+	if err != nil || z == nil {
+		return []ZMember{}, err
+	}
+	out := make([]ZMember, 0, min(count, z.len()))
+	for len(out) < count && z.len() > 0 {
+		x := z.zsl.header.level[0].forward
+		if max {
+			x = z.zsl.tail
+		}
+		out = append(out, ZMember{x.member, x.score})
+		z.remove(x.member)
+	}
+	if len(out) > 0 {
+		s.touch(key)
+	}
+	s.dropIfEmptyZset(key, z)
+	return out, nil
+}
+
+// метод ZRemRangeByRank - удаляет элементы с рангами от start до stop включительно и возвращает их число.
+func (s *Store) ZRemRangeByRank(key string, start, stop int) (int, error) {
+	defer s.lock(key)()
+	z, err := s.zsetFor(key, false)
+	if err != nil || z == nil {
+		return 0, err
+	}
+	from, to := listRange(start, stop, z.len())
+	var doomed []string
+	for x := z.zsl.byRank(from + 1); x != nil && len(doomed) < to-from; x = x.level[0].forward {
+		doomed = append(doomed, x.member)
+	}
+	return s.zremove(key, z, doomed), nil
+}
+
+// метод ZRemRangeByScore - удаляет элементы со счётом в диапазоне r и возвращает их число.
+func (s *Store) ZRemRangeByScore(key string, r ScoreRange) (int, error) {
+	return s.zremRangeBy(key, scoreRange(r))
+}
+
+// метод ZRemRangeByLex - удаляет элементы в лексикографическом диапазоне r и возвращает их число.
+func (s *Store) ZRemRangeByLex(key string, r LexRange) (int, error) {
+	return s.zremRangeBy(key, lexRange(r))
+}
+
+// метод zremRangeBy - общая часть ZRemRangeByScore и ZRemRangeByLex.
+func (s *Store) zremRangeBy(key string, r zrange) (int, error) {
+	defer s.lock(key)()
+	z, err := s.zsetFor(key, false)
+	if err != nil || z == nil {
+		return 0, err
+	}
+	var doomed []string
+	for _, it := range z.inRange(r, false, 0, -1) {
+		doomed = append(doomed, it.Member)
+	}
+	return s.zremove(key, z, doomed), nil
+}
+
+// метод zremove - удаляет элементы из множества ключа key (вызывается под s.lock(key)).
+func (s *Store) zremove(key string, z *zset, members []string) int {
+	for _, m := range members {
+		z.remove(m)
+	}
+	if len(members) > 0 {
+		s.touch(key)
+	}
+	s.dropIfEmptyZset(key, z)
+	return len(members)
+}
+
+// метод ZScan - порция элементов для курсорного обхода (см. scanNames).
+func (s *Store) ZScan(key string, cursor uint64, count int, match string) ([]ZMember, uint64, error) {
+	defer s.rlock(key)()
+	z, err := s.readZset(key)
+	if err != nil || z == nil {
+		return []ZMember{}, 0, err
+	}
+	members := make([]string, 0, z.len())
+	for m := range z.dict {
+		members = append(members, m)
+	}
+	page, next := scanNames(members, cursor, count, match)
+	out := make([]ZMember, len(page))
+	for i, m := range page {
+		out[i] = ZMember{m, z.dict[m]}
+	}
+	return out, next, nil
+}
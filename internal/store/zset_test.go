@@ -0,0 +1,158 @@
+package store
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// members возвращает только имена элементов по порядку
+func members(items []ZMember) []string {
+	out := make([]string, len(items))
+	for i, it := range items {
+		out[i] = it.Member
+	}
+	return out
+}
+
+// проверяет ранги и поиск по рангу в списке с пропусками на случайных вставках и удалениях
+func TestSkiplist_Rank(t *testing.T) {
+	sl := newSkiplist()
+	want := make(map[string]float64)
+	for i := 0; i < 2000; i++ {
+		member := "m" + strconv.Itoa(rand.IntN(500))
+		if score, ok := want[member]; ok {
+			sl.delete(score, member)
+			delete(want, member)
+			continue
+		}
+		score := float64(rand.IntN(50)) // много одинаковых счетов — порядок по имени
+		sl.insert(score, member)
+		want[member] = score
+	}
+
+	order := make([]ZMember, 0, len(want))
+	for m, s := range want {
+		order = append(order, ZMember{m, s})
+	}
+	sort.Slice(order, func(i, j int) bool {
+		if order[i].Score != order[j].Score {
+			return order[i].Score < order[j].Score
+		}
+		return order[i].Member < order[j].Member
+	})
+
+	if sl.length != len(order) {
+		t.Fatalf("expected length %d, got %d", len(order), sl.length)
+	}
+	for i, it := range order {
+		if r := sl.rank(it.Score, it.Member); r != i+1 {
+			t.Fatalf("rank of %v: expected %d, got %d", it, i+1, r)
+		}
+		if x := sl.byRank(i + 1); x == nil || x.member != it.Member {
+			t.Fatalf("byRank(%d): expected %q, got %v", i+1, it.Member, x)
+		}
+	}
+	if sl.rank(1000, "missing") != 0 || sl.byRank(len(order)+1) != nil {
+		t.Errorf("expected missing element and rank out of range to be reported")
+	}
+}
+
+// проверяет ZADD с флагами NX/XX/GT/LT и ZINCRBY
+func TestStore_ZAddFlags(t *testing.T) {
+	s := NewStore()
+	if added, _, _ := s.ZAdd("z", ZAddFlags{}, []ZMember{{"a", 1}, {"b", 2}}); added != 2 {
+		t.Errorf("expected 2 added, got %d", added)
+	}
+	if added, changed, _ := s.ZAdd("z", ZAddFlags{NX: true}, []ZMember{{"a", 10}, {"c", 3}}); added != 1 || changed != 0 {
+		t.Errorf("NX: expected 1 added and 0 changed, got %d/%d", added, changed)
+	}
+	if added, changed, _ := s.ZAdd("z", ZAddFlags{XX: true}, []ZMember{{"a", 5}, {"d", 4}}); added != 0 || changed != 1 {
+		t.Errorf("XX: expected 0 added and 1 changed, got %d/%d", added, changed)
+	}
+	if _, changed, _ := s.ZAdd("z", ZAddFlags{GT: true}, []ZMember{{"a", 4}, {"b", 7}}); changed != 1 {
+		t.Errorf("GT: expected 1 changed, got %d", changed)
+	}
+	if _, changed, _ := s.ZAdd("z", ZAddFlags{LT: true}, []ZMember{{"a", 1}, {"b", 9}}); changed != 1 {
+		t.Errorf("LT: expected 1 changed, got %d", changed)
+	}
+	if got, _ := s.ZRange("z", 0, -1, false); !reflect.DeepEqual(got, []ZMember{{"a", 1}, {"c", 3}, {"b", 7}}) {
+		t.Errorf("unexpected order: %v", got)
+	}
+
+	if score, ok, _ := s.ZIncrBy("z", ZAddFlags{}, "a", 2.5); !ok || score != 3.5 {
+		t.Errorf("expected 3.5, got %v (ok=%v)", score, ok)
+	}
+	if _, ok, _ := s.ZIncrBy("z", ZAddFlags{GT: true}, "a", -1); ok {
+		t.Errorf("expected GT to reject decreasing increment")
+	}
+	s.ZAdd("z", ZAddFlags{}, []ZMember{{"inf", math.Inf(1)}})
+	if _, _, err := s.ZIncrBy("z", ZAddFlags{}, "inf", math.Inf(-1)); !errors.Is(err, ErrScoreNaN) {
+		t.Errorf("expected ErrScoreNaN, got %v", err)
+	}
+
+	// XX к отсутствующему ключу не создаёт его
+	s.ZAdd("none", ZAddFlags{XX: true}, []ZMember{{"a", 1}})
+	if ttl := s.TTL("none"); ttl != -2 {
+		t.Errorf("expected key not to be created, TTL=%d", ttl)
+	}
+}
+
+// проверяет диапазоны по рангу, счёту и строкам, в том числе в обратном порядке и с LIMIT
+func TestStore_ZRanges(t *testing.T) {
+	s := NewStore()
+	s.ZAdd("z", ZAddFlags{}, []ZMember{{"a", 1}, {"b", 2}, {"c", 3}, {"d", 4}, {"e", 5}})
+
+	if got, _ := s.ZRange("z", 1, -2, true); !reflect.DeepEqual(members(got), []string{"d", "c", "b"}) {
+		t.Errorf("unexpected reverse rank range: %v", got)
+	}
+	r := ScoreRange{Min: 2, Max: 5, MaxEx: true}
+	if got, _ := s.ZRangeByScore("z", r, false, 1, 5); !reflect.DeepEqual(members(got), []string{"c", "d"}) {
+		t.Errorf("unexpected score range with offset: %v", got)
+	}
+	if got, _ := s.ZRangeByScore("z", r, true, 0, 2); !reflect.DeepEqual(members(got), []string{"d", "c"}) {
+		t.Errorf("unexpected reverse score range: %v", got)
+	}
+	if n, _ := s.ZCount("z", ScoreRange{Min: math.Inf(-1), Max: 3}); n != 3 {
+		t.Errorf("expected count 3, got %d", n)
+	}
+	if n, _ := s.ZCount("z", ScoreRange{Min: 3, Max: 3, MinEx: true}); n != 0 {
+		t.Errorf("expected empty count, got %d", n)
+	}
+
+	s.ZAdd("lex", ZAddFlags{}, []ZMember{{"apple", 0}, {"banana", 0}, {"cherry", 0}})
+	lr := LexRange{Min: LexBound{Value: "b"}, Max: LexBound{Inf: 1}}
+	if got, _ := s.ZRangeByLex("lex", lr, false, 0, -1); !reflect.DeepEqual(members(got), []string{"banana", "cherry"}) {
+		t.Errorf("unexpected lex range: %v", got)
+	}
+	if n, _ := s.ZLexCount("lex", LexRange{Min: LexBound{Inf: -1}, Max: LexBound{Value: "banana", Exclusive: true}}); n != 1 {
+		t.Errorf("expected lex count 1, got %d", n)
+	}
+
+	if rank, ok, _ := s.ZRank("z", "b", true); !ok || rank != 3 {
+		t.Errorf("expected reverse rank 3, got %d (ok=%v)", rank, ok)
+	}
+}
+
+// проверяет ZPOPMIN/ZPOPMAX, ZREMRANGEBY* и удаление опустевшего множества
+func TestStore_ZPopRemRange(t *testing.T) {
+	s := NewStore()
+	s.ZAdd("z", ZAddFlags{}, []ZMember{{"a", 1}, {"b", 2}, {"c", 3}, {"d", 4}, {"e", 5}})
+
+	if got, _ := s.ZPop("z", 2, true); !reflect.DeepEqual(got, []ZMember{{"e", 5}, {"d", 4}}) {
+		t.Errorf("unexpected ZPOPMAX: %v", got)
+	}
+	if n, _ := s.ZRemRangeByRank("z", 0, 0); n != 1 {
+		t.Errorf("expected 1 removed by rank, got %d", n)
+	}
+	if n, _ := s.ZRemRangeByScore("z", ScoreRange{Min: math.Inf(-1), Max: math.Inf(1)}); n != 2 {
+		t.Errorf("expected 2 removed by score, got %d", n)
+	}
+	if ttl := s.TTL("z"); ttl != -2 {
+		t.Errorf("expected empty zset to be deleted, TTL=%d", ttl)
+	}
+}
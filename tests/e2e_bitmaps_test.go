@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+)
+
+// Проверяем SETBIT, GETBIT, BITCOUNT и BITPOS на карте активных пользователей
+func TestBitmapSetCount(t *testing.T) {
+	s := newSession(t)
+	s.do("DEL", "bm:active", "bm:list")
+
+	for _, user := range []string{"1", "5", "9", "100"} {
+		if resp := s.do("SETBIT", "bm:active", user, "1"); resp != ":0" {
+			t.Fatalf("SETBIT %s: got %q", user, resp)
+		}
+	}
+	if resp := s.do("SETBIT", "bm:active", "5", "1"); resp != ":1" {
+		t.Fatalf("SETBIT on set bit: got %q", resp)
+	}
+	if resp := s.do("STRLEN", "bm:active"); resp != ":13" {
+		t.Fatalf("expected the string to grow to 13 bytes, got %q", resp)
+	}
+	if resp := s.do("GETBIT", "bm:active", "9"); resp != ":1" {
+		t.Fatalf("GETBIT: got %q", resp)
+	}
+	if resp := s.do("BITCOUNT", "bm:active"); resp != ":4" {
+		t.Fatalf("BITCOUNT: got %q", resp)
+	}
+	if resp := s.do("BITCOUNT", "bm:active", "0", "9", "BIT"); resp != ":3" {
+		t.Fatalf("BITCOUNT BIT: got %q", resp)
+	}
+	if resp := s.do("BITPOS", "bm:active", "1", "1"); resp != ":9" {
+		t.Fatalf("BITPOS: got %q", resp)
+	}
+
+	if resp := s.do("SETBIT", "bm:active", "-1", "1"); resp != "-ERR bit offset is not an integer or out of range" {
+		t.Fatalf("SETBIT with negative offset: got %q", resp)
+	}
+	if resp := s.do("SETBIT", "bm:active", "4294967296", "1"); resp != "-ERR bit offset is not an integer or out of range" {
+		t.Fatalf("SETBIT beyond 512MB: got %q", resp)
+	}
+	if resp := s.do("SETBIT", "bm:active", "1", "2"); resp != "-ERR bit is not an integer or out of range" {
+		t.Fatalf("SETBIT with bit 2: got %q", resp)
+	}
+	if resp := s.do("BITCOUNT", "bm:active", "0"); resp != "-ERR syntax error" {
+		t.Fatalf("BITCOUNT with only start: got %q", resp)
+	}
+	if resp := s.do("BITPOS", "bm:active", "2"); resp != "-ERR The bit argument must be 1 or 0." {
+		t.Fatalf("BITPOS with bit 2: got %q", resp)
+	}
+	s.do("RPUSH", "bm:list", "x")
+	if resp := s.do("GETBIT", "bm:list", "0"); !strings.HasPrefix(resp, "-WRONGTYPE") {
+		t.Fatalf("GETBIT on a list: got %q", resp)
+	}
+}
+
+// Проверяем BITOP и BITFIELD с разными режимами переполнения
+func TestBitOpBitField(t *testing.T) {
+	s := newSession(t)
+	s.do("DEL", "bm:a", "bm:b", "bm:dest", "bm:field")
+
+	s.do("SET", "bm:a", "foobar")
+	s.do("SET", "bm:b", "abcdef")
+	if resp := s.do("BITOP", "AND", "bm:dest", "bm:a", "bm:b"); resp != ":6" {
+		t.Fatalf("BITOP AND: got %q", resp)
+	}
+	if resp := s.do("GET", "bm:dest"); resp != "`bc`ab" {
+		t.Fatalf("GET after BITOP AND: got %q", resp)
+	}
+	if resp := s.do("BITOP", "NOT", "bm:dest", "bm:a", "bm:b"); resp != "-ERR BITOP NOT must be called with a single source key." {
+		t.Fatalf("BITOP NOT with two keys: got %q", resp)
+	}
+
+	if resp := s.do("BITFIELD", "bm:field", "SET", "u8", "#1", "255", "GET", "u8", "8", "INCRBY", "i8", "8", "1"); resp != "[:0 :255 :0]" {
+		t.Fatalf("BITFIELD: got %q", resp)
+	}
+	if resp := s.do("BITFIELD", "bm:field", "OVERFLOW", "FAIL", "INCRBY", "u8", "8", "-1", "OVERFLOW", "SAT", "INCRBY", "u8", "8", "-1", "SET", "i8", "8", "-1"); resp != "[(nil) :0 :0]" {
+		t.Fatalf("BITFIELD OVERFLOW: got %q", resp)
+	}
+	if resp := s.do("BITFIELD_RO", "bm:field", "GET", "i8", "8"); resp != "[:-1]" {
+		t.Fatalf("BITFIELD_RO: got %q", resp)
+	}
+	if resp := s.do("BITFIELD_RO", "bm:field", "SET", "i8", "8", "1"); resp != "-ERR BITFIELD_RO only supports the GET subcommand" {
+		t.Fatalf("BITFIELD_RO with SET: got %q", resp)
+	}
+	if resp := s.do("BITFIELD", "bm:field", "GET", "u64", "0"); !strings.HasPrefix(resp, "-ERR Invalid bitfield type") {
+		t.Fatalf("BITFIELD u64: got %q", resp)
+	}
+	if resp := s.do("BITFIELD", "bm:field", "OVERFLOW", "MAYBE"); resp != "-ERR Invalid OVERFLOW type specified" {
+		t.Fatalf("BITFIELD with bad OVERFLOW: got %q", resp)
+	}
+}
@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Проверяем, что BLPOP ждёт данных и получает элемент, как только его положили
+func TestBlpopWaitsForPush(t *testing.T) {
+	sendCommand(t, "*2\r\n$3\r\nDEL\r\n$9\r\nblk:queue\r\n")
+
+	worker := newSession(t)
+	if _, err := worker.conn.Write([]byte("*3\r\n$5\r\nBLPOP\r\n$9\r\nblk:queue\r\n$1\r\n5\r\n")); err != nil {
+		t.Fatalf("failed to send BLPOP: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond) // даём клиенту встать в очередь
+
+	if resp := sendCommand(t, "*3\r\n$5\r\nRPUSH\r\n$9\r\nblk:queue\r\n$3\r\njob\r\n"); resp != ":1" {
+		t.Fatalf("RPUSH: got %q, want :1", resp)
+	}
+	if got := strings.Join(worker.readArray(), " "); got != "blk:queue job" {
+		t.Fatalf("BLPOP: got %q, want \"blk:queue job\"", got)
+	}
+
+	// элемент забрал ждущий клиент — в списке ничего не осталось
+	if resp := sendCommand(t, "*2\r\n$4\r\nLLEN\r\n$9\r\nblk:queue\r\n"); resp != ":0" {
+		t.Fatalf("LLEN after BLPOP: got %q, want :0", resp)
+	}
+}
+
+// Проверяем таймаут: без данных BRPOP отвечает nil-массивом
+func TestBrpopTimeout(t *testing.T) {
+	s := newSession(t)
+	start := time.Now()
+	if resp := s.send("*3\r\n$5\r\nBRPOP\r\n$9\r\nblk:empty\r\n$3\r\n0.2\r\n"); resp != "*-1" {
+		t.Fatalf("BRPOP timeout: got %q, want *-1", resp)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Fatalf("BRPOP returned too early: %v", elapsed)
+	}
+}
+
+// Проверяем, что несколько ждущих клиентов обслуживаются в порядке прихода
+func TestBlpopFIFO(t *testing.T) {
+	sendCommand(t, "*2\r\n$3\r\nDEL\r\n$8\r\nblk:fifo\r\n")
+
+	first, second := newSession(t), newSession(t)
+	for _, s := range []*session{first, second} {
+		if _, err := s.conn.Write([]byte("*3\r\n$5\r\nBLPOP\r\n$8\r\nblk:fifo\r\n$1\r\n5\r\n")); err != nil {
+			t.Fatalf("failed to send BLPOP: %v", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	sendCommand(t, "*4\r\n$5\r\nRPUSH\r\n$8\r\nblk:fifo\r\n$1\r\n1\r\n$1\r\n2\r\n")
+	if got := strings.Join(first.readArray(), " "); got != "blk:fifo 1" {
+		t.Fatalf("first waiter: got %q, want \"blk:fifo 1\"", got)
+	}
+	if got := strings.Join(second.readArray(), " "); got != "blk:fifo 2" {
+		t.Fatalf("second waiter: got %q, want \"blk:fifo 2\"", got)
+	}
+}
@@ -0,0 +1,172 @@
+package tests
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Проверяем, что базы изолированы друг от друга, а SELECT действует только на своё соединение
+func TestSelect(t *testing.T) {
+	s := newSession(t)
+	other := newSession(t)
+	s.do("SELECT", "3")
+	s.do("FLUSHDB")
+	s.do("SET", "db:k", "three")
+
+	if resp := other.do("SELECT", "4"); resp != "+OK" {
+		t.Fatalf("SELECT 4: got %q", resp)
+	}
+	if resp := other.do("GET", "db:k"); resp != "(nil)" {
+		t.Fatalf("GET in another db: got %q", resp)
+	}
+	if resp := s.do("GET", "db:k"); resp != "three" {
+		t.Fatalf("GET in selected db: got %q", resp)
+	}
+
+	if resp := s.do("SELECT", "100"); resp != "-ERR DB index is out of range" {
+		t.Fatalf("SELECT out of range: got %q", resp)
+	}
+	if resp := s.do("SELECT", "x"); resp != "-ERR value is not an integer or out of range" {
+		t.Fatalf("SELECT not a number: got %q", resp)
+	}
+	if resp := s.do("DBSIZE"); resp != ":1" {
+		t.Fatalf("failed SELECT must keep the current db, DBSIZE: got %q", resp)
+	}
+
+	// INFO отвечает bulk-строкой из нескольких строк — читаем её целиком
+	header := s.send("*2\r\n$4\r\nINFO\r\n$8\r\nkeyspace\r\n")
+	size, _ := strconv.Atoi(strings.TrimPrefix(header, "$"))
+	body := make([]byte, size+2)
+	if _, err := io.ReadFull(s.rd, body); err != nil {
+		t.Fatalf("failed to read INFO body: %v", err)
+	}
+	if !strings.Contains(string(body), "db3:keys=1,expires=0\r\n") || strings.Contains(string(body), "db4:") {
+		t.Fatalf("INFO keyspace: got %q", body)
+	}
+}
+
+// Проверяем MOVE и COPY ... DB между базами: TTL переносится, занятый ключ в целевой базе не трогается
+func TestMoveCopyDB(t *testing.T) {
+	s := newSession(t)
+	for _, db := range []string{"6", "5"} {
+		s.do("SELECT", db)
+		s.do("FLUSHDB")
+	}
+	s.do("SET", "db:m", "v", "EX", "100")
+
+	if resp := s.do("MOVE", "db:m", "6"); resp != ":1" {
+		t.Fatalf("MOVE: got %q", resp)
+	}
+	if resp := s.do("MOVE", "db:m", "6"); resp != ":0" {
+		t.Fatalf("MOVE of missing key: got %q", resp)
+	}
+	if resp := s.do("MOVE", "db:m", "5"); resp != "-ERR source and destination objects are the same" {
+		t.Fatalf("MOVE into the same db: got %q", resp)
+	}
+
+	s.do("SET", "db:m", "local")
+	s.do("SELECT", "6")
+	if resp := s.do("TTL", "db:m"); resp == ":-1" || resp == ":-2" {
+		t.Fatalf("TTL must move with the key, got %q", resp)
+	}
+	if resp := s.do("MOVE", "db:m", "5"); resp != ":0" {
+		t.Fatalf("MOVE onto existing key: got %q", resp)
+	}
+
+	if resp := s.do("COPY", "db:m", "db:m", "DB", "5"); resp != ":0" {
+		t.Fatalf("COPY onto existing key without REPLACE: got %q", resp)
+	}
+	if resp := s.do("COPY", "db:m", "db:m", "DB", "5", "REPLACE"); resp != ":1" {
+		t.Fatalf("COPY DB REPLACE: got %q", resp)
+	}
+	s.do("SELECT", "5")
+	if resp := s.do("GET", "db:m"); resp != "v" {
+		t.Fatalf("GET after COPY DB: got %q", resp)
+	}
+}
+
+// Проверяем SWAPDB: клиенты остаются на своих номерах баз и видят обменянные данные
+func TestSwapDB(t *testing.T) {
+	s := newSession(t)
+	other := newSession(t)
+	for _, db := range []string{"8", "7"} {
+		s.do("SELECT", db)
+		s.do("FLUSHDB")
+	}
+	s.do("SET", "db:s", "seven")
+	other.do("SELECT", "8")
+
+	if resp := s.do("SWAPDB", "7", "8"); resp != "+OK" {
+		t.Fatalf("SWAPDB: got %q", resp)
+	}
+	if resp := other.do("GET", "db:s"); resp != "seven" {
+		t.Fatalf("GET after SWAPDB: got %q", resp)
+	}
+	if resp := s.do("DBSIZE"); resp != ":0" {
+		t.Fatalf("DBSIZE after SWAPDB: got %q", resp)
+	}
+
+	if resp := s.do("SWAPDB", "a", "8"); resp != "-ERR invalid first DB index" {
+		t.Fatalf("SWAPDB with bad index: got %q", resp)
+	}
+	if resp := s.do("SWAPDB", "7", "100"); resp != "-ERR DB index is out of range" {
+		t.Fatalf("SWAPDB out of range: got %q", resp)
+	}
+}
+
+// Проверяем, что SELECT внутри MULTI выполняется в EXEC и меняет базу для следующих команд
+func TestSelectInMulti(t *testing.T) {
+	s := newSession(t)
+	s.do("SELECT", "9")
+	s.do("FLUSHDB")
+	s.do("SELECT", "0")
+	s.do("DEL", "db:tx")
+
+	s.do("MULTI")
+	if resp := s.do("SELECT", "9"); resp != "+QUEUED" {
+		t.Fatalf("SELECT inside MULTI: got %q", resp)
+	}
+	s.do("SET", "db:tx", "nine")
+	if resp := s.do("EXEC"); resp != "[+OK +OK]" {
+		t.Fatalf("EXEC: got %q", resp)
+	}
+	if resp := s.do("GET", "db:tx"); resp != "nine" {
+		t.Fatalf("GET in db selected by EXEC: got %q", resp)
+	}
+	s.do("SELECT", "0")
+	if resp := s.do("EXISTS", "db:tx"); resp != ":0" {
+		t.Fatalf("key must not appear in db 0, EXISTS: got %q", resp)
+	}
+}
+
+// Проверяем FLUSHDB и FLUSHALL с ASYNC: FLUSHDB очищает только текущую базу, FLUSHALL — все
+func TestFlush(t *testing.T) {
+	s := newSession(t)
+	s.do("SELECT", "10")
+	s.do("SET", "db:f", "10")
+	s.do("SELECT", "11")
+	s.do("SET", "db:f", "11")
+
+	if resp := s.do("FLUSHDB", "ASYNC"); resp != "+OK" {
+		t.Fatalf("FLUSHDB ASYNC: got %q", resp)
+	}
+	if resp := s.do("DBSIZE"); resp != ":0" {
+		t.Fatalf("DBSIZE after FLUSHDB: got %q", resp)
+	}
+	s.do("SELECT", "10")
+	if resp := s.do("GET", "db:f"); resp != "10" {
+		t.Fatalf("FLUSHDB must not touch other dbs: got %q", resp)
+	}
+
+	if resp := s.do("FLUSHALL", "LATER"); resp != "-ERR syntax error" {
+		t.Fatalf("FLUSHALL with bad option: got %q", resp)
+	}
+	if resp := s.do("FLUSHALL", "ASYNC"); resp != "+OK" {
+		t.Fatalf("FLUSHALL ASYNC: got %q", resp)
+	}
+	if resp := s.do("DBSIZE"); resp != ":0" {
+		t.Fatalf("DBSIZE after FLUSHALL: got %q", resp)
+	}
+}
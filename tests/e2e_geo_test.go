@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+)
+
+// Проверяем GEOADD, GEOPOS и GEODIST на примерах из документации Redis
+func TestGeoAddPosDist(t *testing.T) {
+	s := newSession(t)
+	s.do("DEL", "geo:sicily")
+
+	if resp := s.do("GEOADD", "geo:sicily", "13.361389", "38.115556", "Palermo", "15.087269", "37.502669", "Catania"); resp != ":2" {
+		t.Fatalf("GEOADD: got %q", resp)
+	}
+	if resp := s.do("GEOADD", "geo:sicily", "CH", "13.361389", "38.115556", "Palermo", "15.1", "37.5", "Catania"); resp != ":1" {
+		t.Fatalf("GEOADD CH: got %q", resp)
+	}
+	s.do("GEOADD", "geo:sicily", "15.087269", "37.502669", "Catania")
+
+	if resp := s.do("GEOPOS", "geo:sicily", "Palermo", "Rome"); resp != "[[13.36138933897018433 38.11555639549629859] (nil)]" {
+		t.Fatalf("GEOPOS: got %q", resp)
+	}
+	if resp := s.do("GEODIST", "geo:sicily", "Palermo", "Catania"); resp != "166274.1516" {
+		t.Fatalf("GEODIST: got %q", resp)
+	}
+	if resp := s.do("GEODIST", "geo:sicily", "Palermo", "Catania", "km"); resp != "166.2742" {
+		t.Fatalf("GEODIST km: got %q", resp)
+	}
+	if resp := s.do("GEODIST", "geo:sicily", "Palermo", "Catania", "mi"); resp != "103.3182" {
+		t.Fatalf("GEODIST mi: got %q", resp)
+	}
+	if resp := s.do("GEODIST", "geo:sicily", "Palermo", "Rome"); resp != "(nil)" {
+		t.Fatalf("GEODIST with unknown member: got %q", resp)
+	}
+	if resp := s.do("GEODIST", "geo:sicily", "Palermo", "Catania", "yd"); resp != "-ERR unsupported unit provided. please use M, KM, FT, MI" {
+		t.Fatalf("GEODIST with bad unit: got %q", resp)
+	}
+	if resp := s.do("GEOADD", "geo:sicily", "181", "10", "Nowhere"); resp != "-ERR invalid longitude,latitude pair 181.000000,10.000000" {
+		t.Fatalf("GEOADD out of range: got %q", resp)
+	}
+	if resp := s.do("ZSCORE", "geo:sicily", "Palermo"); resp != "3479099956230698" {
+		t.Fatalf("geo index must be a sorted set with geohash scores, got %q", resp)
+	}
+}
+
+// Проверяем GEOSEARCH в круге и прямоугольнике и GEOSEARCHSTORE
+func TestGeoSearch(t *testing.T) {
+	s := newSession(t)
+	s.do("DEL", "geo:sicily", "geo:near")
+	s.do("GEOADD", "geo:sicily", "13.361389", "38.115556", "Palermo", "15.087269", "37.502669", "Catania",
+		"12.758489", "38.788135", "edge1", "17.241510", "38.788135", "edge2")
+
+	if resp := s.do("GEOSEARCH", "geo:sicily", "FROMLONLAT", "15", "37", "BYRADIUS", "200", "km", "ASC"); resp != "[Catania Palermo]" {
+		t.Fatalf("GEOSEARCH BYRADIUS: got %q", resp)
+	}
+	want := "[[Catania 56.4413 [15.08726745843887329 37.50266842333162032]] " +
+		"[Palermo 190.4424 [13.36138933897018433 38.11555639549629859]] " +
+		"[edge2 279.7403 [17.24151045083999634 38.78813451624225195]] " +
+		"[edge1 279.7405 [12.7584877610206604 38.78813451624225195]]]"
+	if resp := s.do("GEOSEARCH", "geo:sicily", "FROMLONLAT", "15", "37", "BYBOX", "400", "400", "km", "ASC", "WITHCOORD", "WITHDIST"); resp != want {
+		t.Fatalf("GEOSEARCH BYBOX:\n got %q\nwant %q", resp, want)
+	}
+	if resp := s.do("GEOSEARCH", "geo:sicily", "FROMMEMBER", "Palermo", "BYRADIUS", "200", "km", "COUNT", "1", "WITHHASH"); resp != "[[Palermo :3479099956230698]]" {
+		t.Fatalf("GEOSEARCH FROMMEMBER COUNT WITHHASH: got %q", resp)
+	}
+
+	if resp := s.do("GEOSEARCHSTORE", "geo:near", "geo:sicily", "FROMLONLAT", "15", "37", "BYRADIUS", "100", "km", "STOREDIST"); resp != ":1" {
+		t.Fatalf("GEOSEARCHSTORE: got %q", resp)
+	}
+	if resp := s.do("ZRANGE", "geo:near", "0", "-1", "WITHSCORES"); !strings.HasPrefix(resp, "[Catania 56.44") {
+		t.Fatalf("GEOSEARCHSTORE STOREDIST: got %q", resp)
+	}
+
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"BYRADIUS", "10", "km", "ASC", "WITHDIST"}, "-ERR exactly one of FROMMEMBER or FROMLONLAT can be specified for GEOSEARCH"},
+		{[]string{"FROMLONLAT", "15", "37", "BYRADIUS", "10", "km", "BYBOX", "1", "1", "km"}, "-ERR exactly one of BYRADIUS and BYBOX can be specified for GEOSEARCH"},
+		{[]string{"FROMLONLAT", "15", "37", "BYRADIUS", "-1", "km"}, "-ERR radius cannot be negative"},
+		{[]string{"FROMLONLAT", "15", "37", "BYRADIUS", "10", "km", "COUNT", "0"}, "-ERR COUNT must be > 0"},
+		{[]string{"FROMMEMBER", "Rome", "BYRADIUS", "10", "km"}, "-ERR could not decode requested zset member"},
+		{[]string{"FROMLONLAT", "15", "37", "BYRADIUS", "10", "km", "STOREDIST"}, "-ERR syntax error"},
+	} {
+		if resp := s.do(append([]string{"GEOSEARCH", "geo:sicily"}, tc.args...)...); resp != tc.want {
+			t.Fatalf("GEOSEARCH %v: got %q, want %q", tc.args, resp, tc.want)
+		}
+	}
+}
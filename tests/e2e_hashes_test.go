@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Проверяем HSET → HGET/HGETALL и удаление ключа вместе с последним полем
+func TestHashSetGet(t *testing.T) {
+	s := newSession(t)
+	s.send("*2\r\n$3\r\nDEL\r\n$6\r\nhash:u\r\n")
+	if resp := s.send("*6\r\n$4\r\nHSET\r\n$6\r\nhash:u\r\n$4\r\nname\r\n$5\r\nanton\r\n$4\r\ncity\r\n$3\r\nmsk\r\n"); resp != ":2" {
+		t.Fatalf("HSET: got %q, want :2", resp)
+	}
+	if resp := s.send("*3\r\n$4\r\nHGET\r\n$6\r\nhash:u\r\n$4\r\nname\r\n"); resp != "$5" || s.readLine() != "anton" {
+		t.Fatalf("HGET: unexpected reply %q", resp)
+	}
+
+	if _, err := s.conn.Write([]byte("*2\r\n$7\r\nHGETALL\r\n$6\r\nhash:u\r\n")); err != nil {
+		t.Fatalf("failed to send HGETALL: %v", err)
+	}
+	items := s.readArray()
+	if len(items) != 4 {
+		t.Fatalf("HGETALL: expected flat array of 4 items, got %v", items)
+	}
+	pairs := []string{items[0] + "=" + items[1], items[2] + "=" + items[3]}
+	sort.Strings(pairs)
+	if got := strings.Join(pairs, " "); got != "city=msk name=anton" {
+		t.Fatalf("HGETALL: got %q", got)
+	}
+
+	if resp := s.send("*4\r\n$4\r\nHDEL\r\n$6\r\nhash:u\r\n$4\r\nname\r\n$4\r\ncity\r\n"); resp != ":2" {
+		t.Fatalf("HDEL: got %q, want :2", resp)
+	}
+	if resp := s.send("*2\r\n$3\r\nTTL\r\n$6\r\nhash:u\r\n"); resp != ":-2" {
+		t.Fatalf("TTL after deleting last field: got %q, want :-2", resp)
+	}
+}
+
+// Проверяем HINCRBY/HINCRBYFLOAT и ошибки при нечисловом значении и переполнении
+func TestHashIncr(t *testing.T) {
+	s := newSession(t)
+	s.send("*2\r\n$3\r\nDEL\r\n$6\r\nhash:n\r\n")
+	if resp := s.send("*4\r\n$7\r\nHINCRBY\r\n$6\r\nhash:n\r\n$1\r\nc\r\n$2\r\n10\r\n"); resp != ":10" {
+		t.Fatalf("HINCRBY: got %q, want :10", resp)
+	}
+	if resp := s.send("*4\r\n$7\r\nHINCRBY\r\n$6\r\nhash:n\r\n$1\r\nc\r\n$19\r\n9223372036854775807\r\n"); !strings.HasPrefix(resp, "-ERR increment or decrement would overflow") {
+		t.Fatalf("HINCRBY overflow: got %q", resp)
+	}
+	if resp := s.send("*4\r\n$12\r\nHINCRBYFLOAT\r\n$6\r\nhash:n\r\n$1\r\nc\r\n$3\r\n0.5\r\n"); resp != "$4" || s.readLine() != "10.5" {
+		t.Fatalf("HINCRBYFLOAT: unexpected reply %q", resp)
+	}
+	if resp := s.send("*4\r\n$7\r\nHINCRBY\r\n$6\r\nhash:n\r\n$1\r\nc\r\n$1\r\n1\r\n"); resp != "-ERR hash value is not an integer" {
+		t.Fatalf("HINCRBY on float: got %q", resp)
+	}
+}
@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Проверяем PFADD, PFCOUNT по одному и нескольким ключам и PFMERGE
+func TestHyperLogLog(t *testing.T) {
+	s := newSession(t)
+	s.do("DEL", "hll:mon", "hll:tue", "hll:week", "hll:text", "hll:list")
+
+	if resp := s.do("PFADD", "hll:mon", "alice", "bob", "carol"); resp != ":1" {
+		t.Fatalf("PFADD: got %q", resp)
+	}
+	if resp := s.do("PFADD", "hll:mon", "alice"); resp != ":0" {
+		t.Fatalf("PFADD of a known element: got %q", resp)
+	}
+	if resp := s.do("PFCOUNT", "hll:mon"); resp != ":3" {
+		t.Fatalf("PFCOUNT: got %q", resp)
+	}
+	if resp := s.do("TYPE", "hll:mon"); resp != "+string" {
+		t.Fatalf("TYPE of HyperLogLog: got %q", resp)
+	}
+
+	s.do("PFADD", "hll:tue", "bob", "dave")
+	if resp := s.do("PFCOUNT", "hll:mon", "hll:tue"); resp != ":4" {
+		t.Fatalf("PFCOUNT over two keys: got %q", resp)
+	}
+	if resp := s.do("PFMERGE", "hll:week", "hll:mon", "hll:tue"); resp != "+OK" {
+		t.Fatalf("PFMERGE: got %q", resp)
+	}
+	if resp := s.do("PFCOUNT", "hll:week"); resp != ":4" {
+		t.Fatalf("PFCOUNT after PFMERGE: got %q", resp)
+	}
+
+	// тысячи элементов одной командой — оценка в пределах нескольких процентов
+	args := []string{"PFADD", "hll:week"}
+	for i := 0; i < 10000; i++ {
+		args = append(args, "visitor:"+strconv.Itoa(i))
+	}
+	s.do(args...)
+	resp := s.do("PFCOUNT", "hll:week")
+	n, _ := strconv.Atoi(strings.TrimPrefix(resp, ":"))
+	if n < 9700 || n > 10300 {
+		t.Fatalf("PFCOUNT after 10004 distinct elements: got %q", resp)
+	}
+
+	s.do("SET", "hll:text", "hello")
+	if resp := s.do("PFADD", "hll:text", "a"); resp != "-WRONGTYPE Key is not a valid HyperLogLog string value." {
+		t.Fatalf("PFADD on plain string: got %q", resp)
+	}
+	s.do("RPUSH", "hll:list", "a")
+	if resp := s.do("PFCOUNT", "hll:list"); !strings.HasPrefix(resp, "-WRONGTYPE Operation against a key") {
+		t.Fatalf("PFCOUNT on a list: got %q", resp)
+	}
+}
@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Проверяем, что SCAN с MATCH и маленьким COUNT обходит все ключи, а TYPE оставляет ключи нужного типа
+func TestScan(t *testing.T) {
+	s := newSession(t)
+	for i := 0; i < 30; i++ {
+		s.do("SET", "ks:"+strconv.Itoa(i), "v")
+	}
+	s.do("DEL", "ks:list")
+	s.do("RPUSH", "ks:list", "a")
+
+	seen := make(map[string]bool)
+	cursor := "0"
+	for {
+		resp := s.do("SCAN", cursor, "MATCH", "ks:*", "COUNT", "5")
+		// ответ вида "[cursor [k1 k2 ...]]"
+		parts := strings.SplitN(strings.Trim(resp, "[]"), " ", 2)
+		cursor = parts[0]
+		if len(parts) == 2 {
+			for _, key := range strings.Fields(strings.Trim(parts[1], "[]")) {
+				seen[key] = true
+			}
+		}
+		if cursor == "0" {
+			break
+		}
+	}
+	if len(seen) != 31 {
+		t.Fatalf("SCAN MATCH ks:*: expected 31 keys, got %d", len(seen))
+	}
+
+	if resp := s.do("SCAN", "0", "MATCH", "ks:*", "TYPE", "list", "COUNT", "100000"); resp != "[0 [ks:list]]" {
+		t.Fatalf("SCAN TYPE list: got %q", resp)
+	}
+	if resp := s.do("SCAN", "0", "TYPE", "nosuchtype"); resp != "-ERR unknown type name 'nosuchtype'" {
+		t.Fatalf("SCAN with unknown type: got %q", resp)
+	}
+	if resp := s.do("HSCAN", "ks:list", "0", "TYPE", "list"); resp != "-ERR syntax error" {
+		t.Fatalf("HSCAN does not take TYPE: got %q", resp)
+	}
+}
+
+// Проверяем KEYS с glob-шаблоном, RANDOMKEY и DBSIZE
+func TestKeysRandomKeyDBSize(t *testing.T) {
+	s := newSession(t)
+	s.do("DEL", "kp:hello", "kp:hallo", "kp:hxllo")
+	s.do("SET", "kp:hello", "1")
+	s.do("SET", "kp:hallo", "2")
+	s.do("SET", "kp:hxllo", "3")
+
+	resp := s.do("KEYS", "kp:h[ae]llo")
+	if resp != "[kp:hello kp:hallo]" && resp != "[kp:hallo kp:hello]" {
+		t.Fatalf("KEYS kp:h[ae]llo: got %q", resp)
+	}
+	if resp := s.do("KEYS", "kp:h?llo"); strings.Count(resp, "kp:") != 3 {
+		t.Fatalf("KEYS kp:h?llo: got %q", resp)
+	}
+	if resp := s.do("RANDOMKEY"); resp == "(nil)" {
+		t.Fatal("RANDOMKEY returned nil on a non-empty database")
+	}
+	resp = s.do("DBSIZE")
+	if n, err := strconv.Atoi(strings.TrimPrefix(resp, ":")); err != nil || n < 3 {
+		t.Fatalf("DBSIZE: got %q", resp)
+	}
+}
+
+// Проверяем RENAME/RENAMENX с переносом TTL, COPY с REPLACE, EXISTS с повторами и TYPE
+func TestRenameCopyType(t *testing.T) {
+	s := newSession(t)
+	s.do("DEL", "km:a", "km:b", "km:c")
+	s.do("SET", "km:a", "1", "EX", "100")
+
+	if resp := s.do("RENAME", "km:a", "km:b"); resp != "+OK" {
+		t.Fatalf("RENAME: got %q", resp)
+	}
+	if resp := s.do("TTL", "km:b"); resp != ":100" && resp != ":99" {
+		t.Fatalf("TTL after RENAME: got %q", resp)
+	}
+	if resp := s.do("RENAME", "km:a", "km:b"); resp != "-ERR no such key" {
+		t.Fatalf("RENAME missing key: got %q", resp)
+	}
+	s.do("SET", "km:c", "3")
+	if resp := s.do("RENAMENX", "km:c", "km:b"); resp != ":0" {
+		t.Fatalf("RENAMENX onto existing key: got %q", resp)
+	}
+
+	if resp := s.do("COPY", "km:b", "km:c"); resp != ":0" {
+		t.Fatalf("COPY without REPLACE: got %q", resp)
+	}
+	if resp := s.do("COPY", "km:b", "km:c", "REPLACE"); resp != ":1" {
+		t.Fatalf("COPY REPLACE: got %q", resp)
+	}
+	if resp := s.do("GET", "km:c"); resp != "1" {
+		t.Fatalf("GET after COPY: got %q", resp)
+	}
+	if resp := s.do("COPY", "km:b", "km:b"); resp != "-ERR source and destination objects are the same" {
+		t.Fatalf("COPY onto itself: got %q", resp)
+	}
+	if resp := s.do("MOVE", "km:b", "100"); resp != "-ERR DB index is out of range" {
+		t.Fatalf("MOVE to missing db: got %q", resp)
+	}
+
+	if resp := s.do("EXISTS", "km:b", "km:b", "km:a"); resp != ":2" {
+		t.Fatalf("EXISTS with repeated key: got %q", resp)
+	}
+	s.do("RPUSH", "km:a", "x")
+	if resp := s.do("TYPE", "km:a"); resp != "+list" {
+		t.Fatalf("TYPE list: got %q", resp)
+	}
+	if resp := s.do("TYPE", "km:none"); resp != "+none" {
+		t.Fatalf("TYPE missing key: got %q", resp)
+	}
+	if resp := s.do("TOUCH", "km:a", "km:none"); resp != ":1" {
+		t.Fatalf("TOUCH: got %q", resp)
+	}
+	if resp := s.do("UNLINK", "km:a", "km:b", "km:c", "km:none"); resp != ":3" {
+		t.Fatalf("UNLINK: got %q", resp)
+	}
+}
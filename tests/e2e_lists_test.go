@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+)
+
+// Проверяем RPUSH/LPUSH → LRANGE возвращает элементы по порядку
+func TestListPushRange(t *testing.T) {
+	s := newSession(t)
+	s.send("*2\r\n$3\r\nDEL\r\n$6\r\nlist:q\r\n")
+	if resp := s.send("*4\r\n$5\r\nRPUSH\r\n$6\r\nlist:q\r\n$1\r\na\r\n$1\r\nb\r\n"); resp != ":2" {
+		t.Fatalf("RPUSH: got %q, want :2", resp)
+	}
+	if resp := s.send("*3\r\n$5\r\nLPUSH\r\n$6\r\nlist:q\r\n$1\r\nz\r\n"); resp != ":3" {
+		t.Fatalf("LPUSH: got %q, want :3", resp)
+	}
+	if _, err := s.conn.Write([]byte("*4\r\n$6\r\nLRANGE\r\n$6\r\nlist:q\r\n$1\r\n0\r\n$2\r\n-1\r\n")); err != nil {
+		t.Fatalf("failed to send LRANGE: %v", err)
+	}
+	if got := strings.Join(s.readArray(), " "); got != "z a b" {
+		t.Fatalf("LRANGE: got %q, want \"z a b\"", got)
+	}
+
+	if resp := s.send("*2\r\n$4\r\nLPOP\r\n$6\r\nlist:q\r\n"); resp != "$1" || s.readLine() != "z" {
+		t.Fatalf("LPOP: unexpected reply %q", resp)
+	}
+	if resp := s.send("*2\r\n$4\r\nLLEN\r\n$6\r\nlist:q\r\n"); resp != ":2" {
+		t.Fatalf("LLEN: got %q, want :2", resp)
+	}
+}
+
+// Проверяем WRONGTYPE: строковая команда к списку и наоборот
+func TestListWrongType(t *testing.T) {
+	s := newSession(t)
+	s.send("*3\r\n$3\r\nSET\r\n$8\r\nlist:str\r\n$1\r\nx\r\n")
+	if resp := s.send("*3\r\n$5\r\nLPUSH\r\n$8\r\nlist:str\r\n$1\r\na\r\n"); !strings.HasPrefix(resp, "-WRONGTYPE") {
+		t.Fatalf("LPUSH to string: got %q, want -WRONGTYPE", resp)
+	}
+
+	s.send("*2\r\n$3\r\nDEL\r\n$8\r\nlist:lst\r\n")
+	s.send("*3\r\n$5\r\nRPUSH\r\n$8\r\nlist:lst\r\n$1\r\na\r\n")
+	if resp := s.send("*2\r\n$3\r\nGET\r\n$8\r\nlist:lst\r\n"); !strings.HasPrefix(resp, "-WRONGTYPE") {
+		t.Fatalf("GET of list: got %q, want -WRONGTYPE", resp)
+	}
+}
+
+// Проверяем, что EXPIRE работает для списка
+func TestListExpire(t *testing.T) {
+	s := newSession(t)
+	s.send("*2\r\n$3\r\nDEL\r\n$8\r\nlist:ttl\r\n")
+	s.send("*3\r\n$5\r\nRPUSH\r\n$8\r\nlist:ttl\r\n$1\r\na\r\n")
+	if resp := s.send("*3\r\n$6\r\nEXPIRE\r\n$8\r\nlist:ttl\r\n$3\r\n100\r\n"); resp != ":1" {
+		t.Fatalf("EXPIRE: got %q, want :1", resp)
+	}
+	if resp := s.send("*2\r\n$3\r\nTTL\r\n$8\r\nlist:ttl\r\n"); resp != ":100" && resp != ":99" {
+		t.Fatalf("TTL: got %q", resp)
+	}
+}
@@ -0,0 +1,27 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+)
+
+// Проверяем SAVE → +OK (снапшот записан синхронно)
+func TestSave(t *testing.T) {
+	resp := sendCommand(t, "*3\r\n$3\r\nSET\r\n$7\r\nsnapkey\r\n$3\r\nval\r\n")
+	if resp != "+OK" {
+		t.Fatalf("SET failed: got %q", resp)
+	}
+
+	resp = sendCommand(t, "*1\r\n$4\r\nSAVE\r\n")
+	if resp != "+OK" {
+		t.Fatalf("SAVE failed: got %q", resp)
+	}
+}
+
+// Проверяем BGSAVE → +Background saving started
+func TestBgsave(t *testing.T) {
+	resp := sendCommand(t, "*1\r\n$6\r\nBGSAVE\r\n")
+	if !strings.HasPrefix(resp, "+Background saving started") && !strings.Contains(resp, "already in progress") {
+		t.Fatalf("BGSAVE failed: got %q", resp)
+	}
+}
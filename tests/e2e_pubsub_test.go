@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+)
+
+// readArray читает из сессии массив bulk-строк и целых чисел (подтверждения и сообщения pub/sub)
+// и возвращает его элементы без RESP-префиксов.
+func (s *session) readArray() []string {
+	header := s.readLine()
+	if !strings.HasPrefix(header, "*") {
+		s.t.Fatalf("expected array, got %q", header)
+	}
+	var n int
+	for _, ch := range header[1:] {
+		n = n*10 + int(ch-'0')
+	}
+	items := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		line := s.readLine()
+		switch line[0] {
+		case '$':
+			if line == "$-1" {
+				items = append(items, "(nil)")
+				continue
+			}
+			items = append(items, s.readLine())
+		default: // ':' и '+'
+			items = append(items, line[1:])
+		}
+	}
+	return items
+}
+
+// Проверяем SUBSCRIBE/PSUBSCRIBE → PUBLISH доставляет сообщения подписчику
+func TestPublishSubscribe(t *testing.T) {
+	sub := newSession(t)
+	if _, err := sub.conn.Write([]byte("*3\r\n$9\r\nSUBSCRIBE\r\n$5\r\nnews1\r\n$5\r\nnews2\r\n")); err != nil {
+		t.Fatalf("failed to send SUBSCRIBE: %v", err)
+	}
+	if got := strings.Join(sub.readArray(), " "); got != "subscribe news1 1" {
+		t.Fatalf("first SUBSCRIBE confirmation: got %q", got)
+	}
+	if got := strings.Join(sub.readArray(), " "); got != "subscribe news2 2" {
+		t.Fatalf("second SUBSCRIBE confirmation: got %q", got)
+	}
+	if _, err := sub.conn.Write([]byte("*2\r\n$10\r\nPSUBSCRIBE\r\n$5\r\nnews*\r\n")); err != nil {
+		t.Fatalf("failed to send PSUBSCRIBE: %v", err)
+	}
+	if got := strings.Join(sub.readArray(), " "); got != "psubscribe news* 3" {
+		t.Fatalf("PSUBSCRIBE confirmation: got %q", got)
+	}
+
+	// сообщение получают и подписка на канал, и подписка на шаблон
+	resp := sendCommand(t, "*3\r\n$7\r\nPUBLISH\r\n$5\r\nnews1\r\n$5\r\nhello\r\n")
+	if resp != ":2" {
+		t.Fatalf("PUBLISH: got %q, want :2", resp)
+	}
+	if got := strings.Join(sub.readArray(), " "); got != "message news1 hello" {
+		t.Fatalf("message: got %q", got)
+	}
+	if got := strings.Join(sub.readArray(), " "); got != "pmessage news* news1 hello" {
+		t.Fatalf("pmessage: got %q", got)
+	}
+
+	// в режиме подписки обычные команды запрещены, а PING отвечает массивом
+	if resp := sub.send("*2\r\n$3\r\nGET\r\n$1\r\na\r\n"); !strings.HasPrefix(resp, "-ERR Can't execute 'get'") {
+		t.Fatalf("GET in subscribed mode: got %q", resp)
+	}
+	if _, err := sub.conn.Write([]byte("*1\r\n$4\r\nPING\r\n")); err != nil {
+		t.Fatalf("failed to send PING: %v", err)
+	}
+	if got := strings.Join(sub.readArray(), " "); got != "pong " {
+		t.Fatalf("PING in subscribed mode: got %q", got)
+	}
+}
+
+// Проверяем PUBSUB NUMSUB и выход из режима подписки через UNSUBSCRIBE
+func TestPubsubNumsubAndUnsubscribe(t *testing.T) {
+	sub := newSession(t)
+	if _, err := sub.conn.Write([]byte("*2\r\n$9\r\nSUBSCRIBE\r\n$6\r\ncounts\r\n")); err != nil {
+		t.Fatalf("failed to send SUBSCRIBE: %v", err)
+	}
+	sub.readArray()
+
+	other := newSession(t)
+	if _, err := other.conn.Write([]byte("*3\r\n$6\r\nPUBSUB\r\n$6\r\nNUMSUB\r\n$6\r\ncounts\r\n")); err != nil {
+		t.Fatalf("failed to send PUBSUB: %v", err)
+	}
+	if got := strings.Join(other.readArray(), " "); got != "counts 1" {
+		t.Fatalf("PUBSUB NUMSUB: got %q", got)
+	}
+
+	if _, err := sub.conn.Write([]byte("*1\r\n$11\r\nUNSUBSCRIBE\r\n")); err != nil {
+		t.Fatalf("failed to send UNSUBSCRIBE: %v", err)
+	}
+	if got := strings.Join(sub.readArray(), " "); got != "unsubscribe counts 0" {
+		t.Fatalf("UNSUBSCRIBE confirmation: got %q", got)
+	}
+	// подписок не осталось — соединение снова принимает обычные команды
+	if resp := sub.send("*1\r\n$4\r\nPING\r\n"); resp != "+PONG" {
+		t.Fatalf("PING after UNSUBSCRIBE: got %q", resp)
+	}
+}
@@ -0,0 +1,50 @@
+package tests
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Проверяем INFO replication: тестовый сервер запущен мастером
+func TestInfoReplication(t *testing.T) {
+	conn, err := net.DialTimeout("tcp", "localhost:6379", time.Second)
+	if err != nil {
+		t.Fatalf("cannot connect to mini-redis: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("*2\r\n$4\r\nINFO\r\n$11\r\nreplication\r\n")); err != nil {
+		t.Fatalf("failed to send command: %v", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	header, err := reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(header, "$") {
+		t.Fatalf("expected bulk reply, got %q (err=%v)", header, err)
+	}
+	size, _ := strconv.Atoi(strings.TrimSpace(header[1:]))
+	body := make([]byte, size)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		t.Fatalf("failed to read INFO body: %v", err)
+	}
+
+	if !strings.Contains(string(body), "role:master") {
+		t.Fatalf("expected role:master in INFO, got %q", body)
+	}
+	if !strings.Contains(string(body), "master_repl_offset:") {
+		t.Fatalf("expected master_repl_offset in INFO, got %q", body)
+	}
+}
+
+// Проверяем REPLICAOF с неверным портом → ошибка (сервер остаётся мастером)
+func TestReplicaOfInvalidPort(t *testing.T) {
+	resp := sendCommand(t, "*3\r\n$9\r\nREPLICAOF\r\n$9\r\nlocalhost\r\n$3\r\nabc\r\n")
+	if !strings.HasPrefix(resp, "-ERR") {
+		t.Fatalf("expected error, got %q", resp)
+	}
+}
@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Проверяем SADD → SMEMBERS/SISMEMBER и SINTERSTORE
+func TestSetAlgebra(t *testing.T) {
+	s := newSession(t)
+	s.send("*4\r\n$3\r\nDEL\r\n$5\r\nset:a\r\n$5\r\nset:b\r\n$5\r\nset:c\r\n")
+	if resp := s.send("*5\r\n$4\r\nSADD\r\n$5\r\nset:a\r\n$1\r\n1\r\n$1\r\n2\r\n$1\r\n3\r\n"); resp != ":3" {
+		t.Fatalf("SADD: got %q, want :3", resp)
+	}
+	s.send("*4\r\n$4\r\nSADD\r\n$5\r\nset:b\r\n$1\r\n2\r\n$1\r\n3\r\n")
+	if resp := s.send("*3\r\n$9\r\nSISMEMBER\r\n$5\r\nset:a\r\n$1\r\n1\r\n"); resp != ":1" {
+		t.Fatalf("SISMEMBER: got %q, want :1", resp)
+	}
+
+	if resp := s.send("*4\r\n$11\r\nSINTERSTORE\r\n$5\r\nset:c\r\n$5\r\nset:a\r\n$5\r\nset:b\r\n"); resp != ":2" {
+		t.Fatalf("SINTERSTORE: got %q, want :2", resp)
+	}
+	if _, err := s.conn.Write([]byte("*2\r\n$8\r\nSMEMBERS\r\n$5\r\nset:c\r\n")); err != nil {
+		t.Fatalf("failed to send SMEMBERS: %v", err)
+	}
+	members := s.readArray()
+	sort.Strings(members)
+	if got := strings.Join(members, " "); got != "2 3" {
+		t.Fatalf("SMEMBERS: got %q, want \"2 3\"", got)
+	}
+
+	if _, err := s.conn.Write([]byte("*4\r\n$10\r\nSMISMEMBER\r\n$5\r\nset:c\r\n$1\r\n2\r\n$1\r\n9\r\n")); err != nil {
+		t.Fatalf("failed to send SMISMEMBER: %v", err)
+	}
+	if got := strings.Join(s.readArray(), " "); got != "1 0" {
+		t.Fatalf("SMISMEMBER: got %q, want \"1 0\"", got)
+	}
+}
+
+// Проверяем SPOP: снятый элемент пропадает из множества, пустое множество удаляется
+func TestSetPop(t *testing.T) {
+	s := newSession(t)
+	s.send("*2\r\n$3\r\nDEL\r\n$5\r\nset:p\r\n")
+	s.send("*3\r\n$4\r\nSADD\r\n$5\r\nset:p\r\n$1\r\nx\r\n")
+	if resp := s.send("*2\r\n$4\r\nSPOP\r\n$5\r\nset:p\r\n"); resp != "$1" || s.readLine() != "x" {
+		t.Fatalf("SPOP: unexpected reply %q", resp)
+	}
+	if resp := s.send("*2\r\n$5\r\nSCARD\r\n$5\r\nset:p\r\n"); resp != ":0" {
+		t.Fatalf("SCARD after SPOP: got %q, want :0", resp)
+	}
+	if resp := s.send("*3\r\n$4\r\nSPOP\r\n$5\r\nset:p\r\n$1\r\n3\r\n"); resp != "*0" {
+		t.Fatalf("SPOP with count on missing key: got %q, want *0", resp)
+	}
+}
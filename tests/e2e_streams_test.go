@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// command собирает команду в формате RESP из аргументов
+func command(args ...string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	return b.String()
+}
+
+// do отправляет команду и читает её ответ целиком (см. readReply)
+func (s *session) do(args ...string) string {
+	if _, err := s.conn.Write([]byte(command(args...))); err != nil {
+		s.t.Fatalf("failed to send %s: %v", args[0], err)
+	}
+	return s.readReply()
+}
+
+// readReply читает ответ любой вложенности и записывает его одной строкой:
+// массивы — в квадратных скобках, nil — как "(nil)"
+func (s *session) readReply() string {
+	line := s.readLine()
+	switch line[0] {
+	case '$':
+		if line == "$-1" {
+			return "(nil)"
+		}
+		return s.readLine()
+	case '*':
+		if line == "*-1" {
+			return "(nil)"
+		}
+		var n int
+		fmt.Sscan(line[1:], &n)
+		items := make([]string, n)
+		for i := range items {
+			items[i] = s.readReply()
+		}
+		return "[" + strings.Join(items, " ") + "]"
+	default: // ':', '+' и '-'
+		return line
+	}
+}
+
+// Проверяем XADD с явными и автоматическими номерами, XRANGE, XREVRANGE и обрезку XTRIM
+func TestStreamAddRange(t *testing.T) {
+	s := newSession(t)
+	s.do("DEL", "stream:log")
+	for _, id := range []string{"1-1", "1-*", "2-0"} {
+		s.do("XADD", "stream:log", id, "n", id)
+	}
+	if resp := s.do("XADD", "stream:log", "1-5", "n", "x"); !strings.HasPrefix(resp, "-ERR The ID specified in XADD is equal or smaller") {
+		t.Fatalf("XADD with smaller ID: got %q", resp)
+	}
+
+	if got := s.do("XRANGE", "stream:log", "(1-1", "+"); got != "[[1-2 [n 1-*]] [2-0 [n 2-0]]]" {
+		t.Fatalf("XRANGE: got %q", got)
+	}
+	if got := s.do("XREVRANGE", "stream:log", "+", "-", "COUNT", "1"); got != "[[2-0 [n 2-0]]]" {
+		t.Fatalf("XREVRANGE COUNT 1: got %q", got)
+	}
+
+	if resp := s.do("XTRIM", "stream:log", "MAXLEN", "1"); resp != ":2" {
+		t.Fatalf("XTRIM: got %q, want :2", resp)
+	}
+	if resp := s.do("XLEN", "stream:log"); resp != ":1" {
+		t.Fatalf("XLEN: got %q, want :1", resp)
+	}
+}
+
+// Проверяем, что XREAD BLOCK с "$" ждёт и получает только запись, добавленную после начала ожидания
+func TestStreamReadBlock(t *testing.T) {
+	writer, reader := newSession(t), newSession(t)
+	writer.do("DEL", "stream:events")
+	writer.do("XADD", "stream:events", "1-0", "old", "1")
+
+	if _, err := reader.conn.Write([]byte(command("XREAD", "BLOCK", "5000", "STREAMS", "stream:events", "$"))); err != nil {
+		t.Fatalf("failed to send XREAD: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond) // даём клиенту встать в очередь
+
+	writer.do("XADD", "stream:events", "2-0", "new", "2")
+	if got := reader.readReply(); got != "[[stream:events [[2-0 [new 2]]]]]" {
+		t.Fatalf("XREAD BLOCK: got %q", got)
+	}
+
+	start := time.Now()
+	if resp := reader.do("XREAD", "BLOCK", "200", "STREAMS", "stream:events", "$"); resp != "(nil)" {
+		t.Fatalf("XREAD BLOCK timeout: got %q, want nil array", resp)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Fatalf("XREAD BLOCK returned too early: %v", elapsed)
+	}
+}
+
+// Проверяем группу потребителей: XREADGROUP раздаёт записи, они ждут XACK в списке ожидающих,
+// а XCLAIM передаёт зависшую запись другому потребителю
+func TestStreamConsumerGroup(t *testing.T) {
+	s := newSession(t)
+	s.do("DEL", "stream:jobs")
+	if resp := s.do("XGROUP", "CREATE", "stream:jobs", "workers", "$", "MKSTREAM"); resp != "+OK" {
+		t.Fatalf("XGROUP CREATE: got %q", resp)
+	}
+	s.do("XADD", "stream:jobs", "1-0", "job", "a")
+	s.do("XADD", "stream:jobs", "2-0", "job", "b")
+
+	if got := s.do("XREADGROUP", "GROUP", "workers", "alice", "COUNT", "1", "STREAMS", "stream:jobs", ">"); got != "[[stream:jobs [[1-0 [job a]]]]]" {
+		t.Fatalf("XREADGROUP alice: got %q", got)
+	}
+	if got := s.do("XREADGROUP", "GROUP", "workers", "bob", "STREAMS", "stream:jobs", ">"); got != "[[stream:jobs [[2-0 [job b]]]]]" {
+		t.Fatalf("XREADGROUP bob: got %q", got)
+	}
+
+	if got := s.do("XPENDING", "stream:jobs", "workers"); got != "[:2 1-0 2-0 [[alice 1] [bob 1]]]" {
+		t.Fatalf("XPENDING: got %q", got)
+	}
+	if resp := s.do("XACK", "stream:jobs", "workers", "1-0", "1-0"); resp != ":1" {
+		t.Fatalf("XACK: got %q, want :1", resp)
+	}
+
+	// bob не подтвердил запись — её забирает alice
+	if got := s.do("XCLAIM", "stream:jobs", "workers", "alice", "0", "2-0", "JUSTID"); got != "[2-0]" {
+		t.Fatalf("XCLAIM: got %q", got)
+	}
+	if got := s.do("XPENDING", "stream:jobs", "workers", "-", "+", "10"); !strings.HasPrefix(got, "[[2-0 alice :") || !strings.HasSuffix(got, " :1]]") {
+		t.Fatalf("XPENDING after XCLAIM: got %q", got)
+	}
+
+	if resp := s.do("XREADGROUP", "GROUP", "missing", "c", "STREAMS", "stream:jobs", ">"); !strings.HasPrefix(resp, "-NOGROUP") {
+		t.Fatalf("XREADGROUP with missing group: got %q", resp)
+	}
+}
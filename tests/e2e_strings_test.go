@@ -0,0 +1,181 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+)
+
+// Проверяем SET с опциями: NX/XX, PX вместе со значением, GET со старым значением и KEEPTTL
+func TestSetOptions(t *testing.T) {
+	s := newSession(t)
+	s.do("DEL", "str:lock")
+
+	if resp := s.do("SET", "str:lock", "token", "NX", "PX", "30000"); resp != "+OK" {
+		t.Fatalf("SET NX PX: got %q", resp)
+	}
+	if resp := s.do("SET", "str:lock", "other", "NX", "PX", "30000"); resp != "(nil)" {
+		t.Fatalf("second SET NX: got %q, want nil", resp)
+	}
+	if resp := s.do("TTL", "str:lock"); resp != ":29" && resp != ":30" {
+		t.Fatalf("TTL after SET PX: got %q", resp)
+	}
+
+	if resp := s.do("SET", "str:lock", "next", "GET", "KEEPTTL"); resp != "token" {
+		t.Fatalf("SET GET: got %q, want old value", resp)
+	}
+	if resp := s.do("TTL", "str:lock"); resp == ":-1" {
+		t.Fatal("SET KEEPTTL dropped the TTL")
+	}
+	if resp := s.do("SET", "str:lock", "plain"); resp != "+OK" {
+		t.Fatalf("SET: got %q", resp)
+	}
+	if resp := s.do("TTL", "str:lock"); resp != ":-1" {
+		t.Fatalf("plain SET must clear TTL: got %q", resp)
+	}
+
+	if resp := s.do("SET", "str:lock", "v", "EX", "0"); resp != "-ERR invalid expire time in 'set' command" {
+		t.Fatalf("SET EX 0: got %q", resp)
+	}
+	if resp := s.do("SET", "str:lock", "v", "NX", "XX"); resp != "-ERR syntax error" {
+		t.Fatalf("SET NX XX: got %q", resp)
+	}
+}
+
+// Проверяем старые команды SETNX, SETEX, PSETEX и GETSET
+func TestSetLegacyCommands(t *testing.T) {
+	s := newSession(t)
+	s.do("DEL", "str:legacy", "str:list")
+
+	if resp := s.do("SETNX", "str:legacy", "a"); resp != ":1" {
+		t.Fatalf("SETNX: got %q", resp)
+	}
+	if resp := s.do("SETNX", "str:legacy", "b"); resp != ":0" {
+		t.Fatalf("SETNX on existing key: got %q", resp)
+	}
+	if resp := s.do("GETSET", "str:legacy", "c"); resp != "a" {
+		t.Fatalf("GETSET: got %q, want a", resp)
+	}
+
+	if resp := s.do("SETEX", "str:legacy", "100", "d"); resp != "+OK" {
+		t.Fatalf("SETEX: got %q", resp)
+	}
+	if resp := s.do("TTL", "str:legacy"); resp != ":99" && resp != ":100" {
+		t.Fatalf("TTL after SETEX: got %q", resp)
+	}
+	if resp := s.do("PSETEX", "str:legacy", "-5", "e"); resp != "-ERR invalid expire time in 'psetex' command" {
+		t.Fatalf("PSETEX with negative TTL: got %q", resp)
+	}
+
+	s.do("RPUSH", "str:list", "x")
+	if resp := s.do("GETSET", "str:list", "y"); !strings.HasPrefix(resp, "-WRONGTYPE") {
+		t.Fatalf("GETSET on a list: got %q", resp)
+	}
+}
+
+// Проверяем счётчики INCR/DECR/INCRBY/DECRBY/INCRBYFLOAT и их ошибки
+func TestCounters(t *testing.T) {
+	s := newSession(t)
+	s.do("DEL", "str:counter", "str:float", "str:text")
+
+	if resp := s.do("INCR", "str:counter"); resp != ":1" {
+		t.Fatalf("INCR: got %q", resp)
+	}
+	if resp := s.do("INCRBY", "str:counter", "10"); resp != ":11" {
+		t.Fatalf("INCRBY: got %q", resp)
+	}
+	if resp := s.do("DECRBY", "str:counter", "20"); resp != ":-9" {
+		t.Fatalf("DECRBY: got %q", resp)
+	}
+	if resp := s.do("DECR", "str:counter"); resp != ":-10" {
+		t.Fatalf("DECR: got %q", resp)
+	}
+	if resp := s.do("INCRBY", "str:counter", "1.5"); resp != "-ERR value is not an integer or out of range" {
+		t.Fatalf("INCRBY with float: got %q", resp)
+	}
+	if resp := s.do("DECRBY", "str:counter", "-9223372036854775808"); resp != "-ERR decrement would overflow" {
+		t.Fatalf("DECRBY MinInt64: got %q", resp)
+	}
+	s.do("SET", "str:counter", "9223372036854775807")
+	if resp := s.do("INCR", "str:counter"); resp != "-ERR increment or decrement would overflow" {
+		t.Fatalf("INCR overflow: got %q", resp)
+	}
+	s.do("SET", "str:text", "abc")
+	if resp := s.do("INCR", "str:text"); resp != "-ERR value is not an integer or out of range" {
+		t.Fatalf("INCR on text: got %q", resp)
+	}
+
+	if resp := s.do("INCRBYFLOAT", "str:float", "10.5"); resp != "10.5" {
+		t.Fatalf("INCRBYFLOAT: got %q", resp)
+	}
+	if resp := s.do("INCRBYFLOAT", "str:float", "0.1"); resp != "10.6" {
+		t.Fatalf("INCRBYFLOAT: got %q", resp)
+	}
+	if resp := s.do("INCRBYFLOAT", "str:text", "1"); resp != "-ERR value is not a valid float" {
+		t.Fatalf("INCRBYFLOAT on text: got %q", resp)
+	}
+}
+
+// Проверяем APPEND, STRLEN, GETRANGE, SETRANGE, GETDEL, GETEX и MSET/MSETNX
+func TestStringParts(t *testing.T) {
+	s := newSession(t)
+	s.do("DEL", "str:part", "str:pad", "str:m1", "str:m2", "str:m3")
+
+	if resp := s.do("APPEND", "str:part", "Hello"); resp != ":5" {
+		t.Fatalf("APPEND: got %q", resp)
+	}
+	s.do("APPEND", "str:part", " World")
+	if resp := s.do("STRLEN", "str:part"); resp != ":11" {
+		t.Fatalf("STRLEN: got %q", resp)
+	}
+	if resp := s.do("GETRANGE", "str:part", "-5", "-1"); resp != "World" {
+		t.Fatalf("GETRANGE: got %q", resp)
+	}
+	if resp := s.do("SETRANGE", "str:part", "6", "Redis"); resp != ":11" {
+		t.Fatalf("SETRANGE: got %q", resp)
+	}
+	if resp := s.do("GET", "str:part"); resp != "Hello Redis" {
+		t.Fatalf("GET after SETRANGE: got %q", resp)
+	}
+	if resp := s.do("SETRANGE", "str:pad", "-1", "x"); resp != "-ERR offset is out of range" {
+		t.Fatalf("SETRANGE with negative offset: got %q", resp)
+	}
+	if resp := s.do("SETRANGE", "str:pad", "2", "x"); resp != ":3" {
+		t.Fatalf("SETRANGE with padding: got %q", resp)
+	}
+
+	if resp := s.do("GETEX", "str:part", "EX", "100"); resp != "Hello Redis" {
+		t.Fatalf("GETEX EX: got %q", resp)
+	}
+	if resp := s.do("TTL", "str:part"); resp != ":100" && resp != ":99" {
+		t.Fatalf("TTL after GETEX: got %q", resp)
+	}
+	s.do("GETEX", "str:part", "PERSIST")
+	if resp := s.do("TTL", "str:part"); resp != ":-1" {
+		t.Fatalf("TTL after GETEX PERSIST: got %q", resp)
+	}
+	if resp := s.do("GETEX", "str:part", "EX", "1", "PERSIST"); resp != "-ERR syntax error" {
+		t.Fatalf("GETEX with two options: got %q", resp)
+	}
+	if resp := s.do("GETDEL", "str:part"); resp != "Hello Redis" {
+		t.Fatalf("GETDEL: got %q", resp)
+	}
+	if resp := s.do("GETDEL", "str:part"); resp != "(nil)" {
+		t.Fatalf("GETDEL on missing key: got %q", resp)
+	}
+
+	if resp := s.do("MSET", "str:m1", "a", "str:m2"); resp != "-ERR wrong number of arguments for 'mset' command" {
+		t.Fatalf("MSET with odd arguments: got %q", resp)
+	}
+	if resp := s.do("MSET", "str:m1", "a", "str:m2", "b"); resp != "+OK" {
+		t.Fatalf("MSET: got %q", resp)
+	}
+	if resp := s.do("MSETNX", "str:m2", "x", "str:m3", "c"); resp != ":0" {
+		t.Fatalf("MSETNX with existing key: got %q", resp)
+	}
+	if resp := s.do("EXISTS", "str:m3"); resp != ":0" {
+		t.Fatalf("failed MSETNX wrote a key: got %q", resp)
+	}
+	if resp := s.do("MSETNX", "str:m3", "c"); resp != ":1" {
+		t.Fatalf("MSETNX: got %q", resp)
+	}
+}
@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// session — одно соединение с mini-redis, в котором команды выполняются по очереди
+// (нужно там, где важно состояние соединения: MULTI, WATCH, подписки).
+type session struct {
+	t    *testing.T
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// newSession подключается к mini-redis; соединение закрывается по окончании теста.
+func newSession(t *testing.T) *session {
+	conn, err := net.DialTimeout("tcp", "localhost:6379", time.Second)
+	if err != nil {
+		t.Fatalf("cannot connect to mini-redis: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &session{t: t, conn: conn, rd: bufio.NewReader(conn)}
+}
+
+// send отправляет RESP-команду и возвращает первую строку ответа.
+func (s *session) send(cmd string) string {
+	if _, err := s.conn.Write([]byte(cmd)); err != nil {
+		s.t.Fatalf("failed to send command: %v", err)
+	}
+	return s.readLine()
+}
+
+// readLine читает следующую строку ответа без \r\n.
+func (s *session) readLine() string {
+	_ = s.conn.SetReadDeadline(time.Now().Add(time.Second))
+	line, err := s.rd.ReadString('\n')
+	if err != nil {
+		s.t.Fatalf("failed to read response: %v", err)
+	}
+	return strings.TrimSpace(line)
+}
+
+// Проверяем MULTI → QUEUED → EXEC: команды выполняются разом, ответы приходят массивом
+func TestMultiExec(t *testing.T) {
+	s := newSession(t)
+	if resp := s.send("*1\r\n$5\r\nMULTI\r\n"); resp != "+OK" {
+		t.Fatalf("MULTI failed: got %q", resp)
+	}
+	if resp := s.send("*3\r\n$3\r\nSET\r\n$5\r\ntxkey\r\n$3\r\nval\r\n"); resp != "+QUEUED" {
+		t.Fatalf("SET inside MULTI: got %q, want +QUEUED", resp)
+	}
+	if resp := s.send("*2\r\n$3\r\nGET\r\n$5\r\ntxkey\r\n"); resp != "+QUEUED" {
+		t.Fatalf("GET inside MULTI: got %q, want +QUEUED", resp)
+	}
+
+	if resp := s.send("*1\r\n$4\r\nEXEC\r\n"); resp != "*2" {
+		t.Fatalf("EXEC: got %q, want *2", resp)
+	}
+	if resp := s.readLine(); resp != "+OK" {
+		t.Fatalf("EXEC reply for SET: got %q", resp)
+	}
+	if resp := s.readLine() + s.readLine(); resp != "$3val" {
+		t.Fatalf("EXEC reply for GET: got %q", resp)
+	}
+}
+
+// Проверяем, что ошибка при постановке в очередь отменяет всю транзакцию (EXECABORT)
+func TestExecAbort(t *testing.T) {
+	s := newSession(t)
+	s.send("*1\r\n$5\r\nMULTI\r\n")
+	if resp := s.send("*1\r\n$7\r\nNOSUCHC\r\n"); !strings.HasPrefix(resp, "-ERR unknown command") {
+		t.Fatalf("unknown command inside MULTI: got %q", resp)
+	}
+	if resp := s.send("*1\r\n$4\r\nEXEC\r\n"); !strings.HasPrefix(resp, "-EXECABORT") {
+		t.Fatalf("EXEC: got %q, want -EXECABORT", resp)
+	}
+}
+
+// Проверяем DISCARD: очередь сбрасывается, EXEC после него — ошибка
+func TestDiscard(t *testing.T) {
+	s := newSession(t)
+	s.send("*1\r\n$5\r\nMULTI\r\n")
+	s.send("*3\r\n$3\r\nSET\r\n$9\r\ndiscarded\r\n$1\r\n1\r\n")
+	if resp := s.send("*1\r\n$7\r\nDISCARD\r\n"); resp != "+OK" {
+		t.Fatalf("DISCARD failed: got %q", resp)
+	}
+	if resp := s.send("*2\r\n$3\r\nGET\r\n$9\r\ndiscarded\r\n"); resp != "$-1" {
+		t.Fatalf("GET after DISCARD: got %q, want $-1", resp)
+	}
+	if resp := s.send("*1\r\n$4\r\nEXEC\r\n"); resp != "-ERR EXEC without MULTI" {
+		t.Fatalf("EXEC after DISCARD: got %q", resp)
+	}
+}
+
+// Проверяем WATCH: если ключ изменил другой клиент, EXEC возвращает nil-массив
+func TestWatchAbortsExec(t *testing.T) {
+	s := newSession(t)
+	if resp := s.send("*2\r\n$5\r\nWATCH\r\n$8\r\nwatchkey\r\n"); resp != "+OK" {
+		t.Fatalf("WATCH failed: got %q", resp)
+	}
+	if resp := sendCommand(t, "*3\r\n$3\r\nSET\r\n$8\r\nwatchkey\r\n$5\r\nother\r\n"); resp != "+OK" {
+		t.Fatalf("SET from another client failed: got %q", resp)
+	}
+
+	s.send("*1\r\n$5\r\nMULTI\r\n")
+	s.send("*3\r\n$3\r\nSET\r\n$8\r\nwatchkey\r\n$4\r\nmine\r\n")
+	if resp := s.send("*1\r\n$4\r\nEXEC\r\n"); resp != "*-1" {
+		t.Fatalf("EXEC after watched key changed: got %q, want *-1", resp)
+	}
+	if resp := s.send("*2\r\n$3\r\nGET\r\n$8\r\nwatchkey\r\n"); resp != "$5" {
+		t.Fatalf("GET watchkey: got %q, want value of the other client", resp)
+	}
+}
@@ -84,3 +84,66 @@ func TestTTLNonexistentKey(t *testing.T) {
 		t.Fatalf("expected TTL=-2 for nonexistent key, got %v", resp)
 	}
 }
+
+// Проверяем миллисекундные команды TTL, условия NX/XX/GT/LT, EXPIRETIME и PERSIST
+func TestExpireFamily(t *testing.T) {
+	s := newSession(t)
+	s.do("SET", "ttl:k", "v")
+
+	if resp := s.do("PEXPIRE", "ttl:k", "1500", "XX"); resp != ":0" {
+		t.Fatalf("PEXPIRE XX on key without TTL: got %q", resp)
+	}
+	if resp := s.do("PEXPIRE", "ttl:k", "1500"); resp != ":1" {
+		t.Fatalf("PEXPIRE: got %q", resp)
+	}
+	resp := s.do("PTTL", "ttl:k")
+	if ms, err := strconv.Atoi(strings.TrimPrefix(resp, ":")); err != nil || ms <= 1000 || ms > 1500 {
+		t.Fatalf("PTTL: got %q, want about 1500", resp)
+	}
+	if resp := s.do("EXPIRE", "ttl:k", "1", "GT"); resp != ":0" {
+		t.Fatalf("EXPIRE GT with smaller TTL: got %q", resp)
+	}
+	if resp := s.do("EXPIRE", "ttl:k", "1", "NX", "GT"); !strings.HasPrefix(resp, "-ERR NX and XX, GT or LT") {
+		t.Fatalf("EXPIRE NX GT: got %q", resp)
+	}
+
+	if resp := s.do("EXPIREAT", "ttl:k", "4102444800"); resp != ":1" {
+		t.Fatalf("EXPIREAT: got %q", resp)
+	}
+	if resp := s.do("EXPIRETIME", "ttl:k"); resp != ":4102444800" {
+		t.Fatalf("EXPIRETIME: got %q", resp)
+	}
+	if resp := s.do("PEXPIRETIME", "ttl:k"); resp != ":4102444800000" {
+		t.Fatalf("PEXPIRETIME: got %q", resp)
+	}
+
+	if resp := s.do("PERSIST", "ttl:k"); resp != ":1" {
+		t.Fatalf("PERSIST: got %q", resp)
+	}
+	if resp := s.do("TTL", "ttl:k"); resp != ":-1" {
+		t.Fatalf("TTL after PERSIST: got %q", resp)
+	}
+
+	// отрицательный TTL удаляет ключ сразу
+	if resp := s.do("EXPIRE", "ttl:k", "-1"); resp != ":1" {
+		t.Fatalf("EXPIRE -1: got %q", resp)
+	}
+	if resp := s.do("EXPIRETIME", "ttl:k"); resp != ":-2" {
+		t.Fatalf("EXPIRETIME of deleted key: got %q", resp)
+	}
+}
+
+// Проверяем, что истёкший ключ пропадает сразу, не дожидаясь фонового удаления
+func TestLazyExpire(t *testing.T) {
+	s := newSession(t)
+	for i := 0; i < 5; i++ {
+		s.do("SET", "ttl:lazy", "v", "PX", "20")
+		time.Sleep(30 * time.Millisecond)
+		if resp := s.do("GET", "ttl:lazy"); resp != "(nil)" {
+			t.Fatalf("GET of expired key: got %q, want nil", resp)
+		}
+		if resp := s.do("DEL", "ttl:lazy"); resp != ":0" {
+			t.Fatalf("DEL of expired key: got %q, want :0", resp)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+)
+
+// Проверяем ZADD → ZRANGE WITHSCORES, ZRANK и формат дробных счетов
+func TestZsetLeaderboard(t *testing.T) {
+	s := newSession(t)
+	s.send("*2\r\n$3\r\nDEL\r\n$7\r\nzset:lb\r\n")
+	if resp := s.send("*8\r\n$4\r\nZADD\r\n$7\r\nzset:lb\r\n$2\r\n10\r\n$5\r\nalice\r\n$3\r\n2.5\r\n$3\r\nbob\r\n$2\r\n30\r\n$5\r\ncarol\r\n"); resp != ":3" {
+		t.Fatalf("ZADD: got %q, want :3", resp)
+	}
+	if _, err := s.conn.Write([]byte("*5\r\n$6\r\nZRANGE\r\n$7\r\nzset:lb\r\n$1\r\n0\r\n$2\r\n-1\r\n$10\r\nWITHSCORES\r\n")); err != nil {
+		t.Fatalf("failed to send ZRANGE: %v", err)
+	}
+	if got := strings.Join(s.readArray(), " "); got != "bob 2.5 alice 10 carol 30" {
+		t.Fatalf("ZRANGE WITHSCORES: got %q", got)
+	}
+	if resp := s.send("*3\r\n$8\r\nZREVRANK\r\n$7\r\nzset:lb\r\n$5\r\nalice\r\n"); resp != ":1" {
+		t.Fatalf("ZREVRANK: got %q, want :1", resp)
+	}
+	if resp := s.send("*4\r\n$7\r\nZINCRBY\r\n$7\r\nzset:lb\r\n$3\r\n0.1\r\n$3\r\nbob\r\n"); resp != "$3" || s.readLine() != "2.6" {
+		t.Fatalf("ZINCRBY: unexpected reply %q", resp)
+	}
+}
+
+// Проверяем ZRANGE BYSCORE REV LIMIT и ZADD с флагами
+func TestZsetRangeByScore(t *testing.T) {
+	s := newSession(t)
+	s.send("*2\r\n$3\r\nDEL\r\n$6\r\nzset:q\r\n")
+	s.send("*10\r\n$4\r\nZADD\r\n$6\r\nzset:q\r\n$1\r\n1\r\n$1\r\na\r\n$1\r\n2\r\n$1\r\nb\r\n$1\r\n3\r\n$1\r\nc\r\n$1\r\n4\r\n$1\r\nd\r\n")
+	if _, err := s.conn.Write([]byte("*9\r\n$6\r\nZRANGE\r\n$6\r\nzset:q\r\n$2\r\n(4\r\n$1\r\n1\r\n$7\r\nBYSCORE\r\n$3\r\nREV\r\n$5\r\nLIMIT\r\n$1\r\n0\r\n$1\r\n2\r\n")); err != nil {
+		t.Fatalf("failed to send ZRANGE: %v", err)
+	}
+	if got := strings.Join(s.readArray(), " "); got != "c b" {
+		t.Fatalf("ZRANGE BYSCORE REV LIMIT: got %q, want \"c b\"", got)
+	}
+
+	if resp := s.send("*6\r\n$4\r\nZADD\r\n$6\r\nzset:q\r\n$2\r\nGT\r\n$2\r\nCH\r\n$1\r\n0\r\n$1\r\na\r\n"); resp != ":0" {
+		t.Fatalf("ZADD GT with lower score: got %q, want :0", resp)
+	}
+	if resp := s.send("*6\r\n$4\r\nZADD\r\n$6\r\nzset:q\r\n$2\r\nNX\r\n$2\r\nXX\r\n$1\r\n0\r\n$1\r\na\r\n"); !strings.HasPrefix(resp, "-ERR XX and NX") {
+		t.Fatalf("ZADD NX XX: got %q", resp)
+	}
+}